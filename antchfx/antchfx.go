@@ -0,0 +1,107 @@
+// Package antchfx bridges the node types of the antchfx packages
+// (htmlquery and xmlquery) with the selectors of cascadia, so that
+// their parsed trees may be queried without being parsed again.
+//
+// htmlquery works on *html.Node, which is directly supported by
+// cascadia.Query and cascadia.QueryAll; this package provides the
+// adapter for the *xmlquery.Node type.
+//
+// It lives in a separate module so that the main package does not
+// depend on the antchfx packages.
+package antchfx
+
+import (
+	"strings"
+
+	"github.com/antchfx/xmlquery"
+	"github.com/benoitkugler/cascadia"
+	"golang.org/x/net/html"
+)
+
+// xmlNode is the cascadia.Node adapter for *xmlquery.Node.
+type xmlNode struct{ n *xmlquery.Node }
+
+// FromXML returns the cascadia.Node wrapping n, or nil if n is nil.
+//
+// Since the parser lower-cases the tag and attribute names of the
+// selectors, the adapter returns lower-cased local names. The namespace
+// of the nodes is their namespace URI.
+func FromXML(n *xmlquery.Node) cascadia.Node {
+	if n == nil {
+		return nil
+	}
+	return xmlNode{n}
+}
+
+// ToXML returns the *xmlquery.Node wrapped by n, or nil if
+// n was not built by FromXML.
+func ToXML(n cascadia.Node) *xmlquery.Node {
+	if x, ok := n.(xmlNode); ok {
+		return x.n
+	}
+	return nil
+}
+
+func (x xmlNode) Type() html.NodeType {
+	switch x.n.Type {
+	case xmlquery.DocumentNode:
+		return html.DocumentNode
+	case xmlquery.ElementNode:
+		return html.ElementNode
+	case xmlquery.TextNode, xmlquery.CharDataNode:
+		return html.TextNode
+	default: // comments, declarations and processing instructions, as for x/net/html
+		return html.CommentNode
+	}
+}
+
+func (x xmlNode) Data() string {
+	if x.n.Type == xmlquery.ElementNode {
+		return strings.ToLower(x.n.Data)
+	}
+	return x.n.Data
+}
+
+func (x xmlNode) Namespace() string { return x.n.NamespaceURI }
+
+func (x xmlNode) Attributes() []html.Attribute {
+	if len(x.n.Attr) == 0 {
+		return nil
+	}
+	attrs := make([]html.Attribute, len(x.n.Attr))
+	for i, a := range x.n.Attr {
+		attrs[i] = html.Attribute{Namespace: a.NamespaceURI, Key: strings.ToLower(a.Name.Local), Val: a.Value}
+	}
+	return attrs
+}
+
+func (x xmlNode) Parent() cascadia.Node      { return FromXML(x.n.Parent) }
+func (x xmlNode) FirstChild() cascadia.Node  { return FromXML(x.n.FirstChild) }
+func (x xmlNode) LastChild() cascadia.Node   { return FromXML(x.n.LastChild) }
+func (x xmlNode) PrevSibling() cascadia.Node { return FromXML(x.n.PrevSibling) }
+func (x xmlNode) NextSibling() cascadia.Node { return FromXML(x.n.NextSibling) }
+
+// QueryAll returns the elements matching m, from the descendants of top.
+func QueryAll(top *xmlquery.Node, m cascadia.NodeMatcher) []*xmlquery.Node {
+	var out []*xmlquery.Node
+	for _, n := range cascadia.QueryAllNodes(FromXML(top), m) {
+		out = append(out, ToXML(n))
+	}
+	return out
+}
+
+// Query returns the first element matching m, from the descendants of top,
+// or nil.
+func Query(top *xmlquery.Node, m cascadia.NodeMatcher) *xmlquery.Node {
+	return ToXML(cascadia.QueryNode(FromXML(top), m))
+}
+
+// Find parses selector as a group and returns the elements matching it,
+// from the descendants of top, like xmlquery.QueryAll does for XPath expressions.
+func Find(top *xmlquery.Node, selector string) ([]*xmlquery.Node, error) {
+	group, err := cascadia.ParseGroup(selector)
+	if err != nil {
+		return nil, err
+	}
+	return QueryAll(top, group), nil
+}
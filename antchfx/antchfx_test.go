@@ -0,0 +1,75 @@
+package antchfx
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/antchfx/xmlquery"
+	"github.com/benoitkugler/cascadia"
+)
+
+const testFeed = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom" xmlns:media="http://search.yahoo.com/mrss/">
+	<title>Example</title>
+	<!-- entries -->
+	<entry id="a"><title>First</title><link rel="alternate" href="/1"/></entry>
+	<entry id="b" class="hot"><title>Second</title><media:thumbnail url="t.png"/></entry>
+	<svg viewBox="0 0 10 10"><linearGradient id="g"/></svg>
+</feed>`
+
+func TestXMLQuery(t *testing.T) {
+	doc, err := xmlquery.Parse(strings.NewReader(testFeed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		sel string
+		exp []string // text content or local names
+	}{
+		{"entry > title", []string{"First", "Second"}},
+		{"entry.hot > title", []string{"Second"}},
+		{"entry:nth-child(3) title", []string{"Second"}},
+		{"link[rel=alternate]", []string{"link"}},
+		{"thumbnail[url$=png]", []string{"thumbnail"}},
+		{"entry + entry > :first-child", []string{"Second"}},
+		{"title:contains(sec)", []string{"Second"}},
+		{"entry:has(link) title", []string{"First"}},
+		{"svg[viewBox] lineargradient", []string{"linearGradient"}},
+		{":root", []string{"feed"}},
+		{"title:empty", nil},
+	} {
+		nodes, err := Find(doc, test.sel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, n := range nodes {
+			if n.Data == "title" {
+				got = append(got, n.InnerText())
+			} else {
+				got = append(got, n.Data)
+			}
+		}
+		if strings.Join(got, ",") != strings.Join(test.exp, ",") {
+			t.Errorf("%s: expected %v, got %v", test.sel, test.exp, got)
+		}
+	}
+
+	group, err := cascadia.ParseGroup("thumbnail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	thumb := Query(doc, group)
+	if thumb == nil || FromXML(thumb).Namespace() != "http://search.yahoo.com/mrss/" {
+		t.Errorf("unexpected thumbnail %v", thumb)
+	}
+	if Query(doc, cascadia.SelectorGroup{cascadia.TagSelector{Tag: "nope"}}) != nil {
+		t.Error("expected no match")
+	}
+	if ToXML(cascadia.FromHTML(nil)) != nil || FromXML(nil) != nil {
+		t.Error("expected nil nodes")
+	}
+	if _, err := Find(doc, "entry["); err == nil {
+		t.Error("expected an error for an invalid selector")
+	}
+}
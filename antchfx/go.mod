@@ -0,0 +1,17 @@
+module github.com/benoitkugler/cascadia/antchfx
+
+go 1.20
+
+require (
+	github.com/antchfx/xmlquery v1.5.1
+	github.com/benoitkugler/cascadia v0.0.0
+	golang.org/x/net v0.33.0
+)
+
+require (
+	github.com/antchfx/xpath v1.3.6 // indirect
+	github.com/golang/groupcache v0.0.0-20210331224755-41bb18bfe9da // indirect
+	golang.org/x/text v0.21.0 // indirect
+)
+
+replace github.com/benoitkugler/cascadia => ../
@@ -0,0 +1,181 @@
+package cascadia
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// AuditCheck is an accessibility check of an Auditor: the elements matching
+// Selector, accepted by Predicate and Filter, are reported.
+type AuditCheck struct {
+	Name     string // like "img-alt"
+	Message  string
+	Severity Severity
+	Selector string
+	// Predicate, if not nil, is called for each match, and
+	// returns true if it is an issue.
+	Predicate func(n *html.Node) bool
+	// Filter, if not nil, selects the issues among all the matches
+	// (accepted by Predicate), in tree order. It is used by the checks
+	// about several elements, like duplicate ids.
+	Filter func(matches []*html.Node) []*html.Node
+}
+
+// AuditFinding is an issue reported by an Auditor.
+type AuditFinding struct {
+	Check    string // the Name of the check
+	Severity Severity
+	Message  string
+	Node     *html.Node
+}
+
+func (f AuditFinding) String() string {
+	return fmt.Sprintf("%s: %s (%s) at %s", f.Severity, f.Message, f.Check, f.Node.Data)
+}
+
+// Auditor runs a list of accessibility checks against documents.
+type Auditor struct {
+	checks []compiledCheck
+}
+
+type compiledCheck struct {
+	AuditCheck
+	sel SelectorGroup
+}
+
+// NewAuditor compiles the selectors of the checks. Without checks,
+// DefaultAuditChecks are used.
+func NewAuditor(checks ...AuditCheck) (*Auditor, error) {
+	if len(checks) == 0 {
+		checks = DefaultAuditChecks
+	}
+	a := &Auditor{checks: make([]compiledCheck, len(checks))}
+	for i, c := range checks {
+		sel, err := ParseGroup(c.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("audit check %s: %w", c.Name, err)
+		}
+		a.checks[i] = compiledCheck{AuditCheck: c, sel: sel}
+	}
+	return a, nil
+}
+
+// Audit returns the issues found in the descendants of root,
+// grouped by check, in the order of the checks, then in tree order.
+func (a *Auditor) Audit(root *html.Node) []AuditFinding {
+	var out []AuditFinding
+	for _, c := range a.checks {
+		matches := QueryAll(root, c.sel)
+		if c.Predicate != nil {
+			kept := matches[:0]
+			for _, n := range matches {
+				if c.Predicate(n) {
+					kept = append(kept, n)
+				}
+			}
+			matches = kept
+		}
+		if c.Filter != nil {
+			matches = c.Filter(matches)
+		}
+		for _, n := range matches {
+			out = append(out, AuditFinding{Check: c.Name, Severity: c.Severity, Message: c.Message, Node: n})
+		}
+	}
+	return out
+}
+
+// DefaultAuditChecks are the checks used by NewAuditor
+// when none is given.
+var DefaultAuditChecks = []AuditCheck{
+	{
+		Name: "img-alt", Severity: SeverityWarning,
+		Message:  "image without alternative text",
+		Selector: "img:not([alt], [aria-label], [aria-labelledby], [role=presentation], [role=none])",
+	},
+	{
+		Name: "link-name", Severity: SeverityWarning,
+		Message:   "link without accessible name",
+		Selector:  "a[href]:not([aria-label], [aria-labelledby], [title])",
+		Predicate: hasNoAccessibleContent,
+	},
+	{
+		Name: "button-name", Severity: SeverityWarning,
+		Message:   "button without accessible name",
+		Selector:  "button:not([aria-label], [aria-labelledby], [title])",
+		Predicate: hasNoAccessibleContent,
+	},
+	{
+		Name: "input-label", Severity: SeverityWarning,
+		Message:   "form field without label",
+		Selector:  "input:not([type=hidden], [type=submit], [type=reset], [type=button], [type=image], [aria-label], [aria-labelledby], [title]), select:not([aria-label], [aria-labelledby], [title]), textarea:not([aria-label], [aria-labelledby], [title])",
+		Predicate: hasNoLabel,
+	},
+	{
+		Name: "html-lang", Severity: SeverityWarning,
+		Message:  "document without language",
+		Selector: "html:not([lang])",
+	},
+	{
+		Name: "duplicate-id", Severity: SeverityWarning,
+		Message:  "id used by an earlier element",
+		Selector: "[id]",
+		Filter:   duplicateIDs,
+	},
+}
+
+var (
+	labels           = MustCompile("label")
+	accessibleImages = MustCompile("img[alt]:not([alt='']), [aria-label]")
+)
+
+// hasNoAccessibleContent returns true if n has no text
+// and no image with an alternative text
+func hasNoAccessibleContent(n *html.Node) bool {
+	if strings.TrimSpace(nodeText(FromHTML(n))) != "" {
+		return false
+	}
+	return Query(n, accessibleImages) == nil
+}
+
+// hasNoLabel returns true if the form field n is neither
+// in a label, nor referenced by a label of its document
+func hasNoLabel(n *html.Node) bool {
+	root := n
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.Type == html.ElementNode && p.Data == "label" {
+			return false
+		}
+		root = p
+	}
+	id := getAttr(n, "id")
+	if id == "" {
+		return true
+	}
+	for _, l := range QueryAll(root, labels) {
+		if getAttr(l, "for") == id {
+			return false
+		}
+	}
+	return true
+}
+
+// duplicateIDs returns the elements whose id is used
+// by a previous element
+func duplicateIDs(matches []*html.Node) []*html.Node {
+	seen := map[string]bool{}
+	var out []*html.Node
+	for _, n := range matches {
+		id := getAttr(n, "id")
+		if id == "" {
+			continue
+		}
+		if seen[id] {
+			out = append(out, n)
+		}
+		seen[id] = true
+	}
+	return out
+}
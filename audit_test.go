@@ -0,0 +1,54 @@
+package cascadia
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestAuditor(t *testing.T) {
+	doc := MustParseHTML(`<html><body>
+	<img src="a.png"><img src="b.png" alt="">
+	<a href="/1"></a><a href="/2">home</a><a href="/3"><img src="c.png" alt="logo"></a>
+	<button id="b"></button><button>ok</button>
+	<label>Name <input name="n"></label><label for="e">Mail</label><input id="e"><input id="x"><input type="hidden">
+	<p id="b"></p>
+	</body></html>`)
+	a, err := NewAuditor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range a.Audit(doc) {
+		got = append(got, f.Check+":"+f.Node.Data+"#"+getAttr(f.Node, "id"))
+	}
+	expected := "img-alt:img# link-name:a# button-name:button#b input-label:input#x html-lang:html# duplicate-id:p#b"
+	if s := strings.Join(got, " "); s != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, s)
+	}
+}
+
+func TestAuditorCustomCheck(t *testing.T) {
+	check := AuditCheck{
+		Name: "table-caption", Message: "table without caption", Severity: SeverityInfo,
+		Selector:  "table",
+		Predicate: func(n *html.Node) bool { return Query(n, MustCompile("caption")) == nil },
+	}
+	a, err := NewAuditor(check)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := MustParseHTML(`<table><caption>c</caption></table><table></table>`)
+	findings := a.Audit(doc)
+	if len(findings) != 1 || findings[0].Check != "table-caption" || findings[0].Severity != SeverityInfo {
+		t.Fatalf("unexpected findings %v", findings)
+	}
+	if s := findings[0].String(); s != "info: table without caption (table-caption) at table" {
+		t.Errorf("unexpected string %q", s)
+	}
+
+	if _, err := NewAuditor(AuditCheck{Name: "bad", Selector: "a["}); err == nil || !strings.Contains(err.Error(), "bad") {
+		t.Errorf("expected an error naming the check, got %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package cascadia
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 
@@ -51,3 +52,46 @@ func BenchmarkMatchAll(b *testing.B) {
 	}
 	_ = matches
 }
+
+func BenchmarkBulkMatcher(b *testing.B) {
+	var m BulkMatcher
+	for i := 0; i < 20000; i++ {
+		var sel string
+		switch i % 4 {
+		case 0:
+			sel = fmt.Sprintf(".ad-%d", i)
+		case 1:
+			sel = fmt.Sprintf("#banner-%d", i)
+		case 2:
+			sel = fmt.Sprintf(`div[id^="sponsor-%d"]`, i)
+		default:
+			sel = fmt.Sprintf("a[href*=track%d] > img", i)
+		}
+		if _, err := m.Add(sel); err != nil {
+			b.Fatal(err)
+		}
+	}
+	elements := QueryAll(dom, SelectorGroup{CompoundSelector{}})
+	b.ResetTimer()
+	var matches []int
+	for i := 0; i < b.N; i++ {
+		for _, n := range elements {
+			matches = m.AppendMatches(matches[:0], n)
+		}
+	}
+	_ = matches
+}
+
+func BenchmarkQueryAllMulti(b *testing.B) {
+	matchers := make(map[string]Matcher)
+	for i := 0; i < 40; i++ {
+		matchers[fmt.Sprintf("field-%d", i)] = MustParse(fmt.Sprintf("div.matched-%d > span", i))
+	}
+	matchers["matched"] = MustParse("div.matched")
+	b.ResetTimer()
+	var matches map[string][]*html.Node
+	for i := 0; i < b.N; i++ {
+		matches = QueryAllMulti(dom, matchers)
+	}
+	_ = matches
+}
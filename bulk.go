@@ -0,0 +1,370 @@
+package cascadia
+
+import (
+	"bufio"
+	"io"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// BulkMatcher matches elements against a large list of selectors, like
+// the element-hiding rules of ad-block filter lists, which are mostly
+// made of a single class, id or attribute.
+//
+// The selectors are stored in hashed buckets, by the id, class, attribute
+// name or tag required by their key selector, and a fallback list for the
+// other ones, so that only a few of them are tried for each element.
+// The selectors made of a single id, class or tag are matched by the
+// lookup itself.
+// The zero value is an empty matcher, ready to use.
+type BulkMatcher struct {
+	sels []Sel
+
+	ids, classes, attrs, tags map[string][]bulkEntry
+	fallback                  []bulkEntry
+}
+
+type bulkEntry struct {
+	index  int
+	simple bool // the bucket lookup is enough
+}
+
+// Add parses a single selector and adds it to the matcher,
+// returning its index.
+func (b *BulkMatcher) Add(selector string) (int, error) {
+	sel, err := Parse(selector)
+	if err != nil {
+		return 0, err
+	}
+	return b.AddSelector(sel), nil
+}
+
+// AddSelector is like Add, for an already parsed selector.
+func (b *BulkMatcher) AddSelector(sel Sel) int {
+	entry := bulkEntry{index: len(b.sels)}
+	b.sels = append(b.sels, sel)
+
+	if _, ok := nodePseudoElement(sel.PseudoElement()); ok {
+		// the nodes selected are not the elements matched by the key selector
+		b.fallback = append(b.fallback, entry)
+		return entry.index
+	}
+
+	key := KeySelector(sel)
+	components := []Sel{key}
+	if c, ok := key.(CompoundSelector); ok {
+		components = c.Selectors
+	}
+	_, combined := sel.(CombinedSelector)
+	_, extracted := attrPseudoElement(sel.PseudoElement()) // requires the attribute
+	entry.simple = !combined && !extracted && len(components) == 1
+
+	// choose the most selective component, preferring ids, classes, then attributes, then tags
+	var (
+		bucket *map[string][]bulkEntry
+		value  string
+		rank   int
+	)
+	for _, c := range components {
+		switch c := c.(type) {
+		case IDSelector:
+			// the bucket lookups are case-sensitive
+			if !c.quirks && rank < 4 {
+				bucket, value, rank = &b.ids, c.ID, 4
+			}
+		case ClassSelector:
+			if !c.quirks && rank < 3 {
+				bucket, value, rank = &b.classes, c.Class, 3
+			}
+		case AttrSelector:
+			// != and #= may match elements without the attribute (#= with an empty regexp)
+			if c.Operation != "!=" && c.Operation != "#=" && rank < 2 {
+				bucket, value, rank = &b.attrs, c.Key, 2
+			}
+			entry.simple = entry.simple && c.Operation == "" // presence only
+		case TagSelector:
+			if rank < 1 {
+				bucket, value, rank = &b.tags, c.Tag, 1
+			}
+		}
+	}
+	if bucket == nil {
+		entry.simple = false
+		b.fallback = append(b.fallback, entry)
+		return entry.index
+	}
+	if *bucket == nil {
+		*bucket = make(map[string][]bulkEntry)
+	}
+	(*bucket)[value] = append((*bucket)[value], entry)
+	return entry.index
+}
+
+// Len returns the number of selectors of the matcher.
+func (b *BulkMatcher) Len() int { return len(b.sels) }
+
+// Selector returns the selector with the given index.
+func (b *BulkMatcher) Selector(index int) Sel { return b.sels[index] }
+
+// Match returns true if at least one selector matches n,
+// so that a BulkMatcher may be used with Query and QueryAll.
+func (b *BulkMatcher) Match(n *html.Node) bool {
+	found := false
+	b.candidates(n, func(e bulkEntry) bool {
+		found = e.simple || b.sels[e.index].Match(n)
+		return found
+	})
+	return found
+}
+
+// MatchAll returns the indices of the selectors matching n,
+// in ascending order.
+func (b *BulkMatcher) MatchAll(n *html.Node) []int {
+	return b.AppendMatches(nil, n)
+}
+
+// AppendMatches is like MatchAll, but appends the indices to dst,
+// which may be used to avoid allocations.
+func (b *BulkMatcher) AppendMatches(dst []int, n *html.Node) []int {
+	start := len(dst)
+	b.candidates(n, func(e bulkEntry) bool {
+		if e.simple || b.sels[e.index].Match(n) {
+			dst = append(dst, e.index)
+		}
+		return false
+	})
+	sort.Ints(dst[start:])
+	return dst
+}
+
+// candidates calls f with the selectors which may match n,
+// until f returns true. Each selector is given at most once.
+func (b *BulkMatcher) candidates(n *html.Node, f func(bulkEntry) bool) {
+	if n == nil {
+		return
+	}
+	switch n.Type {
+	case html.TextNode, html.CommentNode:
+		// only matched by the node pseudo-elements, like ::text
+		for _, e := range b.fallback {
+			if f(e) {
+				return
+			}
+		}
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+	// the attribute names of the foreign elements are case-sensitive (see attrName),
+	// whereas the buckets use the lower-cased names: the selectors must be checked
+	foreign := n.Namespace == "svg" || n.Namespace == "math"
+	try := func(entries []bulkEntry) bool {
+		for _, e := range entries {
+			if f(e) {
+				return true
+			}
+		}
+		return false
+	}
+	var seenIDs, seenClasses, seenAttrs []string
+	for _, a := range n.Attr {
+		key := a.Key
+		if foreign {
+			key = toLowerASCII(key)
+		}
+		if containsString(seenAttrs, key) {
+			continue
+		}
+		seenAttrs = append(seenAttrs, key)
+		entries := b.attrs[key]
+		if foreign {
+			checked := make([]bulkEntry, len(entries))
+			for i, e := range entries {
+				checked[i] = bulkEntry{index: e.index}
+			}
+			entries = checked
+		}
+		if try(entries) {
+			return
+		}
+	}
+	for _, a := range n.Attr {
+		switch a.Key {
+		case "id":
+			if containsString(seenIDs, a.Val) {
+				continue
+			}
+			seenIDs = append(seenIDs, a.Val)
+			if try(b.ids[a.Val]) {
+				return
+			}
+		case "class":
+			for _, c := range SplitTokens(a.Val) {
+				if containsString(seenClasses, c) {
+					continue
+				}
+				seenClasses = append(seenClasses, c)
+				if try(b.classes[c]) {
+					return
+				}
+			}
+		}
+	}
+	if try(b.tags[n.Data]) {
+		return
+	}
+	try(b.fallback)
+}
+
+// QueryAllMulti is like calling QueryAll(n, m) for each matcher of
+// matchers, but walks the descendants of n once, returning the matches
+// of each key of matchers, in tree order. The keys without match are omitted.
+//
+// The selectors (Sel and SelectorGroup) are indexed in a BulkMatcher, so
+// that only a few of them are tried for each element; the other matchers
+// are tried on every node.
+func QueryAllMulti(n *html.Node, matchers map[string]Matcher) map[string][]*html.Node {
+	root := FromHTML(n)
+	type keyed struct {
+		key string
+		m   Matcher
+	}
+	var (
+		bulk   BulkMatcher
+		owners []string // the key of each selector of bulk
+		others []keyed
+	)
+	for key, m := range matchers {
+		switch m := bindRoot(m, root).(type) {
+		case Sel:
+			bulk.AddSelector(m)
+			owners = append(owners, key)
+		case SelectorGroup:
+			for _, sel := range m {
+				bulk.AddSelector(sel)
+				owners = append(owners, key)
+			}
+		default:
+			others = append(others, keyed{key, m})
+		}
+	}
+
+	out := make(map[string][]*html.Node)
+	var matches []int
+	var visit func(n *html.Node)
+	visit = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			matches = bulk.AppendMatches(matches[:0], c)
+			for _, i := range matches {
+				key := owners[i]
+				// several members of a group may match
+				if l := out[key]; len(l) == 0 || l[len(l)-1] != c {
+					out[key] = append(l, c)
+				}
+			}
+			for _, o := range others {
+				if o.m.Match(c) {
+					out[o.key] = append(out[o.key], c)
+				}
+			}
+			visit(c)
+		}
+	}
+	visit(n)
+	return out
+}
+
+// HidingRule is an element-hiding rule of an ad-block filter list,
+// like example.com,~ads.example.com##.banner.
+type HidingRule struct {
+	// Domains are the domains where the rule applies (all if empty), and
+	// ExcludedDomains the ones where it does not (written with a ~).
+	Domains, ExcludedDomains []string
+	// Exception is true for the rules written with #@#, which
+	// disable the hiding rules with the same selector.
+	Exception bool
+	Selector  string
+	Line      int // the line number in the list, starting at 1
+}
+
+// ParseHidingRules reads the element-hiding rules of an EasyList-style
+// filter list. The comments, the network rules, and the extended
+// syntaxes (#?# and #$#) are ignored. The selectors are not validated:
+// use BulkMatcher.Add to compile them.
+func ParseHidingRules(r io.Reader) ([]HidingRule, error) {
+	var out []HidingRule
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || text[0] == '!' || text[0] == '[' {
+			continue
+		}
+		rule := HidingRule{Line: line}
+		i := strings.Index(text, "##")
+		sepLength := 2
+		if j := strings.Index(text, "#@#"); j != -1 && (i == -1 || j < i) {
+			i, sepLength, rule.Exception = j, 3, true
+		}
+		if i == -1 {
+			continue
+		}
+		rule.Selector = strings.TrimSpace(text[i+sepLength:])
+		if rule.Selector == "" {
+			continue
+		}
+		for _, d := range strings.Split(text[:i], ",") {
+			if d = strings.TrimSpace(d); d == "" {
+				continue
+			}
+			if d[0] == '~' {
+				rule.ExcludedDomains = append(rule.ExcludedDomains, d[1:])
+			} else {
+				rule.Domains = append(rule.Domains, d)
+			}
+		}
+		out = append(out, rule)
+	}
+	return out, scanner.Err()
+}
+
+// UnusedSelectors returns the indices of the selectors of b which
+// match no element in any of the documents, in ascending order.
+// It may be used to find the dead rules of a style sheet: the selectors
+// with a pseudo-element, added with AddSelector, are used
+// when their element is matched.
+func (b *BulkMatcher) UnusedSelectors(docs ...*html.Node) []int {
+	used := make([]bool, len(b.sels))
+	remaining := len(b.sels)
+	var matches []int
+	var visit func(n *html.Node)
+	visit = func(n *html.Node) {
+		for c := n.FirstChild; c != nil && remaining > 0; c = c.NextSibling {
+			matches = b.AppendMatches(matches[:0], c)
+			for _, i := range matches {
+				if !used[i] {
+					used[i] = true
+					remaining--
+				}
+			}
+			visit(c)
+		}
+	}
+	for _, doc := range docs {
+		if remaining == 0 {
+			break
+		}
+		visit(doc)
+	}
+	var out []int
+	for i, u := range used {
+		if !u {
+			out = append(out, i)
+		}
+	}
+	return out
+}
@@ -0,0 +1,206 @@
+package cascadia
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+const testFilterList = `[Adblock Plus 2.0]
+! Title: test list
+||ads.example.com^$third-party
+##.ad
+##.ad
+##  #banner
+##div[id^="ad-"]
+##[data-ad]
+##a[href*="track"] > img
+example.com,~shop.example.com##.sponsored
+example.org#@#.ad
+example.net#?#div:-abp-has(> .ad)
+##:root > body > aside
+##p:not([class])
+##[class!="x"]
+##DIV.promo.big
+`
+
+func TestBulkMatcher(t *testing.T) {
+	rules, err := ParseHidingRules(strings.NewReader(testFilterList))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 12 {
+		t.Fatalf("expected 12 rules, got %d", len(rules))
+	}
+	if r := rules[6]; r.Selector != ".sponsored" || fmt.Sprint(r.Domains, r.ExcludedDomains) != "[example.com] [shop.example.com]" || r.Line != 10 {
+		t.Errorf("unexpected rule %+v", r)
+	}
+	if r := rules[7]; !r.Exception || r.Selector != ".ad" {
+		t.Errorf("unexpected exception %+v", r)
+	}
+
+	var b BulkMatcher
+	for _, r := range rules {
+		if r.Exception {
+			continue
+		}
+		if _, err := b.Add(r.Selector); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := b.Add("div["); err == nil {
+		t.Error("expected an error for an invalid selector")
+	}
+
+	doc := MustParseHTML(`<div class="ad x ad" id="banner"><p>text</p></div>
+		<div id="ad-1" data-ad=""><a href="/track"><img></a></div>
+		<aside class="sponsored promo BIG"></aside><div class="promo big"></div><p class="x"></p>`)
+	for _, test := range []struct {
+		sel string
+		exp string
+	}{
+		{"div.ad", "[0 1 2 9]"},
+		{"div.ad > p", "[8 9]"},
+		{"#ad-1", "[3 4 9]"},
+		{"img", "[5 9]"},
+		{"aside", "[6 7 9]"},
+		{"div.promo", "[9 10]"},
+		{"p.x", "[]"},
+	} {
+		n := Query(doc, MustCompile(test.sel))
+		got := fmt.Sprint(b.MatchAll(n))
+		if got != test.exp {
+			t.Errorf("%s: expected %s, got %s", test.sel, test.exp, got)
+		}
+		if b.Match(n) != (test.exp != "[]") {
+			t.Errorf("%s: inconsistent Match", test.sel)
+		}
+	}
+	if b.MatchAll(doc) != nil {
+		t.Error("expected no match for the document")
+	}
+	if got := len(QueryAll(doc, &b)); got != 10 {
+		t.Errorf("expected 10 hidden elements, got %d", got)
+	}
+}
+
+// TestBulkMatcherIndex checks that the buckets give the same
+// result as trying every selector
+func TestBulkMatcherIndex(t *testing.T) {
+	for _, test := range selectorTests {
+		doc, err := html.Parse(strings.NewReader(test.HTML))
+		if err != nil {
+			t.Fatal(err)
+		}
+		group, err := ParseGroup(test.selector)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var b BulkMatcher
+		for _, sel := range group {
+			b.AddSelector(sel)
+		}
+		for _, n := range QueryAll(doc, SelectorGroup{CompoundSelector{}}) {
+			var exp []int
+			for i, sel := range group {
+				if sel.Match(n) {
+					exp = append(exp, i)
+				}
+			}
+			if got := b.MatchAll(n); fmt.Sprint(got) != fmt.Sprint(exp) {
+				t.Errorf("%s: expected %v, got %v", test.selector, exp, got)
+			}
+		}
+	}
+}
+
+func TestUnusedSelectors(t *testing.T) {
+	var b BulkMatcher
+	for _, sel := range []string{"p", ".a", "#x", "div > p.b", "section", "li:nth-child(2)", "span"} {
+		if _, err := b.Add(sel); err != nil {
+			t.Fatal(err)
+		}
+	}
+	doc1 := MustParseHTML(`<p class="a">1</p><ul><li>a</li><li>b</li></ul>`)
+	doc2 := MustParseHTML(`<div><p class="b" id="x"></p></div>`)
+	if got := fmt.Sprint(b.UnusedSelectors(doc1, doc2)); got != "[4 6]" {
+		t.Errorf("unexpected unused selectors %s", got)
+	}
+	if got := fmt.Sprint(b.UnusedSelectors(doc1)); got != "[2 3 4 6]" {
+		t.Errorf("unexpected unused selectors %s", got)
+	}
+	if got := len(b.UnusedSelectors()); got != b.Len() {
+		t.Errorf("expected all the selectors to be unused, got %d", got)
+	}
+}
+
+// checkQueryAllMulti compares QueryAllMulti with QueryAll
+func checkQueryAllMulti(t *testing.T, doc *html.Node, matchers map[string]Matcher) map[string][]*html.Node {
+	t.Helper()
+	got := QueryAllMulti(doc, matchers)
+	for key, m := range matchers {
+		expected := QueryAll(doc, m)
+		if len(got[key]) != len(expected) {
+			t.Errorf("%s: expected %d matches, got %d", key, len(expected), len(got[key]))
+			continue
+		}
+		for i := range expected {
+			if got[key][i] != expected[i] {
+				t.Errorf("%s: unexpected match %d", key, i)
+			}
+		}
+	}
+	return got
+}
+
+func TestQueryAllMulti(t *testing.T) {
+	doc := parseReference("test_ressources/shakespeare.html")
+	matchers := map[string]Matcher{
+		"tags":     MustParse("div"),
+		"group":    MustParseGroup("p, .dialog, div.dialog"),
+		"combined": MustParse("div > div"),
+		"never":    MustParse("#missing"),
+		"legacy":   MustCompile("div.scene"),
+		"func":     MatcherFunc(func(n *html.Node) bool { return n.Type == html.TextNode }),
+	}
+	got := checkQueryAllMulti(t, doc, matchers)
+	if _, ok := got["never"]; ok {
+		t.Error("expected no entry without match")
+	}
+
+	// the node pseudo-elements select text and comment nodes
+	nodes := map[string]Matcher{}
+	for _, input := range []string{"p::text", "div > p::text", "body::comment", "::comment", ".dialog::text"} {
+		sel, err := ParseWithOptions(input, Options{NodePseudoElements: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		nodes[input] = sel
+	}
+	withComments := MustParseHTML(`<div class="dialog">a<!--b--><p>c<!--d--></p></div><p>e</p>`)
+	checkQueryAllMulti(t, doc, nodes)
+	checkQueryAllMulti(t, withComments, nodes)
+
+	// ::attr() only matches the elements with the attribute
+	attrs := map[string]Matcher{}
+	for _, input := range []string{"p::attr(id)", ".dialog::attr(id)", "div::attr(class)"} {
+		sel, err := ParseWithOptions(input, Options{NodePseudoElements: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		attrs[input] = sel
+	}
+	withIDs := MustParseHTML(`<p id="a">x</p><p>y</p><div class="dialog">z</div>`)
+	if got := checkQueryAllMulti(t, withIDs, attrs); len(got["p::attr(id)"]) != 1 {
+		t.Errorf("unexpected matches %v", got["p::attr(id)"])
+	}
+
+	// :scope is bound to the root
+	body := Query(doc, MustParse("body"))
+	scoped := QueryAllMulti(body, map[string]Matcher{"children": MustParse(":scope > div")})
+	if len(scoped["children"]) != len(QueryAll(body, MustParse("body > div"))) {
+		t.Errorf("unexpected scoped matches %v", scoped)
+	}
+}
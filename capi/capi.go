@@ -0,0 +1,103 @@
+// Command capi exposes the selector engine through a small C ABI,
+// so that non-Go applications share the matching behavior of cascadia.
+// It is built as a shared library with:
+//
+//	go build -buildmode=c-shared -o libcascadia.so ./capi
+//
+// which also generates the header libcascadia.h. The API is:
+//
+//	// cascadia_parse parses a group of selectors and returns its handle,
+//	// or 0 on error, in which case *err is set to the error message.
+//	uintptr_t cascadia_parse(char* sel, char** err);
+//	// cascadia_count returns the number of elements of the HTML
+//	// document doc matching the selector, or -1 for an invalid handle.
+//	int cascadia_count(uintptr_t handle, char* doc);
+//	// cascadia_select returns the JSON array of the outer HTML
+//	// of the elements of doc matching the selector, or NULL for an invalid handle.
+//	char* cascadia_select(uintptr_t handle, char* doc);
+//	// cascadia_free releases the handle returned by cascadia_parse.
+//	void cascadia_free(uintptr_t handle);
+//	// cascadia_free_string releases the strings returned by the library.
+//	void cascadia_free_string(char* s);
+//
+// The documents are parsed with golang.org/x/net/html, like html.Parse,
+// and must be UTF-8 encoded.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/benoitkugler/cascadia"
+	"golang.org/x/net/html"
+)
+
+// main is required by -buildmode=c-shared, but is never called.
+func main() {}
+
+// handles stores the compiled selectors, which can't
+// be passed to C as Go pointers.
+var handles = struct {
+	sync.Mutex
+	next    uintptr
+	entries map[uintptr]cascadia.SelectorGroup
+}{entries: map[uintptr]cascadia.SelectorGroup{}}
+
+// newHandle parses sel and registers it. Handles start at 1,
+// so that 0 means an error.
+func newHandle(sel string) (uintptr, error) {
+	group, err := cascadia.ParseGroup(sel)
+	if err != nil {
+		return 0, err
+	}
+	handles.Lock()
+	defer handles.Unlock()
+	handles.next++
+	handles.entries[handles.next] = group
+	return handles.next, nil
+}
+
+func lookupHandle(h uintptr) (cascadia.SelectorGroup, bool) {
+	handles.Lock()
+	defer handles.Unlock()
+	group, ok := handles.entries[h]
+	return group, ok
+}
+
+func freeHandle(h uintptr) {
+	handles.Lock()
+	defer handles.Unlock()
+	delete(handles.entries, h)
+}
+
+// queryDocument returns the elements of the document doc matching the
+// selector h, with false for an invalid handle.
+func queryDocument(h uintptr, doc string) ([]*html.Node, bool) {
+	group, ok := lookupHandle(h)
+	if !ok {
+		return nil, false
+	}
+	root, err := html.Parse(strings.NewReader(doc))
+	if err != nil { // html.Parse only fails on read errors
+		return nil, true
+	}
+	return cascadia.QueryAll(root, group), true
+}
+
+// renderJSON returns the JSON array of the outer HTML of nodes.
+func renderJSON(nodes []*html.Node) string {
+	out := make([]string, len(nodes))
+	var buf bytes.Buffer
+	for i, n := range nodes {
+		buf.Reset()
+		html.Render(&buf, n) // writing to a bytes.Buffer never fails
+		out[i] = buf.String()
+	}
+	buf.Reset()
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(out) // strings are always encodable
+	return strings.TrimSuffix(buf.String(), "\n")
+}
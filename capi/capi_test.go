@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestHandles(t *testing.T) {
+	if _, err := newHandle("div["); err == nil {
+		t.Error("expected an error for an invalid selector")
+	}
+	h, err := newHandle("li.a, p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h == 0 {
+		t.Fatal("0 is not a valid handle")
+	}
+
+	nodes, ok := queryDocument(h, `<ul><li class="a">1</li><li>2</li></ul><p>text</p>`)
+	if !ok || len(nodes) != 2 {
+		t.Fatalf("unexpected matches %v %v", nodes, ok)
+	}
+	if got, exp := renderJSON(nodes), `["<li class=\"a\">1</li>","<p>text</p>"]`; got != exp {
+		t.Errorf("expected %s, got %s", exp, got)
+	}
+	if got := renderJSON(nil); got != "[]" {
+		t.Errorf("expected an empty array, got %s", got)
+	}
+
+	freeHandle(h)
+	if _, ok := queryDocument(h, "<p>"); ok {
+		t.Error("expected an invalid handle after free")
+	}
+}
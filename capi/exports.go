@@ -0,0 +1,49 @@
+//go:build cgo
+
+package main
+
+// #include <stdint.h>
+// #include <stdlib.h>
+import "C"
+
+import "unsafe"
+
+//export cascadia_parse
+func cascadia_parse(sel *C.char, err **C.char) C.uintptr_t {
+	h, e := newHandle(C.GoString(sel))
+	if e != nil {
+		if err != nil {
+			*err = C.CString(e.Error())
+		}
+		return 0
+	}
+	return C.uintptr_t(h)
+}
+
+//export cascadia_count
+func cascadia_count(handle C.uintptr_t, doc *C.char) C.int {
+	nodes, ok := queryDocument(uintptr(handle), C.GoString(doc))
+	if !ok {
+		return -1
+	}
+	return C.int(len(nodes))
+}
+
+//export cascadia_select
+func cascadia_select(handle C.uintptr_t, doc *C.char) *C.char {
+	nodes, ok := queryDocument(uintptr(handle), C.GoString(doc))
+	if !ok {
+		return nil
+	}
+	return C.CString(renderJSON(nodes))
+}
+
+//export cascadia_free
+func cascadia_free(handle C.uintptr_t) {
+	freeHandle(uintptr(handle))
+}
+
+//export cascadia_free_string
+func cascadia_free_string(s *C.char) {
+	C.free(unsafe.Pointer(s))
+}
@@ -0,0 +1,194 @@
+package cascadia
+
+import (
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Origin is the origin of a style sheet, which takes
+// precedence over the specificity in the cascade.
+type Origin uint8
+
+const (
+	OriginUserAgent Origin = iota // the default style sheet of the user agent
+	OriginUser                    // the style sheets of the user
+	OriginAuthor                  // the style sheets of the document
+)
+
+func (o Origin) String() string {
+	switch o {
+	case OriginUserAgent:
+		return "user-agent"
+	case OriginUser:
+		return "user"
+	default:
+		return "author"
+	}
+}
+
+// CascadeEntry is a source of declarations for an element.
+type CascadeEntry struct {
+	MatchedRule
+	// Important is true for the entry of the !important
+	// declarations of the rule, false for the normal ones.
+	Important bool
+
+	// Inline is true for the entry of the style attribute of the element,
+	// which has the author origin and wins over any selector.
+	// Its MatchedRule has a nil Selector, and an Order of -1.
+	Inline bool
+	// Declarations are the declarations of the style attribute,
+	// with the importance of the entry. It is nil for the rules.
+	Declarations []Declaration
+}
+
+// precedence returns the rank of the origin and importance of e:
+// the normal declarations, by ascending origin, followed by the
+// important ones, by descending origin.
+func (e CascadeEntry) precedence() int {
+	if e.Important {
+		return 2*int(OriginAuthor) + 1 - int(e.Origin)
+	}
+	return int(e.Origin)
+}
+
+// less returns true if e loses against other
+func (e CascadeEntry) less(other CascadeEntry) bool {
+	if p1, p2 := e.precedence(), other.precedence(); p1 != p2 {
+		return p1 < p2
+	}
+	if e.Inline != other.Inline {
+		return other.Inline
+	}
+	if e.Specificity != other.Specificity {
+		return e.Specificity.Less(other.Specificity)
+	}
+	if e.Order != other.Order {
+		return e.Order < other.Order
+	}
+	return !e.Important && other.Important
+}
+
+// SortCascade returns the entries for rules, sorted by ascending precedence:
+// for each property, the declaration of the last entry declaring it wins,
+// as computed by browsers.
+//
+// The precedence is given by the origin and importance, then the specificity, then
+// the source order. important reports whether a rule has !important
+// declarations: such a rule gives two entries, one for its normal declarations,
+// and one with Important set for its important ones. important may be nil if no rule
+// has important declarations.
+func SortCascade(rules []MatchedRule, important func(MatchedRule) bool) []CascadeEntry {
+	out := make([]CascadeEntry, 0, len(rules))
+	for _, r := range rules {
+		out = append(out, CascadeEntry{MatchedRule: r})
+		if important != nil && important(r) {
+			out = append(out, CascadeEntry{MatchedRule: r, Important: true})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].less(out[j]) })
+	return out
+}
+
+// Cascade returns the rules matching the element n, sorted as
+// by SortCascade, and the entries of its style attribute, if any.
+func (rs *RuleSet) Cascade(n *html.Node, important func(MatchedRule) bool) []CascadeEntry {
+	out := SortCascade(rs.RulesFor(n), important)
+	if n == nil || n.Type != html.ElementNode {
+		return out
+	}
+	var style string
+	for _, a := range n.Attr {
+		if a.Key == "style" && a.Namespace == "" {
+			style = a.Val
+			break
+		}
+	}
+	var normal, imp []Declaration
+	for _, d := range ParseStyleAttribute(style) {
+		if d.Important {
+			imp = append(imp, d)
+		} else {
+			normal = append(normal, d)
+		}
+	}
+	inline := MatchedRule{Order: -1, Origin: OriginAuthor}
+	if len(normal) != 0 {
+		out = append(out, CascadeEntry{MatchedRule: inline, Inline: true, Declarations: normal})
+	}
+	if len(imp) != 0 {
+		out = append(out, CascadeEntry{MatchedRule: inline, Inline: true, Important: true, Declarations: imp})
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].less(out[j]) })
+	return out
+}
+
+// Declaration is a CSS declaration, like color: red !important.
+type Declaration struct {
+	Property  string // lower-cased, except for the custom properties (--name)
+	Value     string // without the !important annotation
+	Important bool
+}
+
+// ParseStyleAttribute returns the declarations of the content of
+// a style attribute, in order. The invalid declarations, like the ones
+// without a colon or with an empty value, are ignored. The values are
+// not validated.
+func ParseStyleAttribute(style string) []Declaration {
+	var out []Declaration
+	for _, chunk := range splitDeclarations(style) {
+		colon := strings.IndexByte(chunk, ':')
+		if colon == -1 {
+			continue
+		}
+		d := Declaration{
+			Property: strings.TrimSpace(chunk[:colon]),
+			Value:    strings.TrimSpace(chunk[colon+1:]),
+		}
+		if !strings.HasPrefix(d.Property, "--") {
+			d.Property = toLowerASCII(d.Property)
+		}
+		if i := strings.LastIndexByte(d.Value, '!'); i != -1 &&
+			strings.EqualFold(strings.TrimSpace(d.Value[i+1:]), "important") {
+			d.Value, d.Important = strings.TrimSpace(d.Value[:i]), true
+		}
+		if d.Property == "" || d.Value == "" {
+			continue
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+// splitDeclarations splits s on the semicolons which
+// are not in a string or between parentheses
+func splitDeclarations(s string) []string {
+	var (
+		out          []string
+		depth, start int
+		quote        byte
+	)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')' && depth > 0:
+			depth--
+		case c == ';' && depth == 0:
+			out = append(out, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(out, s[start:])
+}
@@ -0,0 +1,113 @@
+package cascadia
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCascade(t *testing.T) {
+	doc := MustParseHTML(`<div id="main"><p class="a">text</p></div>`)
+	var rs RuleSet
+	for _, rule := range []struct {
+		selector string
+		origin   Origin
+	}{
+		{"p", OriginUserAgent},          // 0
+		{"#main p", OriginAuthor},       // 1
+		{"p.a", OriginAuthor},           // 2
+		{"p", OriginUser},               // 3, important
+		{"div > p", OriginUserAgent},    // 4, important
+		{"p:first-child", OriginAuthor}, // 5, important
+		{"p", OriginAuthor},             // 6
+		{"p.a", OriginAuthor},           // 7
+	} {
+		if err := rs.AddWithOrigin(rs.Len(), rule.selector, rule.origin); err != nil {
+			t.Fatal(err)
+		}
+	}
+	important := func(r MatchedRule) bool { return r.ID == 3 || r.ID == 4 || r.ID == 5 }
+
+	p := Query(doc, MustCompile("p"))
+	var got []string
+	for _, e := range rs.Cascade(p, important) {
+		s := fmt.Sprint(e.ID)
+		if e.Important {
+			s += "!"
+		}
+		got = append(got, s)
+	}
+	if exp := "0 4 3 6 2 5 7 1 5! 3! 4!"; strings.Join(got, " ") != exp {
+		t.Errorf("expected %s, got %s", exp, strings.Join(got, " "))
+	}
+
+	if entries := SortCascade(rs.RulesFor(p), nil); len(entries) != 8 {
+		t.Errorf("expected one entry per rule, got %d", len(entries))
+	}
+	if s := OriginUser.String(); s != "user" {
+		t.Errorf("unexpected origin name %s", s)
+	}
+}
+
+func TestCascadeInlineStyle(t *testing.T) {
+	doc := MustParseHTML(`<p id="a" style="color: red; MARGIN:0 ! Important;--My-Var: x">text</p><p>other</p>`)
+	var rs RuleSet
+	for _, rule := range []string{"#a", "p", "p"} {
+		if err := rs.Add(rs.Len(), rule); err != nil {
+			t.Fatal(err)
+		}
+	}
+	important := func(r MatchedRule) bool { return r.ID == 2 }
+
+	describe := func(entries []CascadeEntry) string {
+		var got []string
+		for _, e := range entries {
+			s := fmt.Sprint(e.ID)
+			if e.Inline {
+				s = "style"
+			}
+			if e.Important {
+				s += "!"
+			}
+			got = append(got, s)
+		}
+		return strings.Join(got, " ")
+	}
+
+	entries := rs.Cascade(Query(doc, MustCompile("#a")), important)
+	if got, exp := describe(entries), "1 2 0 style 2! style!"; got != exp {
+		t.Errorf("expected %s, got %s", exp, got)
+	}
+	if d := entries[3].Declarations; len(d) != 2 || d[0] != (Declaration{"color", "red", false}) || d[1] != (Declaration{"--My-Var", "x", false}) {
+		t.Errorf("unexpected normal declarations %v", d)
+	}
+	if d := entries[5].Declarations; len(d) != 1 || d[0] != (Declaration{"margin", "0", true}) {
+		t.Errorf("unexpected important declarations %v", d)
+	}
+
+	if got, exp := describe(rs.Cascade(Query(doc, MustCompile("p + p")), important)), "1 2 2!"; got != exp {
+		t.Errorf("expected %s, got %s", exp, got)
+	}
+}
+
+func TestParseStyleAttribute(t *testing.T) {
+	for _, test := range []struct {
+		style string
+		exp   []Declaration
+	}{
+		{"", nil},
+		{"color:blue", []Declaration{{"color", "blue", false}}},
+		{` background: url("a;b.png") ; ; font-family: 'x;y' , serif`, []Declaration{
+			{"background", `url("a;b.png")`, false},
+			{"font-family", `'x;y' , serif`, false},
+		}},
+		{"width: calc(1px + 2px)!important", []Declaration{{"width", "calc(1px + 2px)", true}}},
+		{"nocolon; : empty; color:; top: 1px !importan", []Declaration{{"top", "1px !importan", false}}},
+		{`content: "a\"; b"`, []Declaration{{"content", `"a\"; b"`, false}}},
+	} {
+		got := ParseStyleAttribute(test.style)
+		if fmt.Sprint(got) != fmt.Sprint(test.exp) {
+			t.Errorf("%q: expected %v, got %v", test.style, test.exp, got)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// Package cascadiacheck defines an Analyzer reporting the invalid
+// selectors passed as constant strings to the parsing functions
+// of cascadia, such as MustCompile, which would panic or fail at run time.
+//
+// It lives in a separate module so that the main package does not
+// depend on golang.org/x/tools.
+package cascadiacheck
+
+import (
+	"errors"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+
+	"github.com/benoitkugler/cascadia"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+const cascadiaPath = "github.com/benoitkugler/cascadia"
+
+var Analyzer = &analysis.Analyzer{
+	Name:     "cascadiacheck",
+	Doc:      "report invalid CSS selectors passed as constants to cascadia",
+	URL:      "https://pkg.go.dev/github.com/benoitkugler/cascadia/cascadiacheck",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+// parsers maps the checked functions to the parsing
+// function they use
+var parsers = map[string]func(string) error{
+	"Compile":                      group(cascadia.ParseGroup),
+	"MustCompile":                  group(cascadia.ParseGroup),
+	"MustParse":                    single(cascadia.Parse),
+	"MustParseGroup":               group(cascadia.ParseGroup),
+	"Parse":                        single(cascadia.Parse),
+	"ParseWithPseudoElement":       single(cascadia.ParseWithPseudoElement),
+	"ParseWithSpans":               single(cascadia.ParseWithSpans),
+	"ParseGroup":                   group(cascadia.ParseGroup),
+	"ParseGroupWithPseudoElements": group(cascadia.ParseGroupWithPseudoElements),
+	"ParseGroupWithSpans":          group(cascadia.ParseGroupWithSpans),
+}
+
+func single(parse func(string) (cascadia.Sel, error)) func(string) error {
+	return func(s string) error { _, err := parse(s); return err }
+}
+
+func group(parse func(string) (cascadia.SelectorGroup, error)) func(string) error {
+	return func(s string) error { _, err := parse(s); return err }
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	insp.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node) {
+		call := n.(*ast.CallExpr)
+		parse := parserFor(pass.TypesInfo, call)
+		if parse == nil || len(call.Args) != 1 {
+			return
+		}
+		arg := call.Args[0]
+		tv, ok := pass.TypesInfo.Types[arg]
+		if !ok || tv.Value == nil || tv.Value.Kind() != constant.String {
+			return // not a constant
+		}
+		err := parse(constant.StringVal(tv.Value))
+		if err == nil {
+			return
+		}
+		pos := arg.Pos()
+		var perr *cascadia.ParseError
+		if errors.As(err, &perr) {
+			if lit, ok := arg.(*ast.BasicLit); ok {
+				pos = offsetPos(lit, perr.Offset)
+			}
+			pass.Reportf(pos, "invalid selector %q: %s", perr.Source, perr.Message)
+			return
+		}
+		pass.Reportf(pos, "invalid selector: %s", err)
+	})
+	return nil, nil
+}
+
+// parserFor returns the parsing function if call is
+// a call to one of the checked functions, or nil
+func parserFor(info *types.Info, call *ast.CallExpr) func(string) error {
+	var id *ast.Ident
+	switch fun := ast.Unparen(call.Fun).(type) {
+	case *ast.Ident:
+		id = fun
+	case *ast.SelectorExpr:
+		id = fun.Sel
+	default:
+		return nil
+	}
+	fn, ok := info.Uses[id].(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != cascadiaPath {
+		return nil
+	}
+	if sig, ok := fn.Type().(*types.Signature); !ok || sig.Recv() != nil {
+		return nil
+	}
+	return parsers[fn.Name()]
+}
+
+// offsetPos returns the position of the byte at offset in the
+// value of lit, or the start of lit if the mapping is not trivial,
+// because of escape sequences.
+func offsetPos(lit *ast.BasicLit, offset int) token.Pos {
+	raw := lit.Value
+	if strings.HasPrefix(raw, "`") {
+		if strings.ContainsRune(raw, '\r') { // removed from the value
+			return lit.Pos()
+		}
+	} else if strings.ContainsRune(raw, '\\') {
+		return lit.Pos()
+	}
+	if s, err := strconv.Unquote(raw); err != nil || offset > len(s) {
+		return lit.Pos()
+	}
+	return lit.Pos() + token.Pos(1+offset) // skip the opening quote
+}
@@ -0,0 +1,31 @@
+package cascadiacheck
+
+import (
+	"go/ast"
+	"go/token"
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), Analyzer, "a")
+}
+
+func TestOffsetPos(t *testing.T) {
+	for _, test := range []struct {
+		lit    string
+		offset int
+		exp    token.Pos
+	}{
+		{`"div > "`, 6, 17},
+		{"`a[href`", 6, 17},
+		{`"a\t:nope"`, 3, 10},
+		{`"a"`, 5, 10},
+	} {
+		lit := &ast.BasicLit{ValuePos: 10, Kind: token.STRING, Value: test.lit}
+		if got := offsetPos(lit, test.offset); got != test.exp {
+			t.Errorf("%s at %d: expected %d, got %d", test.lit, test.offset, test.exp, got)
+		}
+	}
+}
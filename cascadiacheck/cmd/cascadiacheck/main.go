@@ -0,0 +1,12 @@
+// Command cascadiacheck reports the invalid CSS selectors passed
+// as constants to cascadia. It may be used directly, or with
+//
+//	go vet -vettool=$(which cascadiacheck) ./...
+package main
+
+import (
+	"github.com/benoitkugler/cascadia/cascadiacheck"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() { singlechecker.Main(cascadiacheck.Analyzer) }
@@ -0,0 +1,14 @@
+module github.com/benoitkugler/cascadia/cascadiacheck
+
+go 1.22.0
+
+require github.com/benoitkugler/cascadia v0.0.0
+
+require (
+	golang.org/x/mod v0.23.0 // indirect
+	golang.org/x/net v0.35.0 // indirect
+	golang.org/x/sync v0.11.0 // indirect
+	golang.org/x/tools v0.30.0
+)
+
+replace github.com/benoitkugler/cascadia => ../
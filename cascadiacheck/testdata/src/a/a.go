@@ -0,0 +1,30 @@
+package a
+
+import (
+	"github.com/benoitkugler/cascadia"
+	css "github.com/benoitkugler/cascadia"
+)
+
+const item = "li.item"
+
+func f(dynamic string) {
+	cascadia.MustCompile("div > p")
+	cascadia.MustCompile("div, p:first-child")
+	cascadia.MustCompile("div > ") // want `invalid selector "div > ": expected selector, found EOF`
+	cascadia.MustCompile(`a[href`) // want `invalid selector "a\[href": unexpected EOF`
+	css.Compile("p:nope")          // want `invalid selector "p:nope": unknown pseudoclass`
+	cascadia.Parse("a, b")         // want `invalid selector "a, b"`
+	cascadia.ParseGroup("a, b")
+	cascadia.ParseGroup("a::before") // want `invalid selector "a::before"`
+	cascadia.ParseGroupWithPseudoElements("a::before")
+	cascadia.ParseWithPseudoElement("a::before")
+	cascadia.MustParse("ul > li")
+	cascadia.MustParse("a, b") // want `invalid selector "a, b"`
+	cascadia.MustParseGroup("a, b")
+	cascadia.MustParseGroup("a:nope") // want `invalid selector "a:nope": unknown pseudoclass`
+	cascadia.MustCompile(item + " > a")
+	cascadia.MustCompile(item + " >") // want `invalid selector`
+	cascadia.MustCompile("a\t:nope")  // want `invalid selector`
+	cascadia.MustCompile(dynamic)
+	cascadia.Selector(nil).Parse(":nope")
+}
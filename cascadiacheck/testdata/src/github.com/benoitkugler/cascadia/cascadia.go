@@ -0,0 +1,19 @@
+// Package cascadia is a stub of the real package, with the same signatures.
+package cascadia
+
+type Sel interface{}
+
+type SelectorGroup []Sel
+
+type Selector func() bool
+
+func Compile(sel string) (Selector, error)                           { return nil, nil }
+func MustCompile(sel string) Selector                                { return nil }
+func MustParse(sel string) Sel                                       { return nil }
+func MustParseGroup(sel string) SelectorGroup                        { return nil }
+func Parse(sel string) (Sel, error)                                  { return nil, nil }
+func ParseWithPseudoElement(sel string) (Sel, error)                 { return nil, nil }
+func ParseGroup(sel string) (SelectorGroup, error)                   { return nil, nil }
+func ParseGroupWithPseudoElements(sel string) (SelectorGroup, error) { return nil, nil }
+
+func (s Selector) Parse(sel string) {}
@@ -0,0 +1,326 @@
+// Command cascadia extracts the elements matching CSS selectors
+// from HTML documents, read from the files given as arguments,
+// or from the standard input.
+//
+// Usage:
+//
+//	cascadia [flags] SELECTOR [FILE...]
+//
+// SELECTOR may be a group of selectors, separated by commas. By default,
+// the outer HTML of each matching element is printed, one per line;
+// use -text, -attr or -count to change the output, and -json to
+// print each value as a JSON string.
+//
+// For example, to print the targets of the links of a page:
+//
+//	curl -s https://example.com | cascadia -attr href 'a[href]'
+//
+// With -field, each matching element is a record, printed as a JSON object
+// (one per line, in the JSON Lines format). Each field is given
+// as KEY=SELECTOR::EXTRACTOR, where SELECTOR is matched against the descendants
+// of the record (the record itself if SELECTOR is empty), and EXTRACTOR is one of
+// text (the default), html or attr(NAME). The value of the field is the one of the first
+// matching element, or null; if KEY ends with [], it is the array of the values of all
+// the matching elements. For example:
+//
+//	cascadia -field title='h2::text' -field 'tags[]=.tag::text' -field link='::attr(href)' 'a.item'
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/benoitkugler/cascadia"
+	"golang.org/x/net/html"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, "cascadia:", err)
+		}
+		os.Exit(2)
+	}
+}
+
+// config is the result of the command line parsing
+type config struct {
+	text      bool
+	attr      string
+	count     bool
+	first     bool
+	limit     int
+	delimiter string
+	json      bool
+	fields    fieldsFlag
+
+	selector cascadia.SelectorGroup
+	files    []string
+}
+
+func parseArgs(args []string, stderr io.Writer) (config, error) {
+	var cfg config
+	fs := flag.NewFlagSet("cascadia", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: cascadia [flags] SELECTOR [FILE...]")
+		fs.PrintDefaults()
+	}
+	fs.BoolVar(&cfg.text, "text", false, "print the text content of the elements")
+	fs.StringVar(&cfg.attr, "attr", "", "print the value of the given attribute, skipping the elements without it")
+	fs.BoolVar(&cfg.count, "count", false, "only print the number of matching elements")
+	fs.BoolVar(&cfg.first, "first", false, "only print the first matching element (same as -limit 1)")
+	fs.IntVar(&cfg.limit, "limit", 0, "print at most `n` elements (0 means no limit)")
+	fs.StringVar(&cfg.delimiter, "delimiter", "\n", "the `string` printed after each element")
+	fs.BoolVar(&cfg.json, "json", false, "print each value as a JSON string")
+	fs.Var(&cfg.fields, "field", "print each element as a JSON object, with a field given as `KEY=SELECTOR::EXTRACTOR` (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return cfg, errors.New("missing selector")
+	}
+	if cfg.text && cfg.attr != "" {
+		return cfg, errors.New("-text and -attr are exclusive")
+	}
+	if len(cfg.fields) != 0 && (cfg.text || cfg.attr != "") {
+		return cfg, errors.New("-field is exclusive with -text and -attr")
+	}
+	if cfg.limit < 0 {
+		return cfg, errors.New("-limit must be positive")
+	}
+	if cfg.first {
+		cfg.limit = 1
+	}
+	var err error
+	cfg.selector, err = cascadia.ParseGroup(fs.Arg(0))
+	if err != nil {
+		return cfg, err
+	}
+	cfg.files = fs.Args()[1:]
+	return cfg, nil
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	cfg, err := parseArgs(args, stderr)
+	if err != nil {
+		return err
+	}
+
+	var matches []*html.Node
+	collect := func(r io.Reader) error {
+		doc, err := html.Parse(r)
+		if err != nil {
+			return err
+		}
+		matches = append(matches, cascadia.QueryAll(doc, cfg.selector)...)
+		return nil
+	}
+	if len(cfg.files) == 0 {
+		if err := collect(stdin); err != nil {
+			return err
+		}
+	}
+	for _, file := range cfg.files {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		err = collect(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %s", file, err)
+		}
+	}
+
+	var out []string
+	for _, n := range matches {
+		if cfg.limit > 0 && len(out) == cfg.limit {
+			break
+		}
+		s, ok, err := extract(n, cfg)
+		if err != nil {
+			return err
+		}
+		if ok {
+			out = append(out, s)
+		}
+	}
+
+	if cfg.count {
+		_, err = fmt.Fprintln(stdout, len(out))
+		return err
+	}
+	for _, s := range out {
+		if _, err := io.WriteString(stdout, s+cfg.delimiter); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extract returns the output for n, or false if n has
+// not the attribute required by cfg
+func extract(n *html.Node, cfg config) (string, bool, error) {
+	if len(cfg.fields) != 0 {
+		s, err := cfg.fields.record(n)
+		return s, true, err
+	}
+	s, ok, err := extractValue(n, cfg)
+	if err != nil || !ok || !cfg.json {
+		return s, ok, err
+	}
+	b, err := marshalJSON(s)
+	return string(b), true, err
+}
+
+func extractValue(n *html.Node, cfg config) (string, bool, error) {
+	switch {
+	case cfg.attr != "":
+		for _, a := range n.Attr {
+			if a.Key == cfg.attr {
+				return a.Val, true, nil
+			}
+		}
+		return "", false, nil
+	case cfg.text:
+		return textContent(n), true, nil
+	default:
+		var buf bytes.Buffer
+		err := html.Render(&buf, n)
+		return buf.String(), true, err
+	}
+}
+
+// textContent returns the concatenation of the text nodes of n
+func textContent(n *html.Node) string {
+	var b strings.Builder
+	var visit func(n *html.Node)
+	visit = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			visit(c)
+		}
+	}
+	visit(n)
+	return b.String()
+}
+
+// field is a field of the records printed with -field
+type field struct {
+	key      string
+	array    bool
+	selector cascadia.SelectorGroup // nil for the record itself
+	kind     string                 // text, html or attr
+	attr     string
+}
+
+// fieldsFlag implements flag.Value
+type fieldsFlag []field
+
+func (f *fieldsFlag) String() string { return "" }
+
+func (f *fieldsFlag) Set(value string) error {
+	key, spec, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return errors.New("expected KEY=SELECTOR::EXTRACTOR")
+	}
+	fi := field{key: key, kind: "text"}
+	if strings.HasSuffix(key, "[]") {
+		fi.key, fi.array = strings.TrimSuffix(key, "[]"), true
+	}
+	sel := spec
+	if i := strings.LastIndex(spec, "::"); i != -1 {
+		sel = spec[:i]
+		extractor := spec[i+2:]
+		switch {
+		case extractor == "text", extractor == "html":
+			fi.kind = extractor
+		case strings.HasPrefix(extractor, "attr(") && strings.HasSuffix(extractor, ")"):
+			fi.kind, fi.attr = "attr", strings.TrimSpace(extractor[5:len(extractor)-1])
+		default:
+			return fmt.Errorf("unknown extractor %q (expected text, html or attr(NAME))", extractor)
+		}
+	}
+	if strings.TrimSpace(sel) != "" {
+		var err error
+		if fi.selector, err = cascadia.ParseGroup(sel); err != nil {
+			return err
+		}
+	}
+	*f = append(*f, fi)
+	return nil
+}
+
+// record returns the JSON object for the record n, with the keys in
+// the order of the fields
+func (f fieldsFlag) record(n *html.Node) (string, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, fi := range f {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := marshalJSON(fi.key)
+		buf.Write(key)
+		buf.WriteByte(':')
+
+		var nodes []*html.Node
+		switch {
+		case fi.selector == nil:
+			nodes = []*html.Node{n}
+		case fi.array:
+			nodes = cascadia.QueryAll(n, fi.selector)
+		default:
+			if c := cascadia.Query(n, fi.selector); c != nil {
+				nodes = []*html.Node{c}
+			}
+		}
+		values := []string{} // encoded as [] rather than null
+		cfg := config{text: fi.kind == "text", attr: fi.attr}
+		for _, c := range nodes {
+			s, ok, err := extractValue(c, cfg)
+			if err != nil {
+				return "", err
+			}
+			if ok {
+				values = append(values, s)
+			}
+		}
+
+		var value interface{} = values
+		if !fi.array {
+			value = nil
+			if len(values) != 0 {
+				value = values[0]
+			}
+		}
+		b, err := marshalJSON(value)
+		if err != nil {
+			return "", err
+		}
+		buf.Write(b)
+	}
+	buf.WriteByte('}')
+	return buf.String(), nil
+}
+
+// marshalJSON is like json.Marshal, without escaping the HTML characters
+func marshalJSON(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testPage = `<html><body>
+<h1>Title</h1>
+<ul><li><a href="/a">A</a></li><li><a>B</a></li><li><a href="/c" class="x">C <b>!</b></a></li></ul>
+</body></html>`
+
+func TestRun(t *testing.T) {
+	for _, test := range []struct {
+		args []string
+		exp  string
+	}{
+		{[]string{"h1"}, "<h1>Title</h1>\n"},
+		{[]string{"-text", "a"}, "A\nB\nC !\n"},
+		{[]string{"-attr", "href", "a"}, "/a\n/c\n"},
+		{[]string{"-count", "li, h1"}, "4\n"},
+		{[]string{"-count", "-attr", "href", "a"}, "2\n"},
+		{[]string{"-first", "-text", "a"}, "A\n"},
+		{[]string{"-limit", "2", "-text", "a"}, "A\nB\n"},
+		{[]string{"-limit", "2", "-attr=href", "a"}, "/a\n/c\n"},
+		{[]string{"-delimiter", ",", "-text", "li"}, "A,B,C !,"},
+		{[]string{"p"}, ""},
+		{[]string{"-json", "-text", "li"}, "\"A\"\n\"B\"\n\"C !\"\n"},
+		{[]string{"-json", "-first", "h1"}, "\"<h1>Title</h1>\"\n"},
+		{[]string{"-field", "href=::attr(href)", "-field", "text=", "-field", "bold=b::html", "a"},
+			`{"href":"/a","text":"A","bold":null}` + "\n" +
+				`{"href":null,"text":"B","bold":null}` + "\n" +
+				`{"href":"/c","text":"C !","bold":"<b>!</b>"}` + "\n"},
+		{[]string{"-field", "links[]=a::attr(href)", "-field", "title=h1::text", "-field", "none[]=p", "body"},
+			`{"links":["/a","/c"],"title":"Title","none":[]}` + "\n"},
+		{[]string{"-count", "-field", "x=b", "li"}, "3\n"},
+	} {
+		var stdout, stderr bytes.Buffer
+		if err := run(test.args, strings.NewReader(testPage), &stdout, &stderr); err != nil {
+			t.Fatalf("%v: %s", test.args, err)
+		}
+		if got := stdout.String(); got != test.exp {
+			t.Errorf("%v: expected %q, got %q", test.args, test.exp, got)
+		}
+	}
+}
+
+func TestRunFiles(t *testing.T) {
+	dir := t.TempDir()
+	for i, content := range []string{testPage, `<a href="/d">D</a>`} {
+		if err := os.WriteFile(filepath.Join(dir, string(rune('0'+i))+".html"), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var stdout, stderr bytes.Buffer
+	err := run([]string{"-attr", "href", "a", filepath.Join(dir, "0.html"), filepath.Join(dir, "1.html")}, nil, &stdout, &stderr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := stdout.String(); got != "/a\n/c\n/d\n" {
+		t.Errorf("unexpected output %q", got)
+	}
+
+	if err := run([]string{"a", filepath.Join(dir, "missing.html")}, nil, &stdout, &stderr); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	for _, args := range [][]string{
+		nil,
+		{"a["},
+		{"-text", "-attr", "href", "a"},
+		{"-limit", "-1", "a"},
+		{"-unknown", "a"},
+		{"-field", "a", "a"},
+		{"-field", "=a", "a"},
+		{"-field", "k=a::name", "a"},
+		{"-field", "k=a[::text", "a"},
+		{"-field", "k=a", "-text", "a"},
+	} {
+		var stdout, stderr bytes.Buffer
+		if err := run(args, strings.NewReader(testPage), &stdout, &stderr); err == nil {
+			t.Errorf("%v: expected an error", args)
+		}
+	}
+}
@@ -0,0 +1,468 @@
+package cascadia
+
+import (
+	"regexp"
+	"sync"
+
+	"golang.org/x/net/html"
+)
+
+// And returns a matcher for the elements matched by all of ms.
+//
+// If each of ms is a Sel or a SelectorGroup without pseudo-element, the
+// result is a Sel, whose specificity is the sum of the ones of ms, and whose
+// String is valid CSS: the compound selectors are merged, and the other
+// selectors are wrapped in :is(), as in "li.a:is(ul > li)".
+// Otherwise, the result only implements Matcher and NodeMatcher.
+// With no argument, And returns the universal selector.
+func And(ms ...Matcher) Matcher {
+	if len(ms) == 1 {
+		return ms[0]
+	}
+	sels, ok := selsOf(ms)
+	if !ok {
+		return andMatcher(ms)
+	}
+	var tags, others []Sel
+	for _, sel := range sels {
+		var parts []Sel
+		switch sel := sel.(type) {
+		case CompoundSelector:
+			parts = sel.Selectors
+		case CombinedSelector:
+			parts = []Sel{RelativePseudoClassSelector{Name: "is", Args: SelectorGroup{sel}}}
+		default:
+			parts = []Sel{sel}
+		}
+		for _, part := range parts {
+			if _, isTag := part.(TagSelector); isTag {
+				tags = append(tags, part)
+			} else {
+				others = append(others, part)
+			}
+		}
+	}
+	// a type selector must come first in a compound selector
+	if len(tags) > 1 {
+		for _, tag := range tags[1:] {
+			others = append(others, RelativePseudoClassSelector{Name: "is", Args: SelectorGroup{tag}})
+		}
+		tags = tags[:1]
+	}
+	parts := append(tags, others...)
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return CompoundSelector{Selectors: parts}
+}
+
+// Or returns a matcher for the elements matched by any of ms.
+//
+// If each of ms is a Sel or a SelectorGroup without pseudo-element, the
+// result is the Sel :is(ms...), whose specificity is the one of the most
+// specific of ms. Otherwise, the result only implements Matcher and NodeMatcher.
+// With no argument, Or returns a selector matching nothing.
+func Or(ms ...Matcher) Matcher {
+	if len(ms) == 1 {
+		return ms[0]
+	}
+	sels, ok := selsOf(ms)
+	if !ok {
+		return orMatcher(ms)
+	}
+	if len(sels) == 0 {
+		return NeverMatchSelector{Value: ":not(*)"}
+	}
+	return RelativePseudoClassSelector{Name: "is", Args: sels}
+}
+
+// Not returns a matcher for the elements not matched by m.
+//
+// If m is a Sel or a SelectorGroup without pseudo-element, the result is
+// the Sel :not(m). Otherwise, the result only implements Matcher and NodeMatcher.
+func Not(m Matcher) Matcher {
+	sels, ok := selsOf([]Matcher{m})
+	if !ok {
+		return notMatcher{m}
+	}
+	return RelativePseudoClassSelector{Name: "not", Args: sels}
+}
+
+// selsOf returns the selectors of ms, and false if one of them
+// is not a selector, or has a pseudo-element.
+func selsOf(ms []Matcher) (SelectorGroup, bool) {
+	out := make(SelectorGroup, 0, len(ms))
+	for _, m := range ms {
+		switch m := m.(type) {
+		case Sel:
+			if m.PseudoElement() != "" {
+				return nil, false
+			}
+			out = append(out, m)
+		case SelectorGroup:
+			for _, sel := range m {
+				if sel.PseudoElement() != "" {
+					return nil, false
+				}
+			}
+			switch len(m) {
+			case 0:
+				out = append(out, NeverMatchSelector{Value: ":not(*)"})
+			case 1:
+				out = append(out, m[0])
+			default:
+				out = append(out, RelativePseudoClassSelector{Name: "is", Args: m})
+			}
+		default:
+			return nil, false
+		}
+	}
+	return out, true
+}
+
+type andMatcher []Matcher
+
+func (a andMatcher) Match(n *html.Node) bool { return a.MatchNode(FromHTML(n)) }
+
+func (a andMatcher) MatchNode(n Node) bool {
+	if n.Type() != html.ElementNode {
+		return false
+	}
+	for _, m := range a {
+		if !MatchNode(m, n) {
+			return false
+		}
+	}
+	return true
+}
+
+type orMatcher []Matcher
+
+func (o orMatcher) Match(n *html.Node) bool { return o.MatchNode(FromHTML(n)) }
+
+func (o orMatcher) MatchNode(n Node) bool {
+	for _, m := range o {
+		if MatchNode(m, n) {
+			return true
+		}
+	}
+	return false
+}
+
+type notMatcher struct{ m Matcher }
+
+func (s notMatcher) Match(n *html.Node) bool { return s.MatchNode(FromHTML(n)) }
+
+func (s notMatcher) MatchNode(n Node) bool {
+	return n.Type() == html.ElementNode && !MatchNode(s.m, n)
+}
+
+func (a andMatcher) bindRoot(root Node) anyMatcher {
+	out := make(andMatcher, len(a))
+	for i, m := range a {
+		out[i] = bindRoot(m, root)
+	}
+	return out
+}
+
+func (o orMatcher) bindRoot(root Node) anyMatcher {
+	out := make(orMatcher, len(o))
+	for i, m := range o {
+		out[i] = bindRoot(m, root)
+	}
+	return out
+}
+
+func (s notMatcher) bindRoot(root Node) anyMatcher { return notMatcher{bindRoot(s.m, root)} }
+
+// WithinDepth returns a matcher for the elements matched by m, at most depth
+// levels below the root of the query: with Query, QueryAll, QueryNode and
+// QueryAllNodes, WithinDepth(m, 1) only matches the children of the node queried.
+// Elsewhere, as with Filter, the depth is counted from the root of the tree,
+// so that the children of the document have depth 1.
+func WithinDepth(m Matcher, depth int) Matcher {
+	return depthMatcher{m: m, depth: depth}
+}
+
+// HavingText returns a matcher for the elements matched by m, whose text
+// (including the text of their descendants) matches rx.
+// If m is a Sel without pseudo-element, the result is the Sel
+// And(m, :matches(rx)).
+func HavingText(m Matcher, rx *regexp.Regexp) Matcher {
+	text := RegexpPseudoClassSelector{Regexp: rx}
+	if sel, ok := m.(Sel); ok && sel.PseudoElement() == "" {
+		return And(sel, text)
+	}
+	return andMatcher{m, text}
+}
+
+// InScope returns a matcher for the elements matched by m in the scopes
+// defined by start and end, as the style rules of the CSS rule
+// "@scope (start) to (end)": an element is in scope if it is a scope root
+// matched by start, or one of its descendants, but not a scope limit matched
+// by end below the root, or one of the descendants of a limit (a "donut").
+// end may be nil, for scopes without limits.
+//
+// As in a scoped style rule, the :scope pseudo-class and the nesting
+// selector & of m and end match the scope root, and if m is a Sel without
+// them, it is relative to the root, as if it started with ":where(:scope) ",
+// so that the root itself is not matched by "p", but by ":scope" only.
+func InScope(m, start, end Matcher) Matcher {
+	if sel, ok := m.(Sel); ok && !usesScope(sel) {
+		m = prepend(RelativePseudoClassSelector{Name: "where", Args: SelectorGroup{ScopePseudoClassSelector{}}}, sel)
+	}
+	return scopeRuleMatcher{m: m, start: start, end: end}
+}
+
+type scopeRuleMatcher struct {
+	m, start, end Matcher
+
+	// bound caches m and end bound to each scope root, during
+	// a query; it is nil outside the query functions
+	bound *scopeBindings
+}
+
+// scopeBindings maps the scope roots to the matchers bound to them
+type scopeBindings struct {
+	mu    sync.Mutex
+	roots map[Node]scopeBinding
+}
+
+type scopeBinding struct{ m, end Matcher }
+
+func (s scopeRuleMatcher) Match(n *html.Node) bool { return s.MatchNode(FromHTML(n)) }
+
+func (s scopeRuleMatcher) MatchNode(n Node) bool {
+	if n.Type() != html.ElementNode {
+		return false
+	}
+	for root := n; root != nil && root.Type() == html.ElementNode; root = root.Parent() {
+		if !MatchNode(s.start, root) {
+			continue
+		}
+		bound := s.bindScopeRoot(root)
+		if bound.inScope(n, root) && MatchNode(bound.m, n) {
+			return true
+		}
+	}
+	return false
+}
+
+// bindScopeRoot returns m and end bound to root, computed
+// once per query and scope root
+func (s scopeRuleMatcher) bindScopeRoot(root Node) scopeBinding {
+	if s.bound == nil {
+		return scopeBinding{m: bindRoot(s.m, root), end: bindRoot(s.end, root)}
+	}
+	s.bound.mu.Lock()
+	defer s.bound.mu.Unlock()
+	out, ok := s.bound.roots[root]
+	if !ok {
+		out = scopeBinding{m: bindRoot(s.m, root), end: bindRoot(s.end, root)}
+		s.bound.roots[root] = out
+	}
+	return out
+}
+
+// inScope returns true if n, a descendant of root, is not
+// below a scope limit
+func (b scopeBinding) inScope(n, root Node) bool {
+	if b.end == nil {
+		return true
+	}
+	for ; n != root; n = n.Parent() {
+		if MatchNode(b.end, n) {
+			return false
+		}
+	}
+	return true
+}
+
+// bindRoot only binds start, since m and end are bound to the scope roots,
+// which are cached for the query
+func (s scopeRuleMatcher) bindRoot(root Node) anyMatcher {
+	return scopeRuleMatcher{m: s.m, start: bindRoot(s.start, root), end: s.end,
+		bound: &scopeBindings{roots: make(map[Node]scopeBinding)}}
+}
+
+// anyMatcher is implemented by the decorators of this file
+type anyMatcher interface {
+	Matcher
+	NodeMatcher
+}
+
+// rootedMatcher is implemented by the matchers depending
+// on the root of the query, like WithinDepth.
+type rootedMatcher interface {
+	// bindRoot returns the matcher to use for a query of the descendants of root
+	bindRoot(root Node) anyMatcher
+}
+
+// usesRoot returns true if the matching of m (a Matcher or a NodeMatcher)
+// depends on the root of the query
+func usesRoot(m interface{}) bool {
+	switch m := m.(type) {
+	case andMatcher:
+		for _, c := range m {
+			if usesRoot(c) {
+				return true
+			}
+		}
+		return false
+	case orMatcher:
+		for _, c := range m {
+			if usesRoot(c) {
+				return true
+			}
+		}
+		return false
+	case notMatcher:
+		return usesRoot(m.m)
+	case rootedMatcher:
+		return true
+	case Sel:
+		return usesScope(m)
+	case SelectorGroup:
+		return m.usesScope()
+	}
+	return false
+}
+
+// bindRoot returns the matcher to use for a query of the descendants of root:
+// the selectors using :scope are bound to root
+func bindRoot(m Matcher, root Node) Matcher {
+	switch r := m.(type) {
+	case rootedMatcher:
+		if !usesRoot(r) {
+			return m
+		}
+		return r.bindRoot(root)
+	case Sel:
+		return bindScope(r, root)
+	case SelectorGroup:
+		return bindScopeGroup(r, root)
+	}
+	return m
+}
+
+// bindRootNode is like bindRoot, for a NodeMatcher
+func bindRootNode(m NodeMatcher, root Node) NodeMatcher {
+	switch r := m.(type) {
+	case rootedMatcher:
+		if !usesRoot(r) {
+			return m
+		}
+		return r.bindRoot(root)
+	case Sel:
+		if bound, ok := bindScope(r, root).(NodeMatcher); ok {
+			return bound
+		}
+	case SelectorGroup:
+		return bindScopeGroup(r, root)
+	}
+	return m
+}
+
+// WithScope returns m, where the :scope pseudo-classes match scope.
+// It is only needed to match :scope outside of the query functions,
+// like Query or QueryAll, which use the element queried as scope.
+//
+// The selectors wrapped in a Selector, as returned by Compile,
+// can't be bound: use the ones returned by Parse or ParseGroup instead.
+func WithScope(m Matcher, scope *html.Node) Matcher {
+	return bindRoot(m, FromHTML(scope))
+}
+
+// ForEach calls fn for each descendant of n matched by outer, in tree order,
+// for record-oriented extraction: in fn, the queries of the descendants of
+// scope, like QueryAll(scope, inner), are relative to it, and the :scope
+// pseudo-class of inner matches scope, as in
+//
+//	ForEach(doc, MustParse("article"), func(article *html.Node) {
+//		title := Query(article, MustParse(":scope > h2"))
+//		...
+//	})
+func ForEach(n *html.Node, outer Matcher, fn func(scope *html.Node)) {
+	for _, scope := range QueryAll(n, outer) {
+		fn(scope)
+	}
+}
+
+// bindScope returns sel, where :scope matches root, if it is an element
+func bindScope(sel Sel, root Node) Sel {
+	if root == nil || root.Type() != html.ElementNode || !usesScope(sel) {
+		return sel
+	}
+	return Transform(sel, func(s Sel) Sel {
+		switch s := s.(type) {
+		case ScopePseudoClassSelector:
+			s.scope = root
+			return s
+		case NestingSelector:
+			s.scope = root
+			return s
+		}
+		return s
+	})
+}
+
+func bindScopeGroup(group SelectorGroup, root Node) SelectorGroup {
+	if root == nil || root.Type() != html.ElementNode || !group.usesScope() {
+		return group
+	}
+	out := make(SelectorGroup, len(group))
+	for i, sel := range group {
+		out[i] = bindScope(sel, root)
+	}
+	return out
+}
+
+func (group SelectorGroup) usesScope() bool {
+	for _, sel := range group {
+		if usesScope(sel) {
+			return true
+		}
+	}
+	return false
+}
+
+func usesScope(sel Sel) bool {
+	found := false
+	Walk(sel, func(s Sel) bool {
+		switch s.(type) {
+		case ScopePseudoClassSelector, NestingSelector:
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+type depthMatcher struct {
+	m     Matcher
+	depth int
+	root  Node // nil for the root of the tree
+}
+
+func (d depthMatcher) Match(n *html.Node) bool { return d.MatchNode(FromHTML(n)) }
+
+func (d depthMatcher) MatchNode(n Node) bool { return d.within(n) && MatchNode(d.m, n) }
+
+// within returns true if n is at most d.depth levels below the root
+func (d depthMatcher) within(n Node) bool {
+	for steps := 0; steps <= d.depth; steps++ {
+		parent := n.Parent()
+		if (d.root == nil && parent == nil) || (d.root != nil && n == d.root) {
+			return true
+		}
+		if parent == nil { // not a descendant of root
+			return false
+		}
+		n = parent
+	}
+	return false
+}
+
+func (d depthMatcher) bindRoot(root Node) anyMatcher {
+	return depthMatcher{m: bindRoot(d.m, root), depth: d.depth, root: root}
+}
@@ -0,0 +1,231 @@
+package cascadia
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestCombinators(t *testing.T) {
+	doc := MustParseHTML(`<ul><li class="a" id="x">1</li><li>22</li><li class="a">333</li></ul><p class="a">4444</p>`)
+	long := MatcherFunc(func(n *html.Node) bool { return n.Type == html.ElementNode && len(nodeText(FromHTML(n))) >= 3 })
+
+	for _, test := range []struct {
+		m        Matcher
+		css      string // empty for the custom matchers
+		spec     Specificity
+		expected string // the text of the matches
+	}{
+		{And(MustParse("li"), MustParse(".a")), "li.a", Specificity{0, 1, 1}, "1 333"},
+		{And(MustParse(".a"), MustParse("ul > li")), ".a:is(ul > li)", Specificity{0, 1, 2}, "1 333"},
+		{And(MustParse(".a"), MustParse("p"), MustParse("li")), "p.a:is(li)", Specificity{0, 1, 2}, ""},
+		{And(MustParse("li.a"), MustParse("#x")), "li.a#x", Specificity{1, 1, 1}, "1"},
+		{And(MustParseGroup("li, p"), MustParse(".a")), ":is(li, p).a", Specificity{0, 1, 1}, "1 333 4444"},
+		{And(), "*", Specificity{}, "html head body ul 1 22 333 4444"},
+		{Or(MustParse("p"), MustParse("#x")), ":is(p, #x)", Specificity{1, 0, 0}, "1 4444"},
+		{Or(), ":not(*)", Specificity{}, ""},
+		{Not(MustParse(".a")), ":not(.a)", Specificity{0, 1, 0}, "html head body ul 22"},
+		{Not(MustParseGroup("li, .a")), ":not(:is(li, .a))", Specificity{0, 1, 0}, "html head body ul"},
+		{And(MustParse("li"), long), "", Specificity{}, "333"},
+		{Or(MustParse("p"), long), "", Specificity{}, "html body ul 333 4444"},
+		{And(MustParse("li"), Not(long)), "", Specificity{}, "1 22"},
+	} {
+		var got []string
+		for _, n := range QueryAll(doc, test.m) {
+			if text := nodeText(FromHTML(n)); text != "" && n.Data != "html" && n.Data != "body" && n.Data != "ul" {
+				got = append(got, text)
+			} else {
+				got = append(got, n.Data)
+			}
+		}
+		if s := strings.Join(got, " "); s != test.expected {
+			t.Errorf("%v: expected %q, got %q", test.m, test.expected, s)
+		}
+		sel, isSel := test.m.(Sel)
+		if isSel != (test.css != "") {
+			t.Errorf("%v: unexpected Sel %v", test.m, isSel)
+			continue
+		}
+		if !isSel {
+			if QueryNode(FromHTML(doc), test.m.(NodeMatcher)) == nil && test.expected != "" {
+				t.Errorf("expected MatchNode support")
+			}
+			continue
+		}
+		if sel.String() != test.css || sel.Specificity() != test.spec {
+			t.Errorf("expected %s %s, got %s %s", test.css, test.spec, sel, sel.Specificity())
+		}
+		// the serialization is valid CSS
+		reparsed, err := ParseGroup(sel.String())
+		if err != nil {
+			t.Errorf("%s: %s", sel, err)
+		} else if len(QueryAll(doc, reparsed)) != len(got) {
+			t.Errorf("%s: the serialization doesn't match the same elements", sel)
+		}
+	}
+
+	// pseudo-elements are handled by the generic matchers
+	before, err := ParseWithPseudoElement("p::before")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, isSel := And(before, MustParse(".a")).(Sel); isSel {
+		t.Error("unexpected Sel with a pseudo-element")
+	}
+	if m := And(MustParse("li")); m.(Sel).String() != "li" {
+		t.Errorf("unexpected single matcher %v", m)
+	}
+}
+
+func TestDecorators(t *testing.T) {
+	doc := MustParseHTML(`<main><p>one</p><div><p>two</p><section><p>three</p></section></div></main>`)
+	main := Query(doc, MustParse("main"))
+	texts := func(nodes []*html.Node) string {
+		var out []string
+		for _, n := range nodes {
+			out = append(out, nodeText(FromHTML(n)))
+		}
+		return strings.Join(out, " ")
+	}
+
+	p := MustParse("p")
+	for _, test := range []struct {
+		m        Matcher
+		expected string
+	}{
+		{WithinDepth(p, 1), "one"},
+		{WithinDepth(p, 2), "one two"},
+		{WithinDepth(p, 0), ""},
+		{WithinDepth(p, 10), "one two three"},
+		{HavingText(p, regexp.MustCompile("^t")), "two three"},
+		{HavingText(WithinDepth(p, 2), regexp.MustCompile("^t")), "two"},
+		{WithinDepth(HavingText(p, regexp.MustCompile("e$")), 3), "one three"},
+		{And(WithinDepth(p, 2), Not(MustParse(":first-child"))), ""},
+		{Or(WithinDepth(p, 1), MustParse("section > p")), "one three"},
+	} {
+		if got := texts(QueryAll(main, test.m)); got != test.expected {
+			t.Errorf("expected %q, got %q", test.expected, got)
+		}
+		if got := Query(main, test.m); (got == nil) != (test.expected == "") {
+			t.Errorf("unexpected first match %v", got)
+		}
+		var nodes []*html.Node
+		for _, n := range QueryAllNodes(FromHTML(main), test.m.(NodeMatcher)) {
+			nodes = append(nodes, ToHTML(n))
+		}
+		if got := texts(nodes); got != test.expected {
+			t.Errorf("expected %q with QueryAllNodes, got %q", test.expected, got)
+		}
+	}
+
+	// outside of a query, the depth is counted from the document:
+	// html > body > main > p
+	all := QueryAll(doc, p)
+	if got := texts(Filter(all, WithinDepth(p, 4))); got != "one" {
+		t.Errorf("unexpected filtered nodes %q", got)
+	}
+	if got := texts(QueryAll(doc, WithinDepth(p, 5))); got != "one two" {
+		t.Errorf("unexpected matches %q", got)
+	}
+
+	// parsed selectors are kept
+	sel, ok := HavingText(MustParse("li.a"), regexp.MustCompile("x+")).(Sel)
+	if !ok || sel.String() != "li.a:matches(x+)" {
+		t.Errorf("unexpected selector %v", sel)
+	}
+}
+
+func TestScopePseudoClass(t *testing.T) {
+	doc := MustParseHTML(`<article id="a"><h2>A</h2><div><h2>nested</h2></div><p>a1</p><p>a2</p></article>
+	<article id="b"><h2>B</h2><p>b1</p></article>`)
+
+	type record struct {
+		title string
+		texts []string
+	}
+	var records []record
+	ForEach(doc, MustParse("article"), func(article *html.Node) {
+		r := record{title: nodeText(FromHTML(Query(article, MustParse(":scope > h2"))))}
+		for _, p := range QueryAll(article, MustParseGroup(":scope > p, :scope:not(#a) > h2")) {
+			r.texts = append(r.texts, nodeText(FromHTML(p)))
+		}
+		records = append(records, r)
+	})
+	if len(records) != 2 || records[0].title != "A" || strings.Join(records[0].texts, " ") != "a1 a2" ||
+		records[1].title != "B" || strings.Join(records[1].texts, " ") != "B b1" {
+		t.Errorf("unexpected records %v", records)
+	}
+
+	// the ancestors of the scope are visible
+	b := Query(doc, MustParse("#b"))
+	if got := QueryAll(b, MustParse("body > :scope p")); len(got) != 1 {
+		t.Errorf("unexpected matches %v", got)
+	}
+	// without scope element, :scope is :root
+	if got := QueryAll(doc, MustParse(":scope")); len(got) != 1 || got[0].Data != "html" {
+		t.Errorf("unexpected matches %v", got)
+	}
+	sel := MustParse(":scope > h2")
+	if got := Filter(QueryAll(doc, MustParse("h2")), WithScope(sel, b)); len(got) != 1 || nodeText(FromHTML(got[0])) != "B" {
+		t.Errorf("unexpected filtered nodes %v", got)
+	}
+	if got := QueryAllNodes(FromHTML(b), sel.(NodeMatcher)); len(got) != 1 {
+		t.Errorf("unexpected matches %v", got)
+	}
+	// the decorators bind their selectors
+	if got := QueryAll(b, And(sel, MatcherFunc(func(*html.Node) bool { return true }))); len(got) != 1 {
+		t.Errorf("unexpected matches %v", got)
+	}
+	if s := sel.String(); s != ":scope > h2" || sel.Specificity() != (Specificity{0, 1, 1}) {
+		t.Errorf("unexpected selector %s %s", s, sel.Specificity())
+	}
+}
+
+func TestInScope(t *testing.T) {
+	doc := MustParseHTML(`<div class="card" id="c1">
+		<img id="i1"><div class="content"><img id="i2"><div class="card" id="c2"><img id="i3"></div></div>
+	</div><img id="i4">`)
+	ids := func(nodes []*html.Node) string {
+		var out []string
+		for _, n := range nodes {
+			out = append(out, nodeID(n))
+		}
+		return strings.Join(out, " ")
+	}
+	for _, test := range []struct {
+		m, start, end Matcher
+		exp           string
+	}{
+		{MustParse("img"), MustParse(".card"), nil, "i1 i2 i3"},
+		// donut scope: the content is excluded, except for the nested card
+		{MustParse("img"), MustParse(".card"), MustParse(".content"), "i1 i3"},
+		{MustParse(":scope > img"), MustParse(".card"), nil, "i1 i3"},
+		// the root is only matched by :scope
+		{MustParse(".card"), MustParse(".card"), nil, "c2"},
+		{MustParse(":scope"), MustParse(".card"), nil, "c1 c2"},
+		// :scope in end is the root
+		{MustParse("img"), MustParse(".card"), MustParse(":scope > .content"), "i1 i3"},
+		{MatcherFunc(func(n *html.Node) bool { return n.Data == "img" }), MustParse("#c2"), nil, "i3"},
+	} {
+		m := InScope(test.m, test.start, test.end)
+		got := ids(QueryAll(doc, m))
+		if got != test.exp {
+			t.Errorf("%v from %v to %v: expected %s, got %s", test.m, test.start, test.end, test.exp, got)
+		}
+		// without the bindings cached by the query
+		if got := ids(Filter(QueryAll(doc, MustParse("*")), m)); got != test.exp {
+			t.Errorf("%v from %v to %v: expected %s, got %s with Filter", test.m, test.start, test.end, test.exp, got)
+		}
+	}
+
+	// the limits are searched below the root only
+	m := InScope(MustParse("img"), MustParse(".content"), MustParse(".card"))
+	if got := ids(QueryAll(doc, m)); got != "i2" {
+		t.Errorf("unexpected matches %s", got)
+	}
+	if !m.Match(Query(doc, MustParse("#i2"))) || m.Match(Query(doc, MustParse("#i4"))) {
+		t.Error("unexpected match")
+	}
+}
@@ -0,0 +1,101 @@
+// Package cascadia exposes the API of github.com/andybalholm/cascadia,
+// implemented by github.com/benoitkugler/cascadia, so that a project may
+// switch to this fork by only changing its import path:
+//
+//	import "github.com/benoitkugler/cascadia/compat"
+//
+// The package name is cascadia, like upstream, so that the calling code
+// is unchanged. The types are aliases of the types of the main package:
+// the values returned here may be used with the additional features of the fork,
+// like the AST types, Explain or the generic matching functions.
+//
+// The supported syntax is the one of upstream, including its non-standard
+// pseudo-classes (:contains(), :containsOwn(), :matches(), :matchesOwn(), :haschild())
+// and the attribute operators != and #=. No limit is applied to the selectors,
+// and, as upstream, :has() may be nested in the arguments of :has().
+package cascadia
+
+import (
+	"github.com/benoitkugler/cascadia"
+	"golang.org/x/net/html"
+)
+
+// Matcher is the interface for basic selector functionality.
+// Match returns whether a selector matches n.
+type Matcher = cascadia.Matcher
+
+// Sel is the interface for all the functionality provided by selectors.
+type Sel = cascadia.Sel
+
+// SelectorGroup is a group of selectors, which matches if any of the
+// members match.
+type SelectorGroup = cascadia.SelectorGroup
+
+// A Selector is a function which tells whether a node matches or not.
+type Selector = cascadia.Selector
+
+// Specificity is the CSS specificity as defined in
+// https://www.w3.org/TR/selectors/#specificity-rules
+// with the convention Specificity = [A,B,C].
+type Specificity = cascadia.Specificity
+
+// options accepts the syntax of upstream, which does not restrict
+// the arguments of :has()
+var options = cascadia.Options{RelaxedHas: true}
+
+// Compile parses a selector and returns, if successful, a Selector object
+// that can be used to match against html.Node objects.
+func Compile(sel string) (Selector, error) {
+	compiled, err := ParseGroup(sel)
+	if err != nil {
+		return nil, err
+	}
+	return Selector(compiled.Match), nil
+}
+
+// MustCompile is like Compile, but panics instead of returning an error.
+func MustCompile(sel string) Selector {
+	compiled, err := Compile(sel)
+	if err != nil {
+		panic(err)
+	}
+	return compiled
+}
+
+// Parse parses a selector. Use `ParseWithPseudoElement`
+// if you need support for pseudo-elements.
+func Parse(sel string) (Sel, error) { return cascadia.ParseWithOptions(sel, options) }
+
+// ParseWithPseudoElement parses a single selector,
+// with support for pseudo-element.
+func ParseWithPseudoElement(sel string) (Sel, error) {
+	opts := options
+	opts.PseudoElements = true
+	return cascadia.ParseWithOptions(sel, opts)
+}
+
+// ParseGroup parses a selector, or a group of selectors separated by commas.
+// Use `ParseGroupWithPseudoElements`
+// if you need support for pseudo-elements.
+func ParseGroup(sel string) (SelectorGroup, error) {
+	return cascadia.ParseGroupWithOptions(sel, options)
+}
+
+// ParseGroupWithPseudoElements parses a selector, or a group of selectors separated by commas.
+// It supports pseudo-elements.
+func ParseGroupWithPseudoElements(sel string) (SelectorGroup, error) {
+	opts := options
+	opts.PseudoElements = true
+	return cascadia.ParseGroupWithOptions(sel, opts)
+}
+
+// Query returns the first node that matches m, from the descendants of n.
+// If none matches, it returns nil.
+func Query(n *html.Node, m Matcher) *html.Node { return cascadia.Query(n, m) }
+
+// QueryAll returns a slice of all the nodes that match m, from the descendants
+// of n.
+func QueryAll(n *html.Node, m Matcher) []*html.Node { return cascadia.QueryAll(n, m) }
+
+// Filter returns the nodes that match m.
+func Filter(nodes []*html.Node, m Matcher) []*html.Node { return cascadia.Filter(nodes, m) }
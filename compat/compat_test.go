@@ -0,0 +1,81 @@
+package cascadia
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+// the signatures of the upstream package
+var (
+	_ func(string) (Selector, error)           = Compile
+	_ func(string) Selector                    = MustCompile
+	_ func(string) (Sel, error)                = Parse
+	_ func(string) (Sel, error)                = ParseWithPseudoElement
+	_ func(string) (SelectorGroup, error)      = ParseGroup
+	_ func(string) (SelectorGroup, error)      = ParseGroupWithPseudoElements
+	_ func(*html.Node, Matcher) *html.Node     = Query
+	_ func(*html.Node, Matcher) []*html.Node   = QueryAll
+	_ func([]*html.Node, Matcher) []*html.Node = Filter
+
+	_ Matcher = Selector(nil)
+	_ Matcher = SelectorGroup(nil)
+)
+
+func TestCompat(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(`<ul><li class="a">one</li><li>two</li><li lang="fr">trois</li></ul>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := MustCompile("li:containsOwn(t)")
+	if got := s.MatchAll(doc); len(got) != 2 {
+		t.Errorf("expected 2 matches, got %d", len(got))
+	}
+	if first := s.MatchFirst(doc); first == nil || first.FirstChild.Data != "two" {
+		t.Errorf("unexpected first match %v", first)
+	}
+	if got := s.Filter(QueryAll(doc, MustCompile("li"))); len(got) != 2 {
+		t.Errorf("expected 2 matches, got %d", len(got))
+	}
+
+	for _, test := range []struct {
+		sel string
+		exp int
+	}{
+		{"li.a, li:lang(fr)", 2},
+		{"ul:haschild(.a)", 1},
+		{"li:matches(^t)", 2},
+		{"li[class!=a]", 2},
+		{"li:nth-child(2n+1)", 2},
+		{":root:has(ul:has(.a))", 1},
+	} {
+		group, err := ParseGroup(test.sel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := QueryAll(doc, group); len(got) != test.exp {
+			t.Errorf("%s: expected %d matches, got %d", test.sel, test.exp, len(got))
+		}
+	}
+
+	// nested :has(), accepted by upstream
+	if _, err := Parse("div:has(p:has(a))"); err != nil {
+		t.Error(err)
+	}
+	if got := QueryAll(doc, MustCompile("body:has(ul:has(:containsOwn(trois)))")); len(got) != 1 {
+		t.Errorf("expected 1 match, got %d", len(got))
+	}
+
+	if _, err := Parse("p::before"); err == nil {
+		t.Error("expected an error for a pseudo-element")
+	}
+	sel, err := ParseWithPseudoElement("p::before")
+	if err != nil || sel.PseudoElement() != "before" {
+		t.Errorf("unexpected result %v %v", sel, err)
+	}
+	if (Specificity{0, 1, 1}).Less(sel.Specificity()) {
+		t.Errorf("unexpected specificity %v", sel.Specificity())
+	}
+}
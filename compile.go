@@ -0,0 +1,135 @@
+package cascadia
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// CompileAST validates a selector tree, typically built by hand or decoded
+// from JSON, and returns a selector ready for matching.
+// The returned selector is normalized the same way the parser does
+// (lower-cased tags and attribute keys, etc.)
+//
+// Custom implementations of Sel found in the tree are kept as it is.
+func CompileAST(node Sel) (Sel, error) {
+	switch s := node.(type) {
+	case nil:
+		return nil, errors.New("missing selector")
+	case TagSelector:
+		if s.Tag == "" {
+			return nil, errors.New("empty tag name in type selector")
+		}
+		out := newTagSelector(s.Tag)
+		out.Pos = s.Pos
+		return out, nil
+	case ClassSelector:
+		if s.Class == "" {
+			return nil, errors.New("empty class name in class selector")
+		}
+	case IDSelector:
+		if s.ID == "" {
+			return nil, errors.New("empty id in id selector")
+		}
+	case AttrSelector:
+		if s.Key == "" {
+			return nil, errors.New("empty attribute name in attribute selector")
+		}
+		if s.rawKey == "" {
+			s.Key, s.rawKey = attrKeys(s.Key)
+		}
+		switch s.Operation {
+		case "", "=", "!=", "~=", "|=", "^=", "$=", "*=":
+			s.Regexp = nil
+		case "#=":
+			if s.Regexp == nil {
+				return nil, errors.New("missing regular expression with operator #=")
+			}
+			s.Val = ""
+		default:
+			return nil, fmt.Errorf("attribute operator %q is not supported", s.Operation)
+		}
+		return s, nil
+	case RelativePseudoClassSelector:
+		switch s.Name {
+		case "not", "has", "haschild", "is", "where":
+		default:
+			return nil, fmt.Errorf("unsupported relative pseudo class selector : %s", s.Name)
+		}
+		if len(s.Args) == 0 {
+			return nil, fmt.Errorf("missing arguments for :%s", s.Name)
+		}
+		args, err := CompileASTGroup(s.Args)
+		if err != nil {
+			return nil, err
+		}
+		s.Args = args
+		return s, nil
+	case ContainsPseudoClassSelector:
+		s.Value = strings.ToLower(s.Value)
+		return s, nil
+	case RegexpPseudoClassSelector:
+		if s.Regexp == nil {
+			return nil, errors.New("missing regular expression in :matches")
+		}
+	case LangPseudoClassSelector:
+		if s.Lang == "" {
+			return nil, errors.New("empty language in :lang")
+		}
+		s.Lang = strings.ToLower(s.Lang)
+		return s, nil
+	case CompoundSelector:
+		if s.Pseudo != "" && !pseudoElements[s.Pseudo] && !isExtensionPseudoElement(s.Pseudo) {
+			return nil, fmt.Errorf("unknown pseudoelement :%s", s.Pseudo)
+		}
+		var inner []Sel
+		for _, c := range s.Selectors {
+			switch c.(type) {
+			case CompoundSelector, CombinedSelector:
+				return nil, fmt.Errorf("invalid %T in compound selector", c)
+			}
+			c, err := CompileAST(c)
+			if err != nil {
+				return nil, err
+			}
+			inner = append(inner, c)
+		}
+		s.Selectors = inner
+		return s, nil
+	case CombinedSelector:
+		first, err := CompileAST(s.First)
+		if err != nil {
+			return nil, err
+		}
+		s.First = first
+		if s.Second == nil {
+			if s.Combinator != 0 {
+				return nil, fmt.Errorf("missing second selector for combinator %q", s.Combinator)
+			}
+			return s, nil
+		}
+		switch s.Combinator {
+		case ' ', '>', '+', '~':
+		default:
+			return nil, fmt.Errorf("unknown combinator %q", s.Combinator)
+		}
+		if s.Second, err = CompileAST(s.Second); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+	return node, nil
+}
+
+// CompileASTGroup applies CompileAST to each selector of group.
+func CompileASTGroup(group SelectorGroup) (SelectorGroup, error) {
+	out := make(SelectorGroup, len(group))
+	for i, s := range group {
+		var err error
+		out[i], err = CompileAST(s)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
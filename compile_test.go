@@ -0,0 +1,61 @@
+package cascadia
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestCompileAST(t *testing.T) {
+	for _, test := range selectorTests {
+		group, err := ParseGroup(test.selector)
+		if err != nil {
+			t.Fatal(err)
+		}
+		compiled, err := CompileASTGroup(group)
+		if err != nil {
+			t.Fatalf("%s: %s", test.selector, err)
+		}
+		if !reflect.DeepEqual(group, compiled) {
+			t.Errorf("%s: CompileAST should not change a parsed selector, got %s", test.selector, compiled)
+		}
+	}
+
+	sel, err := CompileAST(CompoundSelector{Selectors: []Sel{
+		TagSelector{Tag: "DIV"},
+		AttrSelector{Key: "Data-X", Operation: "^=", Val: "a"},
+		LangPseudoClassSelector{Lang: "EN"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the attribute name is lower-cased, but its case is kept for the foreign elements
+	exp, _ := Parse(`div[Data-X^="a"]:lang(en)`)
+	if !reflect.DeepEqual(sel, exp) {
+		t.Errorf("expected %#v, got %#v", exp, sel)
+	}
+
+	for _, invalid := range []Sel{
+		nil,
+		TagSelector{},
+		ClassSelector{},
+		AttrSelector{Key: "a", Operation: "=="},
+		AttrSelector{Key: "a", Operation: "#="},
+		RelativePseudoClassSelector{Name: "matches-any", Args: SelectorGroup{ClassSelector{Class: "a"}}},
+		RelativePseudoClassSelector{Name: "not"},
+		RegexpPseudoClassSelector{},
+		CompoundSelector{Pseudo: "unknown"},
+		CompoundSelector{Selectors: []Sel{CompoundSelector{}}},
+		CombinedSelector{First: TagSelector{Tag: "a"}, Combinator: '>'},
+		CombinedSelector{First: TagSelector{Tag: "a"}, Combinator: '|', Second: TagSelector{Tag: "b"}},
+		CombinedSelector{First: TagSelector{Tag: "a"}, Combinator: '>', Second: AttrSelector{}},
+	} {
+		if _, err := CompileAST(invalid); err == nil {
+			t.Errorf("expected error for %#v", invalid)
+		}
+	}
+
+	if _, err := CompileAST(AttrSelector{Key: "a", Operation: "#=", Regexp: regexp.MustCompile("a+")}); err != nil {
+		t.Error(err)
+	}
+}
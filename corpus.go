@@ -0,0 +1,114 @@
+package cascadia
+
+import (
+	"runtime"
+	"sync"
+
+	"golang.org/x/net/html"
+)
+
+// DocumentResult is the result of QueryCorpus for one document.
+type DocumentResult struct {
+	Index int   // the index of the document
+	Err   error // returned by the load function
+	// Matches are the elements matched by each matcher, in tree order.
+	Matches [][]*html.Node
+	// FirstMatch is, for each matcher, the position of its first match
+	// among the elements of the document, in tree order, or -1.
+	FirstMatch []int
+}
+
+// CorpusSummary aggregates the results of QueryCorpus.
+type CorpusSummary struct {
+	Documents int // the number of documents successfully loaded
+	Errors    int // the number of documents which failed to load
+	// Counts are the number of matches of each matcher, in all the documents.
+	Counts []int
+	// DocumentCounts are the number of documents matched by each matcher.
+	DocumentCounts []int
+}
+
+// QueryCorpus runs the matchers against the n documents returned by load,
+// using at most workers goroutines (runtime.GOMAXPROCS(0) if workers <= 0),
+// and returns the results of each document, by index, and their summary.
+//
+// load is called once for each index in [0, n), concurrently, so that
+// the documents may be read and parsed in parallel. The matchers must be
+// safe for concurrent use, which is the case of the selectors of this
+// package.
+func QueryCorpus(n int, load func(i int) (*html.Node, error), matchers []Matcher, workers int) ([]DocumentResult, CorpusSummary) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > n {
+		workers = n
+	}
+	results := make([]DocumentResult, n)
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				results[i] = queryDocument(i, load, matchers)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+
+	summary := CorpusSummary{Counts: make([]int, len(matchers)), DocumentCounts: make([]int, len(matchers))}
+	for _, r := range results {
+		if r.Err != nil {
+			summary.Errors++
+			continue
+		}
+		summary.Documents++
+		for j, matches := range r.Matches {
+			summary.Counts[j] += len(matches)
+			if len(matches) != 0 {
+				summary.DocumentCounts[j]++
+			}
+		}
+	}
+	return results, summary
+}
+
+func queryDocument(i int, load func(i int) (*html.Node, error), matchers []Matcher) DocumentResult {
+	out := DocumentResult{Index: i}
+	doc, err := load(i)
+	if err != nil {
+		out.Err = err
+		return out
+	}
+	out.Matches = make([][]*html.Node, len(matchers))
+	out.FirstMatch = make([]int, len(matchers))
+	for j := range out.FirstMatch {
+		out.FirstMatch[j] = -1
+	}
+	position := 0
+	var visit func(n *html.Node)
+	visit = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			for j, m := range matchers {
+				if m.Match(c) {
+					if out.FirstMatch[j] == -1 {
+						out.FirstMatch[j] = position
+					}
+					out.Matches[j] = append(out.Matches[j], c)
+				}
+			}
+			position++
+			visit(c)
+		}
+	}
+	visit(doc)
+	return out
+}
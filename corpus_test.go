@@ -0,0 +1,56 @@
+package cascadia
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestQueryCorpus(t *testing.T) {
+	sources := []string{
+		`<p>a</p><p class="x">b</p>`,
+		`<div><span class="x">c</span></div>`,
+		"", // fails
+		`<p>d</p>`,
+	}
+	load := func(i int) (*html.Node, error) {
+		if sources[i] == "" {
+			return nil, errors.New("not found")
+		}
+		return html.Parse(strings.NewReader(sources[i]))
+	}
+	matchers := []Matcher{MustCompile("p"), MustCompile(".x"), MustCompile("table")}
+	for _, workers := range []int{0, 1, 3, 10} {
+		results, summary := QueryCorpus(len(sources), load, matchers, workers)
+		expected := CorpusSummary{Documents: 3, Errors: 1, Counts: []int{3, 2, 0}, DocumentCounts: []int{2, 2, 0}}
+		if !reflect.DeepEqual(summary, expected) {
+			t.Errorf("workers %d: expected %+v, got %+v", workers, expected, summary)
+		}
+		if len(results) != len(sources) {
+			t.Fatalf("unexpected results %v", results)
+		}
+		for i, r := range results {
+			if r.Index != i {
+				t.Errorf("unexpected index %d for document %d", r.Index, i)
+			}
+		}
+		if results[2].Err == nil {
+			t.Error("expected an error for document 2")
+		}
+		// html, head, body, p, p
+		if got := fmt.Sprint(results[0].FirstMatch); got != "[3 4 -1]" {
+			t.Errorf("unexpected first matches %s", got)
+		}
+		if got := len(results[0].Matches[0]); got != 2 {
+			t.Errorf("expected 2 matches, got %d", got)
+		}
+	}
+
+	if results, summary := QueryCorpus(0, load, matchers, 0); len(results) != 0 || summary.Documents != 0 {
+		t.Errorf("unexpected results for an empty corpus: %v %v", results, summary)
+	}
+}
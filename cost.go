@@ -0,0 +1,113 @@
+package cascadia
+
+// Cost is an estimation of the cost of matching a selector,
+// returned by Estimate. The counters describe the structure
+// of the selector, and Score summarizes them, so that selectors
+// can be compared (larger is more expensive). The scale of Score
+// is arbitrary and may change between versions.
+type Cost struct {
+	SimpleSelectors int // number of simple selectors
+	Combinators     int // number of combinators
+	// Descendants is the number of combinators which may visit
+	// an unbounded number of nodes (descendant ' ' and general sibling '~')
+	Descendants int
+	// Relational is the number of :has() and :haschild() pseudo-classes,
+	// which visit the subtree of the elements
+	Relational int
+	// Regexps is the number of regular expressions (:matches(), [attr#=regexp])
+	Regexps int
+	// TextScans is the number of pseudo-classes inspecting the text content,
+	// like :contains() or :matches()
+	TextScans int
+	// UniversalKey is true if the rightmost compound selector
+	// (the one tested first on each node) has no type, id or class selector,
+	// so that it can't be used to quickly discard elements.
+	UniversalKey bool
+
+	Score int
+}
+
+// weights used to compute the score
+const (
+	costSimple     = 1
+	costCombinator = 1
+	costDescendant = 4
+	costRelational = 10
+	costRegexp     = 5
+	costTextScan   = 3
+)
+
+// Estimate returns the estimated matching cost of sel.
+func Estimate(sel Sel) Cost {
+	var c Cost
+	c.add(sel)
+	c.UniversalKey = RuleKey(sel).Kind == KeyUniversal
+	c.Score = c.score()
+	return c
+}
+
+// EstimateGroup returns the sum of the costs of the selectors in group.
+// UniversalKey is true if it is true for one of the selectors.
+func EstimateGroup(group SelectorGroup) Cost {
+	var out Cost
+	for _, sel := range group {
+		c := Estimate(sel)
+		out.SimpleSelectors += c.SimpleSelectors
+		out.Combinators += c.Combinators
+		out.Descendants += c.Descendants
+		out.Relational += c.Relational
+		out.Regexps += c.Regexps
+		out.TextScans += c.TextScans
+		out.UniversalKey = out.UniversalKey || c.UniversalKey
+		out.Score += c.Score
+	}
+	return out
+}
+
+func (c *Cost) score() int {
+	s := c.SimpleSelectors*costSimple +
+		(c.Combinators-c.Descendants)*costCombinator +
+		c.Descendants*costDescendant +
+		c.Relational*costRelational +
+		c.Regexps*costRegexp +
+		c.TextScans*costTextScan
+	if c.UniversalKey {
+		s *= 2
+	}
+	return s
+}
+
+// add accumulates the counters of sel and its components
+func (c *Cost) add(sel Sel) {
+	Walk(sel, func(s Sel) bool {
+		switch s := s.(type) {
+		case CompoundSelector:
+			return true
+		case CombinedSelector:
+			if s.Second != nil {
+				c.Combinators++
+				if s.Combinator == ' ' || s.Combinator == '~' {
+					c.Descendants++
+				}
+			}
+			return true
+		case RelativePseudoClassSelector:
+			c.SimpleSelectors++
+			if s.Name == "has" || s.Name == "haschild" {
+				c.Relational++
+			}
+			return true
+		case AttrSelector:
+			if s.Regexp != nil {
+				c.Regexps++
+			}
+		case RegexpPseudoClassSelector:
+			c.Regexps++
+			c.TextScans++
+		case ContainsPseudoClassSelector:
+			c.TextScans++
+		}
+		c.SimpleSelectors++
+		return true
+	})
+}
@@ -0,0 +1,38 @@
+package cascadia
+
+import "testing"
+
+func TestEstimate(t *testing.T) {
+	for _, test := range []struct {
+		sel  string
+		cost Cost
+	}{
+		{"div", Cost{SimpleSelectors: 1, Score: 1}},
+		{"*", Cost{UniversalKey: true}},
+		{"div > p.a", Cost{SimpleSelectors: 3, Combinators: 1, Score: 4}},
+		{"div p", Cost{SimpleSelectors: 2, Combinators: 1, Descendants: 1, Score: 6}},
+		{"[href]", Cost{SimpleSelectors: 1, UniversalKey: true, Score: 2}},
+		{"p:has(a)", Cost{SimpleSelectors: 3, Relational: 1, Score: 13}},
+		{"a:matches(x+)", Cost{SimpleSelectors: 2, Regexps: 1, TextScans: 1, Score: 10}},
+		{`p[href#=(\d+)]`, Cost{SimpleSelectors: 2, Regexps: 1, Score: 7}},
+	} {
+		sel, err := ParseWithPseudoElement(test.sel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := Estimate(sel); got != test.cost {
+			t.Errorf("%s: expected %+v, got %+v", test.sel, test.cost, got)
+		}
+	}
+
+	group, _ := ParseGroup("div, [href]")
+	if c := EstimateGroup(group); c.Score != 3 || !c.UniversalKey {
+		t.Errorf("unexpected group cost %+v", c)
+	}
+
+	expensive, _ := Parse("div p:has(a:contains(x))")
+	cheap, _ := Parse("div > p")
+	if Estimate(expensive).Score <= Estimate(cheap).Score {
+		t.Error(":has should be more expensive")
+	}
+}
@@ -0,0 +1,149 @@
+package cascadia
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DiagnosticKind classifies the non fatal issues found while parsing.
+type DiagnosticKind uint8
+
+const (
+	// DiagNonStandard is used for cascadia extensions, which are not
+	// supported by browsers (like :contains() or [attr!=value])
+	DiagNonStandard DiagnosticKind = iota
+	// DiagDeprecated is used for legacy syntax (like :before instead of ::before)
+	DiagDeprecated
+	// DiagNeverMatches is used for valid selectors which never match any element
+	// (like :hover in a static context, or [attr^=""])
+	DiagNeverMatches
+)
+
+func (k DiagnosticKind) String() string {
+	switch k {
+	case DiagNonStandard:
+		return "non-standard"
+	case DiagDeprecated:
+		return "deprecated"
+	case DiagNeverMatches:
+		return "never-matches"
+	default:
+		return fmt.Sprintf("DiagnosticKind(%d)", k)
+	}
+}
+
+// Diagnostic is a warning about a valid, but suspicious, part of a selector.
+type Diagnostic struct {
+	Kind    DiagnosticKind
+	Message string
+	Span    Span // position of the offending component
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %s: %s", d.Span.Start, d.Span.End, d.Kind, d.Message)
+}
+
+// ParseWithDiagnostics is like ParseWithPseudoElement, but also returns the warnings
+// found while parsing, even if an error occurred.
+func ParseWithDiagnostics(sel string) (Sel, []Diagnostic, error) {
+	p := &parser{s: sel, acceptPseudoElements: true, collectDiagnostics: true}
+	compiled, err := p.parseSelector()
+	if err == nil {
+		err = p.checkLeftOver()
+	}
+	if err != nil {
+		return nil, p.diagnostics, err
+	}
+	return compiled, p.diagnostics, nil
+}
+
+// ParseGroupWithDiagnostics is like ParseGroupWithPseudoElements,
+// but also returns the warnings found while parsing, even if an error occurred.
+func ParseGroupWithDiagnostics(sel string) (SelectorGroup, []Diagnostic, error) {
+	p := &parser{s: sel, acceptPseudoElements: true, collectDiagnostics: true}
+	compiled, err := p.parseSelectorGroup()
+	if err == nil {
+		err = p.checkLeftOver()
+	}
+	if err != nil {
+		return nil, p.diagnostics, err
+	}
+	return compiled, p.diagnostics, nil
+}
+
+func (p *parser) warn(kind DiagnosticKind, start int, format string, args ...interface{}) {
+	p.diagnostics = append(p.diagnostics, Diagnostic{
+		Kind:    kind,
+		Message: fmt.Sprintf(format, args...),
+		Span:    Span{start, p.i},
+	})
+}
+
+// checkAttribute is called after parsing `attr`, starting at `start`
+func (p *parser) checkAttribute(start int, attr AttrSelector) {
+	if msg := nonStandardOperator(attr.Operation); msg != "" {
+		p.warn(DiagNonStandard, start, "%s", msg)
+	}
+	switch attr.Operation {
+	case "^=", "$=", "*=":
+		if attr.Val == "" {
+			p.warn(DiagNeverMatches, start, "attribute operator %s with an empty value never matches", attr.Operation)
+		}
+	case "~=":
+		if attr.Val == "" || strings.ContainsAny(attr.Val, " \t\r\n\f") {
+			p.warn(DiagNeverMatches, start, "attribute operator ~= with an empty value or a value containing whitespace never matches")
+		}
+	}
+}
+
+// checkPseudo is called after parsing `name`, starting at `start`
+func (p *parser) checkPseudo(start int, name string, doubleColon bool) {
+	if msg := nonStandardPseudo(name, doubleColon); msg != "" {
+		p.warn(DiagNonStandard, start, "%s", msg)
+	}
+	switch name {
+	case "visited", "hover", "active", "focus", "target":
+		p.warn(DiagNeverMatches, start, ":%s never matches in a static context", name)
+	}
+	if pseudoElements[name] && !doubleColon && isLegacyPseudoElement(name) {
+		p.warn(DiagDeprecated, start, "the single colon syntax for pseudo-element :%s is deprecated, use ::%s", name, name)
+	}
+}
+
+// nonStandardOperator returns a description of the extension
+// used by the attribute operator op, or an empty string.
+func nonStandardOperator(op string) string {
+	switch op {
+	case "!=", "#=":
+		return fmt.Sprintf("attribute operator %s is a non-standard extension", op)
+	}
+	return ""
+}
+
+// nonStandardPseudo returns a description of the extension used by the
+// pseudo-class or pseudo-element `name`, or an empty string.
+func nonStandardPseudo(name string, doubleColon bool) string {
+	switch name {
+	case "contains", "containsown", "matchesown", "input", "haschild":
+		return fmt.Sprintf(":%s is a non-standard extension", name)
+	case "matches":
+		return ":matches is a non-standard extension, which differs from the standard :matches() (now :is())"
+	}
+	if _, ok := nodePseudoElement(name); (ok || name == "attr") && doubleColon {
+		return fmt.Sprintf("::%s is a non-standard extension", name)
+	}
+	if pseudoElements[name] && !doubleColon && !isLegacyPseudoElement(name) {
+		return fmt.Sprintf("pseudo-element ::%s requires a double colon", name)
+	}
+	return ""
+}
+
+// isLegacyPseudoElement returns true for the pseudo-elements
+// which may be written with a single colon.
+func isLegacyPseudoElement(name string) bool {
+	switch name {
+	case "before", "after", "first-line", "first-letter":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package cascadia
+
+import "testing"
+
+func TestDiagnostics(t *testing.T) {
+	for _, test := range []struct {
+		input  string
+		kinds  []DiagnosticKind
+		sample string // expected text of the first diagnostic
+	}{
+		{"div > p.a::before", nil, ""},
+		{"a:hover", []DiagnosticKind{DiagNeverMatches}, ":hover"},
+		{"a:contains(x), b:before", []DiagnosticKind{DiagNonStandard, DiagDeprecated}, ":contains(x)"},
+		{"a[href!=x]", []DiagnosticKind{DiagNonStandard}, "[href!=x]"},
+		{`a[href^=""]`, []DiagnosticKind{DiagNeverMatches}, `[href^=""]`},
+		{`a[class~="a b"]`, []DiagnosticKind{DiagNeverMatches}, `[class~="a b"]`},
+		{`p:not(:focus):marker`, []DiagnosticKind{DiagNeverMatches, DiagNonStandard}, `:focus`},
+	} {
+		_, diags, err := ParseGroupWithDiagnostics(test.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(diags) != len(test.kinds) {
+			t.Fatalf("%s: expected %d diagnostics, got %v", test.input, len(test.kinds), diags)
+		}
+		for i, d := range diags {
+			if d.Kind != test.kinds[i] {
+				t.Errorf("%s: expected %s, got %s", test.input, test.kinds[i], d)
+			}
+		}
+		if len(diags) != 0 {
+			if s := test.input[diags[0].Span.Start:diags[0].Span.End]; s != test.sample {
+				t.Errorf("%s: unexpected span %q", test.input, s)
+			}
+		}
+	}
+
+	// diagnostics are returned even on error
+	_, diags, err := ParseWithDiagnostics("a:hover[")
+	if err == nil || len(diags) != 1 {
+		t.Errorf("expected error and one diagnostic, got %s %v", err, diags)
+	}
+}
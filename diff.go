@@ -0,0 +1,146 @@
+package cascadia
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// ChangeKind is the kind of a NodeChange.
+type ChangeKind uint8
+
+const (
+	ChangeAdded ChangeKind = iota
+	ChangeRemoved
+	ChangeModified
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeAdded:
+		return "added"
+	case ChangeRemoved:
+		return "removed"
+	case ChangeModified:
+		return "modified"
+	default:
+		return fmt.Sprintf("ChangeKind(%d)", k)
+	}
+}
+
+// NodeChange is a difference between two documents, returned by Diff.
+type NodeChange struct {
+	Kind ChangeKind
+	// Old is nil for the added nodes, and New for the removed ones.
+	Old, New *html.Node
+	// Selector designates the node in its document (New, or Old if removed),
+	// as returned by SelectorFor.
+	Selector string
+}
+
+func (c NodeChange) String() string { return c.Kind.String() + " " + c.Selector }
+
+// Diff compares the elements matched by m in the documents old and new,
+// and returns the changes, in the order of the documents.
+//
+// The matches with the same HTML (including their content) are first
+// paired in order, using a longest common subsequence, so that inserting
+// an element does not change its following siblings. Between them, the
+// remaining matches are paired by their identity (the tag, id and classes)
+// and reported as modified. As a consequence, when m matches both an
+// element and one of its descendants, a change in the descendant is also
+// reported for the ancestor.
+func Diff(old, new *html.Node, m Matcher) []NodeChange {
+	olds, news := QueryAll(old, m), QueryAll(new, m)
+	oldHTML, newHTML := make([]string, len(olds)), make([]string, len(news))
+	for i, n := range olds {
+		oldHTML[i] = renderString(n)
+	}
+	for i, n := range news {
+		newHTML[i] = renderString(n)
+	}
+
+	var out []NodeChange
+	// diffGap compares olds[i0:i1] and news[j0:j1], which have no equal elements
+	diffGap := func(i0, i1, j0, j1 int) {
+		oldKeys, newKeys := make([]string, i1-i0), make([]string, j1-j0)
+		for i := range oldKeys {
+			oldKeys[i] = diffIdentity(olds[i0+i])
+		}
+		for j := range newKeys {
+			newKeys[j] = diffIdentity(news[j0+j])
+		}
+		i, j := i0, j0
+		flush := func(i1, j1 int) {
+			for ; i < i1; i++ {
+				out = append(out, NodeChange{Kind: ChangeRemoved, Old: olds[i], Selector: SelectorFor(olds[i])})
+			}
+			for ; j < j1; j++ {
+				out = append(out, NodeChange{Kind: ChangeAdded, New: news[j], Selector: SelectorFor(news[j])})
+			}
+		}
+		for _, p := range commonSubsequence(oldKeys, newKeys) {
+			flush(i0+p[0], j0+p[1])
+			out = append(out, NodeChange{Kind: ChangeModified, Old: olds[i], New: news[j], Selector: SelectorFor(news[j])})
+			i++
+			j++
+		}
+		flush(i1, j1)
+	}
+
+	i, j := 0, 0
+	for _, p := range commonSubsequence(oldHTML, newHTML) {
+		diffGap(i, p[0], j, p[1])
+		i, j = p[0]+1, p[1]+1
+	}
+	diffGap(i, len(olds), j, len(news))
+	return out
+}
+
+// commonSubsequence returns the indices of the pairs of equal elements
+// of a longest common subsequence of a and b, in increasing order.
+func commonSubsequence(a, b []string) [][2]int {
+	// lengths[i][j] is the length of the LCS of a[i:] and b[j:]
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			case lengths[i+1][j] >= lengths[i][j+1]:
+				lengths[i][j] = lengths[i+1][j]
+			default:
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+	var out [][2]int
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			out = append(out, [2]int{i, j})
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return out
+}
+
+// diffIdentity returns the key used to pair the elements
+func diffIdentity(n *html.Node) string {
+	return n.Data + "#" + nodeID(n) + "." + strings.Join(SplitTokens(getAttr(n, "class")), ".")
+}
+
+func renderString(n *html.Node) string {
+	var b strings.Builder
+	html.Render(&b, n) // writing to a strings.Builder never fails
+	return b.String()
+}
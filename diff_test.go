@@ -0,0 +1,34 @@
+package cascadia
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	old := MustParseHTML(`<main><div class="content"><p>one</p><p>two</p><p class="x">three</p></div></main><aside><p>ignored</p></aside>`)
+	new := MustParseHTML(`<main><div class="content"><p>new</p><p>one</p><p>TWO</p></div></main><aside><p>changed</p></aside>`)
+	changes := Diff(old, new, MustCompile("main .content p"))
+	var got []string
+	for _, c := range changes {
+		got = append(got, c.String())
+	}
+	expected := []string{
+		"added div > p:nth-child(1)",
+		"modified p:nth-child(3)",
+		"removed p:nth-child(3)",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+	if c := changes[1]; c.Old == nil || c.New == nil || nodeText(FromHTML(c.Old)) != "two" {
+		t.Errorf("unexpected modified nodes %v", c)
+	}
+	if c := changes[2]; c.New != nil || c.Old == nil {
+		t.Errorf("unexpected removed nodes %v", c)
+	}
+
+	if changes := Diff(old, old, MustCompile("p")); len(changes) != 0 {
+		t.Errorf("expected no changes, got %v", changes)
+	}
+}
@@ -0,0 +1,71 @@
+package cascadia
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dump returns a multi-line, indented, description of the tree of sel,
+// with one line per component giving its kind (as in the JSON representation),
+// its arguments and its specificity, such as
+//
+//	combined ">" (0,1,2)
+//	  compound (0,1,1)
+//	    tag "div" (0,0,1)
+//	    class "a" (0,1,0)
+//	  tag "p" (0,0,1)
+//
+// Contrary to String, the output is not valid CSS: it is intended for debugging
+// and golden tests.
+func Dump(sel Sel) string {
+	var b strings.Builder
+	dump(&b, sel, 0)
+	return b.String()
+}
+
+// DumpGroup is like Dump, for each selector of group.
+func DumpGroup(group SelectorGroup) string {
+	var b strings.Builder
+	for i, sel := range group {
+		fmt.Fprintf(&b, "selector %d\n", i)
+		dump(&b, sel, 1)
+	}
+	return b.String()
+}
+
+func dump(b *strings.Builder, sel Sel, depth int) {
+	b.WriteString(strings.Repeat("  ", depth))
+	b.WriteString(dumpLine(sel))
+	fmt.Fprintf(b, " %s\n", sel.Specificity())
+	for _, c := range children(sel) {
+		dump(b, c, depth+1)
+	}
+}
+
+// dumpLine describes sel, without its components
+func dumpLine(sel Sel) string {
+	js, err := toJSONSel(sel)
+	if err != nil { // custom type
+		return fmt.Sprintf("%T %q", sel, sel.String())
+	}
+	line := js.Kind
+	if js.Name != "" {
+		line += fmt.Sprintf(" %q", js.Name)
+	}
+	if js.Op != "" {
+		line += " " + js.Op
+	}
+	if js.Value != "" || js.Op != "" {
+		line += fmt.Sprintf(" %q", js.Value)
+	}
+	if js.A != nil {
+		line += fmt.Sprintf(" a=%d b=%d", *js.A, *js.B)
+	}
+	if js.Combinator != "" {
+		line += fmt.Sprintf(" %q", js.Combinator)
+	}
+	if js.PseudoElement != "" {
+		line += " ::" + js.PseudoElement
+	}
+	return line
+}
@@ -0,0 +1,50 @@
+package cascadia
+
+import "testing"
+
+func TestDump(t *testing.T) {
+	for _, test := range []struct{ sel, exp string }{
+		{"div.a > p", `combined ">" (0,1,2)
+  compound (0,1,1)
+    tag "div" (0,0,1)
+    class "a" (0,1,0)
+  tag "p" (0,0,1)
+`},
+		{`a[href^="http"]:nth-child(2n+1)::before`, `compound ::before (0,2,2)
+  tag "a" (0,0,1)
+  attr "href" ^= "http" (0,1,0)
+  pseudo-class "nth-child" a=2 b=1 (0,1,0)
+`},
+		{"li:not(#x, [title]):hover", `compound (1,0,1)
+  tag "li" (0,0,1)
+  pseudo-class "not" (1,0,0)
+    id "x" (1,0,0)
+    attr "title" (0,1,0)
+  pseudo-class "hover" (0,0,0)
+`},
+		{"*", "compound (0,0,0)\n"},
+	} {
+		sel, err := ParseWithPseudoElement(test.sel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := Dump(sel); got != test.exp {
+			t.Errorf("%s: expected\n%s\ngot\n%s", test.sel, test.exp, got)
+		}
+	}
+}
+
+func TestDumpGroup(t *testing.T) {
+	group, err := ParseGroup("p, #a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := `selector 0
+  tag "p" (0,0,1)
+selector 1
+  id "a" (1,0,0)
+`
+	if got := DumpGroup(group); got != exp {
+		t.Errorf("expected\n%s\ngot\n%s", exp, got)
+	}
+}
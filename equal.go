@@ -0,0 +1,103 @@
+package cascadia
+
+import (
+	"reflect"
+	"regexp"
+)
+
+// Equal returns true if a and b are structurally identical,
+// that is, if they are made of the same components, in the same order.
+// Contrary to reflect.DeepEqual, it ignores positions and internal caches,
+// and compares regular expressions by their source.
+func Equal(a, b Sel) bool { return equal(a, b, false) }
+
+// EqualIgnoringOrder is like Equal, but ignores the order
+// of the components of compound selectors, and of the arguments
+// of relative pseudo-classes like :not(), so that "a.b.c" and "a.c.b"
+// are considered equal.
+func EqualIgnoringOrder(a, b Sel) bool { return equal(a, b, true) }
+
+// EqualGroup returns true if a and b have the same length
+// and their selectors are Equal, in the same order.
+func EqualGroup(a, b SelectorGroup) bool { return equalList(a, b, false) }
+
+func equal(a, b Sel, unordered bool) bool {
+	switch a := a.(type) {
+	case TagSelector:
+		b, ok := b.(TagSelector)
+		return ok && a.Tag == b.Tag
+	case AttrSelector:
+		b, ok := b.(AttrSelector)
+		return ok && a.Key == b.Key && a.Operation == b.Operation && a.Val == b.Val &&
+			regexpString(a.Regexp) == regexpString(b.Regexp) && a.rawKey == b.rawKey &&
+			a.foldForeignCase == b.foldForeignCase && a.legacyInclude == b.legacyInclude
+	case RegexpPseudoClassSelector:
+		b, ok := b.(RegexpPseudoClassSelector)
+		return ok && a.Own == b.Own && regexpString(a.Regexp) == regexpString(b.Regexp)
+	case RelativePseudoClassSelector:
+		b, ok := b.(RelativePseudoClassSelector)
+		return ok && a.Name == b.Name && equalList(a.Args, b.Args, unordered)
+	case CustomStatePseudoClassSelector:
+		b, ok := b.(CustomStatePseudoClassSelector)
+		return ok && a.State == b.State
+	case TimePseudoClassSelector:
+		b, ok := b.(TimePseudoClassSelector)
+		return ok && a.Name == b.Name && (a.Args == nil) == (b.Args == nil) && equalList(a.Args, b.Args, unordered)
+	case CompoundSelector:
+		b, ok := b.(CompoundSelector)
+		return ok && a.Pseudo == b.Pseudo && equalList(a.Selectors, b.Selectors, unordered)
+	case CombinedSelector:
+		b, ok := b.(CombinedSelector)
+		if !ok || a.Combinator != b.Combinator || !equal(a.First, b.First, unordered) {
+			return false
+		}
+		if a.Second == nil || b.Second == nil {
+			return a.Second == nil && b.Second == nil
+		}
+		return equal(a.Second, b.Second, unordered)
+	case ClassSelector, IDSelector, LangPseudoClassSelector, ContainsPseudoClassSelector, NthPseudoClassSelector,
+		OnlyChildPseudoClassSelector, NeverMatchSelector, InputPseudoClassSelector, EmptyElementPseudoClassSelector,
+		RootPseudoClassSelector, ScopePseudoClassSelector, NestingSelector, LinkPseudoClassSelector, EnabledPseudoClassSelector, DisabledPseudoClassSelector,
+		CheckedPseudoClassSelector:
+		// ignore the positions and the sources
+		return withSource(withSpan(a, Span{}), "") == withSource(withSpan(b, Span{}), "")
+	}
+	// custom selectors
+	return reflect.DeepEqual(a, b)
+}
+
+func regexpString(rx *regexp.Regexp) string {
+	if rx == nil {
+		return ""
+	}
+	return rx.String()
+}
+
+func equalList(a, b []Sel, unordered bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	if !unordered {
+		for i := range a {
+			if !equal(a[i], b[i], false) {
+				return false
+			}
+		}
+		return true
+	}
+	// match each element of a with a distinct element of b
+	used := make([]bool, len(b))
+	for _, sa := range a {
+		found := false
+		for j, sb := range b {
+			if !used[j] && equal(sa, sb, true) {
+				used[j], found = true, true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,66 @@
+package cascadia
+
+import "testing"
+
+func TestEqual(t *testing.T) {
+	for _, test := range []struct {
+		a, b                string
+		equal, equalNoOrder bool
+	}{
+		{"div.a", "DIV.a", true, true},
+		{"div.a.b", "div.b.a", false, true},
+		{`[href="x"]`, "[href='x']", true, true},
+		{`[href="x"]`, "[href=x]", true, true},
+		{`[href="x"]`, "[href^=x]", false, false},
+		{`p:not(.a, .b)`, "p:not(.b, .a)", false, true},
+		{`p:not(.a, .b)`, "p:not(.a)", false, false},
+		{`[a#=(\d+)]`, `[a#=(\d+)]`, true, true},
+		{`:matches(a+)`, `:matches(a*)`, false, false},
+		{"a > b", "a b", false, false},
+		{"a > b.c.d", "a > b.d.c", false, true},
+		{"a:nth-child(2n+1)", "a:nth-child(odd)", true, true},
+		{"a:first-child", "a:first-of-type", false, false},
+		{"a.a.b", "a.b.b", false, false},
+		{"[viewBox]", "[viewbox]", false, false},
+	} {
+		a, err := ParseWithPseudoElement(test.a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ParseWithPseudoElement(test.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := Equal(a, b); got != test.equal {
+			t.Errorf("Equal(%s, %s): expected %v", test.a, test.b, test.equal)
+		}
+		if got := EqualIgnoringOrder(a, b); got != test.equalNoOrder {
+			t.Errorf("EqualIgnoringOrder(%s, %s): expected %v", test.a, test.b, test.equalNoOrder)
+		}
+	}
+
+	g1, _ := ParseGroup("a, b")
+	g2, _ := ParseGroup("a,b")
+	g3, _ := ParseGroup("b, a")
+	if !EqualGroup(g1, g2) || EqualGroup(g1, g3) {
+		t.Error("unexpected EqualGroup result")
+	}
+
+	// hand built selectors compare equal to parsed ones
+	parsed, _ := Parse("div")
+	if !Equal(parsed, TagSelector{Tag: "div"}) {
+		t.Error("expected equality with hand built selector")
+	}
+
+	// the options changing the matching are compared
+	for _, opts := range []Options{{LegacyInclude: true}, {FoldForeignAttributes: true}, {Quirks: true}} {
+		a, _ := ParseWithOptions(".a[viewBox~=x]", Options{})
+		b, err := ParseWithOptions(".a[viewBox~=x]", opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if Equal(a, b) {
+			t.Errorf("%+v: unexpected equality", opts)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package cascadia
+
+// Equivalent returns true if a and b are proven to match the same elements,
+// even if they are written differently, such as *[id="x"] and #x,
+// or :nth-child(odd) and :nth-child(2n+1).
+// Contrary to Normalize, the specificity is not taken into account:
+// #x and [id="x"] are equivalent, but have different specificities.
+//
+// It returns false if the selectors are different, or if the
+// analysis can't decide.
+func Equivalent(a, b Sel) bool {
+	ca, cb := canonicalize(a), canonicalize(b)
+	if EqualIgnoringOrder(ca, cb) {
+		return true
+	}
+	return CheckSubsumption(ca, cb) == Yes && CheckSubsumption(cb, ca) == Yes
+}
+
+// canonicalize rewrites sel in a form matching the same elements,
+// using id and class selectors instead of the equivalent
+// attribute selectors, and removing duplicated components.
+func canonicalize(sel Sel) Sel {
+	sel = Transform(sel, func(s Sel) Sel {
+		switch s := s.(type) {
+		case AttrSelector:
+			switch {
+			case s.Key == "id" && s.Operation == "=":
+				return IDSelector{ID: s.Val, Pos: s.Pos}
+			case s.Key == "class" && s.Operation == "~=" && s.Val != "" && !containsWhitespace(s.Val):
+				return ClassSelector{Class: s.Val, Pos: s.Pos}
+			}
+		case CompoundSelector:
+			var unique []Sel
+			for _, c := range s.Selectors {
+				if !containsSel(unique, c) {
+					unique = append(unique, c)
+				}
+			}
+			s.Selectors = unique
+			if len(unique) == 1 && s.Pseudo == "" {
+				return unique[0]
+			}
+			return s
+		}
+		return s
+	})
+	return Normalize(sel)
+}
+
+func containsSel(list []Sel, s Sel) bool {
+	for _, other := range list {
+		if Equal(other, s) {
+			return true
+		}
+	}
+	return false
+}
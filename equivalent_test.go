@@ -0,0 +1,35 @@
+package cascadia
+
+import "testing"
+
+func TestEquivalent(t *testing.T) {
+	for _, test := range []struct {
+		a, b string
+		want bool
+	}{
+		{`*[id="x"]`, "#x", true},
+		{":nth-child(odd)", ":nth-child(2n+1)", true},
+		{":first-child", ":nth-child(1)", true},
+		{`[class~="a"].b`, ".b.a", true},
+		{".a.a", ".a", true},
+		{"div > p.a.b", "div>p.b.a", true},
+		{":not(.a, .b)", ":not(.b, .a)", true},
+		{"[lang|=en]", "[lang|=en]", true},
+		{"div", "span", false},
+		{"div p", "div > p", false},
+		{`[class~="a b"]`, ".a.b", false},
+		{"#x", "#x::before", false},
+	} {
+		a, err := ParseWithPseudoElement(test.a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ParseWithPseudoElement(test.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := Equivalent(a, b); got != test.want {
+			t.Errorf("%s and %s: expected %v, got %v", test.a, test.b, test.want, got)
+		}
+	}
+}
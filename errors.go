@@ -0,0 +1,171 @@
+package cascadia
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseError is the error returned when a selector can't be parsed.
+// It describes where the parser stopped, what it found there and
+// what it expected instead.
+type ParseError struct {
+	// Code is the category of the error.
+	Code ErrorCode
+	// Source is the full selector being parsed.
+	Source string
+	// Offset is the byte offset in Source where the error occurred.
+	Offset int
+	// Found is the text of the token at Offset, or empty at the end of input.
+	Found string
+	// Expected lists the classes of tokens accepted at Offset,
+	// such as "identifier" or "')'". It may be empty.
+	Expected []string
+	// Message is the human readable description of the error.
+	Message string
+	// Err is the underlying error, if any (for instance the error
+	// returned by regexp.Compile).
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("parsing %q: %s (at offset %d)", e.Source, e.Message, e.Offset)
+}
+
+// Unwrap returns the underlying error, if any.
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// Is returns true if target is the ErrorCode of e, so that
+// errors.Is(err, ErrUnknownPseudo) may be used to test for a category.
+func (e *ParseError) Is(target error) bool {
+	code, ok := target.(ErrorCode)
+	return ok && code == e.Code
+}
+
+// Snippet returns the line of Source containing the error,
+// followed by a line with a caret pointing at Offset.
+func (e *ParseError) Snippet() string {
+	offset := e.Offset
+	if offset > len(e.Source) {
+		offset = len(e.Source)
+	}
+	lineStart := strings.LastIndexByte(e.Source[:offset], '\n') + 1
+	lineEnd := strings.IndexByte(e.Source[offset:], '\n')
+	if lineEnd == -1 {
+		lineEnd = len(e.Source)
+	} else {
+		lineEnd += offset
+	}
+	line := e.Source[lineStart:lineEnd]
+	return line + "\n" + strings.Repeat(" ", len([]rune(e.Source[lineStart:offset]))) + "^"
+}
+
+// ErrorCode is a stable, machine-readable category of parse errors.
+// It implements error, so that it may be used as target of errors.Is.
+type ErrorCode uint8
+
+const (
+	ErrUnexpectedToken     ErrorCode = iota + 1 // the input doesn't match the expected syntax
+	ErrUnexpectedEOF                            // the input ends before the selector is complete
+	ErrUnterminatedString                       // a quoted string is not closed
+	ErrInvalidEscape                            // a backslash escape is malformed
+	ErrUnknownPseudo                            // a pseudo-class or pseudo-element is not supported
+	ErrUnsupportedOperator                      // an attribute operator is not supported
+	ErrInvalidRegexp                            // a regular expression is invalid
+	ErrInvalidNumber                            // an integer is out of range
+	ErrInvalidNth                               // an an+b expression is invalid
+	ErrPseudoElement                            // a pseudo-element is misplaced or not allowed
+	ErrTrailingInput                            // the input is not fully consumed
+	ErrLimitExceeded                            // the input exceeds one of the configured Limits
+	ErrNonStandard                              // a non-standard extension is used in strict mode
+	ErrNestedHas                                // :has() is used in the arguments of :has()
+)
+
+var errorCodeNames = [...]string{
+	ErrUnexpectedToken:     "unexpected token",
+	ErrUnexpectedEOF:       "unexpected end of input",
+	ErrUnterminatedString:  "unterminated string",
+	ErrInvalidEscape:       "invalid escape",
+	ErrUnknownPseudo:       "unknown pseudo-class or pseudo-element",
+	ErrUnsupportedOperator: "unsupported attribute operator",
+	ErrInvalidRegexp:       "invalid regular expression",
+	ErrInvalidNumber:       "invalid number",
+	ErrInvalidNth:          "invalid an+b expression",
+	ErrPseudoElement:       "invalid pseudo-element",
+	ErrTrailingInput:       "trailing input",
+	ErrLimitExceeded:       "limit exceeded",
+	ErrNonStandard:         "non-standard extension",
+	ErrNestedHas:           "nested :has()",
+}
+
+func (c ErrorCode) String() string {
+	if int(c) < len(errorCodeNames) && errorCodeNames[c] != "" {
+		return errorCodeNames[c]
+	}
+	return fmt.Sprintf("ErrorCode(%d)", c)
+}
+
+// Error returns the description of the category.
+func (c ErrorCode) Error() string { return c.String() }
+
+// expected token classes, used in ParseError.Expected
+var (
+	expectEnd          = []string{"end of input"}
+	expectIdent        = []string{"identifier"}
+	expectName         = []string{"name"}
+	expectString       = []string{"string"}
+	expectRegexp       = []string{"regular expression"}
+	expectID           = []string{"'#'"}
+	expectClass        = []string{"'.'"}
+	expectAttr         = []string{"'['"}
+	expectAttrOperator = []string{"']'", "'='", "'~='", "'|='", "'^='", "'$='", "'*='", "'#='"}
+	expectAttrValue    = []string{"identifier", "string"}
+	expectCloseBracket = []string{"']'"}
+	expectPseudo       = []string{"':'"}
+	expectOpenParen    = []string{"'('"}
+	expectCloseParen   = []string{"')'"}
+	expectArgument     = []string{"argument"}
+	expectInteger      = []string{"integer"}
+	expectNth          = []string{"an+b expression", "'odd'", "'even'"}
+	expectSelector     = []string{"selector"}
+)
+
+// errorf returns a *ParseError located at the current position.
+func (p *parser) errorf(code ErrorCode, expected []string, format string, args ...interface{}) error {
+	return p.errorAt(code, p.i, expected, format, args...)
+}
+
+// errorAt returns a *ParseError located at offset.
+func (p *parser) errorAt(code ErrorCode, offset int, expected []string, format string, args ...interface{}) error {
+	out := &ParseError{
+		Code:     code,
+		Source:   p.s,
+		Offset:   offset,
+		Expected: expected,
+		Message:  fmt.Sprintf(format, args...),
+	}
+	if !p.tokenizing {
+		out.Found = tokenAt(p.s, offset)
+	}
+	return out
+}
+
+// wrapError returns a *ParseError located at the current position,
+// wrapping err.
+func (p *parser) wrapError(code ErrorCode, err error) error {
+	out := p.errorf(code, nil, "%s", err).(*ParseError)
+	out.Err = err
+	return out
+}
+
+// tokenAt returns the source text of the token starting at offset.
+func tokenAt(s string, offset int) string {
+	if offset >= len(s) {
+		return ""
+	}
+	t := Tokenizer{p: parser{s: s, i: offset, tokenizing: true}}
+	tok := t.NextToken()
+	if tok.Kind == TokenEOF || tok.Span.End <= tok.Span.Start {
+		return s[offset : offset+1]
+	}
+	return s[tok.Span.Start:tok.Span.End]
+}
@@ -0,0 +1,179 @@
+package cascadia
+
+import (
+	"errors"
+	"reflect"
+	"regexp/syntax"
+	"strings"
+	"testing"
+)
+
+func TestParseError(t *testing.T) {
+	for _, test := range []struct {
+		sel      string
+		offset   int
+		found    string
+		expected []string
+	}{
+		{"div:nth-child(2n", 16, "", expectNth},
+		{"a:unknown", 1, ":", nil},
+		{"[href^=foo", 10, "", expectCloseBracket},
+		{"p > > a", 4, ">", expectIdent},
+		{"div.(", 4, "(", expectIdent},
+		{"a b )", 4, ")", expectEnd},
+		{"a::before.b", 9, ".", nil},
+	} {
+		_, err := ParseWithPseudoElement(test.sel)
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Errorf("%s: expected a *ParseError, got %v", test.sel, err)
+			continue
+		}
+		if perr.Source != test.sel {
+			t.Errorf("%s: unexpected source %q", test.sel, perr.Source)
+		}
+		if perr.Offset != test.offset {
+			t.Errorf("%s: expected offset %d, got %d (%s)", test.sel, test.offset, perr.Offset, perr)
+		}
+		if perr.Found != test.found {
+			t.Errorf("%s: expected found %q, got %q", test.sel, test.found, perr.Found)
+		}
+		if !reflect.DeepEqual(perr.Expected, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.sel, test.expected, perr.Expected)
+		}
+	}
+}
+
+func TestParseErrorSnippet(t *testing.T) {
+	_, err := ParseGroup("a,\ndiv..b")
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("expected a *ParseError, got %v", err)
+	}
+	if exp := "div..b\n    ^"; perr.Snippet() != exp {
+		t.Errorf("unexpected snippet:\n%s\nexpected:\n%s", perr.Snippet(), exp)
+	}
+}
+
+func TestParseErrorUnwrap(t *testing.T) {
+	_, err := Parse("div:matches(x**)")
+	var serr *syntax.Error
+	if !errors.As(err, &serr) {
+		t.Fatalf("expected a wrapped *syntax.Error, got %v", err)
+	}
+}
+
+func TestErrorCodes(t *testing.T) {
+	for _, test := range []struct {
+		sel  string
+		code ErrorCode
+	}{
+		{"div:unknown", ErrUnknownPseudo},
+		{"a::unknown", ErrUnknownPseudo},
+		{`[href="foo]`, ErrUnterminatedString},
+		{"[href&=foo]", ErrUnsupportedOperator},
+		{"div.", ErrUnexpectedEOF},
+		{"div >", ErrUnexpectedEOF},
+		{"div.(", ErrUnexpectedToken},
+		{"a b )", ErrTrailingInput},
+		{"div:matches(x**)", ErrInvalidRegexp},
+		{"div:nth-child(foo)", ErrInvalidNth},
+		{"div:nth-child(99999999999999999999)", ErrInvalidNumber},
+		{"a::before.b", ErrPseudoElement},
+		{"a\\\n", ErrInvalidEscape},
+		{"a:has(b:has(c))", ErrNestedHas},
+		{"a:has(:is(:has(c)))", ErrNestedHas},
+		{"a:has(p::before)", ErrPseudoElement},
+	} {
+		_, err := ParseWithPseudoElement(test.sel)
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Errorf("%s: expected a *ParseError, got %v", test.sel, err)
+			continue
+		}
+		if perr.Code != test.code {
+			t.Errorf("%s: expected code %s, got %s (%s)", test.sel, test.code, perr.Code, err)
+		}
+		if !errors.Is(err, test.code) {
+			t.Errorf("%s: errors.Is should match %s", test.sel, test.code)
+		}
+	}
+}
+
+func TestWhitespaceTolerance(t *testing.T) {
+	for _, test := range []struct {
+		sel, want string
+	}{
+		{" div ", "div"},
+		{"\n\tdiv > p\n", "div > p"},
+		{"\r\ndiv\r\n", "div"},
+		{"div ,\n  p\n", "div, p"},
+		{"  div\n,\np>a  /* comment */ ", "div, p > a"},
+		{"\fdiv:not( p , a )\f", "div:not(p, a)"},
+	} {
+		group, err := ParseGroup(test.sel)
+		if err != nil {
+			t.Errorf("%q: %s", test.sel, err)
+			continue
+		}
+		if s := group.String(); s != test.want {
+			t.Errorf("%q: expected %s, got %s", test.sel, test.want, s)
+		}
+	}
+	if _, err := Parse("\n div p \n"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTrailingInput(t *testing.T) {
+	for _, test := range []struct {
+		sel    string
+		offset int
+		found  string
+		msg    string
+	}{
+		{"a b )", 4, ")", `unexpected ")" after the selector`},
+		{"div )x  ", 4, ")", `unexpected ")x  " after the selector`},
+		{"div, p", 3, ",", `unexpected ", p" after the selector`},
+		{"a )" + strings.Repeat("é", 20), 2, ")", `unexpected ")` + strings.Repeat("é", 15) + `..." after the selector`},
+	} {
+		_, err := Parse(test.sel)
+		var perr *ParseError
+		if !errors.As(err, &perr) || perr.Code != ErrTrailingInput {
+			t.Errorf("%q: expected a trailing input error, got %v", test.sel, err)
+			continue
+		}
+		if perr.Offset != test.offset || perr.Found != test.found || perr.Message != test.msg {
+			t.Errorf("%q: unexpected error %d %q %q", test.sel, perr.Offset, perr.Found, perr.Message)
+		}
+	}
+}
+
+func TestHasRestrictions(t *testing.T) {
+	_, err := Parse("a:has(b:has(c))")
+	var perr *ParseError
+	if !errors.As(err, &perr) || perr.Offset != 7 || !strings.Contains(perr.Message, "nested") {
+		t.Errorf("unexpected error %v", err)
+	}
+	// the siblings of :has() are not affected
+	for _, sel := range []string{"a:has(b):has(c)", ":is(:has(a), b:has(c))", "a:has(b) c:has(d)"} {
+		if _, err := Parse(sel); err != nil {
+			t.Errorf("%s: %s", sel, err)
+		}
+	}
+	// opt-out
+	opts := Options{RelaxedHas: true, PseudoElements: true}
+	for _, sel := range []string{"a:has(b:has(c))", "a:has(p::before)"} {
+		if _, err := ParseWithOptions(sel, opts); err != nil {
+			t.Errorf("%s: %s", sel, err)
+		}
+	}
+	doc := MustParseHTML(`<ul><li><ol><li>x</li></ol></li></ul><ul><li></li></ul>`)
+	sel, err := ParseWithOptions("ul:has(li:has(ol))", Options{RelaxedHas: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := QueryAll(doc, sel); len(got) != 1 {
+		t.Errorf("unexpected matches %v", got)
+	}
+}
@@ -0,0 +1,160 @@
+package cascadia
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Trace describes how a selector was matched against a node.
+// It is returned by Explain.
+type Trace struct {
+	Selector string     // the serialized (sub-)selector
+	Node     *html.Node // the node tested
+	Matched  bool
+
+	// Reason is a short description of the failure (or of the relation
+	// with Node for combinators), which may be empty.
+	Reason string
+
+	// Steps are the traces of the components of the selector:
+	// the simple selectors of a compound, or, for combined selectors,
+	// the right operand on Node followed by the left operand on
+	// each candidate ancestor or sibling.
+	Steps []*Trace
+}
+
+// Explain matches sel against n, like sel.Match(n), and returns a trace
+// of the sub-selectors which passed or failed, which is useful to debug
+// a selector. Contrary to Match, all the components of a compound selector
+// are tested, so that all the failures are reported.
+func Explain(sel Sel, n *html.Node) *Trace {
+	t := &Trace{Selector: sel.String(), Node: n}
+	switch s := sel.(type) {
+	case CompoundSelector:
+		if len(s.Selectors) == 0 {
+			t.Matched = n.Type == html.ElementNode
+			if !t.Matched {
+				t.Reason = "not an element"
+			}
+			break
+		}
+		t.Matched = true
+		for _, c := range s.Selectors {
+			step := Explain(c, n)
+			t.Steps = append(t.Steps, step)
+			t.Matched = t.Matched && step.Matched
+		}
+	case CombinedSelector:
+		explainCombined(t, s, n)
+	case RelativePseudoClassSelector:
+		t.Matched = s.Match(n)
+		if s.Name == "not" || s.Name == "is" || s.Name == "where" {
+			for _, arg := range s.Args {
+				t.Steps = append(t.Steps, Explain(arg, n))
+			}
+		}
+	default:
+		t.Matched = sel.Match(n)
+	}
+	return t
+}
+
+func explainCombined(t *Trace, s CombinedSelector, n *html.Node) {
+	if s.Second == nil {
+		first := Explain(s.First, n)
+		t.Steps, t.Matched = []*Trace{first}, first.Matched
+		return
+	}
+	second := Explain(s.Second, n)
+	t.Steps = []*Trace{second}
+	if !second.Matched {
+		return
+	}
+	// try returns true if the candidate c matches the left operand
+	try := func(c *html.Node, relation string) bool {
+		step := Explain(s.First, c)
+		step.Reason = relation
+		t.Steps = append(t.Steps, step)
+		return step.Matched
+	}
+	switch s.Combinator {
+	case ' ':
+		for p := n.Parent; p != nil && !t.Matched; p = p.Parent {
+			t.Matched = try(p, "ancestor")
+		}
+		if !t.Matched {
+			t.Reason = "no ancestor matches " + s.First.String()
+		}
+	case '>':
+		if n.Parent == nil {
+			t.Reason = "no parent"
+			return
+		}
+		t.Matched = try(n.Parent, "parent")
+	case '+':
+		if c := prevElementSibling(FromHTML(n)); c != nil {
+			t.Matched = try(ToHTML(c), "previous sibling")
+			return
+		}
+		t.Reason = "no previous sibling"
+	case '~':
+		for c := prevElementSibling(FromHTML(n)); c != nil && !t.Matched; c = prevElementSibling(c) {
+			t.Matched = try(ToHTML(c), "preceding sibling")
+		}
+		if !t.Matched {
+			t.Reason = "no preceding sibling matches " + s.First.String()
+		}
+	}
+}
+
+// String returns an indented, human readable, version of the trace.
+func (t *Trace) String() string {
+	var b strings.Builder
+	t.write(&b, 0)
+	return b.String()
+}
+
+func (t *Trace) write(b *strings.Builder, depth int) {
+	b.WriteString(strings.Repeat("  ", depth))
+	if t.Matched {
+		b.WriteString("[ok]   ")
+	} else {
+		b.WriteString("[fail] ")
+	}
+	b.WriteString(t.Selector)
+	b.WriteString(" on ")
+	b.WriteString(describeNode(t.Node))
+	if t.Reason != "" {
+		b.WriteString(" (" + t.Reason + ")")
+	}
+	b.WriteByte('\n')
+	for _, s := range t.Steps {
+		s.write(b, depth+1)
+	}
+}
+
+// describeNode returns a short description of n, like <div#id.class>
+func describeNode(n *html.Node) string {
+	switch n.Type {
+	case html.ElementNode:
+		s := "<" + n.Data
+		for _, a := range n.Attr {
+			switch a.Key {
+			case "id":
+				s += "#" + a.Val
+			case "class":
+				for _, c := range SplitTokens(a.Val) {
+					s += "." + c
+				}
+			}
+		}
+		return s + ">"
+	case html.DocumentNode:
+		return "document"
+	case html.TextNode:
+		return "text"
+	default:
+		return "node"
+	}
+}
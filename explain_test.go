@@ -0,0 +1,85 @@
+package cascadia
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestExplain(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(`<html><body>
+		<div id="main" class="a"><ul><li>a</li><li class="x">b</li></ul></div>
+		<p class="b">c</p>
+	</body></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	li := Query(doc, MustCompile("li.x"))
+	p := Query(doc, MustCompile("p"))
+
+	for _, sel := range []string{
+		"li", "li.x", "li.y", "li.x#z", "div li", "section li", "div > li", "ul > li",
+		"li + li", "p + li", "li ~ li", "div ~ li", "div + p", "div ~ p", "div.a ~ p.b",
+		"li:not(.x)", "li:is(.y, .x)", "*", "body > * li", ":has(li)", "ul li:first-child",
+	} {
+		s, err := Parse(sel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, n := range []*html.Node{li, p, doc} {
+			if trace := Explain(s, n); trace.Matched != s.Match(n) {
+				t.Errorf("%s on %s: Explain returned %v\n%s", sel, describeNode(n), trace.Matched, trace)
+			}
+		}
+	}
+}
+
+func TestExplainTrace(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(`<div class="a"><p id="x">c</p></div>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := Query(doc, MustCompile("p"))
+
+	parse := func(sel string) Sel {
+		s, err := Parse(sel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	trace := Explain(parse("div.b > p#x.c"), p)
+	if trace.Matched {
+		t.Fatal("expected no match")
+	}
+	// the right operand fails on .c, so the parent is not tested
+	if len(trace.Steps) != 1 {
+		t.Fatalf("unexpected steps:\n%s", trace)
+	}
+	compound := trace.Steps[0]
+	if len(compound.Steps) != 3 || !compound.Steps[0].Matched || !compound.Steps[1].Matched || compound.Steps[2].Matched {
+		t.Fatalf("unexpected compound trace:\n%s", trace)
+	}
+
+	trace = Explain(parse("div.b p"), p)
+	exp := `[fail] div.b   p on <p#x> (no ancestor matches div.b)
+  [ok]   p on <p#x>
+  [fail] div.b on <div.a> (ancestor)
+    [ok]   div on <div.a>
+    [fail] .b on <div.a>
+  [fail] div.b on <body> (ancestor)
+    [fail] div on <body>
+    [fail] .b on <body>
+  [fail] div.b on <html> (ancestor)
+    [fail] div on <html>
+    [fail] .b on <html>
+  [fail] div.b on document (ancestor)
+    [fail] div on document
+    [fail] .b on document
+`
+	if got := trace.String(); got != exp {
+		t.Errorf("expected\n%s\ngot\n%s", exp, got)
+	}
+}
@@ -0,0 +1,208 @@
+package cascadia
+
+import (
+	"strings"
+)
+
+// QuoteStyle defines how attribute values and strings are serialized.
+type QuoteStyle uint8
+
+const (
+	QuoteDouble  QuoteStyle = iota // always use double quotes : [a="b"]
+	QuoteSingle                    // always use single quotes : [a='b']
+	QuoteMinimal                   // omit quotes when the value is an identifier : [a=b]
+)
+
+// Case defines the case normalization of the (case-insensitive)
+// tag names and attribute names (see FormatOptions.Case).
+type Case uint8
+
+const (
+	CasePreserve Case = iota // keep the case of the selector (which is lower case when parsed)
+	CaseLower
+	CaseUpper
+)
+
+// FormatOptions controls the style of the CSS output of Format.
+type FormatOptions struct {
+	// Quotes is the style used for attribute values and
+	// :contains() arguments.
+	Quotes QuoteStyle
+
+	// Case is applied to tag names, and to attribute names parsed with
+	// Options.FoldForeignAttributes: otherwise, the case of the attribute
+	// names is significant for the foreign elements (like viewBox), and is kept.
+	// Note that class names, ids and attribute values are case-sensitive
+	// and never modified.
+	Case Case
+
+	// SpaceAroundCombinators writes a space before and after
+	// the '>', '+' and '~' combinators.
+	SpaceAroundCombinators bool
+
+	// SpaceAfterComma writes a space after the commas separating the selectors
+	// of groups and of pseudo-classes arguments.
+	SpaceAfterComma bool
+
+	// OneSelectorPerLine writes each selector of a (top-level) group
+	// on its own line.
+	OneSelectorPerLine bool
+
+	// UppercaseHex writes hexadecimal escapes using upper case letters,
+	// instead of lower case.
+	UppercaseHex bool
+
+	// Minify produces the shortest output, see `Minify`.
+	// Quotes, SpaceAroundCombinators and SpaceAfterComma are then ignored.
+	Minify bool
+}
+
+// DefaultFormat is a readable style, similar to the one used in most stylesheets.
+var DefaultFormat = FormatOptions{SpaceAroundCombinators: true, SpaceAfterComma: true}
+
+// Format returns a CSS input compiling to sel, written with the given style.
+func Format(sel Sel, opts FormatOptions) string {
+	f := formatter{opts: opts}
+	f.writeSel(sel)
+	return f.String()
+}
+
+// FormatGroup is like Format, for a group of selectors.
+func FormatGroup(group SelectorGroup, opts FormatOptions) string {
+	f := formatter{opts: opts}
+	f.writeGroup(group, true)
+	return f.String()
+}
+
+type formatter struct {
+	strings.Builder
+	opts FormatOptions
+}
+
+func (f *formatter) writeGroup(group []Sel, topLevel bool) {
+	for i, s := range group {
+		if i != 0 {
+			f.WriteByte(',')
+			if topLevel && f.opts.OneSelectorPerLine {
+				f.WriteByte('\n')
+			} else if f.opts.SpaceAfterComma && !f.opts.Minify {
+				f.WriteByte(' ')
+			}
+		}
+		f.writeSel(s)
+	}
+}
+
+func (f *formatter) writeSel(sel Sel) {
+	switch s := sel.(type) {
+	case CombinedSelector:
+		f.writeSel(s.First)
+		if s.Second != nil {
+			if s.Combinator == ' ' || (f.opts.SpaceAroundCombinators && !f.opts.Minify) {
+				f.WriteByte(' ')
+			}
+			if s.Combinator != ' ' {
+				f.WriteByte(s.Combinator)
+				if f.opts.SpaceAroundCombinators && !f.opts.Minify {
+					f.WriteByte(' ')
+				}
+			}
+			f.writeSel(s.Second)
+		}
+	case CompoundSelector:
+		if len(s.Selectors) == 0 && (s.Pseudo == "" || !f.opts.Minify) {
+			f.WriteByte('*')
+		}
+		for _, c := range s.Selectors {
+			f.writeSel(c)
+		}
+		if s.Pseudo != "" {
+			switch s.Pseudo {
+			case "before", "after", "first-line", "first-letter": // legacy syntax is allowed
+				if f.opts.Minify {
+					f.WriteString(":")
+					break
+				}
+				fallthrough
+			default:
+				f.WriteString("::")
+			}
+			f.WriteString(s.Pseudo)
+		}
+	case TagSelector:
+		f.WriteString(escapeIdent(f.applyCase(s.Tag), f.opts.UppercaseHex))
+	case IDSelector:
+		f.WriteString("#" + escapeIdent(s.ID, f.opts.UppercaseHex))
+	case ClassSelector:
+		f.WriteString("." + escapeIdent(s.Class, f.opts.UppercaseHex))
+	case AttrSelector:
+		f.WriteByte('[')
+		key := s.writtenKey()
+		if s.foldForeignCase {
+			// the case of the name is only significant for the foreign elements
+			key = f.applyCase(key)
+		}
+		f.WriteString(escapeIdent(key, f.opts.UppercaseHex))
+		f.WriteString(s.Operation)
+		if s.Operation == "#=" {
+			f.WriteString(s.Regexp.String())
+		} else if s.Operation != "" {
+			f.writeValue(s.Val)
+		}
+		f.WriteByte(']')
+	case RelativePseudoClassSelector:
+		f.WriteString(":" + s.Name + "(")
+		f.writeGroup(s.Args, false)
+		f.WriteByte(')')
+	case ContainsPseudoClassSelector:
+		if s.Own {
+			f.WriteString(":containsOwn(")
+		} else {
+			f.WriteString(":contains(")
+		}
+		f.writeValue(s.Value)
+		f.WriteByte(')')
+	case NthPseudoClassSelector:
+		str := s.String()
+		if !f.opts.Minify || (s.A == 0 && s.B == 1) {
+			f.WriteString(str) // already minimal
+			return
+		}
+		f.WriteString(str[:strings.IndexByte(str, '(')+1])
+		f.WriteString(minifyNth(s.A, s.B))
+		f.WriteByte(')')
+	default:
+		f.WriteString(sel.String())
+	}
+}
+
+// writeValue writes an identifier if possible (and allowed by the quote style), or a quoted string
+func (f *formatter) writeValue(val string) {
+	quotes := f.opts.Quotes
+	if f.opts.Minify {
+		quotes = QuoteMinimal
+	}
+	switch quotes {
+	case QuoteSingle:
+		f.WriteString(quoteString(val, '\'', f.opts.UppercaseHex))
+	case QuoteMinimal:
+		if isIdentifier(val) {
+			f.WriteString(val)
+			return
+		}
+		fallthrough
+	default:
+		f.WriteString(quoteString(val, '"', f.opts.UppercaseHex))
+	}
+}
+
+func (f *formatter) applyCase(name string) string {
+	switch f.opts.Case {
+	case CaseLower:
+		return toLowerASCII(name)
+	case CaseUpper:
+		return strings.ToUpper(name)
+	default:
+		return name
+	}
+}
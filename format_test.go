@@ -0,0 +1,47 @@
+package cascadia
+
+import "testing"
+
+func TestFormat(t *testing.T) {
+	for _, test := range []struct {
+		input, expected string
+		opts            FormatOptions
+	}{
+		{"a>b  c+d", "a > b c + d", DefaultFormat},
+		{"a > b c + d", "a>b c+d", FormatOptions{}},
+		{"a, b,c", "a, b, c", DefaultFormat},
+		{"a, b,c", "a,b,c", FormatOptions{}},
+		{"a,b:not(c,d)", "a,\nb:not(c, d)", FormatOptions{OneSelectorPerLine: true, SpaceAfterComma: true}},
+		{"*::before", "*::before", DefaultFormat},
+		{"p:before", "p::before", DefaultFormat},
+		{`[a="x\9 y"]`, `[a="x\9 y"]`, DefaultFormat},
+		{`[a="x\1f y"]`, `[a="x\1f y"]`, DefaultFormat},
+		{`[a="x\1f y"]`, `[a="x\1F y"]`, FormatOptions{UppercaseHex: true}},
+		{":nth-child(2n+1)", ":nth-child(2n+1)", DefaultFormat},
+		{`[a="b"]`, `[a='b']`, FormatOptions{Quotes: QuoteSingle}},
+		{`[a="b'c"]`, `[a='b\'c']`, FormatOptions{Quotes: QuoteSingle}},
+		{`[a='b"c']`, `[a="b\"c"]`, FormatOptions{}},
+		{`[a='b'], [a="b c"]`, `[a=b],[a="b c"]`, FormatOptions{Quotes: QuoteMinimal}},
+		{`:contains('b')`, `:contains(b)`, FormatOptions{Quotes: QuoteMinimal}},
+		{`DIV.Foo[Data-X=Y]`, `DIV.Foo[Data-X="Y"]`, FormatOptions{Case: CaseUpper}},
+		{`DIV.Foo[Data-X=Y]`, `div.Foo[Data-X="Y"]`, FormatOptions{Case: CaseLower}},
+		{`svg[viewBox]`, `svg[viewBox]`, FormatOptions{Minify: true}},
+		{`svg[viewBox]`, `SVG[viewBox]`, FormatOptions{Case: CaseUpper}},
+	} {
+		sel, err := ParseGroupWithPseudoElements(test.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := FormatGroup(sel, test.opts)
+		if got != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.input, test.expected, got)
+		}
+		back, err := ParseGroupWithPseudoElements(got)
+		if err != nil {
+			t.Fatalf("invalid output %s: %s", got, err)
+		}
+		if !EqualGroup(sel, back) {
+			t.Errorf("%s: output %s is not equivalent", test.input, got)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package cascadia
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Form is the submission model of a <form> element, returned by Forms.
+type Form struct {
+	Element *html.Node
+	Action  string // as written: an empty action designates the document URL
+	Method  string // "get" (the default), "post" or "dialog"
+	Enctype string // "application/x-www-form-urlencoded" by default
+	// Controls are the controls associated with the form, in tree order:
+	// its descendants, and the elements referencing it with a form attribute.
+	Controls []FormControl
+	// Submit is the default button of the form, used by the implicit
+	// submission (pressing Enter in a field), or nil.
+	Submit *FormControl
+}
+
+// FormControl is a control of a Form.
+type FormControl struct {
+	Element *html.Node
+	Name    string
+	// Type is the lower-cased type of the input elements ("text" by default),
+	// or of the buttons ("submit" by default), or one of "select-one",
+	// "select-multiple" and "textarea".
+	Type  string
+	Value string // the default value (for a textarea, its text)
+	// Checked is the state of the checkbox and radio inputs.
+	Checked            bool
+	Disabled, ReadOnly bool
+	Options            []FormOption // for the select elements
+}
+
+// FormOption is an option of a select control.
+type FormOption struct {
+	Value, Label       string
+	Selected, Disabled bool
+}
+
+var (
+	formElements    = MustCompile("form")
+	controlElements = MustCompile("input, select, textarea, button")
+	optionElements  = MustCompile("option")
+)
+
+// inputTypes are the valid values of the type attribute of inputs
+var inputTypes = map[string]bool{
+	"hidden": true, "text": true, "search": true, "tel": true, "url": true, "email": true,
+	"password": true, "date": true, "month": true, "week": true, "time": true,
+	"datetime-local": true, "number": true, "range": true, "color": true,
+	"checkbox": true, "radio": true, "file": true, "submit": true, "image": true,
+	"reset": true, "button": true,
+}
+
+// Forms returns the forms of the document doc, in tree order.
+func Forms(doc *html.Node) []Form {
+	forms := QueryAll(doc, formElements)
+	out := make([]Form, len(forms))
+	index := make(map[*html.Node]int, len(forms))
+	ids := map[string]*html.Node{} // for the form attribute
+	for i, f := range forms {
+		out[i] = Form{
+			Element: f,
+			Action:  getAttr(f, "action"),
+			Method:  "get",
+			Enctype: "application/x-www-form-urlencoded",
+		}
+		switch m := toLowerASCII(getAttr(f, "method")); m {
+		case "post", "dialog":
+			out[i].Method = m
+		}
+		switch e := toLowerASCII(getAttr(f, "enctype")); e {
+		case "multipart/form-data", "text/plain":
+			out[i].Enctype = e
+		}
+		index[f] = i
+		if id := nodeID(f); id != "" {
+			if _, ok := ids[id]; !ok {
+				ids[id] = f
+			}
+		}
+	}
+
+	for _, c := range QueryAll(doc, controlElements) {
+		owner := formOwner(c, ids)
+		if owner == nil {
+			continue
+		}
+		i := index[owner]
+		out[i].Controls = append(out[i].Controls, newFormControl(c))
+	}
+
+	for i := range out {
+		for j, c := range out[i].Controls {
+			if c.Type == "submit" || (c.Type == "image" && c.Element.Data == "input") {
+				out[i].Submit = &out[i].Controls[j]
+				break
+			}
+		}
+	}
+	return out
+}
+
+// formOwner returns the form associated with the control c, or nil
+func formOwner(c *html.Node, ids map[string]*html.Node) *html.Node {
+	if hasAttr(FromHTML(c), "form") {
+		return ids[getAttr(c, "form")] // nil for an invalid reference
+	}
+	for p := c.Parent; p != nil; p = p.Parent {
+		if p.Type == html.ElementNode && p.Data == "form" {
+			return p
+		}
+	}
+	return nil
+}
+
+func newFormControl(c *html.Node) FormControl {
+	out := FormControl{
+		Element:  c,
+		Name:     getAttr(c, "name"),
+		Value:    getAttr(c, "value"),
+		Disabled: DisabledPseudoClassSelector{}.Match(c),
+	}
+	switch c.Data {
+	case "input":
+		out.Type = toLowerASCII(getAttr(c, "type"))
+		if !inputTypes[out.Type] {
+			out.Type = "text"
+		}
+		out.Checked = CheckedPseudoClassSelector{}.Match(c)
+		out.ReadOnly = hasAttr(FromHTML(c), "readonly")
+	case "button":
+		out.Type = toLowerASCII(getAttr(c, "type"))
+		if out.Type != "reset" && out.Type != "button" {
+			out.Type = "submit"
+		}
+	case "textarea":
+		out.Type = "textarea"
+		out.Value = nodeText(FromHTML(c))
+		out.ReadOnly = hasAttr(FromHTML(c), "readonly")
+	case "select":
+		out.Type = "select-one"
+		if hasAttr(FromHTML(c), "multiple") {
+			out.Type = "select-multiple"
+		}
+		for _, o := range QueryAll(c, optionElements) {
+			label := strings.Join(strings.Fields(nodeText(FromHTML(o))), " ")
+			value := label
+			if hasAttr(FromHTML(o), "value") {
+				value = getAttr(o, "value")
+			}
+			out.Options = append(out.Options, FormOption{
+				Value:    value,
+				Label:    label,
+				Selected: hasAttr(FromHTML(o), "selected"),
+				Disabled: DisabledPseudoClassSelector{}.Match(o),
+			})
+		}
+	}
+	return out
+}
+
+// Values returns the data submitted by the form with its default values,
+// following the rules of the HTML specification: the disabled and unnamed
+// controls, the unchecked checkboxes and radios, the files and the buttons
+// are skipped. If submitter is not nil, it is the button used to submit the
+// form, whose value is included.
+func (f *Form) Values(submitter *FormControl) url.Values {
+	out := url.Values{}
+	for i := range f.Controls {
+		c := &f.Controls[i]
+		if c.Disabled || c.Name == "" {
+			continue
+		}
+		switch c.Type {
+		case "submit", "image", "reset", "button":
+			if submitter != nil && c.Element == submitter.Element {
+				if c.Type == "image" {
+					out.Add(c.Name+".x", "0")
+					out.Add(c.Name+".y", "0")
+				} else {
+					out.Add(c.Name, c.Value)
+				}
+			}
+		case "checkbox", "radio":
+			if c.Checked {
+				value := c.Value
+				if !hasAttr(FromHTML(c.Element), "value") {
+					value = "on"
+				}
+				out.Add(c.Name, value)
+			}
+		case "file":
+			// not supported
+		case "select-multiple":
+			for _, o := range c.Options {
+				if o.Selected && !o.Disabled {
+					out.Add(c.Name, o.Value)
+				}
+			}
+		case "select-one":
+			// as in browsers, the last option marked as selected wins
+			last := -1
+			for i, o := range c.Options {
+				if o.Selected {
+					last = i
+				}
+			}
+			if last != -1 {
+				if o := c.Options[last]; !o.Disabled {
+					out.Add(c.Name, o.Value)
+				}
+			} else if !hasAttr(FromHTML(c.Element), "size") {
+				// a drop-down list selects its first option by default
+				for _, o := range c.Options {
+					if !o.Disabled {
+						out.Add(c.Name, o.Value)
+						break
+					}
+				}
+			}
+		default:
+			out.Add(c.Name, c.Value)
+		}
+	}
+	return out
+}
@@ -0,0 +1,77 @@
+package cascadia
+
+import (
+	"testing"
+)
+
+func TestForms(t *testing.T) {
+	doc := MustParseHTML(`<form id="f" action="/search" method="POST">
+		<input name="q" value="go">
+		<input type="hidden" name="lang" value="en">
+		<input type="checkbox" name="exact" checked>
+		<input type="checkbox" name="safe" value="1">
+		<input type="radio" name="kind" value="a"><input type="radio" name="kind" value="b" checked>
+		<input type="strange" name="t" readonly>
+		<fieldset disabled><input name="off" value="x"></fieldset>
+		<select name="sort"><option value="date">By date</option><option> Relevance  first </option></select>
+		<select name="tags" multiple><option selected>a</option><option selected disabled>b</option><option selected>c</option></select>
+		<textarea name="notes">some text</textarea>
+		<input type="file" name="upload">
+		<button type="button" name="b">B</button>
+		<button name="go" value="1">Go</button>
+	</form>
+	<input name="outside" form="f" value="yes">
+	<input name="orphan">
+	<form></form>`)
+	forms := Forms(doc)
+	if len(forms) != 2 {
+		t.Fatalf("expected 2 forms, got %d", len(forms))
+	}
+	f := forms[0]
+	if f.Action != "/search" || f.Method != "post" || f.Enctype != "application/x-www-form-urlencoded" {
+		t.Errorf("unexpected form attributes %+v", f)
+	}
+	if len(f.Controls) != 15 {
+		t.Errorf("expected 15 controls, got %d", len(f.Controls))
+	}
+	byName := map[string]FormControl{}
+	for _, c := range f.Controls {
+		byName[c.Name] = c
+	}
+	if c := byName["t"]; c.Type != "text" || !c.ReadOnly {
+		t.Errorf("unexpected control %+v", c)
+	}
+	if c := byName["off"]; !c.Disabled {
+		t.Errorf("expected a disabled control, got %+v", c)
+	}
+	if c := byName["sort"]; c.Type != "select-one" || len(c.Options) != 2 || c.Options[1].Value != "Relevance first" {
+		t.Errorf("unexpected control %+v", c)
+	}
+	if f.Submit == nil || f.Submit.Name != "go" {
+		t.Errorf("unexpected default button %v", f.Submit)
+	}
+
+	expected := "exact=on&go=1&kind=b&lang=en&notes=some+text&outside=yes&q=go&sort=date&t=&tags=a&tags=c"
+	if got := f.Values(f.Submit).Encode(); got != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, got)
+	}
+	if got := f.Values(nil).Get("go"); got != "" {
+		t.Errorf("unexpected submitter value %q", got)
+	}
+
+	if empty := forms[1]; empty.Method != "get" || len(empty.Controls) != 0 || empty.Submit != nil {
+		t.Errorf("unexpected empty form %+v", empty)
+	}
+}
+
+func TestFormsSelectOne(t *testing.T) {
+	doc := MustParseHTML(`<form>
+		<select name="a"><option selected>1</option><option>2</option><option selected>3</option></select>
+		<select name="b"><option>1</option><option selected disabled>2</option></select>
+		<select name="c"><option disabled>1</option><option>2</option></select>
+	</form>`)
+	f := Forms(doc)[0]
+	if got, exp := f.Values(nil).Encode(), "a=3&c=2"; got != exp {
+		t.Errorf("expected %s, got %s", exp, got)
+	}
+}
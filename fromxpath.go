@@ -0,0 +1,417 @@
+package cascadia
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FromXPath translates a common subset of XPath 1.0 into selectors, to help
+// migrating existing scraping rules. The supported expressions are unions
+// of absolute location paths using the child ('/'), descendant ('//') and
+// following-sibling axes, with element names (or '*') and predicates made of:
+//   - attribute tests: @a, @a = 'v', @a != 'v'
+//   - contains(@a, 'v'), starts-with(@a, 'v'), ends-with(@a, 'v'),
+//     and the class test contains(concat(' ', normalize-space(@class), ' '), ' v ')
+//   - positions: [2], [last()], [position() = 2], only in the first predicate
+//     of the child and descendant steps
+//   - self:: steps, such as not(self::a)
+//   - the boolean operators and, or, not()
+//
+// For instance, //div[@class="x"]/a[1] is translated to div[class="x"] > a:nth-of-type(1).
+func FromXPath(expr string) (SelectorGroup, error) {
+	x := xpathParser{s: expr}
+	var out SelectorGroup
+	for {
+		sel, err := x.parsePath()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, sel)
+		x.skipSpace()
+		if x.i >= len(x.s) {
+			return out, nil
+		}
+		if x.s[x.i] != '|' {
+			return nil, x.errorf("unexpected %q", x.s[x.i:])
+		}
+		x.i++
+	}
+}
+
+type xpathParser struct {
+	s string
+	i int
+}
+
+func (x *xpathParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("unsupported XPath %q at offset %d: %s", x.s, x.i, fmt.Sprintf(format, args...))
+}
+
+func (x *xpathParser) skipSpace() {
+	for x.i < len(x.s) && strings.IndexByte(" \t\r\n", x.s[x.i]) != -1 {
+		x.i++
+	}
+}
+
+// consume skips whitespace, and consumes prefix if present
+func (x *xpathParser) consume(prefix string) bool {
+	x.skipSpace()
+	if strings.HasPrefix(x.s[x.i:], prefix) {
+		x.i += len(prefix)
+		return true
+	}
+	return false
+}
+
+// parseName parses an XML name, or '*'
+func (x *xpathParser) parseName() (string, error) {
+	x.skipSpace()
+	if x.consume("*") {
+		return "*", nil
+	}
+	start := x.i
+	for x.i < len(x.s) {
+		c := x.s[x.i]
+		if c == '-' || c == '_' || c == '.' || ('0' <= c && c <= '9' && x.i > start) || (c|0x20 >= 'a' && c|0x20 <= 'z') || c >= 0x80 {
+			x.i++
+		} else {
+			break
+		}
+	}
+	if x.i == start {
+		return "", x.errorf("expected a name")
+	}
+	return x.s[start:x.i], nil
+}
+
+// parsePath parses an absolute location path
+func (x *xpathParser) parsePath() (Sel, error) {
+	var out Sel
+	x.skipSpace()
+	if !strings.HasPrefix(x.s[x.i:], "/") {
+		return nil, x.errorf("only absolute paths are supported")
+	}
+	first := true
+	for {
+		var combinator byte
+		switch {
+		case x.consume("//"):
+			combinator = ' '
+		case x.consume("/"):
+			combinator = '>'
+		default:
+			return out, nil
+		}
+		if x.consume("following-sibling::") {
+			if combinator != '>' {
+				return nil, x.errorf("following-sibling must follow '/'")
+			}
+			combinator = '~'
+		} else {
+			x.consume("child::")
+		}
+		if first && combinator == '~' {
+			return nil, x.errorf("a path can't start with following-sibling")
+		}
+
+		step, err := x.parseStep(combinator == '~')
+		if err != nil {
+			return nil, err
+		}
+		if first {
+			if combinator == '>' {
+				// a child of the document: the root element
+				step = appendSimple(step, RootPseudoClassSelector{})
+			}
+			out = step
+			first = false
+		} else {
+			out = CombinedSelector{First: out, Combinator: combinator, Second: step}
+		}
+	}
+}
+
+// appendSimple adds s to the compound selector sel,
+// flattening s if it is itself a compound selector
+func appendSimple(sel Sel, s Sel) Sel {
+	c, ok := sel.(CompoundSelector)
+	if !ok {
+		c = CompoundSelector{Selectors: []Sel{sel}}
+	} else {
+		c.Selectors = append([]Sel(nil), c.Selectors...)
+	}
+	if inner, ok := s.(CompoundSelector); ok {
+		c.Selectors = append(c.Selectors, inner.Selectors...)
+	} else {
+		c.Selectors = append(c.Selectors, s)
+	}
+	return c
+}
+
+// parseStep parses a name test and its predicates;
+// sibling is true for the following-sibling axis
+func (x *xpathParser) parseStep(sibling bool) (Sel, error) {
+	name, err := x.parseName()
+	if err != nil {
+		return nil, err
+	}
+	var compound CompoundSelector
+	if name != "*" {
+		compound.Selectors = append(compound.Selectors, newTagSelector(name))
+	}
+	for index := 0; x.consume("["); index++ {
+		start := x.i
+		pred, err := x.parseOr(name != "*")
+		if err != nil {
+			return nil, err
+		}
+		if !x.consume("]") {
+			return nil, x.errorf("expected ']'")
+		}
+		// the positions are counted in the result of the previous predicate,
+		// and along the axis, which can't be expressed with :nth-of-type()
+		if isPositional(pred) {
+			if index != 0 {
+				x.i = start
+				return nil, x.errorf("a position is only supported in the first predicate")
+			}
+			if sibling {
+				x.i = start
+				return nil, x.errorf("a position is not supported on the following-sibling axis")
+			}
+		}
+		// the predicates made of several selectors, like [@a and @b], are flattened
+		if inner, ok := pred.(CompoundSelector); ok {
+			compound.Selectors = append(compound.Selectors, inner.Selectors...)
+		} else {
+			compound.Selectors = append(compound.Selectors, pred)
+		}
+	}
+	if len(compound.Selectors) == 1 {
+		return compound.Selectors[0], nil
+	}
+	return compound, nil
+}
+
+// isPositional returns true if pred tests the position of the element
+func isPositional(pred Sel) bool {
+	found := false
+	Walk(pred, func(s Sel) bool {
+		if _, ok := s.(NthPseudoClassSelector); ok {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// parseOr parses a boolean expression; ofType is true if
+// positions are counted among elements with the same name
+func (x *xpathParser) parseOr(ofType bool) (Sel, error) {
+	first, err := x.parseAnd(ofType)
+	if err != nil {
+		return nil, err
+	}
+	args := SelectorGroup{first}
+	for x.consumeKeyword("or") {
+		next, err := x.parseAnd(ofType)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, next)
+	}
+	if len(args) == 1 {
+		return first, nil
+	}
+	return RelativePseudoClassSelector{Name: "is", Args: args}, nil
+}
+
+func (x *xpathParser) parseAnd(ofType bool) (Sel, error) {
+	first, err := x.parseUnary(ofType)
+	if err != nil {
+		return nil, err
+	}
+	out := first
+	for x.consumeKeyword("and") {
+		next, err := x.parseUnary(ofType)
+		if err != nil {
+			return nil, err
+		}
+		out = appendSimple(out, next)
+	}
+	return out, nil
+}
+
+// consumeKeyword consumes kw, if it is not the prefix of a longer name
+func (x *xpathParser) consumeKeyword(kw string) bool {
+	save := x.i
+	if !x.consume(kw) {
+		return false
+	}
+	if x.i < len(x.s) && (x.s[x.i] == '-' || x.s[x.i] == '_' || x.s[x.i]|0x20 >= 'a' && x.s[x.i]|0x20 <= 'z') {
+		x.i = save
+		return false
+	}
+	return true
+}
+
+func (x *xpathParser) parseUnary(ofType bool) (Sel, error) {
+	switch {
+	case x.consume("not("):
+		arg, err := x.parseOr(ofType)
+		if err != nil {
+			return nil, err
+		}
+		if !x.consume(")") {
+			return nil, x.errorf("expected ')'")
+		}
+		return RelativePseudoClassSelector{Name: "not", Args: SelectorGroup{arg}}, nil
+	case x.consume("("):
+		arg, err := x.parseOr(ofType)
+		if err != nil {
+			return nil, err
+		}
+		if !x.consume(")") {
+			return nil, x.errorf("expected ')'")
+		}
+		return arg, nil
+	case x.consume("@"):
+		return x.parseAttributeTest()
+	case x.consume("self::"):
+		return x.parseStep(false)
+	case x.consume("last()"):
+		return NthPseudoClassSelector{A: 0, B: 1, Last: true, OfType: ofType}, nil
+	case x.consume("position()"):
+		if !x.consume("=") {
+			return nil, x.errorf("expected '=' after position()")
+		}
+		return x.parsePosition(ofType)
+	}
+	for _, name := range [...]string{"contains", "starts-with", "ends-with"} {
+		if x.consume(name + "(") {
+			return x.parseFunction(name)
+		}
+	}
+	x.skipSpace()
+	if x.i < len(x.s) && '0' <= x.s[x.i] && x.s[x.i] <= '9' {
+		return x.parsePosition(ofType)
+	}
+	return nil, x.errorf("unsupported predicate")
+}
+
+func (x *xpathParser) parsePosition(ofType bool) (Sel, error) {
+	x.skipSpace()
+	start := x.i
+	for x.i < len(x.s) && '0' <= x.s[x.i] && x.s[x.i] <= '9' {
+		x.i++
+	}
+	n, err := strconv.Atoi(x.s[start:x.i])
+	if err != nil {
+		return nil, x.errorf("expected a position")
+	}
+	return NthPseudoClassSelector{A: 0, B: n, OfType: ofType}, nil
+}
+
+func (x *xpathParser) parseLiteral() (string, error) {
+	x.skipSpace()
+	if x.i >= len(x.s) || (x.s[x.i] != '\'' && x.s[x.i] != '"') {
+		return "", x.errorf("expected a string literal")
+	}
+	quote := x.s[x.i]
+	end := strings.IndexByte(x.s[x.i+1:], quote)
+	if end == -1 {
+		return "", x.errorf("unterminated string literal")
+	}
+	val := x.s[x.i+1 : x.i+1+end]
+	x.i += end + 2
+	return val, nil
+}
+
+// parseAttributeTest parses the rest of @name [op literal]
+func (x *xpathParser) parseAttributeTest() (Sel, error) {
+	key, err := x.parseName()
+	if err != nil {
+		return nil, err
+	}
+	key = toLowerASCII(key)
+	op := ""
+	switch {
+	case x.consume("!="):
+		op = "!="
+	case x.consume("="):
+		op = "="
+	default:
+		return AttrSelector{Key: key}, nil
+	}
+	val, err := x.parseLiteral()
+	if err != nil {
+		return nil, err
+	}
+	if key == "id" && op == "=" {
+		return IDSelector{ID: val}, nil
+	}
+	if op == "!=" {
+		// contrary to [a!=v], @a != 'v' is false without @a
+		return CompoundSelector{Selectors: []Sel{
+			AttrSelector{Key: key},
+			RelativePseudoClassSelector{Name: "not", Args: SelectorGroup{AttrSelector{Key: key, Operation: "=", Val: val}}},
+		}}, nil
+	}
+	return AttrSelector{Key: key, Operation: op, Val: val}, nil
+}
+
+// parseFunction parses the arguments of contains, starts-with and ends-with
+func (x *xpathParser) parseFunction(name string) (Sel, error) {
+	var key string
+	isClassTest := false
+	if x.consume("concat(") {
+		// concat(' ', normalize-space(@class), ' ')
+		if lit, err := x.parseLiteral(); err != nil || lit != " " || !x.consume(",") || !x.consume("normalize-space(@") {
+			return nil, x.errorf("unsupported concat() expression")
+		}
+		var err error
+		if key, err = x.parseName(); err != nil {
+			return nil, err
+		}
+		if !x.consume(")") || !x.consume(",") {
+			return nil, x.errorf("unsupported concat() expression")
+		}
+		if lit, err := x.parseLiteral(); err != nil || lit != " " || !x.consume(")") {
+			return nil, x.errorf("unsupported concat() expression")
+		}
+		isClassTest = true
+	} else {
+		if !x.consume("@") {
+			return nil, x.errorf("expected an attribute as first argument of %s()", name)
+		}
+		var err error
+		if key, err = x.parseName(); err != nil {
+			return nil, err
+		}
+	}
+	key = toLowerASCII(key)
+	if !x.consume(",") {
+		return nil, x.errorf("expected ','")
+	}
+	val, err := x.parseLiteral()
+	if err != nil {
+		return nil, err
+	}
+	if !x.consume(")") {
+		return nil, x.errorf("expected ')'")
+	}
+
+	if isClassTest {
+		word := strings.TrimSpace(val)
+		if name != "contains" || val != " "+word+" " || word == "" || containsWhitespace(word) {
+			return nil, x.errorf("unsupported class test")
+		}
+		if key == "class" {
+			return ClassSelector{Class: word}, nil
+		}
+		return AttrSelector{Key: key, Operation: "~=", Val: word}, nil
+	}
+	op := map[string]string{"contains": "*=", "starts-with": "^=", "ends-with": "$="}[name]
+	return AttrSelector{Key: key, Operation: op, Val: val}, nil
+}
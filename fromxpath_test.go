@@ -0,0 +1,92 @@
+package cascadia
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestFromXPath(t *testing.T) {
+	for _, test := range []struct{ xpath, exp string }{
+		{`//div`, "div"},
+		{`//div[@class="x"]`, `div[class="x"]`},
+		{`//div[@id='main']//a`, "div#main a"},
+		{`//ul/li[2]`, "ul > li:nth-of-type(2)"},
+		{`//ul/*[1]`, "ul > :first-child"},
+		{`//ul/li[last()]`, "ul > li:last-of-type"},
+		{`//li[position() = 3]`, "li:nth-of-type(3)"},
+		{`//p[1][@a]`, "p:first-of-type[a]"},
+		{`/html/body`, "html:root > body"},
+		{`//a[@href and not(@rel="nofollow")]`, `a[href]:not([rel="nofollow"])`},
+		{`//a[contains(@href, "x") or starts-with(@href, 'http')]`, `a:is([href*="x"], [href^="http"])`},
+		{`//p[contains(concat(' ', normalize-space(@class), ' '), ' intro ')]`, "p.intro"},
+		{`//h1/following-sibling::p`, "h1 ~ p"},
+		{`//input[@type!='hidden']`, `input[type]:not([type="hidden"])`},
+		{`//input[not(@type!='hidden')]`, `input:not([type]:not([type="hidden"]))`},
+		{`//a | //b`, "a, b"},
+		{`//div[@a and @b]`, "div[a][b]"},
+		{`//div[(@a and @b) and @c][@d]`, "div[a][b][c][d]"},
+	} {
+		got, err := FromXPath(test.xpath)
+		if err != nil {
+			t.Fatalf("%s: %s", test.xpath, err)
+		}
+		exp, err := ParseGroup(test.exp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != exp.String() {
+			t.Errorf("%s: expected %s, got %s", test.xpath, exp, got)
+		}
+		if _, err := CompileASTGroup(got); err != nil {
+			t.Errorf("%s: %s", test.xpath, err)
+		}
+	}
+
+	// @a != 'v' requires the attribute
+	notHidden, err := FromXPath(`//input[@type!='hidden']`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := MustParseHTML(`<input><input type="text"><input type="hidden">`)
+	if got := QueryAll(doc, notHidden); len(got) != 1 || getAttr(got[0], "type") != "text" {
+		t.Errorf("unexpected matches %v", got)
+	}
+
+	for _, invalid := range []string{
+		"div", "//div[text()='x']", "//div[@a='x", "//a[contains(@href)]", "//following-sibling::a",
+		// the positions count the result of the previous predicates, or along the axis
+		"//p[@a][1]", "//p[@a][last()]", "//p[@a][not(position() = 2)]", "//div/following-sibling::p[1]",
+		"//div/following-sibling::*[@a or last()]",
+	} {
+		if _, err := FromXPath(invalid); err == nil {
+			t.Errorf("%s: expected an error", invalid)
+		}
+	}
+}
+
+func TestXPathRoundTrip(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(`<ul><li class="a b">1</li><li>2</li><li class="b">3</li></ul>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, css := range []string{"li.b", "ul > li", "li:not(.a)", "li[class]"} {
+		sel, err := Parse(css)
+		if err != nil {
+			t.Fatal(err)
+		}
+		xpath, err := ToXPath(sel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// ToXPath uses a relative path
+		back, err := FromXPath("//" + strings.TrimPrefix(xpath, "descendant-or-self::"))
+		if err != nil {
+			t.Fatalf("%s (%s): %s", css, xpath, err)
+		}
+		if len(QueryAll(doc, sel)) != len(QueryAll(doc, back)) {
+			t.Errorf("%s: %s doesn't match the same elements", css, back)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package cascadia
+
+import (
+	"fmt"
+
+	"golang.org/x/net/html"
+)
+
+// NodeLike is the constraint satisfied by the node types T which may be
+// matched without converting them to the Node interface.
+// Its methods are the ones of Node, with the navigation methods
+// returning T, whose zero value means "no node".
+//
+// The Node interface itself satisfies NodeLike[Node].
+type NodeLike[T any] interface {
+	comparable
+
+	Type() html.NodeType
+	Data() string
+	Namespace() string
+	Attributes() []html.Attribute
+
+	Parent() T
+	FirstChild() T
+	LastChild() T
+	PrevSibling() T
+	NextSibling() T
+}
+
+// MatcherFor is a matcher for the node type T.
+//
+// The selectors of this package implement MatcherFor[Node];
+// use CompileFor to obtain a matcher specialized for a concrete type.
+type MatcherFor[T any] interface {
+	MatchNode(n T) bool
+}
+
+func isNil[T comparable](n T) bool {
+	var zero T
+	return n == zero
+}
+
+// compiledMatcher is the MatcherFor returned by CompileFor
+type compiledMatcher[T any] func(T) bool
+
+func (m compiledMatcher[T]) MatchNode(n T) bool { return m(n) }
+
+// CompileFor returns a matcher equivalent to sel, working directly on
+// the node type T, so that the nodes are never converted to the Node
+// interface. An error is returned if sel uses a type not defined by
+// this package.
+func CompileFor[T NodeLike[T]](sel Sel) (MatcherFor[T], error) {
+	m, err := compileFor[T](sel)
+	if err != nil {
+		return nil, err
+	}
+	return compiledMatcher[T](m), nil
+}
+
+// CompileGroupFor is like CompileFor, for a group of selectors.
+func CompileGroupFor[T NodeLike[T]](group SelectorGroup) (MatcherFor[T], error) {
+	m, err := compileGroupFor[T](group)
+	if err != nil {
+		return nil, err
+	}
+	return compiledMatcher[T](m), nil
+}
+
+// QueryFor returns the first node that matches m, from the descendants of n,
+// or the zero value of T if none matches.
+func QueryFor[T NodeLike[T]](n T, m MatcherFor[T]) T {
+	for c := n.FirstChild(); !isNil(c); c = c.NextSibling() {
+		if m.MatchNode(c) {
+			return c
+		}
+		if matched := QueryFor(c, m); !isNil(matched) {
+			return matched
+		}
+	}
+	var zero T
+	return zero
+}
+
+// QueryAllFor returns all the nodes that match m, from the descendants of n.
+func QueryAllFor[T NodeLike[T]](n T, m MatcherFor[T]) []T {
+	return queryForInto(n, m, nil)
+}
+
+func queryForInto[T NodeLike[T]](n T, m MatcherFor[T], storage []T) []T {
+	for c := n.FirstChild(); !isNil(c); c = c.NextSibling() {
+		if m.MatchNode(c) {
+			storage = append(storage, c)
+		}
+		storage = queryForInto(c, m, storage)
+	}
+	return storage
+}
+
+// FilterFor returns the nodes that match m.
+func FilterFor[T NodeLike[T]](nodes []T, m MatcherFor[T]) (result []T) {
+	for _, n := range nodes {
+		if m.MatchNode(n) {
+			result = append(result, n)
+		}
+	}
+	return result
+}
+
+func compileGroupFor[T NodeLike[T]](group SelectorGroup) (func(T) bool, error) {
+	matchers := make([]func(T) bool, len(group))
+	for i, sel := range group {
+		var err error
+		if matchers[i], err = compileFor[T](sel); err != nil {
+			return nil, err
+		}
+	}
+	return func(n T) bool {
+		for _, m := range matchers {
+			if m(n) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+func compileFor[T NodeLike[T]](sel Sel) (func(T) bool, error) {
+	switch s := sel.(type) {
+	case TagSelector:
+		return func(n T) bool { return n.Type() == html.ElementNode && n.Data() == s.Tag }, nil
+	case ClassSelector:
+		return func(n T) bool { return matchClass(s, n) }, nil
+	case IDSelector:
+		return func(n T) bool { return matchID(s, n) }, nil
+	case AttrSelector:
+		return func(n T) bool { return matchAttr(s, n) }, nil
+	case NeverMatchSelector:
+		return func(T) bool { return false }, nil
+	case CompoundSelector:
+		return compileCompoundFor[T](s)
+	case CombinedSelector:
+		return compileCombinedFor[T](s)
+	case RelativePseudoClassSelector:
+		return compileRelativeFor[T](s)
+	case ContainsPseudoClassSelector:
+		return func(n T) bool { return matchContains(s, n) }, nil
+	case RegexpPseudoClassSelector:
+		return func(n T) bool { return matchRegexp(s, n) }, nil
+	case NthPseudoClassSelector:
+		return func(n T) bool { return matchNth(s, n) }, nil
+	case OnlyChildPseudoClassSelector:
+		return func(n T) bool { return matchOnlyChild(s, n) }, nil
+	case InputPseudoClassSelector:
+		return func(n T) bool { return matchInput(s, n) }, nil
+	case EmptyElementPseudoClassSelector:
+		return func(n T) bool { return matchEmptyElement(s, n) }, nil
+	case RootPseudoClassSelector:
+		return func(n T) bool { return matchRoot(s, n) }, nil
+	case ScopePseudoClassSelector:
+		return func(n T) bool { return matchScope(s, n) }, nil
+	case NestingSelector:
+		scope := s.asScope()
+		return func(n T) bool { return matchScope(scope, n) }, nil
+	case TimePseudoClassSelector:
+		return func(n T) bool {
+			node, ok := any(n).(Node)
+			return ok && s.MatchNode(node)
+		}, nil
+	case CustomStatePseudoClassSelector:
+		return func(n T) bool {
+			node, ok := any(n).(Node)
+			return ok && s.MatchNode(node)
+		}, nil
+	case LinkPseudoClassSelector:
+		return func(n T) bool { return matchLink(s, n) }, nil
+	case LangPseudoClassSelector:
+		return func(n T) bool { return matchLang(s, n) }, nil
+	case EnabledPseudoClassSelector:
+		return func(n T) bool { return matchEnabled(s, n) }, nil
+	case DisabledPseudoClassSelector:
+		return func(n T) bool { return matchDisabled(s, n) }, nil
+	case CheckedPseudoClassSelector:
+		return func(n T) bool { return matchChecked(s, n) }, nil
+	}
+	return nil, fmt.Errorf("unsupported selector type %T", sel)
+}
+
+func compileCompoundFor[T NodeLike[T]](s CompoundSelector) (func(T) bool, error) {
+	if typ, ok := nodePseudoElement(s.Pseudo); ok {
+		return compileNodePseudoFor[T](s, typ)
+	}
+	matchers := make([]func(T) bool, len(s.Selectors))
+	for i, sel := range s.Selectors {
+		var err error
+		if matchers[i], err = compileFor[T](sel); err != nil {
+			return nil, err
+		}
+	}
+	if name, ok := attrPseudoElement(s.Pseudo); ok {
+		matchers = append(matchers, func(n T) bool { return hasAttr(n, name) })
+	}
+	return func(n T) bool {
+		if len(matchers) == 0 {
+			return n.Type() == html.ElementNode
+		}
+		for _, m := range matchers {
+			if !m(n) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func compileCombinedFor[T NodeLike[T]](s CombinedSelector) (func(T) bool, error) {
+	if s.First == nil {
+		return func(T) bool { return false }, nil
+	}
+	if typ, ok := nodePseudoElement(s.PseudoElement()); ok {
+		return compileNodePseudoFor[T](s, typ)
+	}
+	first, err := compileFor[T](s.First)
+	if err != nil || s.Combinator == 0 {
+		return first, err
+	}
+	second, err := compileFor[T](s.Second)
+	if err != nil {
+		return nil, err
+	}
+	switch s.Combinator {
+	case ' ':
+		return func(n T) bool {
+			if !second(n) {
+				return false
+			}
+			for p := n.Parent(); !isNil(p); p = p.Parent() {
+				if first(p) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	case '>':
+		return func(n T) bool {
+			if !second(n) {
+				return false
+			}
+			p := n.Parent()
+			return !isNil(p) && first(p)
+		}, nil
+	case '+':
+		return func(n T) bool {
+			if !second(n) {
+				return false
+			}
+			c := prevElementSibling(n)
+			return !isNil(c) && first(c)
+		}, nil
+	case '~':
+		return func(n T) bool {
+			if !second(n) {
+				return false
+			}
+			for c := prevElementSibling(n); !isNil(c); c = prevElementSibling(c) {
+				if first(c) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown combinator %q", s.Combinator)
+}
+
+func compileRelativeFor[T NodeLike[T]](s RelativePseudoClassSelector) (func(T) bool, error) {
+	args, err := compileGroupFor[T](s.Args)
+	if err != nil {
+		return nil, err
+	}
+	var match func(T) bool
+	switch s.Name {
+	case "not":
+		match = func(n T) bool { return !args(n) }
+	case "is", "where":
+		match = args
+	case "has":
+		match = func(n T) bool { return hasDescendantMatch(n, args) }
+	case "haschild":
+		match = func(n T) bool { return hasChildMatch(n, args) }
+	default:
+		return nil, fmt.Errorf("unsupported relative pseudo class selector : %s", s.Name)
+	}
+	return func(n T) bool { return n.Type() == html.ElementNode && match(n) }, nil
+}
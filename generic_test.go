@@ -0,0 +1,84 @@
+package cascadia
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+// valueNode is a NodeLike[valueNode], whose zero value is the nil node
+type valueNode struct{ t *treeNode }
+
+func (v valueNode) Type() html.NodeType          { return v.t.typ }
+func (v valueNode) Data() string                 { return v.t.data }
+func (v valueNode) Namespace() string            { return "" }
+func (v valueNode) Attributes() []html.Attribute { return v.t.attrs }
+func (v valueNode) Parent() valueNode            { return valueNode{v.t.parent} }
+func (v valueNode) FirstChild() valueNode        { return valueNode{v.t.first} }
+func (v valueNode) LastChild() valueNode         { return valueNode{v.t.last} }
+func (v valueNode) PrevSibling() valueNode       { return valueNode{v.t.prev} }
+func (v valueNode) NextSibling() valueNode       { return valueNode{v.t.next} }
+
+func TestCompileFor(t *testing.T) {
+	for _, test := range selectorTests {
+		doc, err := html.Parse(strings.NewReader(test.HTML))
+		if err != nil {
+			t.Fatal(err)
+		}
+		group, err := ParseGroup(test.selector)
+		if err != nil {
+			t.Fatal(err)
+		}
+		exp := QueryAll(doc, group)
+
+		m, err := CompileGroupFor[valueNode](group)
+		if err != nil {
+			t.Fatal(err)
+		}
+		root := valueNode{copyTree(doc)}
+		got := QueryAllFor(root, m)
+		if len(got) != len(exp) {
+			t.Errorf("%s: expected %d matches, got %d", test.selector, len(exp), len(got))
+			continue
+		}
+		for i, n := range got {
+			if n.t.source != exp[i] {
+				t.Errorf("%s: unexpected match %d", test.selector, i)
+			}
+		}
+		if first := QueryFor(root, m); len(exp) != 0 && first.t.source != exp[0] {
+			t.Errorf("%s: unexpected first match", test.selector)
+		}
+
+		// the selectors are matchers for the Node interface
+		if len(QueryAllFor[Node](FromHTML(doc), group)) != len(exp) {
+			t.Errorf("%s: unexpected matches for Node", test.selector)
+		}
+	}
+}
+
+func TestCompileForErrors(t *testing.T) {
+	sel, err := Parse("div > p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CompileFor[valueNode](Instrument(sel)); err == nil {
+		t.Error("expected an error for a custom selector")
+	}
+	if _, err := CompileFor[valueNode](CompoundSelector{Selectors: []Sel{Instrument(sel)}}); err == nil {
+		t.Error("expected an error for a custom component")
+	}
+
+	m, err := CompileFor[valueNode](sel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := valueNode{copyTree(MustParseHTML(`<div><span></span></div>`))}
+	if n := QueryFor(root, m); n != (valueNode{}) {
+		t.Error("expected no match")
+	}
+	if got := FilterFor([]valueNode{root}, m); len(got) != 0 {
+		t.Error("expected no match")
+	}
+}
@@ -1,5 +1,5 @@
 module github.com/benoitkugler/cascadia
 
-go 1.16
+go 1.20
 
 require golang.org/x/net v0.0.0-20210916014120-12bc252f5db8
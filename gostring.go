@@ -0,0 +1,218 @@
+package cascadia
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// This file implements fmt.GoStringer for the selectors, so that the %#v
+// verb shows the structure of a compiled selector, as a Go composite literal
+// omitting the zero fields, instead of the raw fields of the default format.
+// The unexported options changing the matching (see Options) are
+// shown as comments.
+
+// goLiteral builds the composite literal of a struct
+type goLiteral struct {
+	b      strings.Builder
+	fields int
+}
+
+func newGoLiteral(typ string) *goLiteral {
+	l := &goLiteral{}
+	l.b.WriteString("cascadia." + typ + "{")
+	return l
+}
+
+func (l *goLiteral) field(name, value string) *goLiteral {
+	if l.fields > 0 {
+		l.b.WriteString(", ")
+	}
+	l.fields++
+	l.b.WriteString(name + ": " + value)
+	return l
+}
+
+func (l *goLiteral) str(name, value string) *goLiteral {
+	if value == "" {
+		return l
+	}
+	return l.field(name, strconv.Quote(value))
+}
+
+func (l *goLiteral) flag(name string, value bool) *goLiteral {
+	if !value {
+		return l
+	}
+	return l.field(name, "true")
+}
+
+func (l *goLiteral) regexp(name string, rx *regexp.Regexp) *goLiteral {
+	if rx == nil {
+		return l
+	}
+	return l.field(name, "regexp.MustCompile("+strconv.Quote(rx.String())+")")
+}
+
+func (l *goLiteral) pos(pos Span) *goLiteral {
+	if pos == (Span{}) {
+		return l
+	}
+	return l.field("Pos", fmt.Sprintf("cascadia.Span{Start: %d, End: %d}", pos.Start, pos.End))
+}
+
+// comment notes an unexported option, if set
+func (l *goLiteral) comment(option string, set bool) *goLiteral {
+	if set {
+		l.b.WriteString(" /* " + option + " */")
+	}
+	return l
+}
+
+func (l *goLiteral) String() string { return l.b.String() + "}" }
+
+func (c TagSelector) GoString() string {
+	return newGoLiteral("TagSelector").str("Tag", c.Tag).pos(c.Pos).String()
+}
+
+func (c ClassSelector) GoString() string {
+	return newGoLiteral("ClassSelector").str("Class", c.Class).pos(c.Pos).
+		comment("quirks", c.quirks).String()
+}
+
+func (c IDSelector) GoString() string {
+	return newGoLiteral("IDSelector").str("ID", c.ID).pos(c.Pos).
+		comment("quirks", c.quirks).String()
+}
+
+func (c AttrSelector) GoString() string {
+	l := newGoLiteral("AttrSelector").str("Key", c.Key).str("Operation", c.Operation)
+	if c.Operation != "" && c.Operation != "#=" {
+		l.field("Val", strconv.Quote(c.Val))
+	}
+	return l.regexp("Regexp", c.Regexp).pos(c.Pos).
+		comment("legacy include", c.legacyInclude).
+		comment("written "+strconv.Quote(c.rawKey), c.rawKey != "").
+		comment("fold foreign case", c.foldForeignCase).String()
+}
+
+func (c NeverMatchSelector) GoString() string {
+	return newGoLiteral("NeverMatchSelector").str("Value", c.Value).pos(c.Pos).String()
+}
+
+func (c CompoundSelector) GoString() string {
+	l := newGoLiteral("CompoundSelector")
+	if len(c.Selectors) != 0 {
+		l.field("Selectors", goStringSels("[]cascadia.Sel", c.Selectors))
+	}
+	return l.str("Pseudo", c.Pseudo).pos(c.Pos).String()
+}
+
+func (c CombinedSelector) GoString() string {
+	l := newGoLiteral("CombinedSelector")
+	if c.First != nil {
+		l.field("First", fmt.Sprintf("%#v", c.First))
+	}
+	if c.Combinator != 0 {
+		l.field("Combinator", strconv.QuoteRune(rune(c.Combinator)))
+	}
+	if c.Second != nil {
+		l.field("Second", fmt.Sprintf("%#v", c.Second))
+	}
+	l.pos(c.Pos)
+	if c.CombinatorPos != (Span{}) {
+		l.field("CombinatorPos", fmt.Sprintf("cascadia.Span{Start: %d, End: %d}", c.CombinatorPos.Start, c.CombinatorPos.End))
+	}
+	return l.String()
+}
+
+func (c SelectorGroup) GoString() string { return goStringSels("cascadia.SelectorGroup", c) }
+
+func goStringSels(typ string, sels []Sel) string {
+	parts := make([]string, len(sels))
+	for i, sel := range sels {
+		parts[i] = fmt.Sprintf("%#v", sel)
+	}
+	return typ + "{" + strings.Join(parts, ", ") + "}"
+}
+
+func (c RelativePseudoClassSelector) GoString() string {
+	l := newGoLiteral("RelativePseudoClassSelector").str("Name", c.Name)
+	if len(c.Args) != 0 {
+		l.field("Args", c.Args.GoString())
+	}
+	return l.pos(c.Pos).String()
+}
+
+func (c ContainsPseudoClassSelector) GoString() string {
+	return newGoLiteral("ContainsPseudoClassSelector").str("Value", c.Value).flag("Own", c.Own).String()
+}
+
+func (c RegexpPseudoClassSelector) GoString() string {
+	return newGoLiteral("RegexpPseudoClassSelector").regexp("Regexp", c.Regexp).flag("Own", c.Own).String()
+}
+
+func (c NthPseudoClassSelector) GoString() string {
+	// A and B are always shown, since A: 0, B: 1 is meaningful
+	return newGoLiteral("NthPseudoClassSelector").
+		field("A", strconv.Itoa(c.A)).field("B", strconv.Itoa(c.B)).
+		flag("Last", c.Last).flag("OfType", c.OfType).String()
+}
+
+func (c OnlyChildPseudoClassSelector) GoString() string {
+	return newGoLiteral("OnlyChildPseudoClassSelector").flag("OfType", c.OfType).String()
+}
+
+func (c LangPseudoClassSelector) GoString() string {
+	return newGoLiteral("LangPseudoClassSelector").str("Lang", c.Lang).String()
+}
+
+func (c InputPseudoClassSelector) GoString() string {
+	return newGoLiteral("InputPseudoClassSelector").String()
+}
+
+func (c EmptyElementPseudoClassSelector) GoString() string {
+	return newGoLiteral("EmptyElementPseudoClassSelector").String()
+}
+
+func (c RootPseudoClassSelector) GoString() string {
+	return newGoLiteral("RootPseudoClassSelector").String()
+}
+
+func (c ScopePseudoClassSelector) GoString() string {
+	return newGoLiteral("ScopePseudoClassSelector").comment("bound", c.scope != nil).String()
+}
+
+func (c NestingSelector) GoString() string {
+	return newGoLiteral("NestingSelector").pos(c.Pos).comment("bound", c.scope != nil).String()
+}
+
+func (c TimePseudoClassSelector) GoString() string {
+	l := newGoLiteral("TimePseudoClassSelector").str("Name", c.Name)
+	if c.Args != nil {
+		l.field("Args", c.Args.GoString())
+	}
+	return l.pos(c.Pos).comment("with states", c.states != nil).String()
+}
+
+func (c CustomStatePseudoClassSelector) GoString() string {
+	return newGoLiteral("CustomStatePseudoClassSelector").str("State", c.State).pos(c.Pos).
+		comment("with states", c.states != nil).String()
+}
+
+func (c LinkPseudoClassSelector) GoString() string {
+	return newGoLiteral("LinkPseudoClassSelector").String()
+}
+
+func (c EnabledPseudoClassSelector) GoString() string {
+	return newGoLiteral("EnabledPseudoClassSelector").String()
+}
+
+func (c DisabledPseudoClassSelector) GoString() string {
+	return newGoLiteral("DisabledPseudoClassSelector").String()
+}
+
+func (c CheckedPseudoClassSelector) GoString() string {
+	return newGoLiteral("CheckedPseudoClassSelector").String()
+}
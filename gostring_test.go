@@ -0,0 +1,60 @@
+package cascadia
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestGoString(t *testing.T) {
+	for _, test := range []struct {
+		sel, want string
+	}{
+		{"div", `cascadia.TagSelector{Tag: "div"}`},
+		{"*", `cascadia.CompoundSelector{}`},
+		{"p.a", `cascadia.CompoundSelector{Selectors: []cascadia.Sel{cascadia.TagSelector{Tag: "p"}, cascadia.ClassSelector{Class: "a"}}}`},
+		{"a > #b", `cascadia.CombinedSelector{First: cascadia.TagSelector{Tag: "a"}, Combinator: '>', Second: cascadia.IDSelector{ID: "b"}}`},
+		{`[href^="http"]`, `cascadia.AttrSelector{Key: "href", Operation: "^=", Val: "http"}`},
+		{`[lang]`, `cascadia.AttrSelector{Key: "lang"}`},
+		{`[id=""]`, `cascadia.AttrSelector{Key: "id", Operation: "=", Val: ""}`},
+		{`[x#=(^a+$)]`, `cascadia.AttrSelector{Key: "x", Operation: "#=", Regexp: regexp.MustCompile("(^a+$)")}`},
+		{":not(a, b)", `cascadia.RelativePseudoClassSelector{Name: "not", Args: cascadia.SelectorGroup{cascadia.TagSelector{Tag: "a"}, cascadia.TagSelector{Tag: "b"}}}`},
+		{":first-child", `cascadia.NthPseudoClassSelector{A: 0, B: 1}`},
+		{":nth-last-of-type(2n+1)", `cascadia.NthPseudoClassSelector{A: 2, B: 1, Last: true, OfType: true}`},
+		{":only-of-type", `cascadia.OnlyChildPseudoClassSelector{OfType: true}`},
+		{":containsOwn(Foo)", `cascadia.ContainsPseudoClassSelector{Value: "foo", Own: true}`},
+		{":matches(^x)", `cascadia.RegexpPseudoClassSelector{Regexp: regexp.MustCompile("^x")}`},
+		{":lang(en)", `cascadia.LangPseudoClassSelector{Lang: "en"}`},
+		{":root", `cascadia.RootPseudoClassSelector{}`},
+		{":hover", `cascadia.NeverMatchSelector{Value: ":hover"}`},
+		{"p::before", `cascadia.CompoundSelector{Selectors: []cascadia.Sel{cascadia.TagSelector{Tag: "p"}}, Pseudo: "before"}`},
+	} {
+		sel, err := ParseWithPseudoElement(test.sel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fmt.Sprintf("%#v", sel); got != test.want {
+			t.Errorf("%s: expected\n%s\ngot\n%s", test.sel, test.want, got)
+		}
+	}
+
+	group := MustParseGroup("a, b")
+	if got := fmt.Sprintf("%#v", group); got != `cascadia.SelectorGroup{cascadia.TagSelector{Tag: "a"}, cascadia.TagSelector{Tag: "b"}}` {
+		t.Errorf("unexpected group %s", got)
+	}
+
+	sel, err := ParseWithSpans("a.b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprintf("%#v", sel.(CompoundSelector).Selectors[1]); got != `cascadia.ClassSelector{Class: "b", Pos: cascadia.Span{Start: 1, End: 3}}` {
+		t.Errorf("unexpected span %s", got)
+	}
+
+	quirks, err := ParseWithOptions(".A", Options{Quirks: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprintf("%#v", quirks); got != `cascadia.ClassSelector{Class: "A" /* quirks */}` {
+		t.Errorf("unexpected option %s", got)
+	}
+}
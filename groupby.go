@@ -0,0 +1,42 @@
+package cascadia
+
+import "golang.org/x/net/html"
+
+// AncestorGroup is a container element, with the items it contains,
+// as returned by GroupByAncestor.
+type AncestorGroup struct {
+	Container *html.Node
+	Items     []*html.Node // in tree order
+}
+
+// GroupByAncestor returns the descendants of n matched by item, grouped under
+// their nearest ancestor matched by container, like the <li> of each <ul>,
+// in one traversal.
+//
+// The groups are returned in tree order, including the containers without
+// items. The items without container ancestor (below n) are dropped. For nested
+// containers, an item belongs to the innermost one only.
+func GroupByAncestor(n *html.Node, container, item Matcher) []AncestorGroup {
+	root := FromHTML(n)
+	container, item = bindRoot(container, root), bindRoot(item, root)
+	var out []AncestorGroup
+	var visit func(n *html.Node, current int)
+	visit = func(n *html.Node, current int) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			if current != -1 && item.Match(c) {
+				out[current].Items = append(out[current].Items, c)
+			}
+			inner := current
+			if container.Match(c) {
+				out = append(out, AncestorGroup{Container: c})
+				inner = len(out) - 1
+			}
+			visit(c, inner)
+		}
+	}
+	visit(n, -1)
+	return out
+}
@@ -0,0 +1,36 @@
+package cascadia
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGroupByAncestor(t *testing.T) {
+	doc := MustParseHTML(`<li>orphan</li>
+	<ul id="a"><li>1</li><li>2<ul id="b"><li>2.1</li></ul></li></ul>
+	<ul id="c"></ul>
+	<ol><li>x</li></ol>
+	<ul id="d"><li>3</li></ul>`)
+
+	groups := GroupByAncestor(doc, MustParse("ul"), MustParse("li"))
+	var got []string
+	for _, g := range groups {
+		var items []string
+		for _, item := range g.Items {
+			items = append(items, nodeOwnText(FromHTML(item)))
+		}
+		got = append(got, nodeID(g.Container)+":"+strings.Join(items, ","))
+	}
+	if s := strings.Join(got, " "); s != "a:1,2 b:2.1 c: d:3" {
+		t.Errorf("unexpected groups %s", s)
+	}
+
+	// the containers are searched below the root only
+	b := Query(doc, MustParse("#b"))
+	if groups := GroupByAncestor(b, MustParse("ul"), MustParse("li")); len(groups) != 0 {
+		t.Errorf("unexpected groups %v", groups)
+	}
+	if groups := GroupByAncestor(Query(doc, MustParse("#a")), MustParse("li"), MustParse("ul")); len(groups) != 3 || len(groups[1].Items) != 1 {
+		t.Errorf("unexpected groups %v", groups)
+	}
+}
@@ -0,0 +1,185 @@
+package cascadia
+
+import (
+	"errors"
+	"sort"
+
+	"golang.org/x/net/html"
+)
+
+// maxInduceDepth is the number of ancestors considered by Induce
+const maxInduceDepth = 3
+
+// Induce synthesizes a selector matching all the examples, which must
+// be elements of the same document, and as few other elements as possible.
+// The selector never matches the counterExamples, if possible.
+//
+// The candidate components are the tags, ids, classes, attributes and
+// positions shared by all the examples, or by their ancestors (up to
+// a small depth, linked by child combinators). They are greedily added,
+// starting with the tag, until the selector is precise enough.
+func Induce(examples, counterExamples []*html.Node) (Sel, error) {
+	if len(examples) == 0 {
+		return nil, errors.New("at least one example is required")
+	}
+	root := documentRoot(examples[0])
+	for _, n := range examples {
+		if n.Type != html.ElementNode {
+			return nil, errors.New("examples must be elements")
+		}
+		if documentRoot(n) != root {
+			return nil, errors.New("examples must belong to the same document")
+		}
+	}
+
+	candidates := commonFeatures(examples)
+	isExample := make(map[*html.Node]bool, len(examples))
+	for _, n := range examples {
+		isExample[n] = true
+	}
+	// cost returns the number of matched counter-examples
+	// (weighted, so that they are avoided first), and unwanted matches
+	cost := func(sel Sel) int {
+		c := 0
+		for _, n := range counterExamples {
+			if sel.Match(n) {
+				c += 1 << 20
+			}
+		}
+		for _, n := range QueryAll(root, sel) {
+			if !isExample[n] {
+				c++
+			}
+		}
+		return c
+	}
+
+	var chosen []inducedFeature
+	for i, f := range candidates {
+		if f.depth == 0 {
+			if _, isTag := f.sel.(TagSelector); isTag {
+				chosen = append(chosen, f)
+				candidates = append(candidates[:i], candidates[i+1:]...)
+				break
+			}
+		}
+	}
+	best := buildInduced(chosen)
+	bestCost := cost(best)
+	for bestCost != 0 {
+		index := -1
+		for i, f := range candidates {
+			sel := buildInduced(append(chosen[:len(chosen):len(chosen)], f))
+			if c := cost(sel); c < bestCost {
+				best, bestCost, index = sel, c, i
+			}
+		}
+		if index == -1 { // no improvement
+			break
+		}
+		chosen = append(chosen, candidates[index])
+		candidates = append(candidates[:index], candidates[index+1:]...)
+	}
+	return best, nil
+}
+
+// inducedFeature is a simple selector to be matched
+// by the ancestor at the given depth (0 for the element itself)
+type inducedFeature struct {
+	sel   Sel
+	depth int
+}
+
+func documentRoot(n *html.Node) *html.Node {
+	for n.Parent != nil {
+		n = n.Parent
+	}
+	return n
+}
+
+// commonFeatures returns the simple selectors matched by all the nodes,
+// and by their ancestors
+func commonFeatures(nodes []*html.Node) []inducedFeature {
+	var out []inducedFeature
+	level := append([]*html.Node(nil), nodes...)
+	for depth := 0; depth <= maxInduceDepth; depth++ {
+		for _, sel := range nodeFeatures(level[0]) {
+			common := true
+			for _, n := range level[1:] {
+				if !sel.Match(n) {
+					common = false
+					break
+				}
+			}
+			if common {
+				out = append(out, inducedFeature{sel: sel, depth: depth})
+			}
+		}
+		for i, n := range level {
+			if n.Parent == nil || n.Parent.Type != html.ElementNode {
+				return out
+			}
+			level[i] = n.Parent
+		}
+	}
+	return out
+}
+
+// nodeFeatures returns the simple selectors matching n,
+// in order of preference
+func nodeFeatures(n *html.Node) []Sel {
+	out := []Sel{newTagSelector(n.Data)}
+	var attrs []Sel
+	for _, a := range n.Attr {
+		switch a.Key {
+		case "id":
+			out = append(out, IDSelector{ID: a.Val})
+		case "class":
+			for _, class := range SplitTokens(a.Val) {
+				attrs = append(attrs, ClassSelector{Class: class})
+			}
+		default:
+			attrs = append(attrs, AttrSelector{Key: a.Key}, AttrSelector{Key: a.Key, Operation: "=", Val: a.Val})
+		}
+	}
+	sort.SliceStable(attrs, func(i, j int) bool { return kindRank(attrs[i]) < kindRank(attrs[j]) })
+	out = append(out, attrs...)
+	index := 0
+	for c := n; c != nil; c = c.PrevSibling {
+		if c.Type == html.ElementNode {
+			index++
+		}
+	}
+	return append(out, NthPseudoClassSelector{B: index})
+}
+
+// buildInduced returns the selector made of features,
+// using child combinators between levels
+func buildInduced(features []inducedFeature) Sel {
+	maxDepth := 0
+	for _, f := range features {
+		if f.depth > maxDepth {
+			maxDepth = f.depth
+		}
+	}
+	var out Sel
+	for depth := maxDepth; depth >= 0; depth-- {
+		var compound CompoundSelector
+		for _, f := range features {
+			if f.depth == depth {
+				compound.Selectors = append(compound.Selectors, f.sel)
+			}
+		}
+		compound.Selectors = sortSelectors(compound.Selectors, true)
+		var sel Sel = compound
+		if len(compound.Selectors) == 1 {
+			sel = compound.Selectors[0]
+		}
+		if out == nil {
+			out = sel
+		} else {
+			out = CombinedSelector{First: out, Combinator: '>', Second: sel}
+		}
+	}
+	return out
+}
@@ -0,0 +1,68 @@
+package cascadia
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestInduce(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(`<html><body>
+		<ul id="menu"><li class="item a">1</li><li class="item b">2</li><li class="other">3</li></ul>
+		<ul id="list"><li class="item">4</li><li>5</li></ul>
+		<p class="item">6</p>
+	</body></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	all := QueryAll(doc, MustCompile("*"))
+	find := func(text string) *html.Node {
+		for _, n := range all {
+			if n.FirstChild != nil && n.FirstChild.Data == text {
+				return n
+			}
+		}
+		t.Fatalf("%s not found", text)
+		return nil
+	}
+
+	for _, test := range []struct {
+		examples, counters []string
+		exp                string
+	}{
+		{[]string{"1", "2"}, nil, "#menu > li.item"},
+		{[]string{"1", "2", "3"}, nil, "#menu > li"},
+		{[]string{"1", "2", "4"}, nil, "li.item"},
+		{[]string{"1", "4"}, []string{"2"}, "li:first-child"},
+		{[]string{"6"}, nil, "p"},
+	} {
+		var examples, counters []*html.Node
+		for _, s := range test.examples {
+			examples = append(examples, find(s))
+		}
+		for _, s := range test.counters {
+			counters = append(counters, find(s))
+		}
+		sel, err := Induce(examples, counters)
+		if err != nil {
+			t.Fatal(err)
+		}
+		exp, err := Parse(test.exp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sel.String() != exp.String() {
+			t.Errorf("%v: expected %s, got %s", test.examples, exp, sel)
+		}
+		for _, n := range examples {
+			if !sel.Match(n) {
+				t.Errorf("%s should match all the examples", sel)
+			}
+		}
+	}
+
+	if _, err := Induce(nil, nil); err == nil {
+		t.Error("expected error without examples")
+	}
+}
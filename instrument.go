@@ -0,0 +1,182 @@
+package cascadia
+
+import (
+	"encoding/json"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// This file implements optional instrumentation of selectors,
+// used to find expensive selectors in production.
+
+// ComponentStats stores the counters of one component
+// (simple selector, compound, combined selector...) of an instrumented selector.
+type ComponentStats struct {
+	Calls int64 // number of nodes tested by the component
+	Hits  int64 // number of nodes matched by the component
+
+	// Selector is the CSS serialization of the component
+	Selector string
+}
+
+// HitRate returns the ratio Hits / Calls, or 0 if the component
+// has never been called.
+func (c *ComponentStats) HitRate() float64 {
+	calls := atomic.LoadInt64(&c.Calls)
+	if calls == 0 {
+		return 0
+	}
+	return float64(atomic.LoadInt64(&c.Hits)) / float64(calls)
+}
+
+// MatchStats stores the counters collected by an `InstrumentedSel`.
+// The counters are updated atomically, so that they may be read
+// while matching is in progress.
+//
+// MatchStats implements `expvar.Var`, so it may be published
+// directly with `expvar.Publish`.
+type MatchStats struct {
+	Calls       int64 // number of calls to Match on the root selector
+	Matches     int64 // number of successful calls to Match on the root selector
+	MatchCalls  int64 // total number of calls to Match on all the components (the sum of their Calls)
+	Nanoseconds int64 // total time spent in Match, only updated if timing is enabled
+
+	// Components is the list of all the components of the selector,
+	// in depth-first order; the first one is the root selector.
+	Components []*ComponentStats
+}
+
+// Reset sets all the counters to zero.
+func (s *MatchStats) Reset() {
+	atomic.StoreInt64(&s.Calls, 0)
+	atomic.StoreInt64(&s.Matches, 0)
+	atomic.StoreInt64(&s.MatchCalls, 0)
+	atomic.StoreInt64(&s.Nanoseconds, 0)
+	for _, c := range s.Components {
+		atomic.StoreInt64(&c.Calls, 0)
+		atomic.StoreInt64(&c.Hits, 0)
+	}
+}
+
+// Snapshot returns a copy of the counters, safe to inspect while
+// matching continues. Each counter is read atomically, but the counters
+// are not read together: while matching is in progress, they may be
+// slightly inconsistent with each other.
+func (s *MatchStats) Snapshot() MatchStats {
+	out := MatchStats{
+		Calls:       atomic.LoadInt64(&s.Calls),
+		Matches:     atomic.LoadInt64(&s.Matches),
+		MatchCalls:  atomic.LoadInt64(&s.MatchCalls),
+		Nanoseconds: atomic.LoadInt64(&s.Nanoseconds),
+		Components:  make([]*ComponentStats, len(s.Components)),
+	}
+	for i, c := range s.Components {
+		out.Components[i] = &ComponentStats{
+			Calls:    atomic.LoadInt64(&c.Calls),
+			Hits:     atomic.LoadInt64(&c.Hits),
+			Selector: c.Selector,
+		}
+	}
+	return out
+}
+
+// String returns a JSON representation of the counters,
+// as required by `expvar.Var`.
+func (s *MatchStats) String() string {
+	snap := s.Snapshot()
+	b, _ := json.Marshal(snap)
+	return string(b)
+}
+
+// InstrumentedSel wraps a selector and records
+// statistics each time it (or one of its components) is matched.
+type InstrumentedSel struct {
+	Sel // the wrapped selector, with instrumented components
+
+	Stats *MatchStats
+
+	// If Timing is true, the time spent in Match is accumulated
+	// in Stats.Nanoseconds. It is disabled by default, since
+	// timing each call has a significant cost.
+	Timing bool
+}
+
+// Instrument returns an instrumented version of sel.
+// The returned selector behaves exactly like sel, and also
+// records statistics for all its components.
+func Instrument(sel Sel) *InstrumentedSel {
+	stats := new(MatchStats)
+	return &InstrumentedSel{Sel: instrument(sel, stats), Stats: stats}
+}
+
+// Match implements Matcher, updating the root counters.
+func (s *InstrumentedSel) Match(n *html.Node) bool {
+	var start time.Time
+	if s.Timing {
+		start = time.Now()
+	}
+	ok := s.Sel.Match(n)
+	atomic.AddInt64(&s.Stats.Calls, 1)
+	if ok {
+		atomic.AddInt64(&s.Stats.Matches, 1)
+	}
+	if s.Timing {
+		atomic.AddInt64(&s.Stats.Nanoseconds, int64(time.Since(start)))
+	}
+	return ok
+}
+
+// countingSel is the wrapper used on every component
+type countingSel struct {
+	Sel
+	stats      *ComponentStats
+	matchCalls *int64 // see MatchStats.MatchCalls
+}
+
+func (c countingSel) Match(n *html.Node) bool {
+	atomic.AddInt64(&c.stats.Calls, 1)
+	atomic.AddInt64(c.matchCalls, 1)
+	ok := c.Sel.Match(n)
+	if ok {
+		atomic.AddInt64(&c.stats.Hits, 1)
+	}
+	return ok
+}
+
+// instrument recursively wraps sel and its components,
+// registering them in stats.
+func instrument(sel Sel, stats *MatchStats) Sel {
+	component := &ComponentStats{Selector: sel.String()}
+	stats.Components = append(stats.Components, component)
+
+	switch s := sel.(type) {
+	case CompoundSelector:
+		inner := make([]Sel, len(s.Selectors))
+		for i, c := range s.Selectors {
+			inner[i] = instrument(c, stats)
+		}
+		s.Selectors = inner
+		sel = s
+	case CombinedSelector:
+		s.First = instrument(s.First, stats)
+		if s.Second != nil {
+			s.Second = instrument(s.Second, stats)
+		}
+		sel = s
+	case RelativePseudoClassSelector:
+		s.Args = instrumentGroup(s.Args, stats)
+		sel = s
+	}
+
+	return countingSel{Sel: sel, stats: component, matchCalls: &stats.MatchCalls}
+}
+
+func instrumentGroup(group SelectorGroup, stats *MatchStats) SelectorGroup {
+	out := make(SelectorGroup, len(group))
+	for i, s := range group {
+		out[i] = instrument(s, stats)
+	}
+	return out
+}
@@ -0,0 +1,55 @@
+package cascadia
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestInstrument(t *testing.T) {
+	sel, err := Parse("div.matched > div")
+	if err != nil {
+		t.Fatal(err)
+	}
+	inst := Instrument(sel)
+	inst.Timing = true
+
+	got, want := len(QueryAll(dom, inst)), len(QueryAll(dom, sel))
+	if got != want {
+		t.Fatalf("instrumented selector: expected %d matches, got %d", want, got)
+	}
+	if inst.String() != sel.String() {
+		t.Fatalf("instrumented selector: expected %s, got %s", sel, inst)
+	}
+
+	stats := inst.Stats.Snapshot()
+	if stats.Matches != int64(want) {
+		t.Errorf("expected %d matches, got %d", want, stats.Matches)
+	}
+	if stats.Calls == 0 || stats.Calls != stats.Components[0].Calls {
+		t.Errorf("inconsistent root calls: %d and %d", stats.Calls, stats.Components[0].Calls)
+	}
+	// combined, compound, div, .matched, div
+	if len(stats.Components) != 5 {
+		t.Fatalf("expected 5 components, got %d", len(stats.Components))
+	}
+	var total int64
+	for _, c := range stats.Components {
+		total += c.Calls
+		if c.Hits > c.Calls {
+			t.Errorf("invalid counters for %s: %d hits for %d calls", c.Selector, c.Hits, c.Calls)
+		}
+	}
+	if total != stats.MatchCalls {
+		t.Errorf("expected %d match calls, got %d", total, stats.MatchCalls)
+	}
+
+	var decoded MatchStats
+	if err := json.Unmarshal([]byte(inst.Stats.String()), &decoded); err != nil {
+		t.Fatalf("invalid expvar output: %s", err)
+	}
+
+	inst.Stats.Reset()
+	if s := inst.Stats.Snapshot(); s.Calls != 0 || s.Components[1].HitRate() != 0 {
+		t.Errorf("Reset didn't clear the counters")
+	}
+}
@@ -0,0 +1,76 @@
+package cascadia
+
+// Disjoint returns true if a and b are proven to never match
+// the same element, such as div.a and span.a.
+// It returns false if they may match the same element, or if the
+// analysis can't decide (see CheckIntersection).
+func Disjoint(a, b Sel) bool { return CheckIntersection(a, b) == No }
+
+// CheckIntersection decides whether some element may be matched
+// by both a and b. It returns No if the selectors are disjoint, Yes if
+// a common element is proven to exist, and Unknown otherwise.
+func CheckIntersection(a, b Sel) Answer {
+	chainA, chainB := flattenChain(a), flattenChain(b)
+	if chainA == nil || chainB == nil {
+		return Unknown
+	}
+	keyA, keyB := chainA[len(chainA)-1].compound, chainB[len(chainB)-1].compound
+	if keyA.Pseudo != keyB.Pseudo {
+		return No
+	}
+	key := append(append([]Sel(nil), keyA.Selectors...), keyB.Selectors...)
+	if unsatisfiable(key) {
+		return No
+	}
+
+	// a common element exists if one of the selectors only constrains
+	// the element itself, and the other one is satisfiable
+	if len(chainA) > 1 && len(chainB) > 1 {
+		return Unknown
+	}
+	context := chainA
+	if len(chainA) == 1 {
+		context = chainB
+	}
+	if !witnessExists(key) {
+		return Unknown
+	}
+	for _, link := range context[:len(context)-1] {
+		if unsatisfiable(link.compound.Selectors) {
+			return No
+		}
+		if !witnessExists(link.compound.Selectors) {
+			return Unknown
+		}
+	}
+	return Yes
+}
+
+// witnessExists returns true if an element matched by all the selectors
+// in list is proven to exist. It only handles type, id and class
+// selectors, and attribute presence, equality and inequality, for which
+// a non contradictory list is satisfiable.
+func witnessExists(list []Sel) bool {
+	for _, s := range list {
+		switch s := s.(type) {
+		case TagSelector, IDSelector:
+		case ClassSelector:
+			if s.Class == "" || containsWhitespace(s.Class) {
+				return false
+			}
+		case AttrSelector:
+			switch s.Operation {
+			case "", "=", "!=":
+			default:
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return !unsatisfiable(list)
+}
+
+func containsWhitespace(s string) bool {
+	return spaceAsciiSet.index(s) != -1
+}
@@ -0,0 +1,47 @@
+package cascadia
+
+import "testing"
+
+func TestIntersection(t *testing.T) {
+	for _, test := range []struct {
+		a, b string
+		want Answer
+	}{
+		{"div.a", "span.a", No},
+		{"div.a", "div.b", Yes},
+		{"#x", "#y", No},
+		{"#x", `[id="x"]`, Yes},
+		{"[lang=fr]", "[lang|=en]", No},
+		{"[lang=en-US]", "[lang|=en]", Unknown},
+		{"[href^=http]", "[href^=ftp]", No},
+		{"[href^=http]", "[href^=https]", Unknown},
+		{"[a$=x]", "[a$=y]", No},
+		{"[a=x]", "[a!=x]", No},
+		{"a:not(.b)", "a.b", No},
+		{"a:not(.b)", "a.c", Unknown},
+		{"p", "div > p", Yes},
+		{"div > p", "section p", Unknown},
+		{"div > p", "section span", No},
+		{"p", "#x#y > p", No},
+		{"a::before", "a", No},
+		{"a:hover", "a", No},
+	} {
+		a, err := ParseWithPseudoElement(test.a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ParseWithPseudoElement(test.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := CheckIntersection(a, b); got != test.want {
+			t.Errorf("%s and %s: expected %s, got %s", test.a, test.b, test.want, got)
+		}
+		if got := CheckIntersection(b, a); got != test.want {
+			t.Errorf("%s and %s: expected %s, got %s", test.b, test.a, test.want, got)
+		}
+		if Disjoint(a, b) != (test.want == No) {
+			t.Errorf("%s and %s: inconsistent Disjoint", test.a, test.b)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package cascadia
+
+import (
+	"sort"
+	"strings"
+)
+
+// HasCombinators returns true if sel (or one of the arguments of its
+// pseudo-classes) contains a combinator.
+func HasCombinators(sel Sel) bool {
+	found := false
+	Walk(sel, func(s Sel) bool {
+		if c, ok := s.(CombinedSelector); ok && c.Second != nil {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// HasPseudoElement returns true if sel targets a pseudo-element, like ::before.
+func HasPseudoElement(sel Sel) bool { return sel.PseudoElement() != "" }
+
+// dynamicPseudoClasses depend on the user interaction, and never
+// match in a static document
+var dynamicPseudoClasses = map[string]bool{
+	"visited": true, "hover": true, "active": true, "focus": true, "target": true,
+}
+
+// HasDynamicPseudoClasses returns true if sel uses pseudo-classes depending
+// on the user interaction (like :hover or :focus), which never match
+// in a static document.
+func HasDynamicPseudoClasses(sel Sel) bool {
+	found := false
+	Walk(sel, func(s Sel) bool {
+		if n, ok := s.(NeverMatchSelector); ok && dynamicPseudoClasses[strings.TrimPrefix(n.Value, ":")] {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// References lists the names referenced by a selector,
+// each sorted and without duplicates.
+type References struct {
+	Tags       []string // lower-cased type selectors
+	IDs        []string
+	Classes    []string
+	Attributes []string // lower-cased keys of attribute selectors
+}
+
+// CollectReferences returns the tags, ids, classes and attribute keys
+// used in sel, including the arguments of its pseudo-classes.
+func CollectReferences(sel Sel) References {
+	return CollectGroupReferences(SelectorGroup{sel})
+}
+
+// CollectGroupReferences is like CollectReferences, for all the selectors of group.
+func CollectGroupReferences(group SelectorGroup) References {
+	var tags, ids, classes, attrs []string
+	WalkGroup(group, func(s Sel) bool {
+		switch s := s.(type) {
+		case TagSelector:
+			tags = append(tags, s.Tag)
+		case IDSelector:
+			ids = append(ids, s.ID)
+		case ClassSelector:
+			classes = append(classes, s.Class)
+		case AttrSelector:
+			attrs = append(attrs, s.Key)
+		}
+		return true
+	})
+	return References{
+		Tags:       sortUnique(tags),
+		IDs:        sortUnique(ids),
+		Classes:    sortUnique(classes),
+		Attributes: sortUnique(attrs),
+	}
+}
+
+func sortUnique(list []string) []string {
+	if len(list) == 0 {
+		return nil
+	}
+	sort.Strings(list)
+	out := list[:1]
+	for _, s := range list[1:] {
+		if s != out[len(out)-1] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
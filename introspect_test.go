@@ -0,0 +1,53 @@
+package cascadia
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIntrospection(t *testing.T) {
+	for _, test := range []struct {
+		sel                          string
+		combinators, pseudo, dynamic bool
+	}{
+		{"div.a", false, false, false},
+		{"div > p", true, false, false},
+		{"p:not(div a)", true, false, false},
+		{"p::before", false, true, false},
+		{"a:hover", false, false, true},
+		{"ul :not(a:focus)", true, false, true},
+	} {
+		sel, err := ParseWithPseudoElement(test.sel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := HasCombinators(sel); got != test.combinators {
+			t.Errorf("%s: HasCombinators: expected %v", test.sel, test.combinators)
+		}
+		if got := HasPseudoElement(sel); got != test.pseudo {
+			t.Errorf("%s: HasPseudoElement: expected %v", test.sel, test.pseudo)
+		}
+		if got := HasDynamicPseudoClasses(sel); got != test.dynamic {
+			t.Errorf("%s: HasDynamicPseudoClasses: expected %v", test.sel, test.dynamic)
+		}
+	}
+}
+
+func TestCollectReferences(t *testing.T) {
+	group, err := ParseGroup(`DIV#main > p.a.b, p.a[HREF^=x]:not(#other, span[title])`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := References{
+		Tags:       []string{"div", "p", "span"},
+		IDs:        []string{"main", "other"},
+		Classes:    []string{"a", "b"},
+		Attributes: []string{"href", "title"},
+	}
+	if got := CollectGroupReferences(group); !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected %+v, got %+v", exp, got)
+	}
+	if got := CollectReferences(group[0]); !reflect.DeepEqual(got.IDs, []string{"main"}) || got.Attributes != nil {
+		t.Errorf("unexpected %+v", got)
+	}
+}
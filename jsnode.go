@@ -0,0 +1,168 @@
+//go:build js && wasm
+
+package cascadia
+
+import (
+	"syscall/js"
+
+	"golang.org/x/net/html"
+)
+
+// JSTree provides the Node adapters for the nodes of a browser DOM,
+// accessed with syscall/js, so that the selectors (including the extensions
+// browsers lack, like :contains()) may be evaluated against a live document.
+//
+// Since js.Value is not comparable, each DOM node is wrapped by a unique
+// *JSNode, recorded by the tree. The adapters cache the attributes,
+// so that a tree should be discarded once the matching is done, and not
+// be used across mutations of the DOM.
+type JSTree struct {
+	ids   js.Value // WeakMap from the DOM nodes to their index in nodes
+	nodes []*JSNode
+}
+
+// NewJSTree returns an empty tree.
+func NewJSTree() *JSTree {
+	return &JSTree{ids: js.Global().Get("WeakMap").New()}
+}
+
+// JSNode is the Node adapter for a DOM node, returned by JSTree.Node.
+//
+// Like the parser of the x/net/html package, it lower-cases the tag and
+// attribute names, and uses the short names "svg" and "math" for the
+// SVG and MathML namespaces.
+type JSNode struct {
+	tree  *JSTree
+	value js.Value
+
+	attrs       []html.Attribute
+	attrsLoaded bool
+}
+
+// Node returns the adapter for the DOM node v,
+// or nil if v is null or undefined.
+func (t *JSTree) Node(v js.Value) Node {
+	if v.IsNull() || v.IsUndefined() {
+		return nil
+	}
+	if id := t.ids.Call("get", v); !id.IsUndefined() {
+		return t.nodes[id.Int()]
+	}
+	n := &JSNode{tree: t, value: v}
+	t.ids.Call("set", v, len(t.nodes))
+	t.nodes = append(t.nodes, n)
+	return n
+}
+
+// QueryAll returns the DOM elements matching m, from the descendants of root.
+func (t *JSTree) QueryAll(root js.Value, m NodeMatcher) []js.Value {
+	n := t.Node(root)
+	if n == nil {
+		return nil
+	}
+	var out []js.Value
+	for _, c := range QueryAllNodes(n, m) {
+		out = append(out, c.(*JSNode).value)
+	}
+	return out
+}
+
+// Query returns the first DOM element matching m, from the descendants of root,
+// or null.
+func (t *JSTree) Query(root js.Value, m NodeMatcher) js.Value {
+	if n := t.Node(root); n != nil {
+		if c := QueryNode(n, m); c != nil {
+			return c.(*JSNode).value
+		}
+	}
+	return js.Null()
+}
+
+// Value returns the wrapped DOM node.
+func (n *JSNode) Value() js.Value { return n.value }
+
+func (n *JSNode) Type() html.NodeType {
+	switch n.value.Get("nodeType").Int() {
+	case 1: // ELEMENT_NODE
+		return html.ElementNode
+	case 3, 4: // TEXT_NODE, CDATA_SECTION_NODE
+		return html.TextNode
+	case 9, 11: // DOCUMENT_NODE, DOCUMENT_FRAGMENT_NODE
+		return html.DocumentNode
+	case 10: // DOCUMENT_TYPE_NODE
+		return html.DoctypeNode
+	default: // comments and processing instructions, as for x/net/html
+		return html.CommentNode
+	}
+}
+
+func (n *JSNode) Data() string {
+	if n.Type() == html.ElementNode {
+		return toLowerASCII(n.value.Get("localName").String())
+	}
+	if v := n.value.Get("nodeValue"); !v.IsNull() {
+		return v.String()
+	}
+	return ""
+}
+
+func (n *JSNode) Namespace() string {
+	if n.Type() != html.ElementNode {
+		return ""
+	}
+	return jsNamespace(n.value.Get("namespaceURI"))
+}
+
+func (n *JSNode) Attributes() []html.Attribute {
+	if n.attrsLoaded {
+		return n.attrs
+	}
+	n.attrsLoaded = true
+	if n.Type() != html.ElementNode {
+		return nil
+	}
+	// as x/net/html, the attributes of the foreign elements keep
+	// their case, like viewBox
+	foreign := n.Namespace() != ""
+	list := n.value.Get("attributes")
+	n.attrs = make([]html.Attribute, list.Length())
+	for i := range n.attrs {
+		a := list.Index(i)
+		key := a.Get("localName").String()
+		if !foreign {
+			key = toLowerASCII(key)
+		}
+		n.attrs[i] = html.Attribute{
+			Namespace: jsNamespace(a.Get("namespaceURI")),
+			Key:       key,
+			Val:       a.Get("value").String(),
+		}
+	}
+	return n.attrs
+}
+
+func (n *JSNode) Parent() Node      { return n.tree.Node(n.value.Get("parentNode")) }
+func (n *JSNode) FirstChild() Node  { return n.tree.Node(n.value.Get("firstChild")) }
+func (n *JSNode) LastChild() Node   { return n.tree.Node(n.value.Get("lastChild")) }
+func (n *JSNode) PrevSibling() Node { return n.tree.Node(n.value.Get("previousSibling")) }
+func (n *JSNode) NextSibling() Node { return n.tree.Node(n.value.Get("nextSibling")) }
+
+// jsNamespaces maps the namespace URIs to the names used by x/net/html
+var jsNamespaces = map[string]string{
+	"http://www.w3.org/1999/xhtml":         "",
+	"http://www.w3.org/2000/svg":           "svg",
+	"http://www.w3.org/1998/Math/MathML":   "math",
+	"http://www.w3.org/1999/xlink":         "xlink",
+	"http://www.w3.org/XML/1998/namespace": "xml",
+	"http://www.w3.org/2000/xmlns/":        "xmlns",
+}
+
+func jsNamespace(uri js.Value) string {
+	if uri.IsNull() || uri.IsUndefined() {
+		return ""
+	}
+	if ns, ok := jsNamespaces[uri.String()]; ok {
+		return ns
+	}
+	return uri.String()
+}
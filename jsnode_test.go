@@ -0,0 +1,134 @@
+//go:build js && wasm
+
+package cascadia
+
+import (
+	"strings"
+	"syscall/js"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+// toJSDOM builds JS objects with the properties of the DOM nodes
+// used by JSNode, mirroring n. It returns the object of n and
+// records the source node of each object in sources.
+func toJSDOM(n *html.Node, sources map[*html.Node]js.Value) js.Value {
+	object := js.Global().Get("Object")
+	v := object.New()
+	switch n.Type {
+	case html.ElementNode:
+		v.Set("nodeType", 1)
+		v.Set("localName", n.Data)
+		uri := "http://www.w3.org/1999/xhtml"
+		for u, ns := range jsNamespaces {
+			if ns == n.Namespace && ns != "" {
+				uri = u
+			}
+		}
+		v.Set("namespaceURI", uri)
+		v.Set("nodeValue", js.Null())
+	case html.TextNode:
+		v.Set("nodeType", 3)
+		v.Set("nodeValue", n.Data)
+	case html.CommentNode:
+		v.Set("nodeType", 8)
+		v.Set("nodeValue", n.Data)
+	case html.DoctypeNode:
+		v.Set("nodeType", 10)
+		v.Set("nodeValue", js.Null())
+	default:
+		v.Set("nodeType", 9)
+		v.Set("nodeValue", js.Null())
+	}
+	attrs := js.Global().Get("Array").New()
+	for _, a := range n.Attr {
+		attr := object.New()
+		attr.Set("localName", a.Key)
+		attr.Set("value", a.Val)
+		attr.Set("namespaceURI", js.Null())
+		attrs.Call("push", attr)
+	}
+	v.Set("attributes", attrs)
+	v.Set("parentNode", js.Null())
+	v.Set("previousSibling", js.Null())
+	v.Set("nextSibling", js.Null())
+	v.Set("firstChild", js.Null())
+	v.Set("lastChild", js.Null())
+
+	var prev js.Value
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		child := toJSDOM(c, sources)
+		child.Set("parentNode", v)
+		if c.PrevSibling == nil {
+			v.Set("firstChild", child)
+		} else {
+			prev.Set("nextSibling", child)
+			child.Set("previousSibling", prev)
+		}
+		v.Set("lastChild", child)
+		prev = child
+	}
+	sources[n] = v
+	return v
+}
+
+func TestJSTree(t *testing.T) {
+	for _, test := range selectorTests {
+		doc, err := html.Parse(strings.NewReader(test.HTML))
+		if err != nil {
+			t.Fatal(err)
+		}
+		group, err := ParseGroup(test.selector)
+		if err != nil {
+			t.Fatal(err)
+		}
+		exp := QueryAll(doc, group)
+
+		sources := map[*html.Node]js.Value{}
+		root := toJSDOM(doc, sources)
+		tree := NewJSTree()
+		got := tree.QueryAll(root, group)
+		if len(got) != len(exp) {
+			t.Errorf("%s: expected %d matches, got %d", test.selector, len(exp), len(got))
+			continue
+		}
+		for i, v := range got {
+			if !v.Equal(sources[exp[i]]) {
+				t.Errorf("%s: unexpected match %d", test.selector, i)
+			}
+		}
+		if first := tree.Query(root, group); len(exp) != 0 && !first.Equal(sources[exp[0]]) {
+			t.Errorf("%s: unexpected first match", test.selector)
+		}
+	}
+}
+
+func TestJSTreeNil(t *testing.T) {
+	tree := NewJSTree()
+	if tree.Node(js.Null()) != nil || tree.Node(js.Undefined()) != nil {
+		t.Error("expected nil nodes")
+	}
+	v := js.Global().Get("Object").New()
+	if tree.Node(v) != tree.Node(v) {
+		t.Error("expected a unique adapter")
+	}
+}
+
+func TestJSTreeForeignAttributes(t *testing.T) {
+	doc := MustParseHTML(`<div DATA-X="1"></div><svg viewBox="0 0 1 1"></svg>`)
+	root := toJSDOM(doc, map[*html.Node]js.Value{})
+	tree := NewJSTree()
+	for _, test := range []struct {
+		sel string
+		exp int
+	}{
+		{"[data-x]", 1},
+		{"svg[viewBox]", 1},
+		{"svg[viewbox]", 0},
+	} {
+		if got := tree.QueryAll(root, MustParseGroup(test.sel)); len(got) != test.exp {
+			t.Errorf("%s: expected %d matches, got %d", test.sel, test.exp, len(got))
+		}
+	}
+}
@@ -0,0 +1,327 @@
+package cascadia
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+// This file implements a JSON representation of the selectors AST,
+// which is intended to be stable and usable by non Go tools.
+//
+// Each component is an object with a "kind" field, which is one of
+//	- "tag" : {"name": "div"}
+//	- "class" : {"name": "item"}
+//	- "id" : {"name": "main"}
+//	- "attr" : {"name": "href", "op": "^=", "value": "http"} ("op" is empty for [href],
+//	  and "value" is the regular expression for the "#=" operator)
+//	- "pseudo-class" : {"name": "nth-child", "a": 2, "b": 1}, {"name": "not", "args": [...]},
+//	  {"name": "contains", "value": "text"}, {"name": "lang", "value": "en"}, {"name": "hover"},
+//	  {"name": "state", "value": "checked"}
+//	- "compound" : {"selectors": [...], "pseudoElement": "before"}
+//	- "combined" : {"first": {...}, "combinator": ">", "second": {...}}
+//	- "nesting" : {} (the nesting selector &)
+// A group is an array of components.
+
+type jsonSel struct {
+	Kind string `json:"kind"`
+
+	Name  string `json:"name,omitempty"`
+	Op    string `json:"op,omitempty"`
+	Value string `json:"value,omitempty"`
+
+	A *int `json:"a,omitempty"`
+	B *int `json:"b,omitempty"`
+
+	Args          []jsonSel `json:"args,omitempty"`
+	Selectors     []jsonSel `json:"selectors,omitempty"`
+	PseudoElement string    `json:"pseudoElement,omitempty"`
+
+	First      *jsonSel `json:"first,omitempty"`
+	Combinator string   `json:"combinator,omitempty"`
+	Second     *jsonSel `json:"second,omitempty"`
+}
+
+func pseudoClass(name string) jsonSel { return jsonSel{Kind: "pseudo-class", Name: name} }
+
+func toJSONSel(sel Sel) (jsonSel, error) {
+	switch s := sel.(type) {
+	case TagSelector:
+		return jsonSel{Kind: "tag", Name: s.Tag}, nil
+	case ClassSelector:
+		return jsonSel{Kind: "class", Name: s.Class}, nil
+	case IDSelector:
+		return jsonSel{Kind: "id", Name: s.ID}, nil
+	case AttrSelector:
+		out := jsonSel{Kind: "attr", Name: s.writtenKey(), Op: s.Operation, Value: s.Val}
+		if s.Operation == "#=" && s.Regexp != nil {
+			out.Value = s.Regexp.String()
+		}
+		return out, nil
+	case RelativePseudoClassSelector:
+		args, err := toJSONGroup(s.Args)
+		out := pseudoClass(s.Name)
+		out.Args = args
+		return out, err
+	case ContainsPseudoClassSelector:
+		out := pseudoClass("contains")
+		if s.Own {
+			out.Name = "containsown"
+		}
+		out.Value = s.Value
+		return out, nil
+	case RegexpPseudoClassSelector:
+		out := pseudoClass("matches")
+		if s.Own {
+			out.Name = "matchesown"
+		}
+		if s.Regexp != nil {
+			out.Value = s.Regexp.String()
+		}
+		return out, nil
+	case NthPseudoClassSelector:
+		name := "nth-child"
+		switch {
+		case s.Last && s.OfType:
+			name = "nth-last-of-type"
+		case s.Last:
+			name = "nth-last-child"
+		case s.OfType:
+			name = "nth-of-type"
+		}
+		a, b := s.A, s.B
+		out := pseudoClass(name)
+		out.A, out.B = &a, &b
+		return out, nil
+	case OnlyChildPseudoClassSelector:
+		if s.OfType {
+			return pseudoClass("only-of-type"), nil
+		}
+		return pseudoClass("only-child"), nil
+	case LangPseudoClassSelector:
+		out := pseudoClass("lang")
+		out.Value = s.Lang
+		return out, nil
+	case NestingSelector:
+		return jsonSel{Kind: "nesting"}, nil
+	case CustomStatePseudoClassSelector:
+		out := pseudoClass("state")
+		out.Value = s.State
+		return out, nil
+	case TimePseudoClassSelector:
+		args, err := toJSONGroup(s.Args)
+		out := pseudoClass(s.Name)
+		out.Args = args
+		return out, err
+	case NeverMatchSelector:
+		// Value is the CSS input, like ":hover"
+		if len(s.Value) > 0 && s.Value[0] == ':' {
+			return pseudoClass(s.Value[1:]), nil
+		}
+		return pseudoClass(s.Value), nil
+	case CompoundSelector:
+		inner, err := toJSONGroup(s.Selectors)
+		return jsonSel{Kind: "compound", Selectors: inner, PseudoElement: s.Pseudo}, err
+	case CombinedSelector:
+		first, err := toJSONSel(s.First)
+		if err != nil {
+			return jsonSel{}, err
+		}
+		out := jsonSel{Kind: "combined", First: &first}
+		if s.Second != nil {
+			second, err := toJSONSel(s.Second)
+			if err != nil {
+				return jsonSel{}, err
+			}
+			out.Combinator = string(s.Combinator)
+			out.Second = &second
+		}
+		return out, nil
+	}
+	// simple pseudo-classes without arguments
+	switch sel.(type) {
+	case InputPseudoClassSelector, EmptyElementPseudoClassSelector, RootPseudoClassSelector, ScopePseudoClassSelector,
+		LinkPseudoClassSelector, EnabledPseudoClassSelector, DisabledPseudoClassSelector, CheckedPseudoClassSelector:
+		return pseudoClass(sel.String()[1:]), nil
+	}
+	return jsonSel{}, fmt.Errorf("unsupported selector type %T", sel)
+}
+
+func toJSONGroup(group []Sel) ([]jsonSel, error) {
+	out := make([]jsonSel, len(group))
+	for i, s := range group {
+		var err error
+		out[i], err = toJSONSel(s)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func (js jsonSel) toSel() (Sel, error) {
+	switch js.Kind {
+	case "tag":
+		return newTagSelector(js.Name), nil
+	case "class":
+		return ClassSelector{Class: js.Name}, nil
+	case "id":
+		return IDSelector{ID: js.Name}, nil
+	case "attr":
+		out := AttrSelector{Operation: js.Op, Val: js.Value}
+		out.Key, out.rawKey = attrKeys(js.Name)
+		if js.Op == "#=" {
+			rx, err := regexp.Compile(js.Value)
+			if err != nil {
+				return nil, err
+			}
+			out.Val, out.Regexp = "", rx
+		}
+		return out, nil
+	case "nesting":
+		return NestingSelector{}, nil
+	case "pseudo-class":
+		return js.toPseudoClass()
+	case "compound":
+		inner, err := fromJSONGroup(js.Selectors)
+		return CompoundSelector{Selectors: inner, Pseudo: js.PseudoElement}, err
+	case "combined":
+		if js.First == nil {
+			return nil, fmt.Errorf("missing first selector in combined selector")
+		}
+		first, err := js.First.toSel()
+		if err != nil {
+			return nil, err
+		}
+		out := CombinedSelector{First: first}
+		if js.Second != nil {
+			if len(js.Combinator) != 1 {
+				return nil, fmt.Errorf("invalid combinator %q", js.Combinator)
+			}
+			out.Combinator = js.Combinator[0]
+			out.Second, err = js.Second.toSel()
+			if err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("unknown selector kind %q", js.Kind)
+	}
+}
+
+func (js jsonSel) toPseudoClass() (Sel, error) {
+	switch js.Name {
+	case "not", "has", "haschild", "is", "where":
+		args, err := fromJSONGroup(js.Args)
+		return RelativePseudoClassSelector{Name: js.Name, Args: args}, err
+	case "contains", "containsown":
+		return ContainsPseudoClassSelector{Value: js.Value, Own: js.Name == "containsown"}, nil
+	case "matches", "matchesown":
+		rx, err := regexp.Compile(js.Value)
+		if err != nil {
+			return nil, err
+		}
+		return RegexpPseudoClassSelector{Regexp: rx, Own: js.Name == "matchesown"}, nil
+	case "nth-child", "nth-last-child", "nth-of-type", "nth-last-of-type":
+		if js.A == nil || js.B == nil {
+			return nil, fmt.Errorf("missing arguments a and b for :%s", js.Name)
+		}
+		last := js.Name == "nth-last-child" || js.Name == "nth-last-of-type"
+		ofType := js.Name == "nth-of-type" || js.Name == "nth-last-of-type"
+		return NthPseudoClassSelector{A: *js.A, B: *js.B, Last: last, OfType: ofType}, nil
+	case "lang":
+		return LangPseudoClassSelector{Lang: js.Value}, nil
+	case "state":
+		return CustomStatePseudoClassSelector{State: js.Value}, nil
+	case "current", "past", "future":
+		args, err := fromJSONGroup(js.Args)
+		return TimePseudoClassSelector{Name: js.Name, Args: args}, err
+	}
+	// pseudo-classes without arguments are handled by the parser
+	p := &parser{s: ":" + js.Name}
+	out, _, err := p.parsePseudoclassSelector()
+	if err == nil && (out == nil || p.i != len(p.s)) {
+		err = fmt.Errorf("invalid pseudo-class %q", js.Name)
+	}
+	return out, err
+}
+
+func fromJSONGroup(group []jsonSel) (SelectorGroup, error) {
+	if len(group) == 0 {
+		return nil, nil
+	}
+	out := make(SelectorGroup, len(group))
+	for i, js := range group {
+		var err error
+		out[i], err = js.toSel()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// marshal is like json.Marshal, without escaping
+// the HTML characters (like '>' in combinators)
+func marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte{'\n'}), nil
+}
+
+// MarshalSel returns the JSON representation of sel.
+func MarshalSel(sel Sel) ([]byte, error) {
+	js, err := toJSONSel(sel)
+	if err != nil {
+		return nil, err
+	}
+	return marshal(js)
+}
+
+// UnmarshalSel is the reverse operation of MarshalSel.
+// The decoded selector is validated by CompileAST.
+func UnmarshalSel(data []byte) (Sel, error) {
+	var js jsonSel
+	if err := json.Unmarshal(data, &js); err != nil {
+		return nil, err
+	}
+	sel, err := js.toSel()
+	if err != nil {
+		return nil, err
+	}
+	return CompileAST(sel)
+}
+
+// MarshalJSON implements json.Marshaler, using the format
+// described by MarshalSel for each selector.
+func (c SelectorGroup) MarshalJSON() ([]byte, error) {
+	js, err := toJSONGroup(c)
+	if err != nil {
+		return nil, err
+	}
+	return marshal(js)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (c *SelectorGroup) UnmarshalJSON(data []byte) error {
+	var js []jsonSel
+	if err := json.Unmarshal(data, &js); err != nil {
+		return err
+	}
+	group, err := fromJSONGroup(js)
+	if err != nil {
+		return err
+	}
+	group, err = CompileASTGroup(group)
+	if err != nil {
+		return err
+	}
+	*c = group
+	return nil
+}
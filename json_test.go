@@ -0,0 +1,89 @@
+package cascadia
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+	var inputs []string
+	for _, test := range selectorTests {
+		inputs = append(inputs, test.selector)
+	}
+	for _, test := range testsPseudo {
+		inputs = append(inputs, test.selector)
+	}
+
+	for _, input := range inputs {
+		group, err := ParseGroupWithPseudoElements(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := json.Marshal(group)
+		if err != nil {
+			t.Fatalf("%s: %s", input, err)
+		}
+		var decoded SelectorGroup
+		if err = json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("%s: %s (%s)", input, err, data)
+		}
+		if !reflect.DeepEqual(group, decoded) {
+			t.Errorf("%s: JSON round trip failed : %s gives %s", input, data, decoded)
+		}
+	}
+}
+
+func TestJSONExtensionPseudoElements(t *testing.T) {
+	for _, input := range []string{"p::text", "div > p::comment", "a::attr(href)"} {
+		sel, err := ParseWithOptions(input, Options{PseudoElements: true, NodePseudoElements: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := MarshalSel(sel)
+		if err != nil {
+			t.Fatalf("%s: %s", input, err)
+		}
+		decoded, err := UnmarshalSel(data)
+		if err != nil {
+			t.Fatalf("%s: %s (%s)", input, err, data)
+		}
+		if !Equal(sel, decoded) {
+			t.Errorf("%s: JSON round trip failed : %s gives %s", input, data, decoded)
+		}
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	sel, err := Parse("ul > li:nth-child(2n+1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := MarshalSel(sel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := `{"kind":"combined","first":{"kind":"tag","name":"ul"},"combinator":">","second":{"kind":"compound","selectors":[{"kind":"tag","name":"li"},{"kind":"pseudo-class","name":"nth-child","a":2,"b":1}]}}`
+	if string(data) != exp {
+		t.Errorf("expected\n%s\ngot\n%s", exp, data)
+	}
+	back, err := UnmarshalSel(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if back.String() != sel.String() {
+		t.Errorf("expected %s, got %s", sel, back)
+	}
+
+	for _, invalid := range []string{
+		`{"kind":"unknown"}`,
+		`{"kind":"pseudo-class","name":"unknown"}`,
+		`{"kind":"pseudo-class","name":"nth-child"}`,
+		`{"kind":"attr","name":"a","op":"#=","value":"("}`,
+		`{"kind":"combined"}`,
+	} {
+		if _, err := UnmarshalSel([]byte(invalid)); err == nil {
+			t.Errorf("expected error for %s", invalid)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package cascadia
+
+// KeySelector returns the rightmost compound selector of sel (the one
+// matched against the element itself), such as p.a in div > p.a.
+// For a selector without combinators, it returns sel.
+func KeySelector(sel Sel) Sel {
+	for {
+		comb, ok := sel.(CombinedSelector)
+		if !ok {
+			return sel
+		}
+		if comb.Second == nil {
+			sel = comb.First
+		} else {
+			sel = comb.Second
+		}
+	}
+}
+
+// KeyKind is the type of the component returned by RuleKey.
+type KeyKind uint8
+
+const (
+	KeyUniversal KeyKind = iota // no id, class or type selector
+	KeyTag
+	KeyClass
+	KeyID
+)
+
+func (k KeyKind) String() string {
+	switch k {
+	case KeyTag:
+		return "tag"
+	case KeyClass:
+		return "class"
+	case KeyID:
+		return "id"
+	default:
+		return "universal"
+	}
+}
+
+// Key is the most selective component of a key selector,
+// which may be used to bucket style rules: only the elements
+// with the given id, class or tag may be matched.
+type Key struct {
+	Kind  KeyKind
+	Value string // the id, class or (lower-cased) tag; empty for KeyUniversal
+}
+
+// RuleKey returns the most selective component of the key selector of sel
+// (see KeySelector), preferring ids, then classes, then type selectors.
+// The first one is chosen if there are several candidates of the same kind.
+func RuleKey(sel Sel) Key {
+	var out Key
+	consider := func(s Sel) {
+		var k Key
+		switch s := s.(type) {
+		case IDSelector:
+			if s.quirks { // the index lookups are case-sensitive
+				return
+			}
+			k = Key{KeyID, s.ID}
+		case ClassSelector:
+			if s.quirks {
+				return
+			}
+			k = Key{KeyClass, s.Class}
+		case TagSelector:
+			k = Key{KeyTag, s.Tag}
+		default:
+			return
+		}
+		if k.Kind > out.Kind {
+			out = k
+		}
+	}
+	key := KeySelector(sel)
+	if c, ok := key.(CompoundSelector); ok {
+		for _, s := range c.Selectors {
+			consider(s)
+		}
+	} else {
+		consider(key)
+	}
+	return out
+}
@@ -0,0 +1,30 @@
+package cascadia
+
+import "testing"
+
+func TestRuleKey(t *testing.T) {
+	for _, test := range []struct {
+		sel string
+		key Sel
+		exp Key
+	}{
+		{"div > p.a", CompoundSelector{Selectors: []Sel{newTagSelector("p"), ClassSelector{Class: "a"}}}, Key{KeyClass, "a"}},
+		{"ul li", newTagSelector("li"), Key{KeyTag, "li"}},
+		{"a.b#c.d", nil, Key{KeyID, "c"}},
+		{"div *", CompoundSelector{}, Key{}},
+		{"p [href]", AttrSelector{Key: "href"}, Key{}},
+		{".x.y", nil, Key{KeyClass, "x"}},
+		{"section p::before", nil, Key{KeyTag, "p"}},
+	} {
+		sel, err := ParseWithPseudoElement(test.sel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.key != nil && !Equal(KeySelector(sel), test.key) {
+			t.Errorf("%s: unexpected key selector %s", test.sel, KeySelector(sel))
+		}
+		if got := RuleKey(sel); got != test.exp {
+			t.Errorf("%s: expected %v, got %v", test.sel, test.exp, got)
+		}
+	}
+}
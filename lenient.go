@@ -0,0 +1,133 @@
+package cascadia
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MemberError describes an invalid member of a group of selectors.
+type MemberError struct {
+	Index  int    // index of the member in the group (counting the invalid ones)
+	Source string // source text of the member, without surrounding whitespaces
+	Span   Span   // position of the member in the input
+	Err    error
+}
+
+func (e MemberError) Error() string {
+	return fmt.Sprintf("invalid selector %q (member %d): %s", e.Source, e.Index, e.Err)
+}
+
+// Unwrap returns the underlying parsing error.
+func (e MemberError) Unwrap() error { return e.Err }
+
+// GroupError is returned by ParseGroupLenient and
+// lists all the invalid members of a group.
+type GroupError struct {
+	Members []MemberError
+}
+
+func (e *GroupError) Error() string {
+	chunks := make([]string, len(e.Members))
+	for i, m := range e.Members {
+		chunks[i] = m.Error()
+	}
+	return strings.Join(chunks, "; ")
+}
+
+// Unwrap returns the errors of each invalid member.
+func (e *GroupError) Unwrap() []error {
+	out := make([]error, len(e.Members))
+	for i, m := range e.Members {
+		out[i] = m
+	}
+	return out
+}
+
+// ParseGroupLenient parses a group of selectors separated by commas,
+// with support for pseudo-elements.
+// Contrary to ParseGroupWithPseudoElements, each member of the group
+// is parsed independently: the valid ones are returned, and
+// the invalid ones are described by the returned error, which is then a *GroupError.
+func ParseGroupLenient(sel string) (SelectorGroup, error) {
+	return ParseGroupWithOptions(sel, Options{PseudoElements: true, Lenient: true})
+}
+
+// parseGroupLenient parses each member of a group independently.
+func (p *parser) parseGroupLenient() (SelectorGroup, error) {
+	var (
+		out    SelectorGroup
+		errors []MemberError
+	)
+	for index := 0; ; index++ {
+		if p.limits.MaxGroupSize > 0 && index == p.limits.MaxGroupSize {
+			return nil, p.errorAt(ErrLimitExceeded, p.i-1, nil, "group has more than %d selectors", p.limits.MaxGroupSize)
+		}
+		start := p.i
+		member, err := p.parseSelector()
+		if err == nil && p.i < len(p.s) && p.s[p.i] != ',' {
+			err = p.errorf(ErrTrailingInput, expectEnd, "unexpected %q after the selector", leftOverText(p.s[p.i:]))
+		}
+		if err != nil {
+			end := nextTopLevelComma(p.s, start)
+			errors = append(errors, MemberError{
+				Index:  index,
+				Source: strings.TrimSpace(p.s[start:end]),
+				Span:   Span{start, end},
+				Err:    err,
+			})
+			p.i = end
+		} else {
+			out = append(out, member)
+		}
+
+		if p.i >= len(p.s) {
+			break
+		}
+		p.i++ // consume the comma
+	}
+
+	if len(errors) != 0 {
+		return out, &GroupError{Members: errors}
+	}
+	return out, nil
+}
+
+// nextTopLevelComma returns the index of the first comma after start
+// which is not nested in parenthesis, brackets, strings or comments,
+// or len(s).
+func nextTopLevelComma(s string, start int) int {
+	var blocks []byte // stack of expected closing characters
+	for i := start; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\':
+			i++ // skip the escaped character
+		case '"', '\'':
+			for i++; i < len(s) && s[i] != c; i++ {
+				if s[i] == '\\' {
+					i++
+				}
+			}
+		case '/':
+			if strings.HasPrefix(s[i:], "/*") {
+				end := strings.Index(s[i+2:], "*/")
+				if end == -1 {
+					return len(s)
+				}
+				i += end + 3
+			}
+		case '(':
+			blocks = append(blocks, ')')
+		case '[':
+			blocks = append(blocks, ']')
+		case ')', ']':
+			if len(blocks) > 0 && blocks[len(blocks)-1] == c {
+				blocks = blocks[:len(blocks)-1]
+			}
+		case ',':
+			if len(blocks) == 0 {
+				return i
+			}
+		}
+	}
+	return len(s)
+}
@@ -0,0 +1,48 @@
+package cascadia
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseGroupLenient(t *testing.T) {
+	input := `div, p:unknown, a[href=","]::before, :not(a, ]), span /* , */ > b,, em`
+	group, err := ParseGroupLenient(input)
+	if s := group.String(); s != `div, a[href=","]::before, span > b, em` {
+		t.Errorf("unexpected valid members %s", s)
+	}
+	var groupErr *GroupError
+	if !errors.As(err, &groupErr) {
+		t.Fatalf("expected *GroupError, got %v", err)
+	}
+	if len(groupErr.Members) != 3 {
+		t.Fatalf("expected 3 invalid members, got %v", groupErr.Members)
+	}
+	for i, exp := range []struct {
+		index  int
+		source string
+	}{{1, "p:unknown"}, {3, ":not(a, ])"}, {5, ""}} {
+		got := groupErr.Members[i]
+		if got.Index != exp.index || got.Source != exp.source {
+			t.Errorf("expected invalid member %d %q, got %d %q", exp.index, exp.source, got.Index, got.Source)
+		}
+	}
+
+	group, err = ParseGroupLenient("a, b")
+	if err != nil || len(group) != 2 {
+		t.Errorf("unexpected result %s %v", group, err)
+	}
+
+	group, err = ParseGroupLenient("a b c")
+	if err != nil || len(group) != 1 {
+		t.Errorf("unexpected result %s %v", group, err)
+	}
+	group, err = ParseGroupLenient("a, b)")
+	if err == nil || len(group) != 1 {
+		t.Errorf("unexpected result %s %v", group, err)
+	}
+	var parseErr *ParseError
+	if !errors.Is(err, ErrTrailingInput) || !errors.As(err, &parseErr) || parseErr.Offset != 4 {
+		t.Errorf("expected a trailing input error at 4, got %v", err)
+	}
+}
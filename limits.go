@@ -0,0 +1,92 @@
+package cascadia
+
+import "regexp/syntax"
+
+// Limits bounds the work done when parsing untrusted selectors.
+// A zero field means no limit.
+// Exceeding a limit is reported by a *ParseError with code ErrLimitExceeded.
+type Limits struct {
+	// MaxLength is the maximum length of the selector, in bytes.
+	MaxLength int
+	// MaxDepth is the maximum nesting of functional pseudo-classes
+	// taking selectors, like :not(:has(a)), which has depth 2.
+	MaxDepth int
+	// MaxGroupSize is the maximum number of selectors in a group,
+	// including the groups used as arguments of :not(), :is(), ...
+	MaxGroupSize int
+	// MaxRegexpLength is the maximum length, in bytes, of the regular
+	// expressions of [attr#=(...)], :matches() and :matchesOwn().
+	MaxRegexpLength int
+	// MaxRegexpComplexity is the maximum number of instructions of the
+	// compiled regular expressions, which expands the repetitions: a{100}
+	// has about 100 instructions. Since the regular expressions of Go run
+	// in time linear in the size of the text, this bounds the cost of
+	// matching them, and no timeout is needed.
+	MaxRegexpComplexity int
+}
+
+// DefaultLimits are generous limits, which should accept any
+// selector written by hand.
+var DefaultLimits = Limits{
+	MaxLength: 4096, MaxDepth: 16, MaxGroupSize: 256,
+	MaxRegexpLength: 512, MaxRegexpComplexity: 2048,
+}
+
+// checkLength returns an error if the input is too long.
+func (p *parser) checkLength() error {
+	if p.limits.MaxLength > 0 && len(p.s) > p.limits.MaxLength {
+		return p.errorAt(ErrLimitExceeded, p.limits.MaxLength, nil, "selector is longer than %d bytes", p.limits.MaxLength)
+	}
+	return nil
+}
+
+// checkRegexp returns an error if the regular expression expr,
+// located at offset, is too long or too complex.
+func (p *parser) checkRegexp(expr string, offset int) error {
+	if p.limits.MaxRegexpLength > 0 && len(expr) > p.limits.MaxRegexpLength {
+		return p.errorAt(ErrLimitExceeded, offset, nil, "regular expression is longer than %d bytes", p.limits.MaxRegexpLength)
+	}
+	if p.limits.MaxRegexpComplexity > 0 {
+		if n := regexpComplexity(expr); n > p.limits.MaxRegexpComplexity {
+			return p.errorAt(ErrLimitExceeded, offset, nil, "regular expression has more than %d instructions", p.limits.MaxRegexpComplexity)
+		}
+	}
+	return nil
+}
+
+// regexpComplexity returns the size of the program of expr,
+// or 0 if expr is invalid (the error is reported by regexp.Compile).
+func regexpComplexity(expr string) int {
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return 0
+	}
+	prog, err := syntax.Compile(re.Simplify())
+	if err != nil {
+		return 0
+	}
+	return len(prog.Inst)
+}
+
+// ParseWithLimits is like Parse, but returns an error
+// if the selector exceeds limits.
+func ParseWithLimits(sel string, limits Limits) (Sel, error) {
+	return ParseWithOptions(sel, Options{Limits: limits})
+}
+
+// ParseGroupWithLimits is like ParseGroup, but returns an error
+// if the selector exceeds limits.
+func ParseGroupWithLimits(sel string, limits Limits) (SelectorGroup, error) {
+	return ParseGroupWithOptions(sel, Options{Limits: limits})
+}
+
+// CompileWithLimits is like Compile, but returns an error
+// if the selector exceeds limits.
+func CompileWithLimits(sel string, limits Limits) (Selector, error) {
+	compiled, err := ParseGroupWithLimits(sel, limits)
+	if err != nil {
+		return nil, err
+	}
+
+	return Selector(compiled.Match), nil
+}
@@ -0,0 +1,104 @@
+package cascadia
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLimits(t *testing.T) {
+	limits := Limits{MaxLength: 40, MaxDepth: 2, MaxGroupSize: 3}
+	for _, sel := range []string{
+		"div > p.a",
+		"a, b, c",
+		":not(:has(a, b, c))",
+		":not(a), :is(b), :where(c)",
+	} {
+		if _, err := ParseGroupWithLimits(sel, limits); err != nil {
+			t.Errorf("%s: unexpected error %s", sel, err)
+		}
+	}
+
+	for _, test := range []struct {
+		sel    string
+		offset int
+	}{
+		{strings.Repeat("a", 41), 40},
+		{"a, b, c, d", 7},
+		{":not(a, b, c, d)", 12},
+		{"p:not(:is(:has(a)))", 10},
+		{"a, :not(:not(:not(b)))", 13},
+	} {
+		_, err := ParseGroupWithLimits(test.sel, limits)
+		if !errors.Is(err, ErrLimitExceeded) {
+			t.Errorf("%s: expected a limit error, got %v", test.sel, err)
+			continue
+		}
+		if offset := err.(*ParseError).Offset; offset != test.offset {
+			t.Errorf("%s: expected error at %d, got %d", test.sel, test.offset, offset)
+		}
+	}
+
+	// in lenient mode, the depth of an invalid member is not kept
+	group, err := ParseGroupWithOptions(":not(:not(a)), :not(b), :current(:not(c)), :current(d)",
+		Options{Lenient: true, Limits: Limits{MaxDepth: 1}})
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("expected a limit error, got %v", err)
+	}
+	if got := group.String(); got != ":not(b), :current(d)" {
+		t.Errorf("unexpected lenient group %s", got)
+	}
+
+	// zero limits are ignored
+	if _, err := ParseGroupWithLimits(strings.Repeat(":not(", 50)+"a"+strings.Repeat(")", 50), Limits{}); err != nil {
+		t.Error(err)
+	}
+	if _, err := ParseWithLimits("a, b", limits); !errors.Is(err, ErrTrailingInput) {
+		t.Errorf("expected trailing input error, got %v", err)
+	}
+	if _, err := CompileWithLimits(strings.Repeat("a,", 300)+"a", DefaultLimits); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("expected a limit error, got %v", err)
+	}
+}
+
+func TestRegexpLimits(t *testing.T) {
+	limits := Limits{MaxRegexpLength: 20, MaxRegexpComplexity: 100}
+	for _, sel := range []string{
+		`a[href#=(^https?://)]`,
+		`p:matches(\w+@\w+)`,
+		`p:matchesOwn([a-z]{1,10})`,
+	} {
+		if _, err := ParseWithLimits(sel, limits); err != nil {
+			t.Errorf("%s: unexpected error %s", sel, err)
+		}
+	}
+
+	for _, test := range []struct {
+		sel    string
+		offset int
+	}{
+		{`a[href#=(` + strings.Repeat("x", 30) + `)]`, 8},
+		{`p:matches(a{200})`, 10},
+		{`p:matchesOwn((a{10}){10})`, 13},
+	} {
+		_, err := ParseWithLimits(test.sel, limits)
+		if !errors.Is(err, ErrLimitExceeded) {
+			t.Errorf("%s: expected a limit error, got %v", test.sel, err)
+			continue
+		}
+		if offset := err.(*ParseError).Offset; offset != test.offset {
+			t.Errorf("%s: expected error at %d, got %d", test.sel, test.offset, offset)
+		}
+	}
+
+	// invalid expressions are still reported as such
+	if _, err := ParseWithLimits(`p:matches(a**)`, limits); !errors.Is(err, ErrInvalidRegexp) {
+		t.Errorf("expected an invalid regexp error, got %v", err)
+	}
+	if _, err := ParseWithLimits(`p:matches(a{200})`, DefaultLimits); err != nil {
+		t.Error(err)
+	}
+	if _, err := ParseWithLimits(`p:matches((ab|cd){1,900})`, DefaultLimits); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("expected a limit error, got %v", err)
+	}
+}
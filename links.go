@@ -0,0 +1,181 @@
+package cascadia
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// LinkKind is the kind of resource designated by a Link.
+type LinkKind uint8
+
+const (
+	LinkHyperlink  LinkKind = iota // a[href], area[href]
+	LinkImage                      // img[src], img[srcset], picture > source[srcset], video[poster]
+	LinkStylesheet                 // link[rel~=stylesheet]
+	LinkScript                     // script[src]
+	LinkFrame                      // iframe[src], frame[src]
+	LinkMedia                      // the sources of audio, video and track
+	LinkForm                       // form[action], button[formaction], input[formaction]
+	LinkResource                   // the other link elements, like icons
+)
+
+var linkKindNames = [...]string{"hyperlink", "image", "stylesheet", "script", "frame", "media", "form", "resource"}
+
+func (k LinkKind) String() string {
+	if int(k) < len(linkKindNames) {
+		return linkKindNames[k]
+	}
+	return fmt.Sprintf("LinkKind(%d)", k)
+}
+
+// Link is an URL found in a document, returned by Links.
+type Link struct {
+	Kind LinkKind
+	URL  *url.URL // resolved against the base of the document
+	Raw  string   // the URL as written
+	// Element is the element defining the URL, in the attribute Attr.
+	Element *html.Node
+	Attr    string
+	// Rel are the lower-cased tokens of the rel attribute, if any.
+	Rel []string
+	// Descriptor is the density or width descriptor of
+	// the srcset candidates, like "2x" or "480w".
+	Descriptor string
+}
+
+var linkElements = MustCompile("a[href], area[href], link[href], img[src], img[srcset], source[src], source[srcset]," +
+	"script[src], iframe[src], frame[src], video[src], video[poster], audio[src], track[src], embed[src]," +
+	"form[action], button[formaction], input[formaction], input[type=image][src]")
+
+var baseElement = MustCompile("base[href]")
+
+// Links returns the URLs referenced by the elements of doc, in tree order,
+// resolved against the document base: the first <base href> element,
+// itself resolved against base. If base is nil, and the document has no
+// <base href>, the URLs are returned unresolved.
+// Empty and invalid URLs, as well as the javascript: ones, are skipped.
+func Links(doc *html.Node, base *url.URL) []Link {
+	if e := Query(doc, baseElement); e != nil {
+		if u, err := resolveURL(base, getAttr(e, "href")); err == nil {
+			base = u
+		}
+	}
+	var out []Link
+	for _, e := range QueryAll(doc, linkElements) {
+		rel := SplitTokens(toLowerASCII(getAttr(e, "rel")))
+		for _, a := range e.Attr {
+			kind, ok := linkKind(e, a.Key, rel)
+			if !ok {
+				continue
+			}
+			add := func(raw, descriptor string) {
+				raw = strings.TrimSpace(raw)
+				if raw == "" || strings.HasPrefix(toLowerASCII(raw), "javascript:") {
+					return
+				}
+				u, err := resolveURL(base, raw)
+				if err != nil {
+					return
+				}
+				out = append(out, Link{Kind: kind, URL: u, Raw: raw, Element: e, Attr: a.Key, Rel: rel, Descriptor: descriptor})
+			}
+			if a.Key == "srcset" {
+				for _, c := range ParseSrcset(a.Val) {
+					add(c.URL, c.Descriptor)
+				}
+			} else {
+				add(a.Val, "")
+			}
+		}
+	}
+	return out
+}
+
+func resolveURL(base *url.URL, ref string) (*url.URL, error) {
+	if base == nil {
+		return url.Parse(strings.TrimSpace(ref))
+	}
+	return base.Parse(strings.TrimSpace(ref))
+}
+
+// linkKind returns the kind of the URL in the attribute key of e,
+// or false if it is not an URL
+func linkKind(e *html.Node, key string, rel []string) (LinkKind, bool) {
+	switch e.Data + "/" + key {
+	case "a/href", "area/href":
+		return LinkHyperlink, true
+	case "link/href":
+		if containsString(rel, "stylesheet") {
+			return LinkStylesheet, true
+		}
+		return LinkResource, true
+	case "img/src", "img/srcset", "video/poster":
+		return LinkImage, true
+	case "input/src":
+		return LinkImage, toLowerASCII(getAttr(e, "type")) == "image"
+	case "source/src", "source/srcset":
+		if e.Parent != nil && e.Parent.Type == html.ElementNode && e.Parent.Data == "picture" {
+			return LinkImage, true
+		}
+		return LinkMedia, true
+	case "script/src":
+		return LinkScript, true
+	case "iframe/src", "frame/src", "embed/src":
+		return LinkFrame, true
+	case "video/src", "audio/src", "track/src":
+		return LinkMedia, true
+	case "form/action", "button/formaction", "input/formaction":
+		return LinkForm, true
+	}
+	return 0, false
+}
+
+// SrcsetCandidate is an image candidate of a srcset attribute.
+type SrcsetCandidate struct {
+	URL        string
+	Descriptor string // like "2x" or "480w", or empty
+}
+
+// ParseSrcset splits the value of a srcset attribute into its candidates,
+// following the parsing rules of the HTML specification: the URLs
+// may contain commas, but not whitespace.
+func ParseSrcset(srcset string) []SrcsetCandidate {
+	var out []SrcsetCandidate
+	s := srcset
+	for {
+		s = strings.TrimLeft(s, " \t\n\f\r,")
+		if s == "" {
+			return out
+		}
+		end := strings.IndexAny(s, " \t\n\f\r")
+		if end == -1 {
+			end = len(s)
+		}
+		c := SrcsetCandidate{URL: s[:end]}
+		s = s[end:]
+		if strings.HasSuffix(c.URL, ",") {
+			// no descriptor
+			c.URL = strings.TrimRight(c.URL, ",")
+		} else {
+			// the descriptors run until the next comma outside parentheses
+			depth, i := 0, 0
+			for ; i < len(s); i++ {
+				if s[i] == '(' {
+					depth++
+				} else if s[i] == ')' && depth > 0 {
+					depth--
+				} else if s[i] == ',' && depth == 0 {
+					break
+				}
+			}
+			c.Descriptor = strings.Join(strings.Fields(s[:i]), " ")
+			s = s[i:]
+		}
+		if c.URL != "" {
+			out = append(out, c)
+		}
+	}
+}
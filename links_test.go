@@ -0,0 +1,68 @@
+package cascadia
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLinks(t *testing.T) {
+	doc := MustParseHTML(`<head><base href="/docs/"><link rel="Stylesheet" href="main.css"><link rel="icon" href="/favicon.ico">
+	<script src="app.js"></script></head>
+	<body><a href="../about#team">About</a><a href="javascript:void(0)">x</a><a href="">empty</a>
+	<img src="a.png" srcset="a-2x.png 2x, a-3x.png 3x">
+	<picture><source srcset="b.webp"></picture><video poster="p.jpg"><source src="v.mp4"></video>
+	<form action="?q=1"><input type="text" src="ignored.png"><input type="image" src="go.png"></form>
+	<iframe src="https://other.org/frame"></iframe></body>`)
+	base, _ := url.Parse("https://example.com/index.html")
+	var got []string
+	for _, l := range Links(doc, base) {
+		s := l.Kind.String() + " " + l.URL.String()
+		if l.Descriptor != "" {
+			s += " " + l.Descriptor
+		}
+		got = append(got, s)
+	}
+	expected := []string{
+		"stylesheet https://example.com/docs/main.css",
+		"resource https://example.com/favicon.ico",
+		"script https://example.com/docs/app.js",
+		"hyperlink https://example.com/about#team",
+		"image https://example.com/docs/a.png",
+		"image https://example.com/docs/a-2x.png 2x",
+		"image https://example.com/docs/a-3x.png 3x",
+		"image https://example.com/docs/b.webp",
+		"image https://example.com/docs/p.jpg",
+		"media https://example.com/docs/v.mp4",
+		"form https://example.com/docs/?q=1",
+		"image https://example.com/docs/go.png",
+		"frame https://other.org/frame",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+
+	// without base
+	links := Links(MustParseHTML(`<a href="rel/path" rel="NoFollow">x</a>`), nil)
+	if len(links) != 1 || links[0].URL.String() != "rel/path" || !reflect.DeepEqual(links[0].Rel, []string{"nofollow"}) {
+		t.Errorf("unexpected links %v", links)
+	}
+}
+
+func TestParseSrcset(t *testing.T) {
+	for _, test := range []struct {
+		srcset   string
+		expected []SrcsetCandidate
+	}{
+		{"", nil},
+		{"a.png", []SrcsetCandidate{{URL: "a.png"}}},
+		{" a.png 1x,b.png  2x ", []SrcsetCandidate{{"a.png", "1x"}, {"b.png", "2x"}}},
+		{"a.png, b.png 480w", []SrcsetCandidate{{URL: "a.png"}, {"b.png", "480w"}}},
+		{"data:image/png;base64,AAA= 1x, c.png 2x", []SrcsetCandidate{{"data:image/png;base64,AAA=", "1x"}, {"c.png", "2x"}}},
+	} {
+		if got := ParseSrcset(test.srcset); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%q: expected %v, got %v", test.srcset, test.expected, got)
+		}
+	}
+}
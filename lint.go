@@ -0,0 +1,140 @@
+package cascadia
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Severity is the importance of a lint Finding.
+type Severity uint8
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return fmt.Sprintf("Severity(%d)", s)
+	}
+}
+
+// Lint rules, used in Finding.Rule
+const (
+	RuleOverQualified       = "over-qualified"       // a type selector next to an id, like div#id
+	RuleDuplicate           = "duplicate"            // the same simple selector twice in a compound, like .a.a
+	RuleUniversalDescendant = "universal-descendant" // a descendant combinator with a universal operand, like * a or a *
+	RuleNonStandard         = "non-standard"         // an extension not supported by browsers, like :contains()
+	RuleDeprecated          = "deprecated"           // a legacy syntax, like :before
+	RuleNeverMatches        = "never-matches"        // a component which never matches, like :hover
+)
+
+// Finding is an issue reported by Lint.
+type Finding struct {
+	Rule     string
+	Severity Severity
+	Message  string
+	Span     Span // position of the offending component
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%d:%d: %s: %s (%s)", f.Span.Start, f.Span.End, f.Severity, f.Message, f.Rule)
+}
+
+// Lint parses the selector group sel and returns the findings
+// about its style and performance, sorted by position.
+// Since it relies on the parser, an error is returned if sel is not valid.
+// The non-fatal diagnostics of the parser (see ParseGroupWithDiagnostics)
+// are included in the findings.
+func Lint(sel string) ([]Finding, error) {
+	p := &parser{s: sel, acceptPseudoElements: true, recordSpans: true, collectDiagnostics: true}
+	group, err := p.parseSelectorGroup()
+	if err == nil {
+		err = p.checkLeftOver()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var out []Finding
+	for _, d := range p.diagnostics {
+		out = append(out, diagnosticFinding(d))
+	}
+	WalkGroup(group, func(s Sel) bool {
+		out = lintSel(out, s)
+		return true
+	})
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Span.Start < out[j].Span.Start })
+	return out, nil
+}
+
+func diagnosticFinding(d Diagnostic) Finding {
+	f := Finding{Message: d.Message, Span: d.Span, Severity: SeverityWarning}
+	switch d.Kind {
+	case DiagNonStandard:
+		f.Rule = RuleNonStandard
+	case DiagDeprecated:
+		f.Rule, f.Severity = RuleDeprecated, SeverityInfo
+	case DiagNeverMatches:
+		f.Rule = RuleNeverMatches
+	}
+	return f
+}
+
+// lintSel checks sel, without its components
+func lintSel(out []Finding, sel Sel) []Finding {
+	switch s := sel.(type) {
+	case CompoundSelector:
+		var tag Sel
+		for i, c := range s.Selectors {
+			switch c.(type) {
+			case TagSelector:
+				tag = c
+			case IDSelector:
+				if tag != nil {
+					out = append(out, Finding{
+						Rule: RuleOverQualified, Severity: SeverityInfo, Span: SpanOf(tag),
+						Message: fmt.Sprintf("type selector %s is redundant with id selector %s", tag, c),
+					})
+				}
+			}
+			for _, prev := range s.Selectors[:i] {
+				if Equal(prev, c) {
+					out = append(out, Finding{
+						Rule: RuleDuplicate, Severity: SeverityWarning, Span: SpanOf(c),
+						Message: fmt.Sprintf("%s is repeated in the same compound selector", c),
+					})
+					break
+				}
+			}
+		}
+	case CombinedSelector:
+		if s.Combinator != ' ' {
+			break
+		}
+		if isUniversal(s.First) {
+			out = append(out, Finding{
+				Rule: RuleUniversalDescendant, Severity: SeverityInfo, Span: SpanOf(s.First),
+				Message: "universal selector followed by a descendant combinator is redundant",
+			})
+		}
+		if isUniversal(s.Second) {
+			out = append(out, Finding{
+				Rule: RuleUniversalDescendant, Severity: SeverityWarning, Span: SpanOf(s.Second),
+				Message: "universal selector after a descendant combinator matches every descendant and is expensive",
+			})
+		}
+	}
+	return out
+}
+
+// isUniversal returns true for the universal selector *
+func isUniversal(sel Sel) bool {
+	c, ok := sel.(CompoundSelector)
+	return ok && len(c.Selectors) == 0 && c.Pseudo == ""
+}
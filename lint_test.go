@@ -0,0 +1,41 @@
+package cascadia
+
+import (
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	for _, test := range []struct {
+		sel      string
+		rules    []string
+		position []Span
+	}{
+		{"div#main", []string{RuleOverQualified}, []Span{{0, 3}}},
+		{"#main", nil, nil},
+		{"a.b.c.b", []string{RuleDuplicate}, []Span{{5, 7}}},
+		{"* a", []string{RuleUniversalDescendant}, []Span{{0, 1}}},
+		{"a *", []string{RuleUniversalDescendant}, []Span{{2, 3}}},
+		{"a > *", nil, nil},
+		{"p:contains(x), a:hover", []string{RuleNonStandard, RuleNeverMatches}, []Span{{1, 13}, {16, 22}}},
+		{"p:before", []string{RuleDeprecated}, []Span{{1, 8}}},
+		{"p:not(div#a)", []string{RuleOverQualified}, []Span{{6, 9}}},
+	} {
+		findings, err := Lint(test.sel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(findings) != len(test.rules) {
+			t.Errorf("%s: expected %d findings, got %v", test.sel, len(test.rules), findings)
+			continue
+		}
+		for i, f := range findings {
+			if f.Rule != test.rules[i] || f.Span != test.position[i] {
+				t.Errorf("%s: expected %s at %v, got %s", test.sel, test.rules[i], test.position[i], f)
+			}
+		}
+	}
+
+	if _, err := Lint("div["); err == nil {
+		t.Error("expected error for invalid selector")
+	}
+}
@@ -0,0 +1,113 @@
+package cascadia
+
+// LoweredSelector is a selector translated to standard CSS, returned by Lower.
+type LoweredSelector struct {
+	// Selector is a standard selector, supported by browsers, which matches
+	// at least the elements matched by the original selector.
+	Selector string
+	// Residual is nil if Selector is equivalent to the original selector.
+	// Otherwise, it must be used to filter the elements matched by Selector.
+	Residual Matcher
+}
+
+// Lower translates sel, which may use the cascadia extensions (:contains(),
+// :matches(), :input, :haschild(), [a!=v] and [a#=re]), to the closest
+// standard selector, so that the selection may be delegated to a browser.
+//
+// The extensions with a standard equivalent are replaced: [a!=v] by
+// :not([a=v]) and :input by :is(input, select, textarea, button). The other
+// ones are relaxed, like :haschild() to :has(), or removed, and the original
+// selector is then returned as residual predicate.
+func Lower(sel Sel) LoweredSelector {
+	standard, exact := lowerSel(sel)
+	out := LoweredSelector{Selector: standard.String()}
+	if !exact {
+		out.Residual = sel
+	}
+	return out
+}
+
+// LowerGroup is like Lower, for a selector group.
+func LowerGroup(group SelectorGroup) LoweredSelector {
+	standard, exact := lowerGroup(group)
+	out := LoweredSelector{Selector: standard.String()}
+	if !exact {
+		out.Residual = group
+	}
+	return out
+}
+
+var inputElements = RelativePseudoClassSelector{Name: "is", Args: SelectorGroup{
+	TagSelector{Tag: "input"}, TagSelector{Tag: "select"}, TagSelector{Tag: "textarea"}, TagSelector{Tag: "button"},
+}}
+
+// lowerSel returns a standard selector matching at least the elements
+// matched by sel, and true if it is equivalent to sel.
+// The removed components are replaced by the universal selector.
+func lowerSel(sel Sel) (Sel, bool) {
+	switch s := sel.(type) {
+	case CompoundSelector:
+		exact := true
+		inner := make([]Sel, 0, len(s.Selectors))
+		for _, c := range s.Selectors {
+			c, e := lowerSel(c)
+			exact = exact && e
+			if isUniversal(c) {
+				continue
+			}
+			inner = append(inner, c)
+		}
+		s.Selectors = inner
+		return s, exact
+	case CombinedSelector:
+		var exact1, exact2 bool
+		s.First, exact1 = lowerSel(s.First)
+		exact2 = true
+		if s.Second != nil {
+			s.Second, exact2 = lowerSel(s.Second)
+		}
+		return s, exact1 && exact2
+	case RelativePseudoClassSelector:
+		args, exact := lowerGroup(s.Args)
+		switch s.Name {
+		case "not":
+			// relaxing the argument of a negation would restrict it
+			if !exact {
+				return CompoundSelector{}, false
+			}
+		case "haschild":
+			s.Name, exact = "has", false
+		}
+		s.Args = args
+		return s, exact
+	case AttrSelector:
+		switch s.Operation {
+		case "!=":
+			s.Operation = "="
+			return RelativePseudoClassSelector{Name: "not", Args: SelectorGroup{s}}, true
+		case "#=":
+			if s.Regexp.MatchString("") { // may match without the attribute
+				return CompoundSelector{}, false
+			}
+			return AttrSelector{Key: s.Key}, false
+		}
+		return s, true
+	case InputPseudoClassSelector:
+		return inputElements, true
+	case ContainsPseudoClassSelector, RegexpPseudoClassSelector:
+		return CompoundSelector{}, false
+	default:
+		return sel, true
+	}
+}
+
+func lowerGroup(group SelectorGroup) (SelectorGroup, bool) {
+	out := make(SelectorGroup, len(group))
+	exact := true
+	for i, sel := range group {
+		var e bool
+		out[i], e = lowerSel(sel)
+		exact = exact && e
+	}
+	return out, exact
+}
@@ -0,0 +1,63 @@
+package cascadia
+
+import (
+	"fmt"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestLower(t *testing.T) {
+	for _, test := range []struct {
+		sel, standard string
+		exact         bool
+	}{
+		{"div > p.a", "div > p.a", true},
+		{"p[a!=v]", `p:not([a="v"])`, true},
+		{":input", ":is(input, select, textarea, button)", true},
+		{"p:contains(x)", "p", false},
+		{"div > :contains(x)", "div > *", false},
+		{"a[href#=^https]", "a[href]", false},
+		{"a[href#=.*]", "a", false},
+		{"div:haschild(p:containsOwn(x))", "div:has(p)", false},
+		{"li:not(:contains(x))", "li", false},
+		{"li:not([a!=v])", `li:not(:not([a="v"]))`, true},
+		{"p:contains(x)::before", "p::before", false},
+	} {
+		sel, err := ParseWithPseudoElement(test.sel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lowered := Lower(sel)
+		if lowered.Selector != test.standard {
+			t.Errorf("%s: expected %q, got %q", test.sel, test.standard, lowered.Selector)
+		}
+		if exact := lowered.Residual == nil; exact != test.exact {
+			t.Errorf("%s: expected exact %v, got %v", test.sel, test.exact, exact)
+		}
+		if _, err := ParseWithPseudoElement(lowered.Selector); err != nil {
+			t.Errorf("%s: invalid standard selector: %s", test.sel, err)
+		}
+	}
+}
+
+func TestLowerGroupResidual(t *testing.T) {
+	doc := MustParseHTML(`<ul><li>one</li><li class="x">two</li><li>three</li></ul><p>two</p>`)
+	group, err := ParseGroup("li:contains(two), li:contains(three), p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lowered := LowerGroup(group)
+	if lowered.Selector != "li, li, p" || lowered.Residual == nil {
+		t.Fatalf("unexpected lowering %v", lowered)
+	}
+	var got []*html.Node
+	for _, n := range QueryAll(doc, MustCompile(lowered.Selector)) {
+		if lowered.Residual.Match(n) {
+			got = append(got, n)
+		}
+	}
+	if expected := QueryAll(doc, group); fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
@@ -0,0 +1,218 @@
+package cascadia
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// This file implements the extraction of the structured data
+// embedded in HTML documents: microdata, RDFa and the meta tags of
+// Open Graph and Twitter cards.
+// The URLs are returned as written in the document, without resolution.
+
+// MicrodataItem is an item of the microdata model
+// (elements with an itemscope attribute).
+type MicrodataItem struct {
+	Type []string // the tokens of itemtype
+	ID   string   // the itemid
+	// Properties maps the names of the properties to their values, in
+	// tree order. A value is a string or a nested *MicrodataItem.
+	Properties map[string][]any
+}
+
+var (
+	topLevelItems  = MustCompile("[itemscope]:not([itemprop])")
+	openGraphMeta  = MustCompile(`meta[property^="og:"][content]`)
+	twitterMeta    = MustCompile(`meta[name^="twitter:"][content], meta[property^="twitter:"][content]`)
+	rdfaProperties = MustCompile("[property]")
+	elementsWithID = MustCompile("[id]")
+)
+
+// Microdata returns the top-level microdata items
+// found in the descendants of n.
+func Microdata(n *html.Node) []*MicrodataItem {
+	ids := map[string]*html.Node{} // for itemref
+	for _, e := range QueryAll(n, elementsWithID) {
+		id := getAttr(e, "id")
+		if _, ok := ids[id]; !ok {
+			ids[id] = e
+		}
+	}
+	var out []*MicrodataItem
+	for _, e := range QueryAll(n, topLevelItems) {
+		out = append(out, buildMicrodataItem(e, ids, map[*html.Node]bool{}))
+	}
+	return out
+}
+
+// buildMicrodataItem returns the item for the element e, with an itemscope attribute.
+// visiting contains the items being built, to break itemref cycles.
+func buildMicrodataItem(e *html.Node, ids map[string]*html.Node, visiting map[*html.Node]bool) *MicrodataItem {
+	visiting[e] = true
+	defer delete(visiting, e)
+
+	item := &MicrodataItem{
+		Type:       SplitTokens(getAttr(e, "itemtype")),
+		ID:         strings.TrimSpace(getAttr(e, "itemid")),
+		Properties: map[string][]any{},
+	}
+	// the roots whose descendants are searched: the item and its references
+	roots := []*html.Node{e}
+	for _, ref := range SplitTokens(getAttr(e, "itemref")) {
+		if r := ids[ref]; r != nil {
+			roots = append(roots, r)
+		}
+	}
+
+	var visit func(p *html.Node)
+	addProperty := func(p *html.Node) {
+		for _, name := range SplitTokens(getAttr(p, "itemprop")) {
+			var value any
+			if hasAttr(FromHTML(p), "itemscope") {
+				if visiting[p] {
+					continue // a cycle
+				}
+				value = buildMicrodataItem(p, ids, visiting)
+			} else {
+				value = microdataValue(p)
+			}
+			item.Properties[name] = append(item.Properties[name], value)
+		}
+	}
+	visit = func(p *html.Node) {
+		for c := p.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			if hasAttr(FromHTML(c), "itemprop") {
+				addProperty(c)
+			}
+			// the properties of nested items belong to them
+			if !hasAttr(FromHTML(c), "itemscope") {
+				visit(c)
+			}
+		}
+	}
+	for i, r := range roots {
+		if i > 0 && hasAttr(FromHTML(r), "itemprop") { // a referenced element may be a property
+			addProperty(r)
+		}
+		if i == 0 || !hasAttr(FromHTML(r), "itemscope") {
+			visit(r)
+		}
+	}
+	return item
+}
+
+// microdataValue returns the value of the property element p,
+// which is not an item.
+func microdataValue(p *html.Node) string {
+	switch p.Data {
+	case "meta":
+		return getAttr(p, "content")
+	case "audio", "embed", "iframe", "img", "source", "track", "video":
+		return getAttr(p, "src")
+	case "a", "area", "link":
+		return getAttr(p, "href")
+	case "object":
+		return getAttr(p, "data")
+	case "data", "meter":
+		return getAttr(p, "value")
+	case "time":
+		if hasAttr(FromHTML(p), "datetime") {
+			return getAttr(p, "datetime")
+		}
+	}
+	return nodeText(FromHTML(p))
+}
+
+// RDFaProperty is a property expressed with the RDFa attributes.
+type RDFaProperty struct {
+	Property string // one token of the property attribute, like og:title or schema:name
+	Value    string
+	// Subject is the about attribute of the element or, if absent, the about
+	// (or resource) attribute of the nearest ancestor defining one,
+	// or empty for the document.
+	Subject string
+	Type    []string // the typeof tokens of the same element, if any
+}
+
+// RDFa returns the properties defined with the property
+// attribute in the descendants of n, in tree order.
+//
+// The value is given by the content attribute, then by the
+// href, src and resource attributes, then by the text content.
+func RDFa(n *html.Node) []RDFaProperty {
+	var out []RDFaProperty
+	for _, e := range QueryAll(n, rdfaProperties) {
+		value, ok := "", false
+		for _, key := range [...]string{"content", "href", "src", "resource"} {
+			if hasAttr(FromHTML(e), key) {
+				value, ok = getAttr(e, key), true
+				break
+			}
+		}
+		if !ok {
+			value = nodeText(FromHTML(e))
+		}
+		subject := ""
+		for p := e; p != nil; p = p.Parent {
+			if p.Type != html.ElementNode {
+				continue
+			}
+			if hasAttr(FromHTML(p), "about") {
+				subject = getAttr(p, "about")
+				break
+			}
+			// the resource of e itself is its value
+			if p != e && hasAttr(FromHTML(p), "resource") {
+				subject = getAttr(p, "resource")
+				break
+			}
+		}
+		types := SplitTokens(getAttr(e, "typeof"))
+		for _, property := range SplitTokens(getAttr(e, "property")) {
+			out = append(out, RDFaProperty{Property: property, Value: value, Subject: subject, Type: types})
+		}
+	}
+	return out
+}
+
+// OpenGraph returns the Open Graph properties of the document n,
+// given by the <meta property="og:..." content="..."> tags. The values
+// of repeated properties (like og:image) are kept in order.
+func OpenGraph(n *html.Node) map[string][]string {
+	return metaProperties(n, openGraphMeta, "og:", "property")
+}
+
+// TwitterCard returns the Twitter card properties of the document n,
+// given by the <meta name="twitter:..." content="..."> tags (the property
+// attribute is also accepted).
+func TwitterCard(n *html.Node) map[string][]string {
+	return metaProperties(n, twitterMeta, "twitter:", "name", "property")
+}
+
+func metaProperties(n *html.Node, m Matcher, prefix string, keys ...string) map[string][]string {
+	out := map[string][]string{}
+	for _, e := range QueryAll(n, m) {
+		for _, key := range keys {
+			if name := getAttr(e, key); strings.HasPrefix(name, prefix) {
+				out[name] = append(out[name], getAttr(e, "content"))
+				break
+			}
+		}
+	}
+	return out
+}
+
+// getAttr returns the value of the attribute key of n,
+// or an empty string
+func getAttr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
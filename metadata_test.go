@@ -0,0 +1,110 @@
+package cascadia
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMicrodata(t *testing.T) {
+	doc := MustParseHTML(`
+	<div itemscope itemtype="https://schema.org/Movie" itemid="urn:1" itemref="extra">
+		<h1 itemprop="name">Avatar</h1>
+		<span>Director: <span itemprop="director" itemscope itemtype="https://schema.org/Person">
+			<span itemprop="name">James Cameron</span> <time itemprop="birthDate" datetime="1954-08-16">August 16, 1954</time>
+		</span></span>
+		<a href="/trailer" itemprop="trailer url">Trailer</a>
+		<img itemprop="image" src="a.jpg">
+		<meta itemprop="duration" content="PT2H42M">
+		<data itemprop="rating" value="8">eight</data>
+		<time itemprop="year">2009</time>
+	</div>
+	<p id="extra" itemprop="genre">Science fiction</p>
+	<div itemscope><span itemprop="a b">x</span></div>
+	<div itemprop="orphan" itemscope></div>`)
+
+	items := Microdata(doc)
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(items))
+	}
+	movie := items[0]
+	if !reflect.DeepEqual(movie.Type, []string{"https://schema.org/Movie"}) || movie.ID != "urn:1" {
+		t.Errorf("unexpected item %v", movie)
+	}
+	for name, exp := range map[string][]any{
+		"name":     {"Avatar"},
+		"trailer":  {"/trailer"},
+		"url":      {"/trailer"},
+		"image":    {"a.jpg"},
+		"duration": {"PT2H42M"},
+		"rating":   {"8"},
+		"year":     {"2009"},
+		"genre":    {"Science fiction"},
+	} {
+		if got := movie.Properties[name]; !reflect.DeepEqual(got, exp) {
+			t.Errorf("%s: expected %v, got %v", name, exp, got)
+		}
+	}
+	director, ok := movie.Properties["director"][0].(*MicrodataItem)
+	if !ok {
+		t.Fatalf("expected a nested item, got %v", movie.Properties["director"])
+	}
+	if !reflect.DeepEqual(director.Properties, map[string][]any{"name": {"James Cameron"}, "birthDate": {"1954-08-16"}}) {
+		t.Errorf("unexpected nested item %v", director.Properties)
+	}
+	if len(movie.Properties) != 9 {
+		t.Errorf("unexpected properties %v", movie.Properties)
+	}
+	if got := items[1].Properties; !reflect.DeepEqual(got, map[string][]any{"a": {"x"}, "b": {"x"}}) {
+		t.Errorf("unexpected properties %v", got)
+	}
+}
+
+func TestMicrodataCycle(t *testing.T) {
+	doc := MustParseHTML(`<div id="a" itemscope itemref="b"><p itemprop="x">1</p></div><div id="b" itemprop="self" itemscope itemref="a"></div>`)
+	items := Microdata(doc)
+	if len(items) != 1 || len(items[0].Properties["x"]) != 1 {
+		t.Errorf("unexpected items %v", items)
+	}
+}
+
+func TestRDFa(t *testing.T) {
+	doc := MustParseHTML(`<div vocab="https://schema.org/" typeof="Person" about="#me">
+		<span property="name">Alice</span>
+		<a property="url sameAs" href="https://a.example">site</a>
+		<meta property="birthDate" content="1990-01-01">
+		<div property="address" typeof="PostalAddress" resource="#addr"><span property="streetAddress">1 Main St</span></div>
+		<span property="knows" about="#bob">Carol</span>
+	</div>`)
+	var got []RDFaProperty
+	got = append(got, RDFa(doc)...)
+	exp := []RDFaProperty{
+		{"name", "Alice", "#me", nil},
+		{"url", "https://a.example", "#me", nil},
+		{"sameAs", "https://a.example", "#me", nil},
+		{"birthDate", "1990-01-01", "#me", nil},
+		{"address", "#addr", "#me", []string{"PostalAddress"}},
+		{"streetAddress", "1 Main St", "#addr", nil},
+		{"knows", "Carol", "#bob", nil},
+	}
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected\n%v\ngot\n%v", exp, got)
+	}
+}
+
+func TestSocialMeta(t *testing.T) {
+	doc := MustParseHTML(`<html><head>
+		<meta property="og:title" content="Title">
+		<meta property="og:image" content="1.png">
+		<meta property="og:image" content="2.png">
+		<meta property="og:empty">
+		<meta name="twitter:card" content="summary">
+		<meta name="description" property="twitter:site" content="@site">
+		<meta name="og:wrong" content="x">
+	</head></html>`)
+	if got, exp := OpenGraph(doc), map[string][]string{"og:title": {"Title"}, "og:image": {"1.png", "2.png"}}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected %v, got %v", exp, got)
+	}
+	if got, exp := TwitterCard(doc), map[string][]string{"twitter:card": {"summary"}, "twitter:site": {"@site"}}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected %v, got %v", exp, got)
+	}
+}
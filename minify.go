@@ -0,0 +1,61 @@
+package cascadia
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Minify returns the shortest CSS input compiling to a selector equivalent to sel:
+// whitespaces around combinators and redundant universal selectors are dropped,
+// quotes are omitted when possible and :nth-* arguments are written
+// in their shortest form (like `odd` or `even`).
+func Minify(sel Sel) string {
+	return Format(sel, FormatOptions{Minify: true})
+}
+
+// MinifyGroup is like Minify for a group of selectors.
+func MinifyGroup(group SelectorGroup) string {
+	return FormatGroup(group, FormatOptions{Minify: true})
+}
+
+// isIdentifier returns true if s may be written without quotes
+// (and without escapes).
+func isIdentifier(s string) bool {
+	if strings.HasPrefix(s, "-") {
+		s = s[1:]
+	}
+	if s == "" || !nameStart(s[0]) {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if !nameChar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// minifyNth returns the shortest form of an+b
+func minifyNth(a, b int) string {
+	switch {
+	case a == 2 && b == 1:
+		return "odd"
+	case a == 0:
+		return strconv.Itoa(b)
+	}
+	var s string
+	switch a {
+	case 1:
+		s = "n"
+	case -1:
+		s = "-n"
+	default:
+		s = strconv.Itoa(a) + "n"
+	}
+	if b > 0 {
+		s += "+" + strconv.Itoa(b)
+	} else if b < 0 {
+		s += strconv.Itoa(b)
+	}
+	return s
+}
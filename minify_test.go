@@ -0,0 +1,68 @@
+package cascadia
+
+import "testing"
+
+func TestMinify(t *testing.T) {
+	for _, test := range []struct {
+		input, expected string
+	}{
+		{"*", "*"},
+		{"*.a", ".a"},
+		{"div  >  p", "div>p"},
+		{"div p", "div p"},
+		{"a + b ~ c", "a+b~c"},
+		{`[href="foo"]`, "[href=foo]"},
+		{`[href="foo bar"]`, `[href="foo bar"]`},
+		{`[href='3']`, `[href="3"]`},
+		{":nth-child(2n+0)", ":nth-child(2n)"},
+		{":nth-child(even)", ":nth-child(2n)"},
+		{":nth-child(2n+1)", ":nth-child(odd)"},
+		{":nth-last-of-type(0n+3)", ":nth-last-of-type(3)"},
+		{":nth-child(1n-2)", ":nth-child(n-2)"},
+		{":nth-child(-n+2)", ":nth-child(-n+2)"},
+		{":nth-child(0n+1)", ":first-child"},
+		{"p:not( .a , .b )", "p:not(.a,.b)"},
+		{"p::before", "p:before"},
+		{"*::marker", "::marker"},
+		{`p:contains("x")`, "p:contains(x)"},
+		{`[a="x\"y\a z"]`, `[a="x\"y\a z"]`},
+	} {
+		sel, err := ParseGroupWithPseudoElements(test.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := MinifyGroup(sel)
+		if got != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.input, test.expected, got)
+		}
+	}
+}
+
+func TestMinifyRoundTrip(t *testing.T) {
+	var inputs []string
+	for _, test := range selectorTests {
+		inputs = append(inputs, test.selector)
+	}
+	for _, test := range loadValidSelectors(t) {
+		if !test.Xfail {
+			inputs = append(inputs, test.Selector)
+		}
+	}
+	for _, input := range inputs {
+		sel, err := ParseGroupWithPseudoElements(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		min := MinifyGroup(sel)
+		back, err := ParseGroupWithPseudoElements(min)
+		if err != nil {
+			t.Fatalf("%s: invalid minified output %s: %s", input, min, err)
+		}
+		if !EqualGroup(sel, back) {
+			t.Errorf("%s: minified output %s is not equivalent", input, min)
+		}
+		if len(min) > len(sel.String()) {
+			t.Errorf("%s: minified output %s longer than %s", input, min, sel)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package cascadia
+
+import (
+	"fmt"
+
+	"golang.org/x/net/html"
+)
+
+// NestingSelector implements the nesting selector &, of CSS Nesting,
+// which stands for the elements matched by the parent rule: use ResolveNesting
+// to replace it by the parent selectors. Unresolved, as in a top-level
+// rule, it is equivalent to :scope.
+// It is only accepted by the parser with Options.Nesting.
+type NestingSelector struct {
+	Pos    Span   // position in the source, only set by the span-recording parsing functions
+	source string // see SourceOf
+
+	scope Node // as for :scope
+}
+
+func (s NestingSelector) asScope() ScopePseudoClassSelector {
+	return ScopePseudoClassSelector{scope: s.scope}
+}
+
+func (s NestingSelector) Match(n *html.Node) bool { return s.asScope().Match(n) }
+
+func (s NestingSelector) MatchNode(n Node) bool { return matchScope(s.asScope(), n) }
+
+// Specificity returns the specificity of :scope, since once resolved,
+// & has the specificity of the parent selectors.
+func (s NestingSelector) Specificity() Specificity {
+	return Specificity{0, 1, 0}
+}
+
+func (s NestingSelector) PseudoElement() string {
+	return ""
+}
+
+// ResolveNesting returns the selector equivalent to nested, where
+// the nesting selectors & are replaced by the parent selectors, as in
+// "& > p" with the parent "ul, ol", which gives ":is(ul, ol) > p".
+// The compound selectors containing & are merged with a single parent
+// selector, as with And, so that "&.active" with the parent "li.item"
+// gives "li.item.active".
+//
+// nested is returned unchanged if it has no &. An error is returned
+// if one of the parent selectors has a pseudo-element, which &
+// can't represent.
+func ResolveNesting(nested Sel, parent SelectorGroup) (Sel, error) {
+	for _, sel := range parent {
+		if pe := sel.PseudoElement(); pe != "" {
+			return nil, fmt.Errorf("the nesting selector can't represent the pseudo-element ::%s of %s", pe, sel)
+		}
+	}
+	if !usesNesting(nested) {
+		return nested, nil
+	}
+	var replacement Sel
+	switch len(parent) {
+	case 0:
+		replacement = NeverMatchSelector{Value: ":not(*)"}
+	case 1:
+		replacement = parent[0]
+		if _, ok := replacement.(CombinedSelector); ok {
+			// a complex selector can't be written in place of &, as in "div &"
+			replacement = RelativePseudoClassSelector{Name: "is", Args: parent}
+		}
+	default:
+		replacement = RelativePseudoClassSelector{Name: "is", Args: parent}
+	}
+	return Transform(nested, func(s Sel) Sel {
+		switch s := s.(type) {
+		case NestingSelector:
+			return replacement
+		case CompoundSelector:
+			return mergeCompound(s)
+		}
+		return s
+	}), nil
+}
+
+// mergeCompound returns c, where the selectors which are not simple
+// (the replacements of &) are merged with And
+func mergeCompound(c CompoundSelector) Sel {
+	needed := false
+	for i, s := range c.Selectors {
+		switch s.(type) {
+		case CompoundSelector, CombinedSelector:
+			needed = true
+		case TagSelector:
+			needed = needed || i != 0
+		}
+	}
+	if !needed {
+		return c
+	}
+	ms := make([]Matcher, len(c.Selectors))
+	for i, s := range c.Selectors {
+		if _, ok := s.(CombinedSelector); ok {
+			s = RelativePseudoClassSelector{Name: "is", Args: SelectorGroup{s}}
+		}
+		ms[i] = s
+	}
+	merged := And(ms...).(Sel) // the selectors have no pseudo-element
+	if c.Pseudo == "" {
+		return merged
+	}
+	if compound, ok := merged.(CompoundSelector); ok {
+		compound.Pseudo = c.Pseudo
+		return compound
+	}
+	return CompoundSelector{Selectors: []Sel{merged}, Pseudo: c.Pseudo}
+}
+
+// ResolveNested returns the selectors of a rule nested in a rule whose
+// selectors are parent, following the desugaring of CSS Nesting:
+// the nested selectors without & are relative to the parent ones,
+// as if they started with "& " (the relative selectors like "> p" are
+// parsed with an explicit & by Options.Nesting), then each & is
+// replaced by the parent selectors, as with ResolveNesting. So,
+// the rule "a, b { & + p, span {} }" gives ":is(a, b) + p, :is(a, b) span".
+//
+// The parent selectors with a pseudo-element are ignored,
+// since they can't be represented by &.
+func ResolveNested(parent SelectorGroup, nested SelectorGroup) SelectorGroup {
+	parents := make(SelectorGroup, 0, len(parent))
+	for _, sel := range parent {
+		if sel.PseudoElement() == "" {
+			parents = append(parents, sel)
+		}
+	}
+	out := make(SelectorGroup, len(nested))
+	for i, sel := range nested {
+		if !usesNesting(sel) {
+			sel = prepend(NestingSelector{}, sel)
+		}
+		out[i], _ = ResolveNesting(sel, parents) // no pseudo-element in parents
+	}
+	return out
+}
+
+// prepend returns "first sel"
+func prepend(first, sel Sel) Sel {
+	if c, ok := sel.(CombinedSelector); ok && c.Second != nil {
+		c.First = prepend(first, c.First)
+		return c
+	}
+	return CombinedSelector{First: first, Combinator: ' ', Second: sel}
+}
+
+func usesNesting(sel Sel) bool {
+	found := false
+	Walk(sel, func(s Sel) bool {
+		if _, ok := s.(NestingSelector); ok {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
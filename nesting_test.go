@@ -0,0 +1,188 @@
+package cascadia
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNestingSelector(t *testing.T) {
+	for _, input := range []string{"&", "& > p", "&.active", "li&", "a:not(&)", ":is(&, div) ~ span", "& + &"} {
+		sel, err := ParseWithOptions(input, Options{Nesting: true})
+		if err != nil {
+			t.Fatalf("%s: %s", input, err)
+		}
+		if sel.String() != input {
+			t.Errorf("expected %s, got %s", input, sel)
+		}
+	}
+
+	if _, err := Parse("div & p"); err == nil {
+		t.Error("expected error without Options.Nesting")
+	}
+
+	// unresolved, & is :scope
+	doc := MustParseHTML(`<div id="a"><p>1</p><div><p>2</p></div></div>`)
+	a := Query(doc, MustParse("#a"))
+	if got := QueryAll(a, nested("& > p")); len(got) != 1 || nodeText(FromHTML(got[0])) != "1" {
+		t.Errorf("unexpected matches %v", got)
+	}
+	if got := QueryAll(doc, nested("&")); len(got) != 1 || got[0].Data != "html" {
+		t.Errorf("unexpected matches %v", got)
+	}
+
+	// JSON round trip
+	sel := nested("&.a > p")
+	b, err := MarshalSel(sel.(CombinedSelector).First)
+	if err != nil {
+		t.Fatal(err)
+	}
+	back, err := UnmarshalSel(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if back.String() != "&.a" {
+		t.Errorf("unexpected selector %s", back)
+	}
+}
+
+func TestResolveNesting(t *testing.T) {
+	for _, test := range []struct {
+		parent, nested, exp string
+	}{
+		{"ul", "& > li", "ul > li"},
+		{"ul, ol", "& > li", ":is(ul, ol) > li"},
+		{"li.item", "&.active", "li.item.active"},
+		{".item", "li&", "li.item"},
+		{"div", "span&", "span:is(div)"},
+		{"nav > a", "&:hover", ":is(nav > a):hover"},
+		{"a", "p > &", "p > a"},
+		{"a", "& + &", "a + a"},
+		{"a", ":not(&)", ":not(a)"},
+		{"a", "p", "p"},
+	} {
+		parent, err := ParseGroup(test.parent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ResolveNesting(nested(test.nested), parent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != test.exp {
+			t.Errorf("%s in %s: expected %s, got %s", test.nested, test.parent, test.exp, got)
+		}
+		if _, err := Parse(got.String()); err != nil {
+			t.Errorf("invalid result %s: %s", got, err)
+		}
+	}
+
+	// the resolved selector matches as the desugared one
+	doc := MustParseHTML(`<ul><li class="item active">1</li><li class="item">2</li></ul><ol><li class="active">3</li></ol>`)
+	resolved, _ := ResolveNesting(nested("& > li.active"), MustParseGroup("ul, ol"))
+	if got := QueryAll(doc, resolved); len(got) != 2 {
+		t.Errorf("unexpected matches %v", got)
+	}
+	if s := resolved.Specificity(); s != (Specificity{0, 1, 2}) {
+		t.Errorf("unexpected specificity %v", s)
+	}
+
+	if _, err := ResolveNesting(nested("&"), MustParseGroup("p")); err != nil {
+		t.Fatal(err)
+	}
+	pe, err := ParseGroupWithPseudoElements("p::before")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ResolveNesting(nested("& span"), pe); err == nil {
+		t.Error("expected error for a pseudo-element in the parent")
+	}
+}
+
+func nested(sel string) Sel {
+	out, err := ParseWithOptions(sel, Options{Nesting: true})
+	if err != nil {
+		panic(err)
+	}
+	return out
+}
+
+func TestResolveNested(t *testing.T) {
+	for _, test := range []struct {
+		parent, nested, exp string
+	}{
+		{"a, b", "& + p, span", ":is(a, b) + p, :is(a, b)   span"},
+		{".card", "> h2", ".card > h2"},
+		{".card", "h2 > em", ".card   h2 > em"},
+		{".card", "~ .card", ".card ~ .card"},
+		{".card", ".dark &", ".dark   .card"},
+		{"ul", "&.open > li, :hover", "ul.open > li, ul   :hover"},
+	} {
+		got := ResolveNested(MustParseGroup(test.parent), parseNestedGroup(test.nested))
+		if got.String() != test.exp {
+			t.Errorf("%s in %s: expected %s, got %s", test.nested, test.parent, test.exp, got)
+		}
+		if _, err := ParseGroup(got.String()); err != nil {
+			t.Errorf("invalid result %s: %s", got, err)
+		}
+	}
+
+	// a complex parent is wrapped in :is(), so that the serialization
+	// has the same meaning
+	doc := MustParseHTML(`<a id="a1"><div id="d1"><b id="b1"><p id="p1"></p></b></div></a><div id="d2"><a id="a2"><b id="b2"><p id="p2"></p></b></a></div>`)
+	ids := func(m Matcher) string {
+		var out []string
+		for _, n := range QueryAll(doc, m) {
+			out = append(out, nodeID(n))
+		}
+		return strings.Join(out, " ")
+	}
+	for _, test := range []struct {
+		parent, nested, exp string
+	}{
+		{"a b", "div &", "b1 b2"},
+		{"a b", "div > &", "b1"},
+		{"a > b", "div &.z, div & > p", "p2"},
+		{"a b", "&:not(:root) p", "p1 p2"},
+	} {
+		got := ResolveNested(MustParseGroup(test.parent), parseNestedGroup(test.nested))
+		reparsed, err := ParseGroup(got.String())
+		if err != nil {
+			t.Fatalf("invalid result %s: %s", got, err)
+		}
+		if ids(got) != test.exp || ids(reparsed) != test.exp {
+			t.Errorf("%s in %s (%s): expected %s, got %s and %s after round trip", test.nested, test.parent, got, test.exp, ids(got), ids(reparsed))
+		}
+	}
+	if got := ResolveNested(MustParseGroup("a b"), parseNestedGroup("div &")).String(); got != "div   :is(a   b)" {
+		t.Errorf("unexpected resolution %s", got)
+	}
+
+	// nested levels are resolved from the outermost
+	doc = MustParseHTML(`<nav><ul><li><a id="x">1</a></li></ul></nav><ul><li><a>2</a></li></ul>`)
+	level1 := ResolveNested(MustParseGroup("nav"), parseNestedGroup("> ul"))
+	level2 := ResolveNested(level1, parseNestedGroup("li a"))
+	if got := QueryAll(doc, level2); len(got) != 1 || nodeID(got[0]) != "x" {
+		t.Errorf("unexpected matches %v for %s", got, level2)
+	}
+
+	// the relative selectors are only accepted at the top level
+	if _, err := ParseWithOptions(":is(> p)", Options{Nesting: true}); err == nil {
+		t.Error("expected error for a relative selector in :is()")
+	}
+	// the parents with a pseudo-element are ignored
+	pe, err := ParseGroupWithPseudoElements("p::before, div")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ResolveNested(pe, parseNestedGroup("span")).String(); got != "div   span" {
+		t.Errorf("unexpected %s", got)
+	}
+}
+
+func parseNestedGroup(sel string) SelectorGroup {
+	out, err := ParseGroupWithOptions(sel, Options{Nesting: true})
+	if err != nil {
+		panic(err)
+	}
+	return out
+}
@@ -0,0 +1,147 @@
+package cascadia
+
+import (
+	"golang.org/x/net/html"
+)
+
+// Node is the minimal view of a document tree used by the matching engine,
+// which allows to use selectors on other trees than the ones of the
+// golang.org/x/net/html package (see FromHTML for the adapter of *html.Node).
+//
+// Nodes must be comparable : two values are equal if and only if they
+// represent the same node. The navigation methods must return
+// a nil interface (not a typed nil) when the node does not exist.
+type Node interface {
+	// Type returns the kind of the node. Only elements are matched by
+	// the selectors, but the other nodes are used by :empty, :contains(), ...
+	Type() html.NodeType
+	// Data returns the tag name for an element (lower-cased for HTML),
+	// and the content of a text node.
+	Data() string
+	// Namespace is the namespace of an element, which is empty for HTML.
+	Namespace() string
+	// Attributes returns the attributes of an element.
+	Attributes() []html.Attribute
+
+	Parent() Node
+	FirstChild() Node
+	LastChild() Node
+	PrevSibling() Node
+	NextSibling() Node
+}
+
+// NodeMatcher is implemented by the matchers which support
+// any Node. All the selectors of this package implement it.
+type NodeMatcher interface {
+	MatchNode(n Node) bool
+}
+
+// htmlNode is the Node adapter for *html.Node.
+// Its single pointer field avoids allocations when converted to Node.
+type htmlNode struct{ n *html.Node }
+
+// FromHTML returns the Node wrapping n, or nil if n is nil.
+func FromHTML(n *html.Node) Node {
+	if n == nil {
+		return nil
+	}
+	return htmlNode{n}
+}
+
+// ToHTML returns the *html.Node wrapped by n, or nil if
+// n was not built by FromHTML.
+func ToHTML(n Node) *html.Node {
+	if h, ok := n.(htmlNode); ok {
+		return h.n
+	}
+	return nil
+}
+
+func (h htmlNode) Type() html.NodeType          { return h.n.Type }
+func (h htmlNode) Data() string                 { return h.n.Data }
+func (h htmlNode) Namespace() string            { return h.n.Namespace }
+func (h htmlNode) Attributes() []html.Attribute { return h.n.Attr }
+func (h htmlNode) Parent() Node                 { return FromHTML(h.n.Parent) }
+func (h htmlNode) FirstChild() Node             { return FromHTML(h.n.FirstChild) }
+func (h htmlNode) LastChild() Node              { return FromHTML(h.n.LastChild) }
+func (h htmlNode) PrevSibling() Node            { return FromHTML(h.n.PrevSibling) }
+func (h htmlNode) NextSibling() Node            { return FromHTML(h.n.NextSibling) }
+
+// MatchNode returns true if m matches n. If m does not implement NodeMatcher,
+// it may only match the nodes returned by FromHTML.
+func MatchNode(m Matcher, n Node) bool {
+	if nm, ok := m.(NodeMatcher); ok {
+		return nm.MatchNode(n)
+	}
+	if h, ok := n.(htmlNode); ok {
+		return m.Match(h.n)
+	}
+	return false
+}
+
+// QueryNode returns the first node that matches m, from the descendants of n.
+// If none matches, it returns nil.
+func QueryNode(n Node, m NodeMatcher) Node {
+	return queryNode(n, bindRootNode(m, n))
+}
+
+func queryNode(n Node, m NodeMatcher) Node {
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		if m.MatchNode(c) {
+			return c
+		}
+		if matched := queryNode(c, m); matched != nil {
+			return matched
+		}
+	}
+	return nil
+}
+
+// QueryAllNodes returns all the nodes that match m, from the descendants of n.
+func QueryAllNodes(n Node, m NodeMatcher) []Node {
+	return queryNodesInto(n, bindRootNode(m, n), nil)
+}
+
+func queryNodesInto(n Node, m NodeMatcher, storage []Node) []Node {
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		if m.MatchNode(c) {
+			storage = append(storage, c)
+		}
+		storage = queryNodesInto(c, m, storage)
+	}
+	return storage
+}
+
+// IsElementSibling returns true if n is taken into account by the
+// sibling combinators '+' and '~', and the child-indexed pseudo-classes,
+// that is if n is an element: following the DOM, the text, comments,
+// doctypes and processing instructions are skipped.
+// Custom matchers walking the siblings of a node should use it, or
+// PrevElementSibling and NextElementSibling, to behave like the selectors.
+func IsElementSibling(n Node) bool { return isElementSibling(n) }
+
+// PrevElementSibling returns the closest preceding sibling of n for which
+// IsElementSibling is true, or nil.
+func PrevElementSibling(n Node) Node { return prevElementSibling(n) }
+
+// NextElementSibling returns the closest following sibling of n for which
+// IsElementSibling is true, or nil.
+func NextElementSibling(n Node) Node { return nextElementSibling(n) }
+
+func isElementSibling[T NodeLike[T]](n T) bool { return n.Type() == html.ElementNode }
+
+func prevElementSibling[T NodeLike[T]](n T) T {
+	c := n.PrevSibling()
+	for !isNil(c) && !isElementSibling(c) {
+		c = c.PrevSibling()
+	}
+	return c
+}
+
+func nextElementSibling[T NodeLike[T]](n T) T {
+	c := n.NextSibling()
+	for !isNil(c) && !isElementSibling(c) {
+		c = c.NextSibling()
+	}
+	return c
+}
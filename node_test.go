@@ -0,0 +1,157 @@
+package cascadia
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+// treeNode is a minimal Node implementation, unrelated to *html.Node
+type treeNode struct {
+	typ                 html.NodeType
+	data                string
+	attrs               []html.Attribute
+	parent, first, last *treeNode
+	prev, next          *treeNode
+	source              *html.Node // used to compare the results
+}
+
+// asNode avoids typed nil interfaces
+func asNode(t *treeNode) Node {
+	if t == nil {
+		return nil
+	}
+	return t
+}
+
+func (t *treeNode) Type() html.NodeType          { return t.typ }
+func (t *treeNode) Data() string                 { return t.data }
+func (t *treeNode) Namespace() string            { return "" }
+func (t *treeNode) Attributes() []html.Attribute { return t.attrs }
+func (t *treeNode) Parent() Node                 { return asNode(t.parent) }
+func (t *treeNode) FirstChild() Node             { return asNode(t.first) }
+func (t *treeNode) LastChild() Node              { return asNode(t.last) }
+func (t *treeNode) PrevSibling() Node            { return asNode(t.prev) }
+func (t *treeNode) NextSibling() Node            { return asNode(t.next) }
+
+// copyTree returns a copy of n as a treeNode
+func copyTree(n *html.Node) *treeNode {
+	out := &treeNode{typ: n.Type, data: n.Data, attrs: n.Attr, source: n}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		child := copyTree(c)
+		child.parent = out
+		if out.last == nil {
+			out.first = child
+		} else {
+			out.last.next, child.prev = child, out.last
+		}
+		out.last = child
+	}
+	return out
+}
+
+func TestMatchNode(t *testing.T) {
+	for _, test := range selectorTests {
+		doc, err := html.Parse(strings.NewReader(test.HTML))
+		if err != nil {
+			t.Fatal(err)
+		}
+		group, err := ParseGroup(test.selector)
+		if err != nil {
+			t.Fatal(err)
+		}
+		exp := QueryAll(doc, group)
+
+		got := QueryAllNodes(copyTree(doc), group)
+		if len(got) != len(exp) {
+			t.Errorf("%s: expected %d matches, got %d", test.selector, len(exp), len(got))
+			continue
+		}
+		for i, n := range got {
+			if n.(*treeNode).source != exp[i] {
+				t.Errorf("%s: unexpected match %d", test.selector, i)
+			}
+		}
+
+		// the adapter gives the same results
+		wrapped := QueryAllNodes(FromHTML(doc), group)
+		if len(wrapped) != len(exp) {
+			t.Errorf("%s: expected %d matches, got %d", test.selector, len(exp), len(wrapped))
+			continue
+		}
+		for i, n := range wrapped {
+			if ToHTML(n) != exp[i] {
+				t.Errorf("%s: unexpected match %d", test.selector, i)
+			}
+		}
+	}
+}
+
+func TestMatchNodeFallback(t *testing.T) {
+	doc := copyTree(MustParseHTML(`<p></p>`))
+	// Selector only implements Matcher
+	m := MustCompile("html")
+	if MatchNode(m, doc.first) {
+		t.Error("expected no match for a foreign node")
+	}
+	if !MatchNode(m, FromHTML(doc.source.FirstChild)) {
+		t.Error("expected match for an html node")
+	}
+	if FromHTML(nil) != nil || ToHTML(doc) != nil {
+		t.Error("unexpected adapter result")
+	}
+	if QueryNode(doc, CompoundSelector{}) != Node(doc.first) {
+		t.Error("expected the html element")
+	}
+}
+
+func TestElementSiblings(t *testing.T) {
+	div := &html.Node{Type: html.ElementNode, Data: "div"}
+	doc := &html.Node{Type: html.DocumentNode}
+	doc.AppendChild(div)
+	a := &html.Node{Type: html.ElementNode, Data: "a"}
+	b := &html.Node{Type: html.ElementNode, Data: "b"}
+	div.AppendChild(a)
+	for _, typ := range []html.NodeType{html.CommentNode, html.DoctypeNode, html.TextNode, html.RawNode} {
+		div.AppendChild(&html.Node{Type: typ, Data: "x"})
+	}
+	div.AppendChild(b)
+
+	if PrevElementSibling(FromHTML(b)) != FromHTML(a) || NextElementSibling(FromHTML(a)) != FromHTML(b) {
+		t.Error("unexpected element siblings")
+	}
+	if PrevElementSibling(FromHTML(a)) != nil || NextElementSibling(FromHTML(b)) != nil {
+		t.Error("expected no element sibling")
+	}
+	if IsElementSibling(FromHTML(div.FirstChild.NextSibling)) {
+		t.Error("a comment is not an element sibling")
+	}
+
+	for _, s := range []string{"a + b", "a ~ b", "a ~ *", ":not(b) + b"} {
+		sel, err := Parse(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		compiled, err := CompileFor[Node](sel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !sel.Match(b) || !compiled.MatchNode(FromHTML(b)) || !Explain(sel, b).Matched {
+			t.Errorf("%s should match", s)
+		}
+	}
+	for _, s := range []string{"b + b", "a + a", ":not(a) ~ b"} {
+		sel, err := Parse(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		compiled, err := CompileFor[Node](sel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sel.Match(b) || compiled.MatchNode(FromHTML(b)) || Explain(sel, b).Matched {
+			t.Errorf("%s should not match", s)
+		}
+	}
+}
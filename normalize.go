@@ -0,0 +1,137 @@
+package cascadia
+
+import (
+	"hash/fnv"
+	"sort"
+)
+
+// Normalize returns a canonical form of sel, so that selectors
+// which only differ by the order of their components
+// have the same canonical form (and serialization).
+// More precisely, it
+//   - sorts the components of compound selectors (type selector first, then ids,
+//     classes, attributes and pseudo-classes), keeping the duplicates, which
+//     increase the specificity, so that .a.a and .a stay distinct
+//   - sorts the arguments of relative pseudo-classes like :not(), removing duplicates
+//   - unwraps compound selectors made of only one component
+//
+// Use NormalizeGroup to also sort a group and remove its duplicate members.
+//
+// The returned selector is equivalent to sel (it matches the same elements,
+// with the same specificity).
+func Normalize(sel Sel) Sel {
+	return Transform(sel, func(s Sel) Sel {
+		switch s := s.(type) {
+		case CompoundSelector:
+			// duplicates are kept since they increase specificity
+			s.Selectors = sortSelectors(s.Selectors, true)
+			if len(s.Selectors) == 1 && s.Pseudo == "" {
+				return s.Selectors[0]
+			}
+			return s
+		case RelativePseudoClassSelector:
+			s.Args = sortSelectors(s.Args, false)
+			return s
+		}
+		return s
+	})
+}
+
+// NormalizeGroup applies Normalize to the selectors of group,
+// then sorts them, removing duplicates.
+func NormalizeGroup(group SelectorGroup) SelectorGroup {
+	out := make(SelectorGroup, len(group))
+	for i, s := range group {
+		out[i] = Normalize(s)
+	}
+	return sortSelectors(out, false)
+}
+
+// Sort returns a copy of s, sorted by increasing specificity, so that, as
+// in the cascade, the most specific selectors come last. The selectors
+// with the same specificity keep their order in s.
+func (s SelectorGroup) Sort() SelectorGroup {
+	out := append(SelectorGroup(nil), s...)
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Specificity().Less(out[j].Specificity())
+	})
+	return out
+}
+
+// Dedup returns a copy of s, without the selectors equal to a previous one,
+// as defined by EqualIgnoringOrder, so that "a.x.y, a.y.x" gives "a.x.y".
+// Contrary to NormalizeGroup, the order and the form of the selectors kept
+// are preserved.
+func (s SelectorGroup) Dedup() SelectorGroup {
+	var out SelectorGroup
+	for _, sel := range s {
+		duplicate := false
+		for _, kept := range out {
+			if EqualIgnoringOrder(kept, sel) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			out = append(out, sel)
+		}
+	}
+	return out
+}
+
+// Hash returns a hash of the canonical form of sel (see Normalize),
+// so that Hash(a) == Hash(b) if a and b have the same canonical form.
+// The hash is stable across programs, and may be used as a persistent key.
+func Hash(sel Sel) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(Normalize(sel).String()))
+	return h.Sum64()
+}
+
+// kindRank is used to sort the components of compound selectors
+func kindRank(s Sel) int {
+	switch s.(type) {
+	case TagSelector:
+		return 0
+	case IDSelector:
+		return 1
+	case ClassSelector:
+		return 2
+	case AttrSelector:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// sortSelectors returns a sorted copy of list.
+// If compound is true, the list is sorted by kind first, and duplicates are kept;
+// otherwise, it is sorted by serialization, without duplicates.
+func sortSelectors(list []Sel, compound bool) []Sel {
+	type keyed struct {
+		sel  Sel
+		rank int
+		key  string
+	}
+	tmp := make([]keyed, len(list))
+	for i, s := range list {
+		tmp[i] = keyed{sel: s, key: s.String()}
+		if compound {
+			tmp[i].rank = kindRank(s)
+		}
+	}
+	sort.SliceStable(tmp, func(i, j int) bool {
+		if tmp[i].rank != tmp[j].rank {
+			return tmp[i].rank < tmp[j].rank
+		}
+		return tmp[i].key < tmp[j].key
+	})
+	var out []Sel
+	for i, k := range tmp {
+		if !compound && i > 0 && tmp[i-1].key == k.key && Equal(tmp[i-1].sel, k.sel) {
+			continue
+		}
+		out = append(out, k.sel)
+	}
+	return out
+}
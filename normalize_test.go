@@ -0,0 +1,74 @@
+package cascadia
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	for _, test := range []struct {
+		a, b string
+	}{
+		{"div.b.a", "DIV.a.b"},
+		{".b#x.a", "#x.a.b"},
+		{"p.a.b.a", "p.b.a.a"},
+		{":not(.b, .a, .b)", ":not(.a, .b)"},
+		{"ul > li.b.a:first-child", "ul>li:first-child.a.b"},
+		{"ul > li.b.a:first-child", "ul>li:first-child.a.b"},
+		{"[b][a]", "[a][b]"},
+		{"a.x::before", "a.x:before"},
+	} {
+		a, err := ParseWithPseudoElement(test.a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ParseWithPseudoElement(test.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		na, nb := Normalize(a), Normalize(b)
+		if !Equal(na, nb) {
+			t.Errorf("%s and %s: expected same normal form, got %s and %s", test.a, test.b, na, nb)
+		}
+		if Hash(a) != Hash(b) {
+			t.Errorf("%s and %s: expected same hash", test.a, test.b)
+		}
+		if na.Specificity() != a.Specificity() {
+			t.Errorf("%s: normalization changed specificity", test.a)
+		}
+	}
+
+	a, _ := Parse("div.a")
+	b, _ := Parse("div.b")
+	if Hash(a) == Hash(b) {
+		t.Error("expected different hashes")
+	}
+
+	group, _ := ParseGroup("b, a.y.x, b, a.x.y")
+	if s := NormalizeGroup(group).String(); s != "a.x.y, b" {
+		t.Errorf("unexpected normalized group %s", s)
+	}
+}
+
+func TestSelectorGroupSortDedup(t *testing.T) {
+	group := MustParseGroup("#x, p, .a, div p, .b, a.x.y, a.y.x, p, #x")
+
+	sorted := group.Sort()
+	if got, exp := sorted.String(), "p, p, div   p, .a, .b, a.x.y, a.y.x, #x, #x"; got != exp {
+		t.Errorf("expected %s, got %s", exp, got)
+	}
+	if group[0].String() != "#x" {
+		t.Error("the group must not be modified")
+	}
+
+	deduped := group.Dedup()
+	if got, exp := deduped.String(), "#x, p, .a, div   p, .b, a.x.y"; got != exp {
+		t.Errorf("expected %s, got %s", exp, got)
+	}
+	if len(group) != 9 {
+		t.Error("the group must not be modified")
+	}
+	if got := deduped.Sort().Dedup(); len(got) != len(deduped) {
+		t.Errorf("unexpected length %d", len(got))
+	}
+	if got := SelectorGroup(nil).Sort().Dedup(); len(got) != 0 {
+		t.Errorf("unexpected group %v", got)
+	}
+}
@@ -0,0 +1,328 @@
+package cascadia
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+
+	"golang.org/x/net/html"
+)
+
+// offsetAttr is the attribute recording the start offset of
+// the elements while parsing, removed from the tree returned
+const offsetAttr = "data-cascadia-offset"
+
+// Offsets are the positions of the elements of a document in its source,
+// as returned by ParseHTMLWithOffsets.
+type Offsets struct {
+	source []byte
+	spans  map[*html.Node]elementSpan
+}
+
+type elementSpan struct {
+	span     Span
+	explicit bool
+}
+
+// ElementSpan is an element with its position in the source,
+// as returned by Offsets.QueryAll.
+type ElementSpan struct {
+	Node *html.Node
+	Span Span
+	// Explicit is false for the elements created by the parser
+	// without start tag, like an implicit <tbody>, whose Span
+	// is the one of their content.
+	Explicit bool
+}
+
+// ParseHTMLWithOffsets parses the HTML document r, as html.Parse does,
+// and records the byte offsets of its elements in the source, so that the
+// results of a query may be highlighted or patched in the raw text.
+//
+// The span of an element starts at the '<' of its start tag, and ends after
+// its end tag, or, if the end tag is omitted (as in <li>a<li>b), where the
+// element is closed implicitly.
+func ParseHTMLWithOffsets(r io.Reader) (*html.Node, *Offsets, error) {
+	source, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// tokenize the source, recording the tokens and inserting
+	// the offset of each start tag as an attribute
+	var (
+		tokens []offsetToken
+		marked bytes.Buffer
+		offset int
+		// the open foreign elements and HTML integration points, to
+		// tokenize the raw text elements (like <style>) as the parser does
+		foreign []string
+	)
+	z := html.NewTokenizer(bytes.NewReader(source))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if z.Err() != io.EOF {
+				return nil, nil, z.Err()
+			}
+			break
+		}
+		raw := append([]byte(nil), z.Raw()...)
+		tok := offsetToken{typ: tt, start: offset, end: offset + len(raw)}
+		offset = tok.end
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			tok.name = string(name)
+			nameEnd := 1 + len(name) // after "<name"
+			marked.Write(raw[:nameEnd])
+			marked.WriteString(" " + offsetAttr + `="` + strconv.Itoa(tok.start) + `"`)
+			marked.Write(raw[nameEnd:])
+			if tt == html.StartTagToken {
+				foreign = openForeign(foreign, tok.name)
+			}
+			if len(foreign) != 0 && isForeignRoot(foreign[len(foreign)-1]) {
+				z.NextIsNotRawText()
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			tok.name = string(name)
+			marked.Write(raw)
+			if len(foreign) != 0 && foreign[len(foreign)-1] == tok.name {
+				foreign = foreign[:len(foreign)-1]
+			}
+		default:
+			marked.Write(raw)
+		}
+		tokens = append(tokens, tok)
+	}
+
+	doc, err := html.Parse(&marked)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	out := &Offsets{source: source, spans: make(map[*html.Node]elementSpan)}
+	tokenIndex := make(map[int]int, len(tokens)) // start offset -> index
+	for i, tok := range tokens {
+		tokenIndex[tok.start] = i
+	}
+	starts := make(map[*html.Node]int) // index of the start tag of the explicit elements
+	claimed := make(map[int]bool)      // the start tags already given to an element
+	var elements []*html.Node          // in tree order
+	var collect func(n *html.Node)
+	collect = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			elements = append(elements, n)
+			for i, a := range n.Attr {
+				if a.Namespace == "" && a.Key == offsetAttr {
+					if start, err := strconv.Atoi(a.Val); err == nil {
+						// the elements cloned by the parser (like the formatting elements
+						// reopened by the adoption agency) copy the marker of the original,
+						// and a late <html> or <body> copies its attributes on the implied one
+						index, ok := tokenIndex[start]
+						if ok && !claimed[index] && !mergedMarker(n, source, tokens, index) {
+							starts[n] = index
+							claimed[index] = true
+						}
+					}
+					n.Attr = append(n.Attr[:i:i], n.Attr[i+1:]...)
+					break
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			collect(c)
+		}
+	}
+	collect(doc)
+
+	// limits[i] is the first start offset of the explicit elements
+	// following the subtree of elements[i], in tree order
+	limits := make([]int, len(elements))
+	subtreeEnd := make(map[*html.Node]int, len(elements)) // index after the last descendant
+	var measure func(n *html.Node, i int) int
+	measure = func(n *html.Node, i int) int {
+		next := i
+		if n.Type == html.ElementNode {
+			next++
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			next = measure(c, next)
+		}
+		if n.Type == html.ElementNode {
+			subtreeEnd[n] = next
+		}
+		return next
+	}
+	measure(doc, 0)
+
+	// firstStart[i] is the smallest start offset of the explicit elements[i:]
+	firstStart := make([]int, len(elements)+1)
+	firstStart[len(elements)] = len(source)
+	for i := len(elements) - 1; i >= 0; i-- {
+		firstStart[i] = firstStart[i+1]
+		if index, ok := starts[elements[i]]; ok && tokens[index].start < firstStart[i] {
+			firstStart[i] = tokens[index].start
+		}
+	}
+	for i, e := range elements {
+		limits[i] = firstStart[subtreeEnd[e]]
+	}
+
+	// explicit elements first, then the implicit ones, from their content
+	for i, e := range elements {
+		if index, ok := starts[e]; ok {
+			out.spans[e] = elementSpan{span: Span{tokens[index].start, elementEnd(e, index, tokens, limits[i])}, explicit: true}
+		}
+	}
+	for i := len(elements) - 1; i >= 0; i-- {
+		e := elements[i]
+		if _, ok := out.spans[e]; ok {
+			continue
+		}
+		span, found := Span{}, false
+		for c := e.FirstChild; c != nil; c = c.NextSibling {
+			if s, ok := out.spans[c]; ok {
+				if !found || s.span.Start < span.Start {
+					span.Start = s.span.Start
+				}
+				if !found || s.span.End > span.End {
+					span.End = s.span.End
+				}
+				found = true
+			}
+		}
+		if !found {
+			span = Span{limits[i], limits[i]}
+		}
+		out.spans[e] = elementSpan{span: span}
+	}
+	return doc, out, nil
+}
+
+// openForeign updates the stack of the open foreign elements
+// with the start tag name
+func openForeign(foreign []string, name string) []string {
+	switch {
+	case name == "svg" || name == "math":
+		return append(foreign, name)
+	case len(foreign) == 0:
+		return foreign
+	}
+	switch name {
+	case "foreignobject", "desc", "title", "annotation-xml", "mi", "mo", "mn", "ms", "mtext":
+		// back to HTML content, until the end tag
+		if isForeignRoot(foreign[len(foreign)-1]) {
+			return append(foreign, name)
+		}
+	}
+	return foreign
+}
+
+// isForeignRoot returns true for the entries of the foreign stack
+// starting a foreign content, false for the integration points
+func isForeignRoot(name string) bool { return name == "svg" || name == "math" }
+
+// mergedMarker returns true if the marker of the start tag tokens[index]
+// found on n was merged by the parser on the <html> or <body> element
+// implied by the content preceding the tag
+func mergedMarker(n *html.Node, source []byte, tokens []offsetToken, index int) bool {
+	if n.Namespace != "" {
+		return false
+	}
+	switch n.Data {
+	case "html":
+		// only the doctype, comments and whitespace may precede <html>
+		for _, tok := range tokens[:index] {
+			switch tok.typ {
+			case html.DoctypeToken, html.CommentToken:
+			case html.TextToken:
+				if len(bytes.TrimSpace(source[tok.start:tok.end])) != 0 {
+					return true
+				}
+			default:
+				return true
+			}
+		}
+	case "body":
+		// the content preceding <body> implies it
+		prefix, err := html.Parse(bytes.NewReader(source[:tokens[index].start]))
+		if err != nil {
+			return false
+		}
+		if body := Query(prefix, MustParse("body")); body != nil && body.FirstChild != nil {
+			return true
+		}
+	}
+	return false
+}
+
+type offsetToken struct {
+	typ        html.TokenType
+	start, end int
+	name       string // for the tags
+}
+
+// elementEnd returns the end offset of the element e, whose start tag is
+// tokens[index], and which is followed, in tree order, by an element
+// starting at limit
+func elementEnd(e *html.Node, index int, tokens []offsetToken, limit int) int {
+	start := tokens[index]
+	if start.typ == html.SelfClosingTagToken || (e.Namespace == "" && voidElements[e.Data]) {
+		return start.end
+	}
+	ancestors := map[string]bool{}
+	for p := e.Parent; p != nil; p = p.Parent {
+		if p.Type == html.ElementNode {
+			ancestors[p.Data] = true
+		}
+	}
+	depth := 0
+	end := start.end
+	for _, tok := range tokens[index+1:] {
+		if tok.start >= limit {
+			break
+		}
+		switch {
+		case tok.typ == html.StartTagToken && tok.name == start.name:
+			depth++
+		case tok.typ == html.EndTagToken && tok.name == start.name:
+			if depth == 0 {
+				return tok.end
+			}
+			depth--
+		case tok.typ == html.EndTagToken && ancestors[tok.name]:
+			return end // implicitly closed by the end of an ancestor
+		}
+		end = tok.end
+	}
+	return end
+}
+
+// Span returns the position of the element n in the source. explicit is
+// false for the elements created by the parser without start tag, whose
+// span is the one of their content, and for the nodes not returned
+// by ParseHTMLWithOffsets, whose span is empty.
+func (o *Offsets) Span(n *html.Node) (span Span, explicit bool) {
+	s := o.spans[n]
+	return s.span, s.explicit
+}
+
+// Source returns the text of the element n in the source.
+func (o *Offsets) Source(n *html.Node) string {
+	s := o.spans[n].span
+	return string(o.source[s.Start:s.End])
+}
+
+// QueryAll returns the descendants of n matched by m, with
+// their position in the source.
+func (o *Offsets) QueryAll(n *html.Node, m Matcher) []ElementSpan {
+	matches := QueryAll(n, m)
+	out := make([]ElementSpan, len(matches))
+	for i, match := range matches {
+		s := o.spans[match]
+		out[i] = ElementSpan{Node: match, Span: s.span, Explicit: s.explicit}
+	}
+	return out
+}
@@ -0,0 +1,164 @@
+package cascadia
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestParseHTMLWithOffsets(t *testing.T) {
+	const source = `<!DOCTYPE html><title>T</title>
+<DIV id="a" class=x>text <b>bold</b><img src=i.png><br/></DIV>
+<ul><li>1<li>2</ul>
+<div id="b"><div>inner</div> after</div>
+<table><tr><td>cell</table>
+<p>last`
+	doc, offsets, err := ParseHTMLWithOffsets(strings.NewReader(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		sel, want string
+	}{
+		{"title", "<title>T</title>"},
+		{"#a", `<DIV id="a" class=x>text <b>bold</b><img src=i.png><br/></DIV>`},
+		{"b", "<b>bold</b>"},
+		{"img", "<img src=i.png>"},
+		{"br", "<br/>"},
+		{"li:first-child", "<li>1"},
+		{"li:last-child", "<li>2"},
+		{"ul", "<ul><li>1<li>2</ul>"},
+		{"#b", `<div id="b"><div>inner</div> after</div>`},
+		{"#b > div", "<div>inner</div>"},
+		{"td", "<td>cell"},
+		{"tr", "<tr><td>cell"},
+		{"table", "<table><tr><td>cell</table>"},
+		{"p", "<p>last"},
+	} {
+		matches := offsets.QueryAll(doc, MustParse(test.sel))
+		if len(matches) != 1 {
+			t.Fatalf("%s: expected one match, got %d", test.sel, len(matches))
+		}
+		m := matches[0]
+		if got := source[m.Span.Start:m.Span.End]; got != test.want || !m.Explicit {
+			t.Errorf("%s: expected %q, got %q (explicit: %v)", test.sel, test.want, got, m.Explicit)
+		}
+		if got := offsets.Source(m.Node); got != test.want {
+			t.Errorf("%s: expected source %q, got %q", test.sel, test.want, got)
+		}
+	}
+
+	// the implicit <tbody> covers its content
+	tbody := Query(doc, MustParse("tbody"))
+	if span, explicit := offsets.Span(tbody); explicit || source[span.Start:span.End] != "<tr><td>cell" {
+		t.Errorf("unexpected tbody span %v (explicit: %v)", span, explicit)
+	}
+
+	// the offset markers are removed
+	if got := QueryAll(doc, MustParse("[data-cascadia-offset]")); len(got) != 0 {
+		t.Errorf("unexpected markers on %d elements", len(got))
+	}
+	if a := Query(doc, MustParse("#a")); len(a.Attr) != 2 {
+		t.Errorf("unexpected attributes %v", a.Attr)
+	}
+
+	// unknown nodes have no span
+	if span, explicit := offsets.Span(&html.Node{Type: html.ElementNode, Data: "div"}); explicit || span != (Span{}) {
+		t.Errorf("unexpected span %v", span)
+	}
+}
+
+func TestParseHTMLWithOffsetsTree(t *testing.T) {
+	// the tree is the one of html.Parse
+	source, err := os.ReadFile("test_ressources/shakespeare.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, offsets, err := ParseHTMLWithOffsets(bytes.NewReader(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := html.Parse(bytes.NewReader(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renderString(doc) != renderString(ref) {
+		t.Fatal("unexpected tree")
+	}
+
+	// the source of each explicit element starts with its tag, and
+	// parses to the same element
+	for _, m := range offsets.QueryAll(doc, MustParse("*")) {
+		if !m.Explicit {
+			continue
+		}
+		text := offsets.Source(m.Node)
+		if !strings.HasPrefix(strings.ToLower(text), "<"+m.Node.Data) {
+			t.Fatalf("unexpected source %q for <%s>", text, m.Node.Data)
+		}
+	}
+	for _, id := range []string{"speech16", "scene1"} {
+		n := Query(doc, MustParse("#"+id))
+		text := offsets.Source(n)
+		if !strings.HasSuffix(text, "</div>") {
+			t.Fatalf("unexpected source for #%s: %q", id, text)
+		}
+		fragment := MustParseHTML(text)
+		if got := Query(fragment, MustParse("#"+id)); got == nil || renderString(got) != renderString(n) {
+			t.Errorf("unexpected source for #%s", id)
+		}
+	}
+}
+
+func TestParseHTMLWithOffsetsRecovery(t *testing.T) {
+	// the adoption agency clones <b> in <p>: only the original has the offset
+	source := "<b><p>x</b>y</p>"
+	doc, offsets, err := ParseHTMLWithOffsets(strings.NewReader(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs := QueryAll(doc, MustParse("b"))
+	if len(bs) != 2 {
+		t.Fatalf("expected 2 <b>, got %d", len(bs))
+	}
+	if span, explicit := offsets.Span(bs[0]); !explicit || span.Start != 0 {
+		t.Errorf("unexpected span %v for the original <b> (explicit: %v)", span, explicit)
+	}
+	if span, explicit := offsets.Span(bs[1]); explicit || span.Start == 0 {
+		t.Errorf("unexpected span %v for the cloned <b> (explicit: %v)", span, explicit)
+	}
+
+	// a late <body> merges its attributes on the implied one
+	source = "<p>x</p><body class=late>"
+	doc, offsets, err = ParseHTMLWithOffsets(strings.NewReader(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, p := Query(doc, MustParse("body")), Query(doc, MustParse("p"))
+	bodySpan, explicit := offsets.Span(body)
+	pSpan, _ := offsets.Span(p)
+	if explicit || bodySpan.Start > pSpan.Start || bodySpan.End < pSpan.End {
+		t.Errorf("unexpected span %v for <body>, with <p> at %v (explicit: %v)", bodySpan, pSpan, explicit)
+	}
+	if span, explicit := offsets.Span(Query(doc, MustParse("html"))); explicit || span != bodySpan {
+		t.Errorf("unexpected span %v for <html>", span)
+	}
+
+	// <style> is not raw text in foreign content
+	source = "<svg><style><a>"
+	doc, offsets, err = ParseHTMLWithOffsets(strings.NewReader(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := Query(doc, MustParse("a"))
+	if a == nil {
+		t.Fatal("missing <a>")
+	}
+	if span, explicit := offsets.Span(a); !explicit || source[span.Start:span.End] != "<a>" {
+		t.Errorf("unexpected span %v for <a> (explicit: %v)", span, explicit)
+	}
+}
@@ -0,0 +1,123 @@
+package cascadia
+
+// Options configures ParseWithOptions and ParseGroupWithOptions.
+// The zero value gives the behavior of Parse and ParseGroup.
+type Options struct {
+	// PseudoElements enables the support of pseudo-elements, like ::before.
+	PseudoElements bool
+
+	// Spans records the position of each component in the `Pos` field
+	// of the AST types (see ParseWithSpans).
+	Spans bool
+
+	// Lenient parses each member of a group independently (see ParseGroupLenient).
+	// It is ignored by ParseWithOptions.
+	Lenient bool
+
+	// Limits bounds the size of the input (see ParseGroupWithLimits).
+	Limits Limits
+
+	// Strict rejects the extensions which are not supported by browsers,
+	// such as the attribute operators != and #=, :contains() or :haschild(),
+	// with an error of code ErrNonStandard. Deprecated but standard syntax,
+	// like the single colon in :before, is accepted.
+	Strict bool
+
+	// LegacyInclude restores the behavior of previous versions for [a~=v]
+	// when v is empty, which matched the values with consecutive or
+	// leading whitespace. By default, such selectors, as well as the
+	// ones whose value contains whitespace, never match, as required
+	// by the specification.
+	LegacyInclude bool
+
+	// FoldForeignAttributes matches the attribute names of the foreign
+	// elements (SVG and MathML) ignoring the ASCII case, as for the HTML
+	// elements. By default, they are compared case-sensitively with the
+	// name written in the selector, like browsers do, so that [viewBox]
+	// matches <svg viewBox="...">, but [viewbox] does not.
+	FoldForeignAttributes bool
+
+	// Quirks matches the class and id selectors ignoring the ASCII case,
+	// as browsers do for the documents rendered in quirks mode (without
+	// a doctype, typically).
+	Quirks bool
+
+	// Nesting enables the nesting selector & of CSS Nesting, used in the
+	// rules nested in other rules (see NestingSelector and ResolveNested).
+	// The selectors starting with a combinator are accepted as well, with
+	// an implicit & before it, so that "> p" is parsed as "& > p".
+	Nesting bool
+
+	// RelaxedHas accepts, for experimental use, the :has() pseudo-classes
+	// nested in the arguments of :has(), and the pseudo-elements in its
+	// arguments, which are rejected by default, as required by the
+	// specification (with the error codes ErrNestedHas and ErrPseudoElement).
+	RelaxedHas bool
+
+	// NodePseudoElements enables the non-standard ::text and ::comment
+	// pseudo-elements, selecting the text node (respectively comment)
+	// children of the elements matched, as in "p::text", so that the query
+	// functions return these nodes (see also QueryAllText).
+	// It also enables ::attr(name), as in "a::attr(href)", which restricts
+	// the elements matched to the ones with the attribute, whose name
+	// is returned by AttrPseudoElement.
+	// They are accepted even if PseudoElements is false.
+	NodePseudoElements bool
+}
+
+func (opts Options) newParser(sel string) *parser {
+	return &parser{
+		s:                     sel,
+		acceptPseudoElements:  opts.PseudoElements,
+		recordSpans:           opts.Spans,
+		limits:                opts.Limits,
+		strict:                opts.Strict,
+		legacyInclude:         opts.LegacyInclude,
+		foldForeignAttributes: opts.FoldForeignAttributes,
+		quirks:                opts.Quirks,
+		nesting:               opts.Nesting,
+		relaxedHas:            opts.RelaxedHas,
+		nodePseudoElements:    opts.NodePseudoElements,
+	}
+}
+
+// ParseWithOptions parses a single selector, as configured by opts.
+func ParseWithOptions(sel string, opts Options) (Sel, error) {
+	p := opts.newParser(sel)
+	if err := p.checkLength(); err != nil {
+		return nil, err
+	}
+	compiled, err := p.parseSelector()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = p.checkLeftOver(); err != nil {
+		return nil, err
+	}
+
+	return compiled, nil
+}
+
+// ParseGroupWithOptions parses a selector, or a group of selectors separated by commas,
+// as configured by opts.
+// In lenient mode, the valid members are returned even if an error occurred.
+func ParseGroupWithOptions(sel string, opts Options) (SelectorGroup, error) {
+	p := opts.newParser(sel)
+	if err := p.checkLength(); err != nil {
+		return nil, err
+	}
+	if opts.Lenient {
+		return p.parseGroupLenient()
+	}
+	compiled, err := p.parseSelectorGroup()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = p.checkLeftOver(); err != nil {
+		return nil, err
+	}
+
+	return compiled, nil
+}
@@ -0,0 +1,204 @@
+package cascadia
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseWithOptions(t *testing.T) {
+	if _, err := ParseWithOptions("p::before", Options{}); !errors.Is(err, ErrPseudoElement) {
+		t.Errorf("expected pseudo-element error, got %v", err)
+	}
+	sel, err := ParseWithOptions("p::before", Options{PseudoElements: true, Spans: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sel.PseudoElement() != "before" || SpanOf(sel) != (Span{0, 9}) {
+		t.Errorf("unexpected selector %s (%v)", sel, SpanOf(sel))
+	}
+	// Lenient is ignored
+	if _, err := ParseWithOptions("a, b", Options{Lenient: true}); !errors.Is(err, ErrTrailingInput) {
+		t.Errorf("expected trailing input error, got %v", err)
+	}
+	if _, err := ParseWithOptions(":not(:not(a))", Options{Limits: Limits{MaxDepth: 1}}); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("expected a limit error, got %v", err)
+	}
+}
+
+func TestParseGroupWithOptions(t *testing.T) {
+	group, err := ParseGroupWithOptions("a, p:nope, b::after", Options{PseudoElements: true, Lenient: true})
+	var groupErr *GroupError
+	if !errors.As(err, &groupErr) || len(groupErr.Members) != 1 || groupErr.Members[0].Index != 1 {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(group) != 2 || group[1].PseudoElement() != "after" {
+		t.Errorf("unexpected group %s", group)
+	}
+
+	// limits apply in lenient mode
+	_, err = ParseGroupWithOptions("a, b, c", Options{Lenient: true, Limits: Limits{MaxGroupSize: 2}})
+	if !errors.Is(err, ErrLimitExceeded) || err.(*ParseError).Offset != 4 {
+		t.Errorf("expected a limit error at 4, got %v", err)
+	}
+
+	// the wrappers are equivalent
+	for _, input := range []string{"div > p.a, li:not([title])", "a::before"} {
+		exp, expErr := ParseGroupWithPseudoElements(input)
+		got, gotErr := ParseGroupWithOptions(input, Options{PseudoElements: true})
+		if !reflect.DeepEqual(exp, got) || (expErr == nil) != (gotErr == nil) {
+			t.Errorf("%s: unexpected result %v %v", input, got, gotErr)
+		}
+	}
+}
+
+func TestStrict(t *testing.T) {
+	strict := Options{PseudoElements: true, Strict: true}
+	for _, input := range []string{
+		"div > p.a", "a[href^=http]", "li:nth-child(2n+1):not(.x)", "p:is(a, b)", "p:has(a)",
+		"p::before", "p:before", "a:hover", "input:checked",
+	} {
+		if _, err := ParseGroupWithOptions(input, strict); err != nil {
+			t.Errorf("%s: unexpected error %s", input, err)
+		}
+	}
+	for _, test := range []struct {
+		input  string
+		offset int
+	}{
+		{"a[href!=x]", 6},
+		{"a[href#=x.*]", 6},
+		{"p:contains(x)", 1},
+		{"p:containsOwn(x)", 1},
+		{"p:matches(x)", 1},
+		{"div:haschild(a)", 3},
+		{":input", 0},
+		{"a, :not(p:containsown(x))", 9},
+		{"p:marker", 1},
+	} {
+		_, err := ParseGroupWithOptions(test.input, strict)
+		if !errors.Is(err, ErrNonStandard) {
+			t.Errorf("%s: expected a non-standard error, got %v", test.input, err)
+			continue
+		}
+		if offset := err.(*ParseError).Offset; offset != test.offset {
+			t.Errorf("%s: expected error at %d, got %d", test.input, test.offset, offset)
+		}
+		if _, err := ParseGroupWithOptions(test.input, Options{PseudoElements: true}); err != nil {
+			t.Errorf("%s: unexpected error in default mode: %s", test.input, err)
+		}
+	}
+}
+
+func TestIncludeWhitespace(t *testing.T) {
+	doc := MustParseHTML(`<p class="a  b">1</p><p class="a b ">2</p><p class="a b">3</p>`)
+	for _, sel := range []string{`[class~=""]`, `[class~="a b"]`, `[class~="a  b"]`, `[class~="a\9 b"]`} {
+		compiled, err := Parse(sel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := len(QueryAll(doc, compiled)); n != 0 {
+			t.Errorf("%s: expected no match, got %d", sel, n)
+		}
+		if xp, err := ToXPath(compiled); err != nil || xp != "descendant-or-self::*[false()]" {
+			t.Errorf("%s: unexpected XPath %q (%v)", sel, xp, err)
+		}
+		// the specificity is the one of the attribute selector
+		if s := compiled.Specificity(); s != (Specificity{0, 1, 0}) {
+			t.Errorf("%s: unexpected specificity %v", sel, s)
+		}
+	}
+	if n := len(QueryAll(doc, MustCompile(`[class~=b]`))); n != 3 {
+		t.Errorf("expected 3 matches, got %d", n)
+	}
+
+	// the previous behavior
+	sel, err := ParseWithOptions(`[class~=""]`, Options{LegacyInclude: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(QueryAll(doc, sel)); n != 1 {
+		t.Errorf("expected 1 legacy match, got %d", n)
+	}
+}
+
+func TestForeignAttributes(t *testing.T) {
+	doc := MustParseHTML(`<div DATA-X="1"></div><svg viewBox="0 0 1 1"><path fill="red"/></svg>`)
+	for _, test := range []struct {
+		sel     string
+		opts    Options
+		matches int
+	}{
+		{"[data-x], [DATA-x]", Options{}, 1},
+		{"[viewBox]", Options{}, 1},
+		{"[viewbox]", Options{}, 0},
+		{"[VIEWBOX]", Options{}, 0},
+		{"[viewbox]", Options{FoldForeignAttributes: true}, 1},
+		{"[fill=red]", Options{}, 1},
+		{"[FILL=red]", Options{}, 0},
+		{"[FILL=red]", Options{FoldForeignAttributes: true}, 1},
+	} {
+		group, err := ParseGroupWithOptions(test.sel, test.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := len(QueryAll(doc, group)); n != test.matches {
+			t.Errorf("%s (%+v): expected %d matches, got %d", test.sel, test.opts, test.matches, n)
+		}
+		var b BulkMatcher
+		for _, sel := range group {
+			b.AddSelector(sel)
+		}
+		if n := len(QueryAll(doc, &b)); n != test.matches {
+			t.Errorf("%s (%+v): expected %d bulk matches, got %d", test.sel, test.opts, test.matches, n)
+		}
+	}
+	// the case is kept by the serialization
+	if s := mustParseGroup(t, "svg[viewBox]").String(); s != "svg[viewBox]" {
+		t.Errorf("unexpected serialization %s", s)
+	}
+}
+
+func TestQuirks(t *testing.T) {
+	doc := MustParseHTML(`<p id="Main" class="Note big">a</p><p class="note">b</p>`)
+	for _, test := range []struct {
+		sel              string
+		standard, quirks int
+	}{
+		{".note", 1, 2},
+		{"p.NOTE.BIG", 0, 1},
+		{"#main", 0, 1},
+		{"#Main", 1, 1},
+		{"[class~=note]", 1, 1}, // attribute selectors are not affected
+	} {
+		for _, quirks := range []bool{false, true} {
+			group, err := ParseGroupWithOptions(test.sel, Options{Quirks: quirks})
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := test.standard
+			if quirks {
+				expected = test.quirks
+			}
+			if n := len(QueryAll(doc, group)); n != expected {
+				t.Errorf("%s (quirks: %v): expected %d matches, got %d", test.sel, quirks, expected, n)
+			}
+			// the indexes
+			var (
+				b  BulkMatcher
+				rs RuleSet
+			)
+			for _, sel := range group {
+				b.AddSelector(sel)
+			}
+			rs.AddGroup(0, group)
+			count := 0
+			for _, p := range QueryAll(doc, MustCompile("p")) {
+				count += len(rs.RulesFor(p))
+			}
+			if n := len(QueryAll(doc, &b)); n != expected || count != expected {
+				t.Errorf("%s (quirks: %v): expected %d indexed matches, got %d and %d", test.sel, quirks, expected, n, count)
+			}
+		}
+	}
+}
@@ -2,11 +2,10 @@
 package cascadia
 
 import (
-	"errors"
-	"fmt"
 	"regexp"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 // a parser for CSS selectors
@@ -17,19 +16,101 @@ type parser struct {
 	// if `false`, parsing a pseudo-element
 	// returns an error.
 	acceptPseudoElements bool
+
+	// if `true`, the positions of the components
+	// are stored in the AST
+	recordSpans bool
+
+	// if `true`, non fatal issues are stored in diagnostics
+	collectDiagnostics bool
+	diagnostics        []Diagnostic
+
+	// if `true`, errors don't report the token found,
+	// which is used by the tokenizer to avoid recursion
+	tokenizing bool
+
+	limits Limits
+	depth  int // current nesting of functional pseudo-classes
+
+	// if `true`, non-standard extensions are rejected
+	strict bool
+
+	// if `true`, the ~= selectors which can't match are kept (see Options.LegacyInclude)
+	legacyInclude bool
+
+	// if `true`, the attribute names of the foreign elements are case-insensitive
+	foldForeignAttributes bool
+
+	// if `true`, the class and id selectors are case-insensitive
+	quirks bool
+
+	// if `true`, the nesting selector & is accepted
+	nesting bool
+
+	// if `true`, the pseudo-elements ::text, ::comment and ::attr() are accepted
+	nodePseudoElements bool
+
+	// if `true`, :has() may be nested, and contain pseudo-elements
+	relaxedHas bool
+	inHas      int // current nesting of :has()
+}
+
+// span returns the span from start to the current position,
+// or an empty span if positions are not recorded.
+func (p *parser) span(start int) Span {
+	if !p.recordSpans {
+		return Span{}
+	}
+	return Span{Start: start, End: p.i}
+}
+
+// withSource records the source text of sel, if positions are recorded
+func (p *parser) withSource(sel Sel) Sel {
+	if !p.recordSpans {
+		return sel
+	}
+	span := SpanOf(sel)
+	return withSource(sel, p.s[span.Start:span.End])
+}
+
+// checkLeftOver returns an error if the input is not fully consumed,
+// ignoring trailing whitespace and comments.
+func (p *parser) checkLeftOver() error {
+	p.skipWhitespace()
+	if p.i < len(p.s) {
+		return p.errorf(ErrTrailingInput, expectEnd, "unexpected %q after the selector", leftOverText(p.s[p.i:]))
+	}
+	return nil
+}
+
+// maxLeftOverText is the number of bytes of the unconsumed
+// input quoted by the trailing input errors
+const maxLeftOverText = 32
+
+// leftOverText returns the beginning of the unconsumed input s,
+// truncated on a character boundary.
+func leftOverText(s string) string {
+	if len(s) <= maxLeftOverText {
+		return s
+	}
+	end := maxLeftOverText
+	for end > 0 && !utf8.RuneStart(s[end]) {
+		end--
+	}
+	return s[:end] + "..."
 }
 
 // parseEscape parses a backslash escape.
 func (p *parser) parseEscape() (result string, err error) {
 	if len(p.s) < p.i+2 || p.s[p.i] != '\\' {
-		return "", errors.New("invalid escape sequence")
+		return "", p.errorf(ErrInvalidEscape, nil, "invalid escape sequence")
 	}
 
 	start := p.i + 1
 	c := p.s[start]
 	switch {
 	case c == '\r' || c == '\n' || c == '\f':
-		return "", errors.New("escaped line ending outside string")
+		return "", p.errorf(ErrInvalidEscape, nil, "escaped line ending outside string")
 	case hexDigit(c):
 		// unicode escape (hex)
 		var i int
@@ -104,11 +185,12 @@ func (p *parser) parseIdentifier() (result string, err error) {
 	}
 
 	if len(p.s) <= p.i {
-		return "", errors.New("expected identifier, found EOF instead")
+		return "", p.errorf(ErrUnexpectedEOF, expectIdent, "expected identifier, found EOF instead")
 	}
 
-	if c := p.s[p.i]; !(nameStart(c) || c == '\\') {
-		return "", fmt.Errorf("expected identifier, found %c instead", c)
+	// per CSS Syntax Level 3, "--" may start an identifier
+	if c := p.s[p.i]; !(nameStart(c) || c == '\\' || (startingDash && c == '-')) {
+		return "", p.errorf(ErrUnexpectedToken, expectIdent, "expected identifier, found %c instead", c)
 	}
 
 	result, err = p.parseName()
@@ -146,7 +228,7 @@ loop:
 	}
 
 	if result == "" {
-		return "", errors.New("expected name, found EOF instead")
+		return "", p.errorf(ErrUnexpectedEOF, expectName, "expected name, found EOF instead")
 	}
 
 	p.i = i
@@ -157,7 +239,7 @@ loop:
 func (p *parser) parseString() (result string, err error) {
 	i := p.i
 	if len(p.s) < i+2 {
-		return "", errors.New("expected string, found EOF instead")
+		return "", p.errorf(ErrUnexpectedEOF, expectString, "expected string, found EOF instead")
 	}
 
 	quote := p.s[i]
@@ -190,7 +272,7 @@ loop:
 		case quote:
 			break loop
 		case '\r', '\n', '\f':
-			return "", errors.New("unexpected end of line in string")
+			return "", p.errorf(ErrUnterminatedString, expectString, "unexpected end of line in string")
 		default:
 			start := i
 			for i < len(p.s) {
@@ -204,7 +286,7 @@ loop:
 	}
 
 	if i >= len(p.s) {
-		return "", errors.New("EOF in string")
+		return "", p.errorf(ErrUnterminatedString, expectString, "EOF in string")
 	}
 
 	// Consume the final quote.
@@ -219,7 +301,7 @@ loop:
 func (p *parser) parseRegex() (rx *regexp.Regexp, err error) {
 	i := p.i
 	if len(p.s) < i+2 {
-		return nil, errors.New("expected regular expression, found EOF instead")
+		return nil, p.errorf(ErrUnexpectedEOF, expectRegexp, "expected regular expression, found EOF instead")
 	}
 
 	// number of open parens or brackets;
@@ -241,11 +323,18 @@ loop:
 	}
 
 	if i >= len(p.s) {
-		return nil, errors.New("EOF in regular expression")
+		return nil, p.errorf(ErrInvalidRegexp, expectRegexp, "EOF in regular expression")
+	}
+	expr := p.s[p.i:i]
+	if err = p.checkRegexp(expr, p.i); err != nil {
+		return nil, err
+	}
+	rx, err = regexp.Compile(expr)
+	if err != nil {
+		return nil, p.wrapError(ErrInvalidRegexp, err)
 	}
-	rx, err = regexp.Compile(p.s[p.i:i])
 	p.i = i
-	return rx, err
+	return rx, nil
 }
 
 // skipWhitespace consumes whitespace characters and comments.
@@ -302,7 +391,7 @@ func (p *parser) consumeClosingParenthesis() bool {
 }
 
 // parseTypeSelector parses a type selector (one that matches by tag name).
-func (p *parser) parseTypeSelector() (result tagSelector, err error) {
+func (p *parser) parseTypeSelector() (result TagSelector, err error) {
 	tag, err := p.parseIdentifier()
 	if err != nil {
 		return
@@ -311,83 +400,85 @@ func (p *parser) parseTypeSelector() (result tagSelector, err error) {
 }
 
 // parseIDSelector parses a selector that matches by id attribute.
-func (p *parser) parseIDSelector() (idSelector, error) {
+func (p *parser) parseIDSelector() (IDSelector, error) {
 	if p.i >= len(p.s) {
-		return idSelector{}, fmt.Errorf("expected id selector (#id), found EOF instead")
+		return IDSelector{}, p.errorf(ErrUnexpectedEOF, expectID, "expected id selector (#id), found EOF instead")
 	}
 	if p.s[p.i] != '#' {
-		return idSelector{}, fmt.Errorf("expected id selector (#id), found '%c' instead", p.s[p.i])
+		return IDSelector{}, p.errorf(ErrUnexpectedToken, expectID, "expected id selector (#id), found '%c' instead", p.s[p.i])
 	}
 
 	p.i++
 	id, err := p.parseName()
 	if err != nil {
-		return idSelector{}, err
+		return IDSelector{}, err
 	}
 
-	return idSelector{id: id}, nil
+	return IDSelector{ID: id, quirks: p.quirks}, nil
 }
 
 // parseClassSelector parses a selector that matches by class attribute.
-func (p *parser) parseClassSelector() (classSelector, error) {
+func (p *parser) parseClassSelector() (ClassSelector, error) {
 	if p.i >= len(p.s) {
-		return classSelector{}, fmt.Errorf("expected class selector (.class), found EOF instead")
+		return ClassSelector{}, p.errorf(ErrUnexpectedEOF, expectClass, "expected class selector (.class), found EOF instead")
 	}
 	if p.s[p.i] != '.' {
-		return classSelector{}, fmt.Errorf("expected class selector (.class), found '%c' instead", p.s[p.i])
+		return ClassSelector{}, p.errorf(ErrUnexpectedToken, expectClass, "expected class selector (.class), found '%c' instead", p.s[p.i])
 	}
 
 	p.i++
 	class, err := p.parseIdentifier()
 	if err != nil {
-		return classSelector{}, err
+		return ClassSelector{}, err
 	}
 
-	return classSelector{class: class}, nil
+	return ClassSelector{Class: class, quirks: p.quirks}, nil
 }
 
 // parseAttributeSelector parses a selector that matches by attribute value.
-func (p *parser) parseAttributeSelector() (attrSelector, error) {
+func (p *parser) parseAttributeSelector() (AttrSelector, error) {
+	start := p.i
 	if p.i >= len(p.s) {
-		return attrSelector{}, fmt.Errorf("expected attribute selector ([attribute]), found EOF instead")
+		return AttrSelector{}, p.errorf(ErrUnexpectedEOF, expectAttr, "expected attribute selector ([attribute]), found EOF instead")
 	}
 	if p.s[p.i] != '[' {
-		return attrSelector{}, fmt.Errorf("expected attribute selector ([attribute]), found '%c' instead", p.s[p.i])
+		return AttrSelector{}, p.errorf(ErrUnexpectedToken, expectAttr, "expected attribute selector ([attribute]), found '%c' instead", p.s[p.i])
 	}
 
 	p.i++
 	p.skipWhitespace()
 	key, err := p.parseIdentifier()
 	if err != nil {
-		return attrSelector{}, err
+		return AttrSelector{}, err
 	}
-	key = toLowerASCII(key)
+	key, rawKey := attrKeys(key)
 
 	p.skipWhitespace()
 	if p.i >= len(p.s) {
-		return attrSelector{}, errors.New("unexpected EOF in attribute selector")
+		return AttrSelector{}, p.errorf(ErrUnexpectedEOF, expectAttrOperator, "unexpected EOF in attribute selector")
 	}
 
 	if p.s[p.i] == ']' {
 		p.i++
-		return attrSelector{key: key, operation: ""}, nil
+		return AttrSelector{Key: key, Operation: "", rawKey: rawKey, foldForeignCase: p.foldForeignAttributes}, nil
 	}
 
 	if p.i+2 >= len(p.s) {
-		return attrSelector{}, errors.New("unexpected EOF in attribute selector")
+		return AttrSelector{}, p.errorf(ErrUnexpectedEOF, expectAttrOperator, "unexpected EOF in attribute selector")
 	}
 
+	opStart := p.i
 	op := p.s[p.i : p.i+2]
 	if op[0] == '=' {
 		op = "="
 	} else if op[1] != '=' {
-		return attrSelector{}, fmt.Errorf(`expected equality operator, found "%s" instead`, op)
+		return AttrSelector{}, p.errorf(ErrUnexpectedToken, expectAttrOperator, `expected equality operator, found "%s" instead`, op)
 	}
 	p.i += len(op)
 
 	p.skipWhitespace()
 	if p.i >= len(p.s) {
-		return attrSelector{}, errors.New("unexpected EOF in attribute selector")
+		return AttrSelector{}, p.errorf(ErrUnexpectedEOF, expectAttrValue, "unexpected EOF in attribute selector")
 	}
 	var val string
 	var rx *regexp.Regexp
@@ -402,31 +493,59 @@ func (p *parser) parseAttributeSelector() (attrSelector, error) {
 		}
 	}
 	if err != nil {
-		return attrSelector{}, err
+		return AttrSelector{}, err
 	}
 
 	p.skipWhitespace()
 	if p.i >= len(p.s) {
-		return attrSelector{}, errors.New("unexpected EOF in attribute selector")
+		return AttrSelector{}, p.errorf(ErrUnexpectedEOF, expectCloseBracket, "unexpected EOF in attribute selector")
 	}
 	if p.s[p.i] != ']' {
-		return attrSelector{}, fmt.Errorf("expected ']', found '%c' instead", p.s[p.i])
+		return AttrSelector{}, p.errorf(ErrUnexpectedToken, expectCloseBracket, "expected ']', found '%c' instead", p.s[p.i])
 	}
 	p.i++
 
 	switch op {
 	case "=", "!=", "~=", "|=", "^=", "$=", "*=", "#=":
-		return attrSelector{key: key, val: val, operation: op, regexp: rx}, nil
+		if msg := nonStandardOperator(op); p.strict && msg != "" {
+			return AttrSelector{}, p.errorAt(ErrNonStandard, opStart, nil, "%s", msg)
+		}
+		out := AttrSelector{
+			Key: key, Val: val, Operation: op, Regexp: rx,
+			legacyInclude: p.legacyInclude && op == "~=",
+			rawKey:        rawKey, foldForeignCase: p.foldForeignAttributes,
+		}
+		if p.collectDiagnostics {
+			p.checkAttribute(start, out)
+		}
+		return out, nil
 	default:
-		return attrSelector{}, fmt.Errorf("attribute operator %q is not supported", op)
+		return AttrSelector{}, p.errorAt(ErrUnsupportedOperator, opStart, expectAttrOperator, "attribute operator %q is not supported", op)
 	}
 }
 
-var (
-	errExpectedParenthesis        = errors.New("expected '(' but didn't find it")
-	errExpectedClosingParenthesis = errors.New("expected ')' but didn't find it")
-	errUnmatchedParenthesis       = errors.New("unmatched '('")
-)
+// pseudoClasses are the supported pseudo-classes, mapped to
+// `true` if they take arguments. It must be kept in sync
+// with parsePseudoclassSelector.
+var pseudoClasses = map[string]bool{
+	"not": true, "has": true, "haschild": true, "is": true, "where": true, "contains": true, "containsown": true,
+	"matches": true, "matchesown": true, "nth-child": true, "nth-last-child": true,
+	"nth-of-type": true, "nth-last-of-type": true, "lang": true,
+	"first-child": false, "last-child": false, "first-of-type": false, "last-of-type": false,
+	"only-child": false, "only-of-type": false, "input": false, "empty": false, "root": false, "scope": false,
+	"link": false, "enabled": false, "disabled": false, "checked": false,
+	"visited": false, "hover": false, "active": false, "focus": false, "target": false,
+	"current": false, "past": false, "future": false, "state": true,
+}
+
+// attributeOperators are the supported attribute operators
+var attributeOperators = []string{"=", "~=", "|=", "^=", "$=", "*=", "!=", "#="}
+
+// pseudoElements are the supported pseudo-elements
+var pseudoElements = map[string]bool{
+	"after": true, "backdrop": true, "before": true, "cue": true, "first-letter": true, "first-line": true,
+	"grammar-error": true, "marker": true, "placeholder": true, "selection": true, "spelling-error": true,
+}
 
 // parsePseudoclassSelector parses a pseudoclass selector like :not(p) or a pseudo-element
 // For backwards compatibility, both ':' and '::' prefix are allowed for pseudo-elements.
@@ -434,16 +553,17 @@ var (
 // Returning a nil `Sel` (and a nil `error`) means we found a pseudo-element.
 func (p *parser) parsePseudoclassSelector() (out Sel, pseudoElement string, err error) {
 	if p.i >= len(p.s) {
-		return nil, "", fmt.Errorf("expected pseudoclass selector (:pseudoclass), found EOF instead")
+		return nil, "", p.errorf(ErrUnexpectedEOF, expectPseudo, "expected pseudoclass selector (:pseudoclass), found EOF instead")
 	}
 	if p.s[p.i] != ':' {
-		return nil, "", fmt.Errorf("expected attribute selector (:pseudoclass), found '%c' instead", p.s[p.i])
+		return nil, "", p.errorf(ErrUnexpectedToken, expectPseudo, "expected attribute selector (:pseudoclass), found '%c' instead", p.s[p.i])
 	}
 
+	start := p.i
 	p.i++
 	var mustBePseudoElement bool
 	if p.i >= len(p.s) {
-		return nil, "", fmt.Errorf("got empty pseudoclass (or pseudoelement)")
+		return nil, "", p.errorf(ErrUnexpectedToken, expectIdent, "got empty pseudoclass (or pseudoelement)")
 	}
 	if p.s[p.i] == ':' { // we found a pseudo-element
 		mustBePseudoElement = true
@@ -455,33 +575,62 @@ func (p *parser) parsePseudoclassSelector() (out Sel, pseudoElement string, err
 		return
 	}
 	name = toLowerASCII(name)
-	if mustBePseudoElement && (name != "after" && name != "backdrop" && name != "before" &&
-		name != "cue" && name != "first-letter" && name != "first-line" && name != "grammar-error" &&
-		name != "marker" && name != "placeholder" && name != "selection" && name != "spelling-error") {
-		return out, "", fmt.Errorf("unknown pseudoelement :%s", name)
+	_, isExtension := nodePseudoElement(name)
+	isExtension = (isExtension || name == "attr") && mustBePseudoElement && p.nodePseudoElements
+	if mustBePseudoElement && !pseudoElements[name] && !isExtension {
+		return out, "", p.errorAt(ErrUnknownPseudo, start, nil, "unknown pseudoelement :%s", name)
+	}
+	if msg := nonStandardPseudo(name, mustBePseudoElement); p.strict && msg != "" {
+		return out, "", p.errorAt(ErrNonStandard, start, nil, "%s", msg)
+	}
+	if p.collectDiagnostics {
+		defer func() {
+			if err == nil {
+				p.checkPseudo(start, name, mustBePseudoElement)
+			}
+		}()
+	}
+
+	if isExtension && name == "attr" {
+		pseudoElement, err = p.parseAttrPseudoElement()
+		return nil, pseudoElement, err
 	}
 
 	switch name {
-	case "not", "has", "haschild":
+	case "not", "has", "haschild", "is", "where":
+		if name == "has" && p.inHas > 0 && !p.relaxedHas {
+			return out, "", p.errorAt(ErrNestedHas, start, nil, ":has() can't be nested in :has()")
+		}
 		if !p.consumeParenthesis() {
-			return out, "", errExpectedParenthesis
+			return out, "", p.errorf(ErrUnexpectedToken, expectOpenParen, "expected '(' but didn't find it")
+		}
+		if p.depth++; p.limits.MaxDepth > 0 && p.depth > p.limits.MaxDepth {
+			p.depth--
+			return out, "", p.errorAt(ErrLimitExceeded, start, nil, "nesting of functional pseudo-classes exceeds %d", p.limits.MaxDepth)
+		}
+		if name == "has" {
+			p.inHas++
 		}
 		sel, parseErr := p.parseSelectorGroup()
+		if name == "has" {
+			p.inHas--
+		}
+		p.depth--
 		if parseErr != nil {
 			return out, "", parseErr
 		}
 		if !p.consumeClosingParenthesis() {
-			return out, "", errExpectedClosingParenthesis
+			return out, "", p.errorf(ErrUnexpectedToken, expectCloseParen, "expected ')' but didn't find it")
 		}
 
-		out = relativePseudoClassSelector{name: name, match: sel}
+		out = RelativePseudoClassSelector{Name: name, Args: sel}
 
 	case "contains", "containsown":
 		if !p.consumeParenthesis() {
-			return out, "", errExpectedParenthesis
+			return out, "", p.errorf(ErrUnexpectedToken, expectOpenParen, "expected '(' but didn't find it")
 		}
 		if p.i == len(p.s) {
-			return out, "", errUnmatchedParenthesis
+			return out, "", p.errorf(ErrUnexpectedToken, expectArgument, "unmatched '('")
 		}
 		var val string
 		switch p.s[p.i] {
@@ -496,72 +645,74 @@ func (p *parser) parsePseudoclassSelector() (out Sel, pseudoElement string, err
 		val = strings.ToLower(val)
 		p.skipWhitespace()
 		if p.i >= len(p.s) {
-			return out, "", errors.New("unexpected EOF in pseudo selector")
+			return out, "", p.errorf(ErrUnexpectedEOF, expectCloseParen, "unexpected EOF in pseudo selector")
 		}
 		if !p.consumeClosingParenthesis() {
-			return out, "", errExpectedClosingParenthesis
+			return out, "", p.errorf(ErrUnexpectedToken, expectCloseParen, "expected ')' but didn't find it")
 		}
 
-		out = containsPseudoClassSelector{own: name == "containsown", value: val}
+		out = ContainsPseudoClassSelector{Own: name == "containsown", Value: val}
 
 	case "matches", "matchesown":
 		if !p.consumeParenthesis() {
-			return out, "", errExpectedParenthesis
+			return out, "", p.errorf(ErrUnexpectedToken, expectOpenParen, "expected '(' but didn't find it")
 		}
 		rx, err := p.parseRegex()
 		if err != nil {
 			return out, "", err
 		}
 		if p.i >= len(p.s) {
-			return out, "", errors.New("unexpected EOF in pseudo selector")
+			return out, "", p.errorf(ErrUnexpectedEOF, expectCloseParen, "unexpected EOF in pseudo selector")
 		}
 		if !p.consumeClosingParenthesis() {
-			return out, "", errExpectedClosingParenthesis
+			return out, "", p.errorf(ErrUnexpectedToken, expectCloseParen, "expected ')' but didn't find it")
 		}
 
-		out = regexpPseudoClassSelector{own: name == "matchesown", regexp: rx}
+		out = RegexpPseudoClassSelector{Own: name == "matchesown", Regexp: rx}
 
 	case "nth-child", "nth-last-child", "nth-of-type", "nth-last-of-type":
 		if !p.consumeParenthesis() {
-			return out, "", errExpectedParenthesis
+			return out, "", p.errorf(ErrUnexpectedToken, expectOpenParen, "expected '(' but didn't find it")
 		}
 		a, b, err := p.parseNth()
 		if err != nil {
 			return out, "", err
 		}
 		if !p.consumeClosingParenthesis() {
-			return out, "", errExpectedClosingParenthesis
+			return out, "", p.errorf(ErrUnexpectedToken, expectCloseParen, "expected ')' but didn't find it")
 		}
 		last := name == "nth-last-child" || name == "nth-last-of-type"
 		ofType := name == "nth-of-type" || name == "nth-last-of-type"
-		out = nthPseudoClassSelector{a: a, b: b, last: last, ofType: ofType}
+		out = NthPseudoClassSelector{A: a, B: b, Last: last, OfType: ofType}
 
 	case "first-child":
-		out = nthPseudoClassSelector{a: 0, b: 1, ofType: false, last: false}
+		out = NthPseudoClassSelector{A: 0, B: 1, OfType: false, Last: false}
 	case "last-child":
-		out = nthPseudoClassSelector{a: 0, b: 1, ofType: false, last: true}
+		out = NthPseudoClassSelector{A: 0, B: 1, OfType: false, Last: true}
 	case "first-of-type":
-		out = nthPseudoClassSelector{a: 0, b: 1, ofType: true, last: false}
+		out = NthPseudoClassSelector{A: 0, B: 1, OfType: true, Last: false}
 	case "last-of-type":
-		out = nthPseudoClassSelector{a: 0, b: 1, ofType: true, last: true}
+		out = NthPseudoClassSelector{A: 0, B: 1, OfType: true, Last: true}
 	case "only-child":
-		out = onlyChildPseudoClassSelector{ofType: false}
+		out = OnlyChildPseudoClassSelector{OfType: false}
 	case "only-of-type":
-		out = onlyChildPseudoClassSelector{ofType: true}
+		out = OnlyChildPseudoClassSelector{OfType: true}
 	case "input":
-		out = inputPseudoClassSelector{}
+		out = InputPseudoClassSelector{}
 	case "empty":
-		out = emptyElementPseudoClassSelector{}
+		out = EmptyElementPseudoClassSelector{}
 	case "root":
-		out = rootPseudoClassSelector{}
+		out = RootPseudoClassSelector{}
+	case "scope":
+		out = ScopePseudoClassSelector{}
 	case "link":
-		out = linkPseudoClassSelector{}
+		out = LinkPseudoClassSelector{}
 	case "lang":
 		if !p.consumeParenthesis() {
-			return out, "", errExpectedParenthesis
+			return out, "", p.errorf(ErrUnexpectedToken, expectOpenParen, "expected '(' but didn't find it")
 		}
 		if p.i == len(p.s) {
-			return out, "", errUnmatchedParenthesis
+			return out, "", p.errorf(ErrUnexpectedToken, expectArgument, "unmatched '('")
 		}
 		val, err := p.parseIdentifier()
 		if err != nil {
@@ -570,29 +721,92 @@ func (p *parser) parsePseudoclassSelector() (out Sel, pseudoElement string, err
 		val = strings.ToLower(val)
 		p.skipWhitespace()
 		if p.i >= len(p.s) {
-			return out, "", errors.New("unexpected EOF in pseudo selector")
+			return out, "", p.errorf(ErrUnexpectedEOF, expectCloseParen, "unexpected EOF in pseudo selector")
 		}
 		if !p.consumeClosingParenthesis() {
-			return out, "", errExpectedClosingParenthesis
+			return out, "", p.errorf(ErrUnexpectedToken, expectCloseParen, "expected ')' but didn't find it")
 		}
-		out = langPseudoClassSelector{lang: val}
+		out = LangPseudoClassSelector{Lang: val}
 	case "enabled":
-		out = enabledPseudoClassSelector{}
+		out = EnabledPseudoClassSelector{}
 	case "disabled":
-		out = disabledPseudoClassSelector{}
+		out = DisabledPseudoClassSelector{}
 	case "checked":
-		out = checkedPseudoClassSelector{}
+		out = CheckedPseudoClassSelector{}
 	case "visited", "hover", "active", "focus", "target":
 		// Not applicable in a static context: never match.
-		out = neverMatchSelector{value: ":" + name}
-	case "after", "backdrop", "before", "cue", "first-letter", "first-line", "grammar-error", "marker", "placeholder", "selection", "spelling-error":
-		return nil, name, nil
+		out = NeverMatchSelector{Value: ":" + name}
+	case "past", "future":
+		out = TimePseudoClassSelector{Name: name}
+	case "current":
+		if !p.consumeParenthesis() {
+			out = TimePseudoClassSelector{Name: name}
+			break
+		}
+		if p.depth++; p.limits.MaxDepth > 0 && p.depth > p.limits.MaxDepth {
+			p.depth--
+			return out, "", p.errorAt(ErrLimitExceeded, start, nil, "nesting of functional pseudo-classes exceeds %d", p.limits.MaxDepth)
+		}
+		sel, parseErr := p.parseSelectorGroup()
+		p.depth--
+		if parseErr != nil {
+			return out, "", parseErr
+		}
+		if !p.consumeClosingParenthesis() {
+			return out, "", p.errorf(ErrUnexpectedToken, expectCloseParen, "expected ')' but didn't find it")
+		}
+		out = TimePseudoClassSelector{Name: name, Args: sel}
+	case "state":
+		if !p.consumeParenthesis() {
+			return out, "", p.errorf(ErrUnexpectedToken, expectOpenParen, "expected '(' but didn't find it")
+		}
+		if p.i == len(p.s) {
+			return out, "", p.errorf(ErrUnexpectedToken, expectArgument, "unmatched '('")
+		}
+		val, err := p.parseIdentifier()
+		if err != nil {
+			return out, "", err
+		}
+		p.skipWhitespace()
+		if p.i >= len(p.s) {
+			return out, "", p.errorf(ErrUnexpectedEOF, expectCloseParen, "unexpected EOF in pseudo selector")
+		}
+		if !p.consumeClosingParenthesis() {
+			return out, "", p.errorf(ErrUnexpectedToken, expectCloseParen, "expected ')' but didn't find it")
+		}
+		out = CustomStatePseudoClassSelector{State: val}
 	default:
-		return out, "", fmt.Errorf("unknown pseudoclass or pseudoelement :%s", name)
+		if pseudoElements[name] || isExtension {
+			return nil, name, nil
+		}
+		return out, "", p.errorAt(ErrUnknownPseudo, start, nil, "unknown pseudoclass or pseudoelement :%s", name)
 	}
 	return
 }
 
+// parseAttrPseudoElement parses the argument of the ::attr(name) pseudo-element,
+// and returns the pseudo-element, with the name lower-cased.
+func (p *parser) parseAttrPseudoElement() (string, error) {
+	if !p.consumeParenthesis() {
+		return "", p.errorf(ErrUnexpectedToken, expectOpenParen, "expected '(' but didn't find it")
+	}
+	if p.i == len(p.s) {
+		return "", p.errorf(ErrUnexpectedToken, expectArgument, "unmatched '('")
+	}
+	name, err := p.parseIdentifier()
+	if err != nil {
+		return "", err
+	}
+	p.skipWhitespace()
+	if p.i >= len(p.s) {
+		return "", p.errorf(ErrUnexpectedEOF, expectCloseParen, "unexpected EOF in pseudo selector")
+	}
+	if !p.consumeClosingParenthesis() {
+		return "", p.errorf(ErrUnexpectedToken, expectCloseParen, "expected ')' but didn't find it")
+	}
+	return "attr(" + EscapeIdent(toLowerASCII(name)) + ")", nil
+}
+
 // parseInteger parses a  decimal integer.
 func (p *parser) parseInteger() (int, error) {
 	i := p.i
@@ -601,13 +815,13 @@ func (p *parser) parseInteger() (int, error) {
 		i++
 	}
 	if i == start {
-		return 0, errors.New("expected integer, but didn't find it")
+		return 0, p.errorf(ErrUnexpectedToken, expectInteger, "expected integer, but didn't find it")
 	}
 	p.i = i
 
 	val, err := strconv.Atoi(p.s[start:i])
 	if err != nil {
-		return 0, err
+		return 0, p.wrapError(ErrInvalidNumber, err)
 	}
 
 	return val, nil
@@ -644,7 +858,7 @@ func (p *parser) parseNth() (a, b int, err error) {
 		if id == "even" {
 			return 2, 0, nil
 		}
-		return 0, 0, fmt.Errorf("expected 'odd' or 'even', but found '%s' instead", id)
+		return 0, 0, p.errorf(ErrInvalidNth, expectNth, "expected 'odd' or 'even', but found '%s' instead", id)
 	default:
 		goto invalid
 	}
@@ -728,10 +942,10 @@ readN:
 	}
 
 eof:
-	return 0, 0, errors.New("unexpected EOF while attempting to parse expression of form an+b")
+	return 0, 0, p.errorf(ErrUnexpectedEOF, expectNth, "unexpected EOF while attempting to parse expression of form an+b")
 
 invalid:
-	return 0, 0, errors.New("unexpected character while attempting to parse expression of form an+b")
+	return 0, 0, p.errorf(ErrInvalidNth, expectNth, "unexpected character while attempting to parse expression of form an+b")
 }
 
 // parseSimpleSelectorSequence parses a selector sequence that applies to
@@ -740,9 +954,10 @@ func (p *parser) parseSimpleSelectorSequence() (Sel, error) {
 	var selectors []Sel
 
 	if p.i >= len(p.s) {
-		return nil, errors.New("expected selector, found EOF instead")
+		return nil, p.errorf(ErrUnexpectedEOF, expectSelector, "expected selector, found EOF instead")
 	}
 
+	start := p.i
 	switch p.s[p.i] {
 	case '*':
 		// It's the universal selector. Just skip over it, since it doesn't affect the meaning.
@@ -753,11 +968,14 @@ func (p *parser) parseSimpleSelectorSequence() (Sel, error) {
 	case '#', '.', '[', ':':
 		// There's no type selector. Wait to process the other till the main loop.
 	default:
+		if p.nesting && p.s[p.i] == '&' {
+			break // same as above
+		}
 		r, err := p.parseTypeSelector()
 		if err != nil {
 			return nil, err
 		}
-		selectors = append(selectors, r)
+		selectors = append(selectors, withSpan(r, p.span(start)))
 	}
 
 	var pseudoElement string
@@ -767,6 +985,7 @@ loop:
 			ns               Sel
 			newPseudoElement string
 			err              error
+			simpleStart      = p.i
 		)
 		switch p.s[p.i] {
 		case '#':
@@ -777,6 +996,12 @@ loop:
 			ns, err = p.parseAttributeSelector()
 		case ':':
 			ns, newPseudoElement, err = p.parsePseudoclassSelector()
+		case '&':
+			if !p.nesting {
+				break loop
+			}
+			p.i++
+			ns = NestingSelector{}
 		default:
 			break loop
 		}
@@ -789,44 +1014,58 @@ loop:
 		// represents the subjects of the selector.""
 		if ns == nil { // we found a pseudo-element
 			if pseudoElement != "" {
-				return nil, fmt.Errorf("only one pseudo-element is accepted per selector, got %s and %s", pseudoElement, newPseudoElement)
+				return nil, p.errorAt(ErrPseudoElement, simpleStart, nil, "only one pseudo-element is accepted per selector, got %s and %s", pseudoElement, newPseudoElement)
 			}
-			if !p.acceptPseudoElements {
-				return nil, fmt.Errorf("pseudo-element %s found, but pseudo-elements support is disabled", newPseudoElement)
+			if p.inHas > 0 && !p.relaxedHas {
+				return nil, p.errorAt(ErrPseudoElement, simpleStart, nil, "pseudo-element %s is not allowed in :has()", newPseudoElement)
+			}
+			if !p.acceptPseudoElements && !isExtensionPseudoElement(newPseudoElement) {
+				return nil, p.errorAt(ErrPseudoElement, simpleStart, nil, "pseudo-element %s found, but pseudo-elements support is disabled", newPseudoElement)
 			}
 			pseudoElement = newPseudoElement
 		} else {
 			if pseudoElement != "" {
-				return nil, fmt.Errorf("pseudo-element %s must be at the end of selector", pseudoElement)
+				return nil, p.errorAt(ErrPseudoElement, simpleStart, nil, "pseudo-element %s must be at the end of selector", pseudoElement)
 			}
-			selectors = append(selectors, ns)
+			selectors = append(selectors, withSpan(ns, p.span(simpleStart)))
 		}
 
 	}
-	if len(selectors) == 1 && pseudoElement == "" { // no need wrap the selectors in compoundSelector
+	if len(selectors) == 1 && pseudoElement == "" { // no need wrap the selectors in CompoundSelector
 		return selectors[0], nil
 	}
-	return compoundSelector{selectors: selectors, pseudoElement: pseudoElement}, nil
+	return CompoundSelector{Selectors: selectors, Pseudo: pseudoElement, Pos: p.span(start)}, nil
 }
 
 // parseSelector parses a selector that may include combinators.
 func (p *parser) parseSelector() (Sel, error) {
 	p.skipWhitespace()
-	result, err := p.parseSimpleSelectorSequence()
-	if err != nil {
-		return nil, err
+	start := p.i
+	var (
+		result Sel
+		err    error
+	)
+	if p.nesting && p.depth == 0 && p.i < len(p.s) && (p.s[p.i] == '+' || p.s[p.i] == '>' || p.s[p.i] == '~') {
+		// a relative selector, as in a nested rule: "> p" is "& > p"
+		result = withSpan(NestingSelector{}, p.span(start))
+	} else {
+		result, err = p.parseSimpleSelectorSequence()
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	for {
 		var (
-			combinator byte
-			c          Sel
+			combinator      byte
+			c               Sel
+			combinatorStart = p.i
 		)
 		if p.skipWhitespace() {
 			combinator = ' '
 		}
 		if p.i >= len(p.s) {
-			return result, nil
+			return p.withSource(result), nil
 		}
 
 		switch p.s[p.i] {
@@ -836,18 +1075,19 @@ func (p *parser) parseSelector() (Sel, error) {
 			p.skipWhitespace()
 		case ',', ')':
 			// These characters can't begin a selector, but they can legally occur after one.
-			return result, nil
+			return p.withSource(result), nil
 		}
 
 		if combinator == 0 {
-			return result, nil
+			return p.withSource(result), nil
 		}
+		combinatorPos := p.span(combinatorStart)
 
 		c, err = p.parseSimpleSelectorSequence()
 		if err != nil {
 			return nil, err
 		}
-		result = combinedSelector{first: result, combinator: combinator, second: c}
+		result = CombinedSelector{First: result, Combinator: combinator, Second: c, Pos: p.span(start), CombinatorPos: combinatorPos}
 	}
 }
 
@@ -863,6 +1103,9 @@ func (p *parser) parseSelectorGroup() (SelectorGroup, error) {
 		if p.s[p.i] != ',' {
 			break
 		}
+		if p.limits.MaxGroupSize > 0 && len(result) == p.limits.MaxGroupSize {
+			return nil, p.errorf(ErrLimitExceeded, nil, "group has more than %d selectors", p.limits.MaxGroupSize)
+		}
 		p.i++
 		c, err := p.parseSelector()
 		if err != nil {
@@ -8,6 +8,7 @@ var identifierTests = map[string]string{
 	"x":             "x",
 	"96":            "",
 	"-x":            "-x",
+	"--x":           "--x",
 	`r\e9 sumé`:     "résumé",
 	`r\0000e9 sumé`: "résumé",
 	`r\0000e9sumé`:  "résumé",
@@ -86,3 +87,50 @@ func TestParseString(t *testing.T) {
 		}
 	}
 }
+
+func TestExportedAST(t *testing.T) {
+	sel, err := ParseWithPseudoElement(`ul > LI.item#first[data-x="y"]:not(.hidden)::before`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	combined, ok := sel.(CombinedSelector)
+	if !ok || combined.Combinator != '>' {
+		t.Fatalf("expected a child combinator, got %#v", sel)
+	}
+	if tag, ok := combined.First.(TagSelector); !ok || tag.Tag != "ul" {
+		t.Fatalf("unexpected first selector %#v", combined.First)
+	}
+	compound, ok := combined.Second.(CompoundSelector)
+	if !ok || compound.Pseudo != "before" || len(compound.Selectors) != 5 {
+		t.Fatalf("unexpected compound selector %#v", combined.Second)
+	}
+	if tag := compound.Selectors[0].(TagSelector); tag.Tag != "li" {
+		t.Errorf("expected lower-cased tag, got %s", tag.Tag)
+	}
+	if class := compound.Selectors[1].(ClassSelector); class.Class != "item" {
+		t.Errorf("unexpected class %s", class.Class)
+	}
+	if id := compound.Selectors[2].(IDSelector); id.ID != "first" {
+		t.Errorf("unexpected id %s", id.ID)
+	}
+	if attr := compound.Selectors[3].(AttrSelector); attr.Key != "data-x" || attr.Operation != "=" || attr.Val != "y" {
+		t.Errorf("unexpected attribute selector %#v", attr)
+	}
+	if rel := compound.Selectors[4].(RelativePseudoClassSelector); rel.Name != "not" || len(rel.Args) != 1 {
+		t.Errorf("unexpected pseudo-class %#v", rel)
+	}
+
+	// selectors built by hand behave like parsed ones
+	built := CombinedSelector{
+		First:      TagSelector{Tag: "ul"},
+		Combinator: '>',
+		Second:     CompoundSelector{Selectors: []Sel{TagSelector{Tag: "li"}, ClassSelector{Class: "item"}}},
+	}
+	doc := MustParseHTML(`<ul><li class="item"></li><li></li></ul>`)
+	if l := len(QueryAll(doc, built)); l != 1 {
+		t.Errorf("expected 1 match, got %d", l)
+	}
+	if s := built.String(); s != "ul > li.item" {
+		t.Errorf("unexpected serialization %s", s)
+	}
+}
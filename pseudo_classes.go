@@ -13,7 +13,10 @@ import (
 // This file implements the pseudo classes selectors,
 // which share the implementation of PseudoElement() and Specificity()
 
-type abstractPseudoClass struct{}
+type abstractPseudoClass struct {
+	Pos    Span   // position in the source, only set by the span-recording parsing functions
+	source string // see SourceOf
+}
 
 func (s abstractPseudoClass) Specificity() Specificity {
 	return Specificity{0, 1, 0}
@@ -23,34 +26,44 @@ func (c abstractPseudoClass) PseudoElement() string {
 	return ""
 }
 
-type relativePseudoClassSelector struct {
-	name  string // one of "not", "has", "haschild"
-	match SelectorGroup
+// RelativePseudoClassSelector implements the pseudo-classes
+// taking a list of selectors as argument.
+type RelativePseudoClassSelector struct {
+	Name string // one of "not", "has", "haschild", "is", "where"
+	Args SelectorGroup
+	Pos  Span
+
+	source string // see SourceOf
 }
 
-func (s relativePseudoClassSelector) Match(n *html.Node) bool {
-	if n.Type != html.ElementNode {
+func (s RelativePseudoClassSelector) Match(n *html.Node) bool { return s.MatchNode(htmlNode{n}) }
+
+func (s RelativePseudoClassSelector) MatchNode(n Node) bool {
+	if n.Type() != html.ElementNode {
 		return false
 	}
-	switch s.name {
+	switch s.Name {
 	case "not":
 		// matches elements that do not match a.
-		return !s.match.Match(n)
+		return !s.Args.MatchNode(n)
+	case "is", "where":
+		// matches elements that match a.
+		return s.Args.MatchNode(n)
 	case "has":
 		//  matches elements with any descendant that matches a.
-		return hasDescendantMatch(n, s.match)
+		return hasDescendantMatch(n, s.Args.MatchNode)
 	case "haschild":
 		// matches elements with a child that matches a.
-		return hasChildMatch(n, s.match)
+		return hasChildMatch(n, s.Args.MatchNode)
 	default:
-		panic(fmt.Sprintf("unsupported relative pseudo class selector : %s", s.name))
+		panic(fmt.Sprintf("unsupported relative pseudo class selector : %s", s.Name))
 	}
 }
 
 // hasChildMatch returns whether n has any child that matches a.
-func hasChildMatch(n *html.Node, a Matcher) bool {
-	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		if a.Match(c) {
+func hasChildMatch[T NodeLike[T]](n T, a func(T) bool) bool {
+	for c := n.FirstChild(); !isNil(c); c = c.NextSibling() {
+		if a(c) {
 			return true
 		}
 	}
@@ -60,9 +73,9 @@ func hasChildMatch(n *html.Node, a Matcher) bool {
 // hasDescendantMatch performs a depth-first search of n's descendants,
 // testing whether any of them match a. It returns true as soon as a match is
 // found, or false if no match is found.
-func hasDescendantMatch(n *html.Node, a Matcher) bool {
-	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		if a.Match(c) || (c.Type == html.ElementNode && hasDescendantMatch(c, a)) {
+func hasDescendantMatch[T NodeLike[T]](n T, a func(T) bool) bool {
+	for c := n.FirstChild(); !isNil(c); c = c.NextSibling() {
+		if a(c) || (c.Type() == html.ElementNode && hasDescendantMatch(c, a)) {
 			return true
 		}
 	}
@@ -70,11 +83,14 @@ func hasDescendantMatch(n *html.Node, a Matcher) bool {
 }
 
 // Specificity returns the specificity of the most specific selectors
-// in the pseudo-class arguments.
+// in the pseudo-class arguments, or zero for :where().
 // See https://www.w3.org/TR/selectors/#specificity-rules
-func (s relativePseudoClassSelector) Specificity() Specificity {
+func (s RelativePseudoClassSelector) Specificity() Specificity {
 	var max Specificity
-	for _, sel := range s.match {
+	if s.Name == "where" {
+		return max
+	}
+	for _, sel := range s.Args {
 		newSpe := sel.Specificity()
 		if max.Less(newSpe) {
 			max = newSpe
@@ -83,60 +99,70 @@ func (s relativePseudoClassSelector) Specificity() Specificity {
 	return max
 }
 
-func (c relativePseudoClassSelector) PseudoElement() string {
+func (c RelativePseudoClassSelector) PseudoElement() string {
 	return ""
 }
 
-type containsPseudoClassSelector struct {
+// ContainsPseudoClassSelector implements :contains and :containsOwn.
+type ContainsPseudoClassSelector struct {
 	abstractPseudoClass
-	value string
-	own   bool
+	Value string // lower-cased
+	Own   bool
 }
 
-func (s containsPseudoClassSelector) Match(n *html.Node) bool {
+func (s ContainsPseudoClassSelector) Match(n *html.Node) bool { return matchContains(s, FromHTML(n)) }
+
+func (s ContainsPseudoClassSelector) MatchNode(n Node) bool { return matchContains(s, n) }
+
+func matchContains[T NodeLike[T]](s ContainsPseudoClassSelector, n T) bool {
 	var text string
-	if s.own {
+	if s.Own {
 		// matches nodes that directly contain the given text
 		text = strings.ToLower(nodeOwnText(n))
 	} else {
 		// matches nodes that contain the given text.
 		text = strings.ToLower(nodeText(n))
 	}
-	return strings.Contains(text, s.value)
+	return strings.Contains(text, s.Value)
 }
 
-type regexpPseudoClassSelector struct {
+// RegexpPseudoClassSelector implements :matches and :matchesOwn.
+type RegexpPseudoClassSelector struct {
 	abstractPseudoClass
-	regexp *regexp.Regexp
-	own    bool
+	Regexp *regexp.Regexp
+	Own    bool
 }
 
-func (s regexpPseudoClassSelector) Match(n *html.Node) bool {
+func (s RegexpPseudoClassSelector) Match(n *html.Node) bool { return matchRegexp(s, FromHTML(n)) }
+
+func (s RegexpPseudoClassSelector) MatchNode(n Node) bool { return matchRegexp(s, n) }
+
+func matchRegexp[T NodeLike[T]](s RegexpPseudoClassSelector, n T) bool {
 	var text string
-	if s.own {
+	if s.Own {
 		// matches nodes whose text directly matches the specified regular expression
 		text = nodeOwnText(n)
 	} else {
 		// matches nodes whose text matches the specified regular expression
 		text = nodeText(n)
 	}
-	return s.regexp.MatchString(text)
+	return s.Regexp.MatchString(text)
 }
 
 // writeNodeText writes the text contained in n and its descendants to b.
-func writeNodeText(n *html.Node, b *bytes.Buffer) {
-	switch n.Type {
+func writeNodeText[T NodeLike[T]](n T, b *bytes.Buffer) {
+	switch n.Type() {
 	case html.TextNode:
-		b.WriteString(n.Data)
+		b.WriteString(n.Data())
 	case html.ElementNode:
-		for c := n.FirstChild; c != nil; c = c.NextSibling {
+		for c := n.FirstChild(); !isNil(c); c = c.NextSibling() {
 			writeNodeText(c, b)
 		}
 	}
 }
 
 // nodeText returns the text contained in n and its descendants.
-func nodeText(n *html.Node) string {
+func nodeText[T NodeLike[T]](n T) string {
 	var b bytes.Buffer
 	writeNodeText(n, &b)
 	return b.String()
@@ -144,54 +170,60 @@ func nodeText(n *html.Node) string {
 
 // nodeOwnText returns the contents of the text nodes that are direct
 // children of n.
-func nodeOwnText(n *html.Node) string {
+func nodeOwnText[T NodeLike[T]](n T) string {
 	var b bytes.Buffer
-	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		if c.Type == html.TextNode {
-			b.WriteString(c.Data)
+	for c := n.FirstChild(); !isNil(c); c = c.NextSibling() {
+		if c.Type() == html.TextNode {
+			b.WriteString(c.Data())
 		}
 	}
 	return b.String()
 }
 
-type nthPseudoClassSelector struct {
+// NthPseudoClassSelector implements :nth-child(an+b), and its variants
+// :nth-last-child, :nth-of-type, :nth-last-of-type and :first-child, ...
+type NthPseudoClassSelector struct {
 	abstractPseudoClass
-	a, b         int
-	last, ofType bool
+	A, B         int
+	Last, OfType bool
 }
 
-func (s nthPseudoClassSelector) Match(n *html.Node) bool {
-	if s.a == 0 {
-		if s.last {
-			return simpleNthLastChildMatch(s.b, s.ofType, n)
+func (s NthPseudoClassSelector) Match(n *html.Node) bool { return matchNth(s, FromHTML(n)) }
+
+func (s NthPseudoClassSelector) MatchNode(n Node) bool { return matchNth(s, n) }
+
+func matchNth[T NodeLike[T]](s NthPseudoClassSelector, n T) bool {
+	if s.A == 0 {
+		if s.Last {
+			return simpleNthLastChildMatch(s.B, s.OfType, n)
 		} else {
-			return simpleNthChildMatch(s.b, s.ofType, n)
+			return simpleNthChildMatch(s.B, s.OfType, n)
 		}
 	}
-	return nthChildMatch(s.a, s.b, s.last, s.ofType, n)
+	return nthChildMatch(s.A, s.B, s.Last, s.OfType, n)
 }
 
 // nthChildMatch implements :nth-child(an+b).
 // If last is true, implements :nth-last-child instead.
 // If ofType is true, implements :nth-of-type instead.
-func nthChildMatch(a, b int, last, ofType bool, n *html.Node) bool {
-	if n.Type != html.ElementNode {
+func nthChildMatch[T NodeLike[T]](a, b int, last, ofType bool, n T) bool {
+	if n.Type() != html.ElementNode {
 		return false
 	}
 
-	parent := n.Parent
-	if parent == nil {
+	parent := n.Parent()
+	if isNil(parent) {
 		return false
 	}
 
-	if parent.Type == html.DocumentNode {
+	if parent.Type() == html.DocumentNode {
 		return false
 	}
 
 	i := -1
 	count := 0
-	for c := parent.FirstChild; c != nil; c = c.NextSibling {
-		if (c.Type != html.ElementNode) || (ofType && c.Data != n.Data) {
+	for c := parent.FirstChild(); !isNil(c); c = c.NextSibling() {
+		if (c.Type() != html.ElementNode) || (ofType && !sameType(c, n)) {
 			continue
 		}
 		count++
@@ -220,25 +252,32 @@ func nthChildMatch(a, b int, last, ofType bool, n *html.Node) bool {
 	return i%a == 0 && i/a >= 0
 }
 
+// sameType returns true if the elements c and n have the same type,
+// that is the same tag and the same namespace: an HTML <a> and
+// an SVG <a> are not of the same type.
+func sameType[T NodeLike[T]](c, n T) bool {
+	return c.Data() == n.Data() && c.Namespace() == n.Namespace()
+}
+
 // simpleNthChildMatch implements :nth-child(b).
 // If ofType is true, implements :nth-of-type instead.
-func simpleNthChildMatch(b int, ofType bool, n *html.Node) bool {
-	if n.Type != html.ElementNode {
+func simpleNthChildMatch[T NodeLike[T]](b int, ofType bool, n T) bool {
+	if n.Type() != html.ElementNode {
 		return false
 	}
 
-	parent := n.Parent
-	if parent == nil {
+	parent := n.Parent()
+	if isNil(parent) {
 		return false
 	}
 
-	if parent.Type == html.DocumentNode {
+	if parent.Type() == html.DocumentNode {
 		return false
 	}
 
 	count := 0
-	for c := parent.FirstChild; c != nil; c = c.NextSibling {
-		if c.Type != html.ElementNode || (ofType && c.Data != n.Data) {
+	for c := parent.FirstChild(); !isNil(c); c = c.NextSibling() {
+		if c.Type() != html.ElementNode || (ofType && !sameType(c, n)) {
 			continue
 		}
 		count++
@@ -254,23 +293,23 @@ func simpleNthChildMatch(b int, ofType bool, n *html.Node) bool {
 
 // simpleNthLastChildMatch implements :nth-last-child(b).
 // If ofType is true, implements :nth-last-of-type instead.
-func simpleNthLastChildMatch(b int, ofType bool, n *html.Node) bool {
-	if n.Type != html.ElementNode {
+func simpleNthLastChildMatch[T NodeLike[T]](b int, ofType bool, n T) bool {
+	if n.Type() != html.ElementNode {
 		return false
 	}
 
-	parent := n.Parent
-	if parent == nil {
+	parent := n.Parent()
+	if isNil(parent) {
 		return false
 	}
 
-	if parent.Type == html.DocumentNode {
+	if parent.Type() == html.DocumentNode {
 		return false
 	}
 
 	count := 0
-	for c := parent.LastChild; c != nil; c = c.PrevSibling {
-		if c.Type != html.ElementNode || (ofType && c.Data != n.Data) {
+	for c := parent.LastChild(); !isNil(c); c = c.PrevSibling() {
+		if c.Type() != html.ElementNode || (ofType && !sameType(c, n)) {
 			continue
 		}
 		count++
@@ -284,30 +323,37 @@ func simpleNthLastChildMatch(b int, ofType bool, n *html.Node) bool {
 	return false
 }
 
-type onlyChildPseudoClassSelector struct {
+// OnlyChildPseudoClassSelector implements :only-child and :only-of-type.
+type OnlyChildPseudoClassSelector struct {
 	abstractPseudoClass
-	ofType bool
+	OfType bool
 }
 
 // Match implements :only-child.
-// If `ofType` is true, it implements :only-of-type instead.
-func (s onlyChildPseudoClassSelector) Match(n *html.Node) bool {
-	if n.Type != html.ElementNode {
+// If `OfType` is true, it implements :only-of-type instead.
+func (s OnlyChildPseudoClassSelector) Match(n *html.Node) bool {
+	return matchOnlyChild(s, FromHTML(n))
+}
+
+func (s OnlyChildPseudoClassSelector) MatchNode(n Node) bool { return matchOnlyChild(s, n) }
+
+func matchOnlyChild[T NodeLike[T]](s OnlyChildPseudoClassSelector, n T) bool {
+	if n.Type() != html.ElementNode {
 		return false
 	}
 
-	parent := n.Parent
-	if parent == nil {
+	parent := n.Parent()
+	if isNil(parent) {
 		return false
 	}
 
-	if parent.Type == html.DocumentNode {
+	if parent.Type() == html.DocumentNode {
 		return false
 	}
 
 	count := 0
-	for c := parent.FirstChild; c != nil; c = c.NextSibling {
-		if (c.Type != html.ElementNode) || (s.ofType && c.Data != n.Data) {
+	for c := parent.FirstChild(); !isNil(c); c = c.NextSibling() {
+		if (c.Type() != html.ElementNode) || (s.OfType && !sameType(c, n)) {
 			continue
 		}
 		count++
@@ -319,27 +365,46 @@ func (s onlyChildPseudoClassSelector) Match(n *html.Node) bool {
 	return count == 1
 }
 
-type inputPseudoClassSelector struct {
+// InputPseudoClassSelector implements :input.
+type InputPseudoClassSelector struct {
 	abstractPseudoClass
 }
 
 // Matches input, select, textarea and button elements.
-func (s inputPseudoClassSelector) Match(n *html.Node) bool {
-	return n.Type == html.ElementNode && (n.Data == "input" || n.Data == "select" || n.Data == "textarea" || n.Data == "button")
+func (s InputPseudoClassSelector) Match(n *html.Node) bool { return matchInput(s, FromHTML(n)) }
+
+func (s InputPseudoClassSelector) MatchNode(n Node) bool { return matchInput(s, n) }
+
+func matchInput[T NodeLike[T]](s InputPseudoClassSelector, n T) bool {
+	if n.Type() != html.ElementNode {
+		return false
+	}
+	switch n.Data() {
+	case "input", "select", "textarea", "button":
+		return true
+	}
+	return false
 }
 
-type emptyElementPseudoClassSelector struct {
+// EmptyElementPseudoClassSelector implements :empty.
+type EmptyElementPseudoClassSelector struct {
 	abstractPseudoClass
 }
 
 // Matches empty elements.
-func (s emptyElementPseudoClassSelector) Match(n *html.Node) bool {
-	if n.Type != html.ElementNode {
+func (s EmptyElementPseudoClassSelector) Match(n *html.Node) bool {
+	return matchEmptyElement(s, FromHTML(n))
+}
+
+func (s EmptyElementPseudoClassSelector) MatchNode(n Node) bool { return matchEmptyElement(s, n) }
+
+func matchEmptyElement[T NodeLike[T]](s EmptyElementPseudoClassSelector, n T) bool {
+	if n.Type() != html.ElementNode {
 		return false
 	}
 
-	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		switch c.Type {
+	for c := n.FirstChild(); !isNil(c); c = c.NextSibling() {
+		switch c.Type() {
 		case html.ElementNode:
 			return false
 		case html.TextNode:
@@ -354,115 +419,195 @@ func (s emptyElementPseudoClassSelector) Match(n *html.Node) bool {
 	return true
 }
 
-type rootPseudoClassSelector struct {
+// RootPseudoClassSelector implements :root.
+type RootPseudoClassSelector struct {
 	abstractPseudoClass
 }
 
 // Match implements :root
 // "In HTML, :root represents the <html> element and is identical to the selector html"
-func (s rootPseudoClassSelector) Match(n *html.Node) bool {
-	return n.Type == html.ElementNode && n.DataAtom == atom.Html
+// For other documents (like XML), it is the element child of the document node.
+func (s RootPseudoClassSelector) Match(n *html.Node) bool {
+	return n.Type == html.ElementNode && (n.DataAtom == atom.Html || (n.Parent != nil && n.Parent.Type == html.DocumentNode))
+}
+
+func (s RootPseudoClassSelector) MatchNode(n Node) bool { return matchRoot(s, n) }
+
+func matchRoot[T NodeLike[T]](s RootPseudoClassSelector, n T) bool {
+	if n.Type() != html.ElementNode {
+		return false
+	}
+	if n.Data() == "html" {
+		return true
+	}
+	parent := n.Parent()
+	return !isNil(parent) && parent.Type() == html.DocumentNode
+}
+
+// ScopePseudoClassSelector implements :scope, which matches the scope
+// element of a query: with Query, QueryAll, QueryNode and QueryAllNodes,
+// the element queried (see also WithScope). Without scope, as when
+// querying a document, it is equivalent to :root.
+type ScopePseudoClassSelector struct {
+	abstractPseudoClass
+
+	scope Node // nil for :root
 }
 
-func hasAttr(n *html.Node, attr string) bool {
+func (s ScopePseudoClassSelector) Match(n *html.Node) bool {
+	if s.scope == nil {
+		return RootPseudoClassSelector{}.Match(n)
+	}
+	return FromHTML(n) == s.scope
+}
+
+func (s ScopePseudoClassSelector) MatchNode(n Node) bool { return matchScope(s, n) }
+
+func matchScope[T NodeLike[T]](s ScopePseudoClassSelector, n T) bool {
+	if s.scope == nil {
+		return matchRoot(RootPseudoClassSelector{}, n)
+	}
+	return any(n) == any(s.scope)
+}
+
+func hasAttr[T NodeLike[T]](n T, attr string) bool {
 	return matchAttribute(n, attr, func(string) bool { return true })
 }
 
-type linkPseudoClassSelector struct {
+// LinkPseudoClassSelector implements :link.
+type LinkPseudoClassSelector struct {
 	abstractPseudoClass
 }
 
 // Match implements :link
-func (s linkPseudoClassSelector) Match(n *html.Node) bool {
-	return (n.DataAtom == atom.A || n.DataAtom == atom.Area || n.DataAtom == atom.Link) && hasAttr(n, "href")
+func (s LinkPseudoClassSelector) Match(n *html.Node) bool { return matchLink(s, FromHTML(n)) }
+
+func (s LinkPseudoClassSelector) MatchNode(n Node) bool { return matchLink(s, n) }
+
+func matchLink[T NodeLike[T]](s LinkPseudoClassSelector, n T) bool {
+	if n.Type() != html.ElementNode {
+		return false
+	}
+	switch n.Data() {
+	case "a", "area", "link":
+		return hasAttr(n, "href")
+	}
+	return false
 }
 
-type langPseudoClassSelector struct {
+// LangPseudoClassSelector implements :lang.
+type LangPseudoClassSelector struct {
 	abstractPseudoClass
-	lang string
+	Lang string // lower-cased
 }
 
-func (s langPseudoClassSelector) Match(n *html.Node) bool {
+func (s LangPseudoClassSelector) Match(n *html.Node) bool { return matchLang(s, FromHTML(n)) }
+
+func (s LangPseudoClassSelector) MatchNode(n Node) bool { return matchLang(s, n) }
+
+func matchLang[T NodeLike[T]](s LangPseudoClassSelector, n T) bool {
 	own := matchAttribute(n, "lang", func(val string) bool {
-		return val == s.lang || strings.HasPrefix(val, s.lang+"-")
+		return val == s.Lang || strings.HasPrefix(val, s.Lang+"-")
 	})
-	if n.Parent == nil {
+	parent := n.Parent()
+	if isNil(parent) {
 		return own
 	}
-	return own || s.Match(n.Parent)
+	return own || matchLang(s, parent)
 }
 
-type enabledPseudoClassSelector struct {
+// EnabledPseudoClassSelector implements :enabled.
+type EnabledPseudoClassSelector struct {
 	abstractPseudoClass
 }
 
-func (s enabledPseudoClassSelector) Match(n *html.Node) bool {
-	if n.Type != html.ElementNode {
+func (s EnabledPseudoClassSelector) Match(n *html.Node) bool { return matchEnabled(s, FromHTML(n)) }
+
+func (s EnabledPseudoClassSelector) MatchNode(n Node) bool { return matchEnabled(s, n) }
+
+func matchEnabled[T NodeLike[T]](s EnabledPseudoClassSelector, n T) bool {
+	if n.Type() != html.ElementNode {
 		return false
 	}
-	switch n.DataAtom {
-	case atom.A, atom.Area, atom.Link:
+	switch n.Data() {
+	case "a", "area", "link":
 		return hasAttr(n, "href")
-	case atom.Optgroup, atom.Menuitem, atom.Fieldset:
+	case "optgroup", "menuitem", "fieldset":
 		return !hasAttr(n, "disabled")
-	case atom.Button, atom.Input, atom.Select, atom.Textarea, atom.Option:
+	case "button", "input", "select", "textarea", "option":
 		return !hasAttr(n, "disabled") && !inDisabledFieldset(n)
 	}
 	return false
 }
 
-type disabledPseudoClassSelector struct {
+// DisabledPseudoClassSelector implements :disabled.
+type DisabledPseudoClassSelector struct {
 	abstractPseudoClass
 }
 
-func (s disabledPseudoClassSelector) Match(n *html.Node) bool {
-	if n.Type != html.ElementNode {
+func (s DisabledPseudoClassSelector) Match(n *html.Node) bool { return matchDisabled(s, FromHTML(n)) }
+
+func (s DisabledPseudoClassSelector) MatchNode(n Node) bool { return matchDisabled(s, n) }
+
+func matchDisabled[T NodeLike[T]](s DisabledPseudoClassSelector, n T) bool {
+	if n.Type() != html.ElementNode {
 		return false
 	}
-	switch n.DataAtom {
-	case atom.Optgroup, atom.Menuitem, atom.Fieldset:
+	switch n.Data() {
+	case "optgroup", "menuitem", "fieldset":
 		return hasAttr(n, "disabled")
-	case atom.Button, atom.Input, atom.Select, atom.Textarea, atom.Option:
+	case "button", "input", "select", "textarea", "option":
 		return hasAttr(n, "disabled") || inDisabledFieldset(n)
 	}
 	return false
 }
 
-func hasLegendInPreviousSiblings(n *html.Node) bool {
-	for s := n.PrevSibling; s != nil; s = s.PrevSibling {
-		if s.DataAtom == atom.Legend {
+func hasLegendInPreviousSiblings[T NodeLike[T]](n T) bool {
+	for s := n.PrevSibling(); !isNil(s); s = s.PrevSibling() {
+		if isElement(s, "legend") {
 			return true
 		}
 	}
 	return false
 }
 
-func inDisabledFieldset(n *html.Node) bool {
-	if n.Parent == nil {
+func inDisabledFieldset[T NodeLike[T]](n T) bool {
+	parent := n.Parent()
+	if isNil(parent) {
 		return false
 	}
-	if n.Parent.DataAtom == atom.Fieldset && hasAttr(n.Parent, "disabled") &&
-		(n.DataAtom != atom.Legend || hasLegendInPreviousSiblings(n)) {
+	if isElement(parent, "fieldset") && hasAttr(parent, "disabled") &&
+		(!isElement(n, "legend") || hasLegendInPreviousSiblings(n)) {
 		return true
 	}
-	return inDisabledFieldset(n.Parent)
+	return inDisabledFieldset(parent)
 }
 
-type checkedPseudoClassSelector struct {
+// isElement returns true if n is an element with the given tag
+func isElement[T NodeLike[T]](n T, tag string) bool {
+	return n.Type() == html.ElementNode && n.Data() == tag
+}
+
+// CheckedPseudoClassSelector implements :checked.
+type CheckedPseudoClassSelector struct {
 	abstractPseudoClass
 }
 
-func (s checkedPseudoClassSelector) Match(n *html.Node) bool {
-	if n.Type != html.ElementNode {
+func (s CheckedPseudoClassSelector) Match(n *html.Node) bool { return matchChecked(s, FromHTML(n)) }
+
+func (s CheckedPseudoClassSelector) MatchNode(n Node) bool { return matchChecked(s, n) }
+
+func matchChecked[T NodeLike[T]](s CheckedPseudoClassSelector, n T) bool {
+	if n.Type() != html.ElementNode {
 		return false
 	}
-	switch n.DataAtom {
-	case atom.Input, atom.Menuitem:
+	switch n.Data() {
+	case "input", "menuitem":
 		return hasAttr(n, "checked") && matchAttribute(n, "type", func(val string) bool {
 			t := toLowerASCII(val)
 			return t == "checkbox" || t == "radio"
 		})
-	case atom.Option:
+	case "option":
 		return hasAttr(n, "selected")
 	}
 	return false
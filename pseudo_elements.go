@@ -0,0 +1,149 @@
+package cascadia
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// This file implements the non-standard pseudo-elements selecting
+// the nodes which are not elements (see Options.NodePseudoElements):
+// "p::text" matches the text node children of the <p> elements, so
+// that the query functions return them, instead of the elements.
+// Similarly, "body > ::comment" matches the comments children of <body>.
+// Besides, "a::attr(href)" matches the <a> elements with an href attribute,
+// whose value may be extracted with QueryAllText.
+
+// nodePseudoElement returns the type of the nodes selected
+// by the pseudo-element name, if it is a node pseudo-element.
+func nodePseudoElement(name string) (html.NodeType, bool) {
+	switch name {
+	case "text":
+		return html.TextNode, true
+	case "comment":
+		return html.CommentNode, true
+	}
+	return 0, false
+}
+
+// attrPseudoElement returns the attribute name of pseudo,
+// if it is the pseudo-element attr(name)
+func attrPseudoElement(pseudo string) (string, bool) {
+	if !strings.HasPrefix(pseudo, "attr(") || !strings.HasSuffix(pseudo, ")") {
+		return "", false
+	}
+	p := parser{s: pseudo[len("attr(") : len(pseudo)-1]}
+	name, err := p.parseIdentifier()
+	if err != nil || p.i != len(p.s) {
+		return "", false
+	}
+	return name, true
+}
+
+// isExtensionPseudoElement returns true for the pseudo-elements
+// enabled by Options.NodePseudoElements
+func isExtensionPseudoElement(pseudo string) bool {
+	_, isNode := nodePseudoElement(pseudo)
+	_, isAttr := attrPseudoElement(pseudo)
+	return isNode || isAttr
+}
+
+// AttrPseudoElement returns the (lower-cased) name of the attribute
+// extracted by the non-standard ::attr() pseudo-element of sel,
+// like "href" for "a::attr(href)", or false if sel has no ::attr().
+func AttrPseudoElement(sel Sel) (name string, ok bool) {
+	return attrPseudoElement(sel.PseudoElement())
+}
+
+// originating returns sel, without its node pseudo-element,
+// to match the parent of the node selected
+func originating(sel Sel) Sel {
+	switch s := sel.(type) {
+	case CompoundSelector:
+		s.Pseudo = ""
+		if len(s.Selectors) == 1 {
+			return s.Selectors[0]
+		}
+		return s
+	case CombinedSelector:
+		if s.Second != nil {
+			s.Second = originating(s.Second)
+		} else {
+			s.First = originating(s.First)
+		}
+		return s
+	}
+	return sel
+}
+
+// matchNodePseudo returns true if n is selected by sel,
+// whose pseudo-element selects nodes of type typ
+func matchNodePseudo(sel Sel, typ html.NodeType, n Node) bool {
+	if n.Type() != typ {
+		return false
+	}
+	parent := n.Parent()
+	return parent != nil && MatchNode(originating(sel), parent)
+}
+
+func compileNodePseudoFor[T NodeLike[T]](sel Sel, typ html.NodeType) (func(T) bool, error) {
+	origin, err := compileFor[T](originating(sel))
+	if err != nil {
+		return nil, err
+	}
+	return func(n T) bool {
+		if n.Type() != typ {
+			return false
+		}
+		parent := n.Parent()
+		return !isNil(parent) && origin(parent)
+	}, nil
+}
+
+// QueryAllText returns the text selected by m in the descendants of n:
+// the content of the text nodes selected with ::text (or of the comments
+// selected with ::comment), the values of the attributes selected with
+// ::attr(), and the text of the other elements matched, including their
+// descendants, as with QueryAll.
+//
+// With aggregate, ::text selects the text of the descendants as well:
+// the text nodes selected are replaced by the text of their parent,
+// once per parent, as in "p::text" selecting the full text of each <p>.
+func QueryAllText(n *html.Node, m Matcher, aggregate bool) []string {
+	var out []string
+	parents := map[*html.Node]bool{}
+	for _, match := range QueryAll(n, m) {
+		switch {
+		case match.Type == html.ElementNode:
+			if name, ok := extractedAttr(m, match); ok {
+				out = append(out, getAttr(match, name))
+			} else {
+				out = append(out, nodeText(FromHTML(match)))
+			}
+		case aggregate && match.Type == html.TextNode:
+			if !parents[match.Parent] {
+				parents[match.Parent] = true
+				out = append(out, nodeText(FromHTML(match.Parent)))
+			}
+		default:
+			out = append(out, match.Data)
+		}
+	}
+	return out
+}
+
+// extractedAttr returns the attribute extracted by the selector matching n,
+// which is the first one matching in a group
+func extractedAttr(m Matcher, n *html.Node) (string, bool) {
+	switch m := m.(type) {
+	case Sel:
+		return AttrPseudoElement(m)
+	case SelectorGroup:
+		for _, sel := range m {
+			if sel.Match(n) {
+				return AttrPseudoElement(sel)
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,170 @@
+package cascadia
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestTextPseudoElement(t *testing.T) {
+	if _, err := ParseWithPseudoElement("p::text"); err == nil {
+		t.Error("expected an error for ::text without NodePseudoElements")
+	}
+	opts := Options{NodePseudoElements: true}
+	if _, err := ParseWithOptions("p::text", Options{NodePseudoElements: true, Strict: true}); err == nil {
+		t.Error("expected a strict mode error for ::text")
+	}
+	if _, err := ParseWithOptions("p:text", opts); err == nil {
+		t.Error("expected an error for :text")
+	}
+
+	doc := MustParseHTML(`<div><p>a<b>b</b>c</p><section><p>d</p></section></div><p></p>`)
+	datas := func(nodes []*html.Node) []string {
+		var out []string
+		for _, n := range nodes {
+			if n.Type != html.TextNode {
+				t.Errorf("unexpected node %v", n)
+			}
+			out = append(out, n.Data)
+		}
+		return out
+	}
+	for _, test := range []struct {
+		sel string
+		exp []string
+	}{
+		{"p::text", []string{"a", "c", "d"}},
+		{"div > p::text", []string{"a", "c"}},
+		{"div p::text", []string{"a", "c", "d"}},
+		{"section ::text", []string{"d"}},
+		{"::text", []string{"a", "b", "c", "d"}},
+		{"p b::text", []string{"b"}},
+		{"ul::text", nil},
+	} {
+		sel, err := ParseWithOptions(test.sel, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sel.PseudoElement() != "text" {
+			t.Errorf("%s: unexpected pseudo-element %s", test.sel, sel.PseudoElement())
+		}
+		if got := datas(QueryAll(doc, sel)); !reflect.DeepEqual(got, test.exp) {
+			t.Errorf("%s: expected %q, got %q", test.sel, test.exp, got)
+		}
+		if len(test.exp) != 0 {
+			if first := Query(doc, sel); first == nil || first.Data != test.exp[0] {
+				t.Errorf("%s: unexpected first match %v", test.sel, first)
+			}
+		}
+
+		// generic path
+		var got []string
+		for _, n := range QueryAllNodes(FromHTML(doc), sel.(NodeMatcher)) {
+			got = append(got, ToHTML(n).Data)
+		}
+		if !reflect.DeepEqual(got, test.exp) {
+			t.Errorf("%s: expected %q with QueryAllNodes, got %q", test.sel, test.exp, got)
+		}
+		m, err := CompileFor[valueNode](sel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = nil
+		for _, n := range QueryAllFor(valueNode{copyTree(doc)}, m) {
+			got = append(got, n.t.data)
+		}
+		if !reflect.DeepEqual(got, test.exp) {
+			t.Errorf("%s: expected %q with CompileFor, got %q", test.sel, test.exp, got)
+		}
+	}
+}
+
+func TestQueryAllText(t *testing.T) {
+	doc := MustParseHTML(`<p>a<b>b</b>c</p><p>d</p><span>e<i>f</i></span>`)
+	group, err := ParseGroupWithOptions("p::text, span", Options{NodePseudoElements: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := QueryAllText(doc, group, false), []string{"a", "c", "d", "ef"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected %q, got %q", exp, got)
+	}
+	if got, exp := QueryAllText(doc, group, true), []string{"abc", "d", "ef"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected %q, got %q", exp, got)
+	}
+}
+
+func TestCommentPseudoElement(t *testing.T) {
+	doc := MustParseHTML(`<!--top--><div><!--[if IE]>ie<![endif]--><p>a<!--ssr:1--></p></div><!--end-->`)
+	for _, test := range []struct {
+		sel string
+		exp []string
+	}{
+		{"::comment", []string{"[if IE]>ie<![endif]", "ssr:1", "end"}},
+		{"div::comment", []string{"[if IE]>ie<![endif]"}},
+		{"div > ::comment", []string{"ssr:1"}},
+		{"div ::comment", []string{"ssr:1"}},
+		{"p::comment", []string{"ssr:1"}},
+		{"p::text", []string{"a"}},
+	} {
+		sel, err := ParseWithOptions(test.sel, Options{NodePseudoElements: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := QueryAllText(doc, sel, false); !reflect.DeepEqual(got, test.exp) {
+			t.Errorf("%s: expected %q, got %q", test.sel, test.exp, got)
+		}
+	}
+}
+
+func TestAttrPseudoElement(t *testing.T) {
+	opts := Options{NodePseudoElements: true}
+	if _, err := ParseWithPseudoElement("a::attr(href)"); err == nil {
+		t.Error("expected an error for ::attr() without NodePseudoElements")
+	}
+	for _, input := range []string{"a:attr(href)", "a::attr", "a::attr()", "a::attr(href", "a::attr(1)"} {
+		if _, err := ParseWithOptions(input, opts); err == nil {
+			t.Errorf("%s: expected an error", input)
+		}
+	}
+
+	sel, err := ParseWithOptions("a::attr( HREF )", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name, ok := AttrPseudoElement(sel); !ok || name != "href" {
+		t.Errorf("unexpected attribute %s", name)
+	}
+	if s := sel.String(); s != "a::attr(href)" {
+		t.Errorf("unexpected serialization %s", s)
+	}
+	back, err := ParseWithOptions(sel.String(), opts)
+	if err != nil || !Equal(back, sel) {
+		t.Errorf("unexpected round trip %v (%v)", back, err)
+	}
+	if _, ok := AttrPseudoElement(MustParse("a")); ok {
+		t.Error("unexpected attribute for a")
+	}
+
+	doc := MustParseHTML(`<a href="/1">one</a><a>two</a><div><a href="/3" title="t">three</a></div>`)
+	group, err := ParseGroupWithOptions("div a::attr(title), a::attr(href), a", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := QueryAllText(doc, group, false), []string{"/1", "two", "t"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected %q, got %q", exp, got)
+	}
+	if got, exp := QueryAllText(doc, sel, false), []string{"/1", "/3"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected %q, got %q", exp, got)
+	}
+	if got := len(QueryAllNodes(FromHTML(doc), sel.(NodeMatcher))); got != 2 {
+		t.Errorf("expected 2 matches, got %d", got)
+	}
+	m, err := CompileFor[valueNode](sel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(QueryAllFor(valueNode{copyTree(doc)}, m)); got != 2 {
+		t.Errorf("expected 2 matches, got %d", got)
+	}
+}
@@ -0,0 +1,223 @@
+package cascadia
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// RewriteAction is an action of a Rewriter rule.
+type RewriteAction uint8
+
+const (
+	RewriteRename  RewriteAction = iota // change the tag of the element
+	RewriteWrap                         // insert an element around the element
+	RewriteUnwrap                       // replace the element by its content
+	RewriteDrop                         // remove the element and its content
+	RewriteExtract                      // copy the element to the output tree
+)
+
+var rewriteActionNames = [...]string{"rename", "wrap", "unwrap", "drop", "extract"}
+
+func (a RewriteAction) String() string {
+	if int(a) < len(rewriteActionNames) {
+		return rewriteActionNames[a]
+	}
+	return fmt.Sprintf("RewriteAction(%d)", a)
+}
+
+// Rewriter transforms HTML trees with rules made of a selector and
+// an action, in the spirit of XSLT templates.
+//
+// Each element is subject to at most one rule: when several rules match
+// it, the one with the most specific selector wins, and the last added
+// one among equals, as with the CSS cascade.
+// The zero value has no rule, and is ready to use.
+type Rewriter struct {
+	rules []rewriteRule
+}
+
+type rewriteRule struct {
+	sel    SelectorGroup
+	action RewriteAction
+	tag    string // for rename and wrap
+}
+
+// Add adds a rule applying action to the elements matching selector.
+// tag is the new tag for RewriteRename, or the tag of the wrapper for
+// RewriteWrap, and is ignored otherwise.
+func (r *Rewriter) Add(selector string, action RewriteAction, tag string) error {
+	group, err := ParseGroup(selector)
+	if err != nil {
+		return err
+	}
+	tag = toLowerASCII(strings.TrimSpace(tag))
+	if (action == RewriteRename || action == RewriteWrap) && tag == "" {
+		return fmt.Errorf("missing tag for the %s action", action)
+	}
+	r.rules = append(r.rules, rewriteRule{sel: group, action: action, tag: tag})
+	return nil
+}
+
+// Rename adds a rule changing the tag of the elements matching selector.
+func (r *Rewriter) Rename(selector, tag string) error { return r.Add(selector, RewriteRename, tag) }
+
+// Wrap adds a rule inserting a tag element around the elements matching selector.
+func (r *Rewriter) Wrap(selector, tag string) error { return r.Add(selector, RewriteWrap, tag) }
+
+// Unwrap adds a rule replacing the elements matching selector by their content.
+func (r *Rewriter) Unwrap(selector string) error { return r.Add(selector, RewriteUnwrap, "") }
+
+// Drop adds a rule removing the elements matching selector, with their content.
+func (r *Rewriter) Drop(selector string) error { return r.Add(selector, RewriteDrop, "") }
+
+// Extract adds a rule copying the elements matching selector to the
+// output tree returned by Rewrite.
+func (r *Rewriter) Extract(selector string) error { return r.Add(selector, RewriteExtract, "") }
+
+// AddRules adds the rules described by text, one per line, like
+//
+//	rename: b => strong
+//	wrap: table => div
+//	unwrap: font
+//	drop: .ad, script
+//	extract: article
+//
+// Empty lines are ignored.
+func (r *Rewriter) AddRules(text string) error {
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		name, arg, ok := strings.Cut(line, ":")
+		if !ok {
+			return fmt.Errorf("line %d: missing ':' in rewrite rule %q", i+1, line)
+		}
+		name = strings.TrimSpace(name)
+		action := -1
+		for j, n := range rewriteActionNames {
+			if n == name {
+				action = j
+			}
+		}
+		if action == -1 {
+			return fmt.Errorf("line %d: unknown rewrite action %q", i+1, name)
+		}
+		selector, tag := arg, ""
+		if action == int(RewriteRename) || action == int(RewriteWrap) {
+			if selector, tag, ok = strings.Cut(arg, "=>"); !ok {
+				return fmt.Errorf("line %d: missing '=>' in %s rule %q", i+1, name, line)
+			}
+		}
+		if err := r.Add(selector, RewriteAction(action), tag); err != nil {
+			return fmt.Errorf("line %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// rule returns the rule applying to n, or nil
+func (r *Rewriter) rule(n *html.Node) *rewriteRule {
+	var (
+		best     *rewriteRule
+		bestSpec Specificity
+	)
+	for i := range r.rules {
+		rule := &r.rules[i]
+		for _, sel := range rule.sel {
+			if !sel.Match(n) {
+				continue
+			}
+			if spec := sel.Specificity(); best == nil || !spec.Less(bestSpec) {
+				best, bestSpec = rule, spec
+			}
+		}
+	}
+	return best
+}
+
+// Rewrite applies the rules to the descendants of n, in place, and returns
+// a document containing a copy of the extracted elements, in tree order.
+// The extracted elements are copied after the other rules are applied, and
+// the ones contained in another extracted element are not duplicated.
+// The selectors are matched against the original tree, in one traversal,
+// before the tree is modified.
+func (r *Rewriter) Rewrite(n *html.Node) *html.Node {
+	type edit struct {
+		n    *html.Node
+		rule *rewriteRule
+	}
+	var (
+		edits     []edit
+		extracted []*html.Node
+	)
+	var visit func(n *html.Node, inExtracted bool)
+	visit = func(n *html.Node, inExtracted bool) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			rule := r.rule(c)
+			switch {
+			case rule == nil:
+				visit(c, inExtracted)
+			case rule.action == RewriteExtract:
+				if !inExtracted {
+					extracted = append(extracted, c)
+				}
+				visit(c, true)
+			case rule.action == RewriteDrop:
+				edits = append(edits, edit{c, rule})
+			default:
+				edits = append(edits, edit{c, rule})
+				visit(c, inExtracted)
+			}
+		}
+	}
+	visit(n, false)
+
+	for _, e := range edits {
+		switch c := e.n; e.rule.action {
+		case RewriteRename:
+			c.Data, c.DataAtom = e.rule.tag, atom.Lookup([]byte(e.rule.tag))
+		case RewriteWrap:
+			wrapper := &html.Node{Type: html.ElementNode, Data: e.rule.tag, DataAtom: atom.Lookup([]byte(e.rule.tag)), Namespace: c.Namespace}
+			c.Parent.InsertBefore(wrapper, c)
+			c.Parent.RemoveChild(c)
+			wrapper.AppendChild(c)
+		case RewriteUnwrap:
+			for c.FirstChild != nil {
+				child := c.FirstChild
+				c.RemoveChild(child)
+				c.Parent.InsertBefore(child, c)
+			}
+			c.Parent.RemoveChild(c)
+		case RewriteDrop:
+			c.Parent.RemoveChild(c)
+		}
+	}
+
+	out := &html.Node{Type: html.DocumentNode}
+	for _, c := range extracted {
+		out.AppendChild(cloneHTML(c))
+	}
+	return out
+}
+
+// cloneHTML returns a deep copy of n, without parent and siblings
+func cloneHTML(n *html.Node) *html.Node {
+	out := &html.Node{
+		Type:      n.Type,
+		DataAtom:  n.DataAtom,
+		Data:      n.Data,
+		Namespace: n.Namespace,
+		Attr:      append([]html.Attribute(nil), n.Attr...),
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		out.AppendChild(cloneHTML(c))
+	}
+	return out
+}
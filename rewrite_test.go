@@ -0,0 +1,56 @@
+package cascadia
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRewriter(t *testing.T) {
+	var r Rewriter
+	err := r.AddRules(`
+	rename: b => strong
+	wrap: table => div
+	unwrap: font
+	drop: .ad, script
+	extract: article`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := MustParseHTML(`<article><p><b>a</b> <font>f</font></p><p class="ad">x</p><article>inner</article></article><table></table><script>s()</script>`)
+	out := r.Rewrite(doc)
+
+	expected := `<body><article><p><strong>a</strong> f</p><article>inner</article></article><div><table></table></div></body>`
+	if got := renderString(doc.FirstChild.LastChild); got != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, got)
+	}
+	// the nested article is not duplicated
+	expected = `<article><p><strong>a</strong> f</p><article>inner</article></article>`
+	if got := renderString(out); got != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, got)
+	}
+}
+
+func TestRewriterConflicts(t *testing.T) {
+	var r Rewriter
+	if err := r.AddRules("rename: p.keep => section\ndrop: p\nrename: li => p\nunwrap: li"); err != nil {
+		t.Fatal(err)
+	}
+	doc := MustParseHTML(`<p class="keep">k</p><p>dropped</p><ul><li>one</li></ul>`)
+	r.Rewrite(doc)
+	// the most specific rule wins, then the last one
+	expected := `<body><section class="keep">k</section><ul>one</ul></body>`
+	if got := renderString(doc.FirstChild.LastChild); got != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, got)
+	}
+}
+
+func TestRewriterErrors(t *testing.T) {
+	var r Rewriter
+	for _, rules := range []string{"rename p", "move: p", "rename: p", "wrap: p =>", "drop: p["} {
+		if err := r.AddRules(rules); err == nil {
+			t.Errorf("%q: expected an error", rules)
+		} else if !strings.HasPrefix(err.Error(), "line 1: ") {
+			t.Errorf("%q: unexpected error %s", rules, err)
+		}
+	}
+}
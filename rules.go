@@ -0,0 +1,234 @@
+package cascadia
+
+import (
+	"sort"
+
+	"golang.org/x/net/html"
+)
+
+// RuleSet indexes the selectors of style rules by their key (see RuleKey),
+// so that finding the rules matching an element only tries the selectors
+// which may match its id, classes or tag.
+// The zero value is an empty set, ready to use.
+type RuleSet struct {
+	ids, classes, tags map[string][]ruleEntry
+	universal          []ruleEntry
+
+	rules int // number of calls to Add, giving the source order
+
+	active func(condition string) bool // see WithConditions
+}
+
+// ruleEntry is one selector of a rule
+type ruleEntry struct {
+	sel    Sel
+	id     int
+	order  int
+	origin Origin
+
+	conditions []string // all must be active
+}
+
+// MatchedRule is a rule matching an element, returned by RulesFor.
+type MatchedRule struct {
+	ID int // as given to Add
+	// Selector is the member of the selector list of the rule
+	// matching the element, with the highest specificity.
+	Selector    Sel
+	Specificity Specificity
+	// Order is the position of the rule among the calls to Add,
+	// starting at 0.
+	Order  int
+	Origin Origin
+}
+
+// Add parses the selector list of a rule, like ParseGroupWithPseudoElements,
+// and adds it to the set, with the author origin. id is chosen by the caller
+// to identify the rule, and does not need to be unique.
+func (rs *RuleSet) Add(id int, selector string) error {
+	return rs.AddWithOrigin(id, selector, OriginAuthor)
+}
+
+// AddWithOrigin is like Add, for a rule of the given origin.
+func (rs *RuleSet) AddWithOrigin(id int, selector string, origin Origin) error {
+	group, err := ParseGroupWithPseudoElements(selector)
+	if err != nil {
+		return err
+	}
+	rs.AddGroupWithOrigin(id, group, origin)
+	return nil
+}
+
+// AddGroup is like Add, for an already parsed selector list.
+func (rs *RuleSet) AddGroup(id int, group SelectorGroup) {
+	rs.AddGroupWithOrigin(id, group, OriginAuthor)
+}
+
+// AddGroupWithOrigin is like AddWithOrigin, for an already parsed selector list.
+func (rs *RuleSet) AddGroupWithOrigin(id int, group SelectorGroup, origin Origin) {
+	rs.AddGroupConditional(id, group, origin)
+}
+
+// AddConditional is like AddWithOrigin, for a rule which only applies
+// when all the conditions are active, such as the rules nested in
+// @media and @supports blocks. The conditions are opaque keys, like the
+// text of the media query, enabled with WithConditions.
+func (rs *RuleSet) AddConditional(id int, selector string, origin Origin, conditions ...string) error {
+	group, err := ParseGroupWithPseudoElements(selector)
+	if err != nil {
+		return err
+	}
+	rs.AddGroupConditional(id, group, origin, conditions...)
+	return nil
+}
+
+// AddGroupConditional is like AddConditional, for an already parsed selector list.
+func (rs *RuleSet) AddGroupConditional(id int, group SelectorGroup, origin Origin, conditions ...string) {
+	order := rs.rules
+	rs.rules++
+	conditions = append([]string(nil), conditions...)
+	for _, sel := range group {
+		entry := ruleEntry{sel: sel, id: id, order: order, origin: origin, conditions: conditions}
+		key := RuleKey(sel)
+		switch key.Kind {
+		case KeyID:
+			rs.ids = addRuleEntry(rs.ids, key.Value, entry)
+		case KeyClass:
+			rs.classes = addRuleEntry(rs.classes, key.Value, entry)
+		case KeyTag:
+			rs.tags = addRuleEntry(rs.tags, key.Value, entry)
+		default:
+			rs.universal = append(rs.universal, entry)
+		}
+	}
+}
+
+func addRuleEntry(m map[string][]ruleEntry, key string, entry ruleEntry) map[string][]ruleEntry {
+	if m == nil {
+		m = make(map[string][]ruleEntry)
+	}
+	m[key] = append(m[key], entry)
+	return m
+}
+
+// Len returns the number of rules added to the set.
+func (rs *RuleSet) Len() int { return rs.rules }
+
+// WithConditions returns a view of rs, sharing its index, where the conditional
+// rules apply if active returns true for all their conditions.
+// In rs itself, as in a view with a nil active, only the unconditional
+// rules apply. The rules must not be added to the view, but may be added
+// to rs before calling WithConditions again.
+func (rs *RuleSet) WithConditions(active func(condition string) bool) *RuleSet {
+	view := *rs
+	view.active = active
+	return &view
+}
+
+// applies returns true if the conditions of e are active
+func (rs *RuleSet) applies(e ruleEntry) bool {
+	for _, c := range e.conditions {
+		if rs.active == nil || !rs.active(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// RulesFor returns the rules matching the element n, in source order.
+// The selectors with a pseudo-element are ignored: use PseudoElementRulesFor
+// to style the pseudo-elements.
+func (rs *RuleSet) RulesFor(n *html.Node) []MatchedRule {
+	return rs.RulesForNode(FromHTML(n))
+}
+
+// RulesForNode is like RulesFor, for any Node.
+func (rs *RuleSet) RulesForNode(n Node) []MatchedRule {
+	return rs.rulesFor(n, func(pe string) bool { return pe == "" })
+}
+
+// PseudoElementRulesFor returns the rules matching the pseudo-element
+// of the element n, such as "before" or "first-line", in source order.
+func (rs *RuleSet) PseudoElementRulesFor(n *html.Node, pseudoElement string) []MatchedRule {
+	return rs.rulesFor(FromHTML(n), func(pe string) bool { return pe == pseudoElement })
+}
+
+// CriticalRules returns the rules matching at least one of nodes, or one
+// of their pseudo-elements, in source order: they are the rules required
+// to style these nodes, such as the elements above the fold of a page.
+// Each rule is returned once, with the Selector and Specificity of its
+// first match.
+func (rs *RuleSet) CriticalRules(nodes []*html.Node) []MatchedRule {
+	var out []MatchedRule
+	seen := map[int]bool{} // by order
+	anyPseudo := func(string) bool { return true }
+	for _, n := range nodes {
+		for _, r := range rs.rulesFor(FromHTML(n), anyPseudo) {
+			if !seen[r.Order] {
+				seen[r.Order] = true
+				out = append(out, r)
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Order < out[j].Order })
+	return out
+}
+
+// rulesFor returns the rules matching n, restricted to the selectors
+// whose pseudo-element is accepted.
+func (rs *RuleSet) rulesFor(n Node, acceptPseudo func(pseudoElement string) bool) []MatchedRule {
+	if n == nil || n.Type() != html.ElementNode {
+		return nil
+	}
+	var (
+		out   []MatchedRule
+		index = map[int]int{} // order -> index in out
+	)
+	try := func(entries []ruleEntry) {
+		for _, e := range entries {
+			if !acceptPseudo(e.sel.PseudoElement()) || !rs.applies(e) || !MatchNode(e.sel, n) {
+				continue
+			}
+			spec := e.sel.Specificity()
+			if i, ok := index[e.order]; ok {
+				// another selector of the same rule: keep the most specific
+				if out[i].Specificity.Less(spec) {
+					out[i].Selector, out[i].Specificity = e.sel, spec
+				}
+				continue
+			}
+			index[e.order] = len(out)
+			out = append(out, MatchedRule{ID: e.id, Selector: e.sel, Specificity: spec, Order: e.order, Origin: e.origin})
+		}
+	}
+
+	var ids, classes []string
+	for _, a := range n.Attributes() {
+		switch a.Key {
+		case "id":
+			ids = appendUnique(ids, a.Val)
+		case "class":
+			for _, c := range SplitTokens(a.Val) {
+				classes = appendUnique(classes, c)
+			}
+		}
+	}
+	for _, id := range ids {
+		try(rs.ids[id])
+	}
+	for _, c := range classes {
+		try(rs.classes[c])
+	}
+	try(rs.tags[n.Data()])
+	try(rs.universal)
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Order < out[j].Order })
+	return out
+}
+
+func appendUnique(list []string, s string) []string {
+	if containsString(list, s) {
+		return list
+	}
+	return append(list, s)
+}
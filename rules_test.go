@@ -0,0 +1,170 @@
+package cascadia
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+var testRules = []string{
+	"p",
+	"#main, .content > p",
+	"*",
+	"div p.a, p:first-child",
+	"[lang|=fr]",
+	"p::before",
+	".a.b",
+	"div > *:not(p)",
+	"section p, p.a",
+}
+
+func TestRuleSet(t *testing.T) {
+	doc := MustParseHTML(`<div id="main" class="content">
+		<p class="a b a">one</p><p lang="fr-CA">two</p><span>three</span></div>
+		<section><p class="a">four</p></section>`)
+	var rs RuleSet
+	for i, rule := range testRules {
+		if err := rs.Add(i, rule); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if rs.Len() != len(testRules) {
+		t.Errorf("unexpected length %d", rs.Len())
+	}
+
+	for _, test := range []struct {
+		sel string
+		exp string // rule ids and specificities
+	}{
+		{"#main", "1(1,0,0) 2(0,0,0)"},
+		{"p.a.b", "0(0,0,1) 1(0,1,1) 2(0,0,0) 3(0,1,2) 6(0,2,0) 8(0,1,1)"},
+		{"p[lang]", "0(0,0,1) 1(0,1,1) 2(0,0,0) 4(0,1,0)"},
+		{"span", "2(0,0,0) 7(0,0,2)"},
+		{"section p", "0(0,0,1) 2(0,0,0) 3(0,1,1) 8(0,1,1)"},
+	} {
+		n := Query(doc, MustCompile(test.sel))
+		var got []string
+		for _, r := range rs.RulesFor(n) {
+			got = append(got, fmt.Sprintf("%d%s", r.ID, r.Specificity))
+			if !r.Selector.Match(n) || r.Selector.Specificity() != r.Specificity {
+				t.Errorf("%s: inconsistent selector %s", test.sel, r.Selector)
+			}
+		}
+		if strings.Join(got, " ") != test.exp {
+			t.Errorf("%s: expected %s, got %s", test.sel, test.exp, strings.Join(got, " "))
+		}
+	}
+
+	p := Query(doc, MustCompile("p"))
+	if rules := rs.PseudoElementRulesFor(p, "before"); len(rules) != 1 || rules[0].ID != 5 {
+		t.Errorf("unexpected pseudo-element rules %v", rules)
+	}
+	if rules := rs.RulesFor(p.FirstChild); rules != nil {
+		t.Errorf("expected no rules for a text node, got %v", rules)
+	}
+	if err := rs.Add(10, "p["); err == nil {
+		t.Error("expected an error for an invalid selector")
+	}
+}
+
+// TestRuleSetIndex checks that the index gives the same result
+// as trying every rule
+func TestRuleSetIndex(t *testing.T) {
+	for _, test := range selectorTests {
+		doc, err := html.Parse(strings.NewReader(test.HTML))
+		if err != nil {
+			t.Fatal(err)
+		}
+		group, err := ParseGroup(test.selector)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var rs RuleSet
+		for i, sel := range group {
+			rs.AddGroup(i, SelectorGroup{sel})
+		}
+		for _, n := range QueryAll(doc, SelectorGroup{CompoundSelector{}}) {
+			var exp []int
+			for i, sel := range group {
+				if sel.Match(n) {
+					exp = append(exp, i)
+				}
+			}
+			got := rs.RulesFor(n)
+			if len(got) != len(exp) {
+				t.Errorf("%s: expected %d rules, got %d", test.selector, len(exp), len(got))
+				continue
+			}
+			for i, r := range got {
+				if r.ID != exp[i] || r.Order != exp[i] {
+					t.Errorf("%s: unexpected rule %v", test.selector, r)
+				}
+			}
+		}
+	}
+}
+
+func TestRuleSetConditions(t *testing.T) {
+	p := Query(MustParseHTML(`<p class="a">text</p>`), MustCompile("p"))
+	var rs RuleSet
+	if err := rs.Add(0, "p"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rs.AddConditional(1, "p.a", OriginAuthor, "screen"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rs.AddConditional(2, "p", OriginAuthor, "screen", "(display: grid)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rs.AddConditional(3, "p[", OriginAuthor, "print"); err == nil {
+		t.Error("expected an error for an invalid selector")
+	}
+
+	ids := func(rs *RuleSet) string {
+		var out []string
+		for _, r := range rs.RulesFor(p) {
+			out = append(out, fmt.Sprint(r.ID))
+		}
+		return strings.Join(out, " ")
+	}
+	for _, test := range []struct {
+		active []string
+		exp    string
+	}{
+		{nil, "0"},
+		{[]string{"print"}, "0"},
+		{[]string{"screen"}, "0 1"},
+		{[]string{"screen", "(display: grid)"}, "0 1 2"},
+	} {
+		view := rs.WithConditions(func(c string) bool { return containsString(test.active, c) })
+		if got := ids(view); got != test.exp {
+			t.Errorf("%v: expected %s, got %s", test.active, test.exp, got)
+		}
+	}
+	if got := ids(&rs); got != "0" {
+		t.Errorf("expected only the unconditional rules, got %s", got)
+	}
+}
+
+func TestCriticalRules(t *testing.T) {
+	doc := MustParseHTML(`<header><h1 class="title">T</h1></header><main><p>text</p><footer>f</footer></main>`)
+	var rs RuleSet
+	for i, rule := range []string{"h1, footer", "main p", ".title::before", "footer", "header", "p"} {
+		if err := rs.Add(i*10, rule); err != nil {
+			t.Fatal(err)
+		}
+	}
+	aboveTheFold := QueryAll(doc, MustCompile("header, h1"))
+	var got []string
+	for _, r := range rs.CriticalRules(aboveTheFold) {
+		got = append(got, fmt.Sprint(r.ID))
+	}
+	if strings.Join(got, " ") != "0 20 40" {
+		t.Errorf("unexpected critical rules %v", got)
+	}
+	if rules := rs.CriticalRules(nil); len(rules) != 0 {
+		t.Errorf("expected no rules, got %v", rules)
+	}
+}
@@ -0,0 +1,245 @@
+package cascadia
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Sanitizer rewrites HTML trees according to rules expressed
+// as selectors, so that structural conditions (like "the images
+// inside links") may be used to decide what is kept.
+//
+// Whatever the rules, the event handler attributes (onclick, ...)
+// and the URL attributes using the javascript: or vbscript:
+// schemes are always removed, as well as the SVG animation elements
+// (like <animate> and <set>) targeting an URL attribute, whose
+// values would bypass this check.
+// The zero value has no other rule: see NewSanitizer for safe defaults.
+type Sanitizer struct {
+	remove, unwrap SelectorGroup
+	keepAttrs      []keepAttrsRule
+}
+
+type keepAttrsRule struct {
+	sel   SelectorGroup
+	attrs []string // lower-cased
+}
+
+// DefaultRemovedElements are the elements removed by the
+// sanitizers returned by NewSanitizer.
+const DefaultRemovedElements = "script, style, iframe, frame, frameset, object, embed, applet, base, link, meta, template"
+
+// NewSanitizer returns a sanitizer removing the elements
+// listed in DefaultRemovedElements.
+func NewSanitizer() *Sanitizer {
+	var s Sanitizer
+	if err := s.Remove(DefaultRemovedElements); err != nil {
+		panic(err) // DefaultRemovedElements is valid
+	}
+	return &s
+}
+
+// Remove adds a rule removing the elements matching selector,
+// with their content.
+func (s *Sanitizer) Remove(selector string) error {
+	group, err := ParseGroup(selector)
+	if err != nil {
+		return err
+	}
+	s.remove = append(s.remove, group...)
+	return nil
+}
+
+// Unwrap adds a rule replacing the elements matching selector
+// by their content.
+func (s *Sanitizer) Unwrap(selector string) error {
+	group, err := ParseGroup(selector)
+	if err != nil {
+		return err
+	}
+	s.unwrap = append(s.unwrap, group...)
+	return nil
+}
+
+// KeepAttrs adds a rule restricting the attributes of the elements
+// matching selector to attrs. When several rules match an element,
+// the attributes allowed by any of them are kept. The elements matched
+// by no such rule keep all their attributes.
+func (s *Sanitizer) KeepAttrs(selector string, attrs ...string) error {
+	group, err := ParseGroup(selector)
+	if err != nil {
+		return err
+	}
+	rule := keepAttrsRule{sel: group}
+	for _, a := range attrs {
+		rule.attrs = append(rule.attrs, toLowerASCII(strings.TrimSpace(a)))
+	}
+	s.keepAttrs = append(s.keepAttrs, rule)
+	return nil
+}
+
+// AddRules adds the rules described by text, one per line, like
+//
+//	remove: script, [onclick]
+//	unwrap: font
+//	keep-attrs: a => href|title
+//
+// Empty lines are ignored.
+func (s *Sanitizer) AddRules(text string) error {
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		action, arg, ok := strings.Cut(line, ":")
+		if !ok {
+			return fmt.Errorf("line %d: missing ':' in sanitizer rule %q", i+1, line)
+		}
+		var err error
+		switch strings.TrimSpace(action) {
+		case "remove":
+			err = s.Remove(arg)
+		case "unwrap":
+			err = s.Unwrap(arg)
+		case "keep-attrs":
+			selector, attrs, ok := strings.Cut(arg, "=>")
+			if !ok {
+				return fmt.Errorf("line %d: missing '=>' in keep-attrs rule %q", i+1, line)
+			}
+			err = s.KeepAttrs(selector, strings.Split(attrs, "|")...)
+		default:
+			return fmt.Errorf("line %d: unknown sanitizer action %q", i+1, strings.TrimSpace(action))
+		}
+		if err != nil {
+			return fmt.Errorf("line %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// sanitizeAction is an edit decided on the original tree
+type sanitizeAction struct {
+	n      *html.Node
+	unwrap bool
+	attrs  []html.Attribute // the new attributes, if not unwrapped
+}
+
+// Sanitize applies the rules to the descendants of n, in place.
+// The selectors are matched against the original tree, in one traversal,
+// before the tree is modified.
+func (s *Sanitizer) Sanitize(n *html.Node) {
+	var (
+		removed []*html.Node
+		actions []sanitizeAction
+	)
+	var visit func(n *html.Node)
+	visit = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			if s.remove.Match(c) || isUnsafeAnimation(c) {
+				removed = append(removed, c)
+				continue
+			}
+			if s.unwrap.Match(c) {
+				actions = append(actions, sanitizeAction{n: c, unwrap: true})
+			} else {
+				actions = append(actions, sanitizeAction{n: c, attrs: s.filterAttrs(c)})
+			}
+			visit(c)
+		}
+	}
+	visit(n)
+
+	for _, c := range removed {
+		c.Parent.RemoveChild(c)
+	}
+	for _, a := range actions {
+		if !a.unwrap {
+			a.n.Attr = a.attrs
+			continue
+		}
+		for a.n.FirstChild != nil {
+			child := a.n.FirstChild
+			a.n.RemoveChild(child)
+			a.n.Parent.InsertBefore(child, a.n)
+		}
+		a.n.Parent.RemoveChild(a.n)
+	}
+}
+
+// filterAttrs returns the attributes of n to keep
+func (s *Sanitizer) filterAttrs(n *html.Node) []html.Attribute {
+	var allowed []string
+	restricted := false
+	for _, rule := range s.keepAttrs {
+		if rule.sel.Match(n) {
+			restricted = true
+			allowed = append(allowed, rule.attrs...)
+		}
+	}
+	out := n.Attr[:0:0]
+	for _, a := range n.Attr {
+		if isUnsafeAttr(a) || (restricted && !containsString(allowed, a.Key)) {
+			continue
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+// urlAttributes are the attributes whose value is an URL
+var urlAttributes = map[string]bool{
+	"href": true, "src": true, "action": true, "formaction": true,
+	"poster": true, "background": true, "cite": true, "data": true,
+}
+
+// svgAnimations are the SVG elements animating an attribute,
+// as written by the HTML parser
+var svgAnimations = map[string]bool{
+	"animate": true, "set": true, "animateMotion": true, "animateTransform": true,
+}
+
+// isUnsafeAnimation returns true for the SVG animation elements
+// modifying an URL attribute, like <set attributeName="href" to="javascript:...">
+func isUnsafeAnimation(n *html.Node) bool {
+	if n.Namespace != "svg" || !svgAnimations[n.Data] {
+		return false
+	}
+	for _, a := range n.Attr {
+		if a.Key != "attributeName" {
+			continue
+		}
+		target := toLowerASCII(strings.TrimSpace(a.Val))
+		if _, local, ok := strings.Cut(target, ":"); ok {
+			target = local // as in xlink:href
+		}
+		if urlAttributes[target] {
+			return true
+		}
+	}
+	return false
+}
+
+// isUnsafeAttr returns true for the event handlers and
+// the script URLs
+func isUnsafeAttr(a html.Attribute) bool {
+	if strings.HasPrefix(a.Key, "on") {
+		return true
+	}
+	if !urlAttributes[a.Key] {
+		return false
+	}
+	// browsers ignore the ASCII whitespace and control characters in the scheme
+	scheme := strings.Map(func(r rune) rune {
+		if r <= ' ' {
+			return -1
+		}
+		return r
+	}, a.Val)
+	scheme = toLowerASCII(scheme)
+	return strings.HasPrefix(scheme, "javascript:") || strings.HasPrefix(scheme, "vbscript:")
+}
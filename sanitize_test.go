@@ -0,0 +1,105 @@
+package cascadia
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+// renderBody returns the HTML of the content of the body of doc
+func renderBody(t *testing.T, doc *html.Node) string {
+	body := Query(doc, MustCompile("body"))
+	var b strings.Builder
+	for c := body.FirstChild; c != nil; c = c.NextSibling {
+		if err := html.Render(&b, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return b.String()
+}
+
+func TestSanitizer(t *testing.T) {
+	s := NewSanitizer()
+	err := s.AddRules(`
+		remove: [data-tracking], div.ad
+		unwrap: font, span:not([class])
+		keep-attrs: a => href | title
+		keep-attrs: a.ext => rel
+		keep-attrs: img => src
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		input, exp string
+	}{
+		{
+			`<p>a<script>alert(1)</script><style>p{}</style>b</p>`,
+			`<p>ab</p>`,
+		},
+		{
+			`<div class="ad"><p>x</p></div><p data-tracking="1">y</p><p>z</p>`,
+			`<p>z</p>`,
+		},
+		{
+			`<font color="red">a<font>b</font><b>c</b></font>`,
+			`ab<b>c</b>`,
+		},
+		{
+			`<span>a</span><span class="x">b</span>`,
+			`a<span class="x">b</span>`,
+		},
+		{
+			`<a href="/x" title="t" rel="r" class="c" onclick="f()">l</a><a class="ext" href="/y" rel="r">m</a>`,
+			`<a href="/x" title="t">l</a><a href="/y" rel="r">m</a>`,
+		},
+		{
+			`<a href=" JavaScript:alert(1)">l</a><img src="vbscript:x" alt="a"><p onmouseover="f()" class="c">t</p>`,
+			`<a>l</a><img/><p class="c">t</p>`,
+		},
+		{
+			`<svg><a><animate attributeName="href" values="javascript:alert(1)"></animate><text>x</text></a></svg>`,
+			`<svg><a><text>x</text></a></svg>`,
+		},
+		{
+			`<svg><a><set attributeName="xlink:href" to="javascript:alert(1)"></set><animate attributeName="x" values="0;1"></animate></a></svg>`,
+			`<svg><a><animate attributeName="x" values="0;1"></animate></a></svg>`,
+		},
+		{
+			// the selectors are matched against the original tree
+			`<font><span>a</span></font><div><font><i>b</i></font></div>`,
+			`a<div><i>b</i></div>`,
+		},
+	} {
+		doc := MustParseHTML(test.input)
+		s.Sanitize(doc)
+		if got := renderBody(t, doc); got != test.exp {
+			t.Errorf("%s: expected %s, got %s", test.input, test.exp, got)
+		}
+	}
+}
+
+func TestSanitizerRulesErrors(t *testing.T) {
+	for _, rules := range []string{
+		"remove script",
+		"drop: script",
+		"remove: script[",
+		"keep-attrs: a",
+		"unwrap: :unknown",
+	} {
+		var s Sanitizer
+		if err := s.AddRules(rules); err == nil {
+			t.Errorf("%s: expected an error", rules)
+		}
+	}
+
+	// the zero value only removes the unsafe attributes
+	var s Sanitizer
+	doc := MustParseHTML(`<p><script>x</script></p><a href="javascript:x" onclick="y">l</a>`)
+	s.Sanitize(doc)
+	if got := renderBody(t, doc); got != `<p><script>x</script></p><a>l</a>` {
+		t.Errorf("unexpected result %s", got)
+	}
+}
@@ -0,0 +1,260 @@
+package cascadia
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Extraction is the kind of value extracted by a Field.
+type Extraction uint8
+
+const (
+	ExtractText      Extraction = iota // the text content
+	ExtractAttr                        // the value of an attribute
+	ExtractHTML                        // the outer HTML
+	ExtractInnerHTML                   // the HTML of the children
+)
+
+var extractionNames = [...]string{"text", "attr", "html", "inner-html"}
+
+func (e Extraction) String() string {
+	if int(e) < len(extractionNames) {
+		return extractionNames[e]
+	}
+	return fmt.Sprintf("Extraction(%d)", e)
+}
+
+// MarshalText implements encoding.TextMarshaler, so that
+// the schemas may be stored in configuration files.
+func (e Extraction) MarshalText() ([]byte, error) { return []byte(e.String()), nil }
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (e *Extraction) UnmarshalText(text []byte) error {
+	for i, name := range extractionNames {
+		if string(text) == name {
+			*e = Extraction(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown extraction %q", text)
+}
+
+// Field is a named value of a Schema.
+type Field struct {
+	Name string `json:"name"`
+	// Selector is matched against the descendants of the scope of the field;
+	// an empty selector designates the scope itself.
+	Selector string     `json:"selector,omitempty"`
+	Kind     Extraction `json:"kind,omitempty"`
+	Attr     string     `json:"attr,omitempty"` // for ExtractAttr
+	// Multiple returns the values of all the matching elements,
+	// as a []any, instead of the one of the first match (or nil).
+	Multiple bool `json:"multiple,omitempty"`
+	// Transforms are the names of the transforms applied in order to the
+	// extracted strings, looked up in Schema.Transforms then DefaultTransforms.
+	Transforms []string `json:"transforms,omitempty"`
+	// Fields, if not empty, makes the value of each match a nested record,
+	// with the match as scope. Kind, Attr and Transforms are then ignored.
+	Fields []Field `json:"fields,omitempty"`
+}
+
+// ValueTransform is a post-processing step of an extracted value.
+type ValueTransform func(value any) (any, error)
+
+// DefaultTransforms are the transforms available to every schema.
+// The numeric ones fail on invalid input.
+var DefaultTransforms = map[string]ValueTransform{
+	"trim":     stringTransform(strings.TrimSpace),
+	"lower":    stringTransform(strings.ToLower),
+	"upper":    stringTransform(strings.ToUpper),
+	"collapse": stringTransform(func(s string) string { return strings.Join(strings.Fields(s), " ") }),
+	"int": func(v any) (any, error) {
+		return strconv.Atoi(strings.TrimSpace(fmt.Sprint(v)))
+	},
+	"float": func(v any) (any, error) {
+		return strconv.ParseFloat(strings.TrimSpace(fmt.Sprint(v)), 64)
+	},
+}
+
+func stringTransform(f func(string) string) ValueTransform {
+	return func(v any) (any, error) { return f(fmt.Sprint(v)), nil }
+}
+
+// Schema describes the record extracted from a document
+// by a scraper.
+type Schema struct {
+	Fields []Field `json:"fields"`
+	// Transforms are custom transforms, which have
+	// precedence over DefaultTransforms.
+	Transforms map[string]ValueTransform `json:"-"`
+}
+
+// CompiledSchema is a Schema whose selectors and transforms
+// have been resolved, returned by Compile.
+type CompiledSchema struct {
+	fields []compiledField
+}
+
+type compiledField struct {
+	Field
+	selector   SelectorGroup // nil for the scope
+	transforms []ValueTransform
+	fields     []compiledField
+}
+
+// Compile parses the selectors of the schema and resolves its transforms.
+func (s Schema) Compile() (*CompiledSchema, error) {
+	fields, err := s.compileFields(s.Fields, "")
+	if err != nil {
+		return nil, err
+	}
+	return &CompiledSchema{fields: fields}, nil
+}
+
+func (s Schema) compileFields(fields []Field, prefix string) ([]compiledField, error) {
+	out := make([]compiledField, len(fields))
+	for i, f := range fields {
+		path := prefix + f.Name
+		cf := compiledField{Field: f}
+		if strings.TrimSpace(f.Selector) != "" {
+			var err error
+			if cf.selector, err = ParseGroup(f.Selector); err != nil {
+				return nil, fmt.Errorf("field %s: %w", path, err)
+			}
+		}
+		if len(f.Fields) != 0 {
+			var err error
+			if cf.fields, err = s.compileFields(f.Fields, path+"."); err != nil {
+				return nil, err
+			}
+		} else {
+			for _, name := range f.Transforms {
+				t, ok := s.Transforms[name]
+				if !ok {
+					t, ok = DefaultTransforms[name]
+				}
+				if !ok {
+					return nil, fmt.Errorf("field %s: unknown transform %q", path, name)
+				}
+				cf.transforms = append(cf.transforms, t)
+			}
+		}
+		out[i] = cf
+	}
+	return out, nil
+}
+
+// Extract evaluates the schema against n, which is the scope
+// of the top-level fields.
+func (s Schema) Extract(n *html.Node) (map[string]any, error) {
+	c, err := s.Compile()
+	if err != nil {
+		return nil, err
+	}
+	return c.Extract(n)
+}
+
+// Extract evaluates the schema against n, which is the scope
+// of the top-level fields.
+func (c *CompiledSchema) Extract(n *html.Node) (map[string]any, error) {
+	return extractRecord(c.fields, n)
+}
+
+// ExtractJSON is like Extract, but returns the record encoded in JSON,
+// without escaping the HTML characters.
+func (c *CompiledSchema) ExtractJSON(n *html.Node) ([]byte, error) {
+	record, err := c.Extract(n)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(record); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+func extractRecord(fields []compiledField, scope *html.Node) (map[string]any, error) {
+	out := make(map[string]any, len(fields))
+	for _, f := range fields {
+		var matches []*html.Node
+		switch {
+		case f.selector == nil:
+			matches = []*html.Node{scope}
+		case f.Multiple:
+			matches = QueryAll(scope, f.selector)
+		default:
+			if m := Query(scope, f.selector); m != nil {
+				matches = []*html.Node{m}
+			}
+		}
+
+		values := []any{}
+		for _, m := range matches {
+			v, ok, err := f.extract(m)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				values = append(values, v)
+			}
+		}
+		if f.Multiple {
+			out[f.Name] = values
+		} else if len(values) != 0 {
+			out[f.Name] = values[0]
+		} else {
+			out[f.Name] = nil
+		}
+	}
+	return out, nil
+}
+
+// extract returns the value of the field for n, or false
+// if n has not the required attribute
+func (f compiledField) extract(n *html.Node) (any, bool, error) {
+	if len(f.fields) != 0 {
+		record, err := extractRecord(f.fields, n)
+		return record, true, err
+	}
+	var value any
+	switch f.Kind {
+	case ExtractAttr:
+		found := false
+		for _, a := range n.Attr {
+			if a.Key == f.Attr {
+				value, found = a.Val, true
+				break
+			}
+		}
+		if !found {
+			return nil, false, nil
+		}
+	case ExtractHTML, ExtractInnerHTML:
+		var buf bytes.Buffer
+		if f.Kind == ExtractHTML {
+			html.Render(&buf, n) // writing to a bytes.Buffer never fails
+		} else {
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				html.Render(&buf, c)
+			}
+		}
+		value = buf.String()
+	default:
+		value = nodeText(FromHTML(n))
+	}
+	for _, t := range f.transforms {
+		var err error
+		if value, err = t(value); err != nil {
+			return nil, false, fmt.Errorf("field %s: %w", f.Name, err)
+		}
+	}
+	return value, true, nil
+}
@@ -0,0 +1,101 @@
+package cascadia
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const testSchemaPage = `<h1> Products </h1>
+<ul>
+	<li class="item" data-id="1"><a href="/p1">First</a> <span class="price">12.5</span> <span class="tag">new</span> <span class="tag">HOT</span></li>
+	<li class="item" data-id="2"><a href="/p2"><b>Second</b></a> <span class="price">3</span></li>
+	<li class="item"><a>Third</a><span class="price">n/a</span></li>
+</ul>`
+
+// schemaJSON is a schema as it may be found in a configuration file
+const schemaJSON = `{"fields": [
+	{"name": "title", "selector": "h1", "transforms": ["trim", "lower"]},
+	{"name": "count", "selector": "li.item", "multiple": true, "fields": [
+		{"name": "id", "kind": "attr", "attr": "data-id", "transforms": ["int"]}
+	]},
+	{"name": "items", "selector": "li.item", "multiple": true, "fields": [
+		{"name": "name", "selector": "a"},
+		{"name": "link", "selector": "a", "kind": "attr", "attr": "href"},
+		{"name": "label", "selector": "a", "kind": "inner-html"},
+		{"name": "tags", "selector": ".tag", "multiple": true, "transforms": ["shout"]}
+	]},
+	{"name": "missing", "selector": "table"},
+	{"name": "first", "selector": "li", "kind": "html", "transforms": ["collapse"]}
+]}`
+
+func TestSchema(t *testing.T) {
+	var schema Schema
+	if err := json.Unmarshal([]byte(schemaJSON), &schema); err != nil {
+		t.Fatal(err)
+	}
+	schema.Transforms = map[string]ValueTransform{
+		"shout": func(v any) (any, error) { return strings.ToUpper(v.(string)) + "!", nil },
+	}
+	c, err := schema.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.ExtractJSON(MustParseHTML(testSchemaPage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := `{"count":[{"id":1},{"id":2},{"id":null}],` +
+		`"first":"<li class=\"item\" data-id=\"1\"><a href=\"/p1\">First</a> <span class=\"price\">12.5</span> <span class=\"tag\">new</span> <span class=\"tag\">HOT</span></li>",` +
+		`"items":[{"label":"First","link":"/p1","name":"First","tags":["NEW!","HOT!"]},` +
+		`{"label":"<b>Second</b>","link":"/p2","name":"Second","tags":[]},` +
+		`{"label":"Third","link":null,"name":"Third","tags":[]}],` +
+		`"missing":null,"title":"products"}`
+	if string(got) != exp {
+		t.Errorf("expected\n%s\ngot\n%s", exp, got)
+	}
+
+	// round trip of the configuration
+	b, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema2 Schema
+	if err := json.Unmarshal(b, &schema2); err != nil {
+		t.Fatal(err)
+	}
+	if len(schema2.Fields) != len(schema.Fields) || schema2.Fields[2].Fields[2].Kind != ExtractInnerHTML {
+		t.Errorf("unexpected round trip %s", b)
+	}
+}
+
+func TestSchemaErrors(t *testing.T) {
+	for _, schema := range []Schema{
+		{Fields: []Field{{Name: "a", Selector: "p["}}},
+		{Fields: []Field{{Name: "a", Transforms: []string{"unknown"}}}},
+		{Fields: []Field{{Name: "a", Fields: []Field{{Name: "b", Selector: ":x"}}}}},
+	} {
+		if _, err := schema.Compile(); err == nil {
+			t.Errorf("%v: expected an error", schema)
+		}
+		if _, err := schema.Extract(MustParseHTML("")); err == nil {
+			t.Errorf("%v: expected an error", schema)
+		}
+	}
+
+	schema := Schema{Fields: []Field{{Name: "price", Selector: ".price", Multiple: true, Transforms: []string{"float"}}}}
+	_, err := schema.Extract(MustParseHTML(testSchemaPage))
+	var numErr interface{ Unwrap() error }
+	if err == nil || !errors.As(err, &numErr) || !strings.Contains(err.Error(), "field price") {
+		t.Errorf("expected a transform error, got %v", err)
+	}
+
+	var e Extraction
+	if err := e.UnmarshalText([]byte("xml")); err == nil {
+		t.Error("expected an error for an unknown extraction")
+	}
+	if s := Extraction(10).String(); s != "Extraction(10)" {
+		t.Errorf("unexpected name %s", s)
+	}
+}
@@ -0,0 +1,162 @@
+package cascadia
+
+import "golang.org/x/net/html"
+
+// ScopeKind defines how the tree around a scope is presented
+// to the selectors, see Scope.
+type ScopeKind uint8
+
+const (
+	// ScopeRoot makes the scope element the root element of a virtual
+	// document: it matches :root (and html, if it is an <html> element),
+	// it has no siblings and no ancestors, and, like the <html> element,
+	// it is not matched by the child-indexed pseudo-classes (:first-child, ...).
+	ScopeRoot ScopeKind = iota
+	// ScopeFragment makes the children of the scope node the children of a
+	// document fragment, as with the DocumentFragment.querySelectorAll
+	// method of browsers: no element matches :root (except the <html>
+	// elements, which always do), the scope node
+	// itself can't be matched, and the child-indexed pseudo-classes
+	// apply to its children.
+	ScopeFragment
+)
+
+// fragmentNode is the type of the virtual node of a ScopeFragment,
+// which is neither a document nor an element.
+const fragmentNode html.NodeType = 0xff
+
+// Scope returns a view of the subtree of n, where the nodes outside of it
+// are invisible to the selectors, so that the results match the ones of
+// browsers querying a detached fragment (matching a node without parent
+// only depends on its descendants, since the child-indexed pseudo-classes
+// require a parent element).
+//
+// The view of n is returned: for ScopeRoot, its parent is a virtual document;
+// for ScopeFragment, its Type is neither html.DocumentNode nor html.ElementNode.
+// The nodes of the view are converted back with Unscope.
+func Scope(n Node, kind ScopeKind) Node {
+	if n == nil {
+		return nil
+	}
+	return scopedNode{n: n, scope: &scopeInfo{root: n, kind: kind}}
+}
+
+// Unscope returns the node viewed by n, if it has been returned
+// by a Scope view, or n itself.
+func Unscope(n Node) Node {
+	if s, ok := n.(scopedNode); ok {
+		if s.n == nil { // the virtual document
+			return nil
+		}
+		return s.n
+	}
+	return n
+}
+
+// QueryAllScoped returns the elements of the subtree of n, matched by m
+// in the view returned by Scope(FromHTML(n), kind). For ScopeRoot, n
+// itself may be matched.
+func QueryAllScoped(n *html.Node, m NodeMatcher, kind ScopeKind) []*html.Node {
+	view := Scope(FromHTML(n), kind)
+	var out []*html.Node
+	if kind == ScopeRoot {
+		view = view.Parent() // the virtual document
+	}
+	for _, match := range QueryAllNodes(view, m) {
+		out = append(out, ToHTML(Unscope(match)))
+	}
+	return out
+}
+
+type scopeInfo struct {
+	root Node
+	kind ScopeKind
+}
+
+// scopedNode is a node of a Scope view. The virtual
+// document of a ScopeRoot view has a nil n.
+type scopedNode struct {
+	n     Node
+	scope *scopeInfo
+}
+
+func (s scopedNode) wrap(n Node) Node {
+	if n == nil {
+		return nil
+	}
+	return scopedNode{n: n, scope: s.scope}
+}
+
+func (s scopedNode) isRoot() bool { return s.n != nil && s.n == s.scope.root }
+
+func (s scopedNode) Type() html.NodeType {
+	switch {
+	case s.n == nil:
+		return html.DocumentNode
+	case s.isRoot() && s.scope.kind == ScopeFragment:
+		return fragmentNode
+	}
+	return s.n.Type()
+}
+
+func (s scopedNode) Data() string {
+	if s.n == nil || (s.isRoot() && s.scope.kind == ScopeFragment) {
+		return ""
+	}
+	return s.n.Data()
+}
+
+func (s scopedNode) Namespace() string {
+	if s.n == nil || (s.isRoot() && s.scope.kind == ScopeFragment) {
+		return ""
+	}
+	return s.n.Namespace()
+}
+
+func (s scopedNode) Attributes() []html.Attribute {
+	if s.n == nil || (s.isRoot() && s.scope.kind == ScopeFragment) {
+		return nil
+	}
+	return s.n.Attributes()
+}
+
+func (s scopedNode) Parent() Node {
+	switch {
+	case s.n == nil:
+		return nil
+	case s.isRoot():
+		if s.scope.kind == ScopeRoot {
+			return scopedNode{scope: s.scope} // the virtual document
+		}
+		return nil
+	}
+	return s.wrap(s.n.Parent())
+}
+
+func (s scopedNode) FirstChild() Node {
+	if s.n == nil {
+		return s.wrap(s.scope.root)
+	}
+	return s.wrap(s.n.FirstChild())
+}
+
+func (s scopedNode) LastChild() Node {
+	if s.n == nil {
+		return s.wrap(s.scope.root)
+	}
+	return s.wrap(s.n.LastChild())
+}
+
+func (s scopedNode) PrevSibling() Node {
+	if s.n == nil || s.isRoot() {
+		return nil
+	}
+	return s.wrap(s.n.PrevSibling())
+}
+
+func (s scopedNode) NextSibling() Node {
+	if s.n == nil || s.isRoot() {
+		return nil
+	}
+	return s.wrap(s.n.NextSibling())
+}
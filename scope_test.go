@@ -0,0 +1,45 @@
+package cascadia
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScope(t *testing.T) {
+	doc := MustParseHTML(`<main><section id="s"><p id="a">a</p><div><p id="b">b</p></div><p id="c">c</p></section><p id="d">d</p></main>`)
+	scope := Query(doc, MustCompile("#s"))
+	for _, test := range []struct {
+		sel            string
+		root, fragment string // ids of the matches
+	}{
+		{"p", "a b c", "a b c"},
+		{":root", "s", ""},
+		{":root > p", "a c", ""},
+		{"main p", "", ""},
+		{"section p", "a b c", ""},
+		{"section", "s", ""},
+		{"p:first-child", "a b", "a b"},
+		{"section:first-child", "", ""},
+		{"p + div > p", "b", "b"},
+		{"div ~ p, p:last-child", "b c", "b c"},
+		{":root:first-child", "", ""},
+	} {
+		group, err := ParseGroup(test.sel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, kind := range []ScopeKind{ScopeRoot, ScopeFragment} {
+			var ids []string
+			for _, n := range QueryAllScoped(scope, group, kind) {
+				ids = append(ids, nodeID(n))
+			}
+			expected := test.root
+			if kind == ScopeFragment {
+				expected = test.fragment
+			}
+			if got := strings.Join(ids, " "); got != expected {
+				t.Errorf("%s (kind %d): expected %q, got %q", test.sel, kind, expected, got)
+			}
+		}
+	}
+}
@@ -16,7 +16,20 @@ type Matcher interface {
 	Match(n *html.Node) bool
 }
 
+// MatcherFunc is an adapter to use an ordinary function as a Matcher,
+// like http.HandlerFunc, so that one-off predicates may be passed
+// to Query, QueryAll or Filter, or combined with the selectors by And, Or and Not.
+type MatcherFunc func(n *html.Node) bool
+
+// Match returns f(n).
+func (f MatcherFunc) Match(n *html.Node) bool { return f(n) }
+
 // Sel is the interface for all the functionality provided by selectors.
+//
+// The selectors returned by the parsing functions are built from
+// the exported types of this package (TagSelector, ClassSelector, IDSelector,
+// AttrSelector, the pseudo-class types, CompoundSelector and CombinedSelector),
+// which may be inspected with a type switch.
 type Sel interface {
 	Matcher
 	Specificity() Specificity
@@ -30,67 +43,29 @@ type Sel interface {
 
 // Parse parses a selector. Use `ParseWithPseudoElement`
 // if you need support for pseudo-elements.
+// Whitespace and comments around the selector are ignored.
 func Parse(sel string) (Sel, error) {
-	p := &parser{s: sel}
-	compiled, err := p.parseSelector()
-	if err != nil {
-		return nil, err
-	}
-
-	if p.i < len(sel) {
-		return nil, fmt.Errorf("parsing %q: %d bytes left over", sel, len(sel)-p.i)
-	}
-
-	return compiled, nil
+	return ParseWithOptions(sel, Options{})
 }
 
 // ParseWithPseudoElement parses a single selector,
 // with support for pseudo-element.
 func ParseWithPseudoElement(sel string) (Sel, error) {
-	p := &parser{s: sel, acceptPseudoElements: true}
-	compiled, err := p.parseSelector()
-	if err != nil {
-		return nil, err
-	}
-
-	if p.i < len(sel) {
-		return nil, fmt.Errorf("parsing %q: %d bytes left over", sel, len(sel)-p.i)
-	}
-
-	return compiled, nil
+	return ParseWithOptions(sel, Options{PseudoElements: true})
 }
 
 // ParseGroup parses a selector, or a group of selectors separated by commas.
 // Use `ParseGroupWithPseudoElements`
 // if you need support for pseudo-elements.
+// Whitespace and comments around the members of the group are ignored.
 func ParseGroup(sel string) (SelectorGroup, error) {
-	p := &parser{s: sel}
-	compiled, err := p.parseSelectorGroup()
-	if err != nil {
-		return nil, err
-	}
-
-	if p.i < len(sel) {
-		return nil, fmt.Errorf("parsing %q: %d bytes left over", sel, len(sel)-p.i)
-	}
-
-	return compiled, nil
+	return ParseGroupWithOptions(sel, Options{})
 }
 
 // ParseGroupWithPseudoElements parses a selector, or a group of selectors separated by commas.
 // It supports pseudo-elements.
 func ParseGroupWithPseudoElements(sel string) (SelectorGroup, error) {
-	p := &parser{s: sel, acceptPseudoElements: true}
-	compiled, err := p.parseSelectorGroup()
-	if err != nil {
-		return nil, err
-	}
-
-	if p.i < len(sel) {
-		return nil, fmt.Errorf("parsing %q: %d bytes left over", sel, len(sel)-p.i)
-	}
-
-	return compiled, nil
+	return ParseGroupWithOptions(sel, Options{PseudoElements: true})
 }
 
 // A Selector is a function which tells whether a node matches or not.
@@ -119,6 +94,25 @@ func MustCompile(sel string) Selector {
 	return compiled
 }
 
+// MustParse is like Parse, but panics instead of returning an error.
+// It simplifies the initialization of package-level selectors.
+func MustParse(sel string) Sel {
+	compiled, err := Parse(sel)
+	if err != nil {
+		panic(err)
+	}
+	return compiled
+}
+
+// MustParseGroup is like ParseGroup, but panics instead of returning an error.
+func MustParseGroup(sel string) SelectorGroup {
+	compiled, err := ParseGroup(sel)
+	if err != nil {
+		panic(err)
+	}
+	return compiled
+}
+
 // MatchAll returns a slice of the nodes that match the selector,
 // from n and its children.
 func (s Selector) MatchAll(n *html.Node) []*html.Node {
@@ -151,7 +145,7 @@ func queryInto(n *html.Node, m Matcher, storage []*html.Node) []*html.Node {
 // QueryAll returns a slice of all the nodes that match m, from the descendants
 // of n.
 func QueryAll(n *html.Node, m Matcher) []*html.Node {
-	return queryInto(n, m, nil)
+	return queryInto(n, bindRoot(m, FromHTML(n)), nil)
 }
 
 // Match returns true if the node matches the selector.
@@ -177,11 +171,15 @@ func (s Selector) MatchFirst(n *html.Node) *html.Node {
 // Query returns the first node that matches m, from the descendants of n.
 // If none matches, it returns nil.
 func Query(n *html.Node, m Matcher) *html.Node {
+	return query(n, bindRoot(m, FromHTML(n)))
+}
+
+func query(n *html.Node, m Matcher) *html.Node {
 	for c := n.FirstChild; c != nil; c = c.NextSibling {
 		if m.Match(c) {
 			return c
 		}
-		if matched := Query(c, m); matched != nil {
+		if matched := query(c, m); matched != nil {
 			return matched
 		}
 	}
@@ -209,106 +207,208 @@ func Filter(nodes []*html.Node, m Matcher) (result []*html.Node) {
 	return result
 }
 
-type tagSelector struct {
-	tagS string
-	tag  atom.Atom
+// TagSelector matches elements with a given tag name.
+type TagSelector struct {
+	// Tag is the lower-cased tag name.
+	Tag string
+	Pos Span
+
+	tagAtom atom.Atom // cached from Tag, 0 if unknown
+	source  string    // see SourceOf
 }
 
-func newTagSelector(tag string) tagSelector {
+func newTagSelector(tag string) TagSelector {
 	tag = toLowerASCII(tag)
-	tagAtom := atom.Lookup([]byte(tag))
-	if tagAtom == 0 { // default to string matching
-		return tagSelector{tagS: tag}
-	}
-	return tagSelector{tag: tagAtom}
+	return TagSelector{Tag: tag, tagAtom: atom.Lookup([]byte(tag))}
 }
 
 // Matches elements with a given tag name.
-func (t tagSelector) Match(n *html.Node) bool {
-	return n.Type == html.ElementNode && ((n.DataAtom != 0 && n.DataAtom == t.tag) || n.Data == t.tagS)
+func (t TagSelector) Match(n *html.Node) bool {
+	return n.Type == html.ElementNode && ((t.tagAtom != 0 && n.DataAtom == t.tagAtom) || n.Data == t.Tag)
+}
+
+func (t TagSelector) MatchNode(n Node) bool {
+	if h, ok := n.(htmlNode); ok {
+		return t.Match(h.n)
+	}
+	return n.Type() == html.ElementNode && n.Data() == t.Tag
 }
 
-func (c tagSelector) Specificity() Specificity {
+func (c TagSelector) Specificity() Specificity {
 	return Specificity{0, 0, 1}
 }
 
-func (c tagSelector) PseudoElement() string {
+func (c TagSelector) PseudoElement() string {
 	return ""
 }
 
-type classSelector struct {
-	class string
+// ClassSelector matches elements by class attribute.
+type ClassSelector struct {
+	Class string
+	Pos   Span
+
+	quirks bool   // see Options.Quirks
+	source string // see SourceOf
 }
 
 // Matches elements by class attribute.
-func (t classSelector) Match(n *html.Node) bool {
+func (t ClassSelector) Match(n *html.Node) bool { return matchClass(t, FromHTML(n)) }
+
+func (t ClassSelector) MatchNode(n Node) bool { return matchClass(t, n) }
+
+func matchClass[T NodeLike[T]](t ClassSelector, n T) bool {
+	if t.quirks {
+		class := toLowerASCII(t.Class)
+		return matchAttribute(n, "class", func(s string) bool {
+			return matchInclude(class, toLowerASCII(s))
+		})
+	}
 	return matchAttribute(n, "class", func(s string) bool {
-		return matchInclude(t.class, s)
+		return matchInclude(t.Class, s)
 	})
 }
 
-func (c classSelector) Specificity() Specificity {
+func (c ClassSelector) Specificity() Specificity {
 	return Specificity{0, 1, 0}
 }
 
-func (c classSelector) PseudoElement() string {
+func (c ClassSelector) PseudoElement() string {
 	return ""
 }
 
-type idSelector struct {
-	id string
+// IDSelector matches elements by id attribute.
+type IDSelector struct {
+	ID  string
+	Pos Span
+
+	quirks bool   // see Options.Quirks
+	source string // see SourceOf
 }
 
 // Matches elements by id attribute.
-func (t idSelector) Match(n *html.Node) bool {
+func (t IDSelector) Match(n *html.Node) bool { return matchID(t, FromHTML(n)) }
+
+func (t IDSelector) MatchNode(n Node) bool { return matchID(t, n) }
+
+func matchID[T NodeLike[T]](t IDSelector, n T) bool {
+	if t.quirks {
+		id := toLowerASCII(t.ID)
+		return matchAttribute(n, "id", func(s string) bool {
+			return toLowerASCII(s) == id
+		})
+	}
 	return matchAttribute(n, "id", func(s string) bool {
-		return s == t.id
+		return s == t.ID
 	})
 }
 
-func (c idSelector) Specificity() Specificity {
+func (c IDSelector) Specificity() Specificity {
 	return Specificity{1, 0, 0}
 }
 
-func (c idSelector) PseudoElement() string {
+func (c IDSelector) PseudoElement() string {
 	return ""
 }
 
-type attrSelector struct {
-	key, val, operation string
-	regexp              *regexp.Regexp
+// AttrSelector matches elements by attribute value.
+type AttrSelector struct {
+	// Key is the lower-cased attribute name
+	Key string
+	// Val is the value to compare the attribute with. It is
+	// ignored by the regular expression operator
+	Val string
+	// Operation is one of "" (presence), "=", "!=", "~=", "|=", "^=", "$=", "*=", "#=" (regular expression)
+	Operation string
+	// Regexp is only used by the "#=" operation
+	Regexp *regexp.Regexp
+	Pos    Span
+
+	legacyInclude bool   // see Options.LegacyInclude
+	source        string // see SourceOf
+
+	// rawKey is the name as written, if it differs from Key,
+	// used for the foreign elements (see attrName)
+	rawKey          string
+	foldForeignCase bool // see Options.FoldForeignAttributes
+}
+
+// attrKeys returns the lower-cased name and the raw name of an attribute
+// selector written with key
+func attrKeys(key string) (lower, raw string) {
+	lower = toLowerASCII(key)
+	if lower == key {
+		return lower, ""
+	}
+	return lower, key
+}
+
+// writtenKey returns the attribute name, as written in the selector
+func (t AttrSelector) writtenKey() string {
+	if t.rawKey != "" {
+		return t.rawKey
+	}
+	return t.Key
+}
+
+// attrName returns the name of the attribute of n designated by t.
+// As in browsers, the attribute names are ASCII case-insensitive for the HTML
+// elements, but not for the foreign ones (SVG and MathML), whose attributes
+// may preserve their case, like viewBox.
+func attrName[T NodeLike[T]](t AttrSelector, n T) string {
+	if ns := n.Namespace(); ns != "svg" && ns != "math" {
+		return t.Key
+	}
+	if t.foldForeignCase {
+		for _, a := range n.Attributes() {
+			if toLowerASCII(a.Key) == t.Key {
+				return a.Key
+			}
+		}
+	}
+	return t.writtenKey()
 }
 
 // Matches elements by attribute value.
-func (t attrSelector) Match(n *html.Node) bool {
-	switch t.operation {
+func (t AttrSelector) Match(n *html.Node) bool { return matchAttr(t, FromHTML(n)) }
+
+func (t AttrSelector) MatchNode(n Node) bool { return matchAttr(t, n) }
+
+func matchAttr[T NodeLike[T]](t AttrSelector, n T) bool {
+	key := attrName(t, n)
+	switch t.Operation {
 	case "":
-		return matchAttribute(n, t.key, func(string) bool { return true })
+		return matchAttribute(n, key, func(string) bool { return true })
 	case "=":
-		return matchAttribute(n, t.key, func(s string) bool { return s == t.val })
+		return matchAttribute(n, key, func(s string) bool { return s == t.Val })
 	case "!=":
-		return attributeNotEqualMatch(t.key, t.val, n)
+		return attributeNotEqualMatch(key, t.Val, n)
 	case "~=":
 		// matches elements where the attribute named key is a whitespace-separated list that includes val.
-		return matchAttribute(n, t.key, func(s string) bool { return matchInclude(t.val, s) })
+		if t.legacyInclude {
+			return matchAttribute(n, key, func(s string) bool { return legacyMatchInclude(t.Val, s) })
+		}
+		if t.Val == "" || containsWhitespace(t.Val) {
+			return false // without looking at the attributes
+		}
+		return matchAttribute(n, key, func(s string) bool { return matchInclude(t.Val, s) })
 	case "|=":
-		return attributeDashMatch(t.key, t.val, n)
+		return attributeDashMatch(key, t.Val, n)
 	case "^=":
-		return attributePrefixMatch(t.key, t.val, n)
+		return attributePrefixMatch(key, t.Val, n)
 	case "$=":
-		return attributeSuffixMatch(t.key, t.val, n)
+		return attributeSuffixMatch(key, t.Val, n)
 	case "*=":
-		return attributeSubstringMatch(t.key, t.val, n)
+		return attributeSubstringMatch(key, t.Val, n)
 	case "#=":
-		return attributeRegexMatch(t.key, t.regexp, n)
+		return attributeRegexMatch(key, t.Regexp, n)
 	default:
-		panic(fmt.Sprintf("unsuported operation : %s", t.operation))
+		panic(fmt.Sprintf("unsuported operation : %s", t.Operation))
 	}
 }
 
 // matches elements where the attribute named key satisifes the function f.
-func matchAttribute(n *html.Node, key string, f func(string) bool) bool {
-	for _, a := range n.Attr {
+func matchAttribute[T NodeLike[T]](n T, key string, f func(string) bool) bool {
+	for _, a := range n.Attributes() {
 		if a.Key == key && f(a.Val) {
 			return true
 		}
@@ -318,11 +418,11 @@ func matchAttribute(n *html.Node, key string, f func(string) bool) bool {
 
 // attributeNotEqualMatch matches elements where
 // the attribute named key does not have the value val.
-func attributeNotEqualMatch(key, val string, n *html.Node) bool {
-	if n.Type != html.ElementNode {
+func attributeNotEqualMatch[T NodeLike[T]](key, val string, n T) bool {
+	if n.Type() != html.ElementNode {
 		return false
 	}
-	for _, a := range n.Attr {
+	for _, a := range n.Attributes() {
 		if a.Key == key && a.Val == val {
 			return false
 		}
@@ -368,7 +468,19 @@ func (as *asciiSet) index(s string) int {
 var spaceAsciiSet = makeASCIISet(" \t\r\n\f")
 
 // returns true if s is a whitespace-separated list that includes val.
+// As required by the specification, an empty val or a val containing
+// whitespace never matches.
 func matchInclude(val, s string) bool {
+	if val == "" || containsWhitespace(val) {
+		return false
+	}
+	return legacyMatchInclude(val, s)
+}
+
+// legacyMatchInclude is the behavior of matchInclude before the empty
+// values were rejected: they match the lists with consecutive
+// or leading whitespace (see Options.LegacyInclude).
+func legacyMatchInclude(val, s string) bool {
 	for s != "" {
 		i := spaceAsciiSet.index(s)
 		if i == -1 {
@@ -382,8 +494,37 @@ func matchInclude(val, s string) bool {
 	return false
 }
 
+// ContainsToken returns true if list, a whitespace-separated list of tokens
+// like a class attribute, contains token, as the [attr~=token] selector.
+// The tokens are separated by ASCII whitespace, and compared case-sensitively.
+// An empty token, or a token containing whitespace, is never contained.
+func ContainsToken(list, token string) bool { return matchInclude(token, list) }
+
+// ContainsTokenFold is like ContainsToken, ignoring the ASCII case, as required
+// for the attributes with keyword values, like rel.
+func ContainsTokenFold(list, token string) bool {
+	return matchInclude(toLowerASCII(token), toLowerASCII(list))
+}
+
+// SplitTokens returns the tokens of the whitespace-separated list,
+// which are separated by ASCII whitespace only, unlike with strings.Fields.
+func SplitTokens(list string) []string {
+	var out []string
+	for list != "" {
+		i := spaceAsciiSet.index(list)
+		if i == -1 {
+			return append(out, list)
+		}
+		if i > 0 {
+			out = append(out, list[:i])
+		}
+		list = list[i+1:]
+	}
+	return out
+}
+
 //  matches elements where the attribute named key equals val or starts with val plus a hyphen.
-func attributeDashMatch(key, val string, n *html.Node) bool {
+func attributeDashMatch[T NodeLike[T]](key, val string, n T) bool {
 	return matchAttribute(n, key,
 		func(s string) bool {
 			if s == val {
@@ -401,7 +542,7 @@ func attributeDashMatch(key, val string, n *html.Node) bool {
 
 // attributePrefixMatch returns a Selector that matches elements where
 // the attribute named key starts with val.
-func attributePrefixMatch(key, val string, n *html.Node) bool {
+func attributePrefixMatch[T NodeLike[T]](key, val string, n T) bool {
 	return matchAttribute(n, key,
 		func(s string) bool {
 			if strings.TrimSpace(s) == "" {
@@ -413,7 +554,7 @@ func attributePrefixMatch(key, val string, n *html.Node) bool {
 
 // attributeSuffixMatch matches elements where
 // the attribute named key ends with val.
-func attributeSuffixMatch(key, val string, n *html.Node) bool {
+func attributeSuffixMatch[T NodeLike[T]](key, val string, n T) bool {
 	return matchAttribute(n, key,
 		func(s string) bool {
 			if strings.TrimSpace(s) == "" {
@@ -425,7 +566,7 @@ func attributeSuffixMatch(key, val string, n *html.Node) bool {
 
 // attributeSubstringMatch matches nodes where
 // the attribute named key contains val.
-func attributeSubstringMatch(key, val string, n *html.Node) bool {
+func attributeSubstringMatch[T NodeLike[T]](key, val string, n T) bool {
 	return matchAttribute(n, key,
 		func(s string) bool {
 			if strings.TrimSpace(s) == "" {
@@ -437,52 +578,74 @@ func attributeSubstringMatch(key, val string, n *html.Node) bool {
 
 // attributeRegexMatch  matches nodes where
 // the attribute named key matches the regular expression rx
-func attributeRegexMatch(key string, rx *regexp.Regexp, n *html.Node) bool {
+func attributeRegexMatch[T NodeLike[T]](key string, rx *regexp.Regexp, n T) bool {
 	return matchAttribute(n, key,
 		func(s string) bool {
 			return rx.MatchString(s)
 		})
 }
 
-func (c attrSelector) Specificity() Specificity {
+func (c AttrSelector) Specificity() Specificity {
 	return Specificity{0, 1, 0}
 }
 
-func (c attrSelector) PseudoElement() string {
+func (c AttrSelector) PseudoElement() string {
 	return ""
 }
 
 // see pseudo_classes.go for pseudo classes selectors
 
-// on a static context, some selectors can't match anything
-type neverMatchSelector struct {
-	value string
+// NeverMatchSelector is used for the selectors which can't match anything
+// on a static context, like :hover.
+type NeverMatchSelector struct {
+	// Value is the CSS input which produced the selector, like ":hover"
+	Value string
+	Pos   Span
+
+	source string // see SourceOf
+}
+
+func (s NeverMatchSelector) Match(n *html.Node) bool {
+	return false
 }
 
-func (s neverMatchSelector) Match(n *html.Node) bool {
+func (s NeverMatchSelector) MatchNode(n Node) bool {
 	return false
 }
 
-func (s neverMatchSelector) Specificity() Specificity {
+func (s NeverMatchSelector) Specificity() Specificity {
 	return Specificity{0, 0, 0}
 }
 
-func (c neverMatchSelector) PseudoElement() string {
+func (c NeverMatchSelector) PseudoElement() string {
 	return ""
 }
 
-type compoundSelector struct {
-	selectors     []Sel
-	pseudoElement string
+// CompoundSelector is a sequence of simple selectors,
+// applying to the same element, optionally followed by a pseudo-element.
+// An empty list of selectors is the universal selector *.
+type CompoundSelector struct {
+	Selectors []Sel
+	// Pseudo is the optional pseudo-element, without the leading colons
+	Pseudo string
+	Pos    Span
+
+	source string // see SourceOf
 }
 
 // Matches elements if each sub-selectors matches.
-func (t compoundSelector) Match(n *html.Node) bool {
-	if len(t.selectors) == 0 {
+func (t CompoundSelector) Match(n *html.Node) bool {
+	if typ, ok := nodePseudoElement(t.Pseudo); ok {
+		return matchNodePseudo(t, typ, FromHTML(n))
+	}
+	if name, ok := attrPseudoElement(t.Pseudo); ok && !hasAttr(FromHTML(n), name) {
+		return false
+	}
+	if len(t.Selectors) == 0 {
 		return n.Type == html.ElementNode
 	}
 
-	for _, sel := range t.selectors {
+	for _, sel := range t.Selectors {
 		if !sel.Match(n) {
 			return false
 		}
@@ -490,56 +653,88 @@ func (t compoundSelector) Match(n *html.Node) bool {
 	return true
 }
 
-func (s compoundSelector) Specificity() Specificity {
+func (t CompoundSelector) MatchNode(n Node) bool {
+	if typ, ok := nodePseudoElement(t.Pseudo); ok {
+		return matchNodePseudo(t, typ, n)
+	}
+	if name, ok := attrPseudoElement(t.Pseudo); ok && !hasAttr(n, name) {
+		return false
+	}
+	if len(t.Selectors) == 0 {
+		return n.Type() == html.ElementNode
+	}
+
+	for _, sel := range t.Selectors {
+		if !MatchNode(sel, n) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s CompoundSelector) Specificity() Specificity {
 	var out Specificity
-	for _, sel := range s.selectors {
+	for _, sel := range s.Selectors {
 		out = out.Add(sel.Specificity())
 	}
-	if s.pseudoElement != "" {
+	if s.Pseudo != "" {
 		// https://drafts.csswg.org/selectors-3/#specificity
 		out = out.Add(Specificity{0, 0, 1})
 	}
 	return out
 }
 
-func (c compoundSelector) PseudoElement() string {
-	return c.pseudoElement
+func (c CompoundSelector) PseudoElement() string {
+	return c.Pseudo
 }
 
-type combinedSelector struct {
-	first      Sel
-	combinator byte
-	second     Sel
+// CombinedSelector is a selector using a combinator.
+type CombinedSelector struct {
+	First Sel
+	// Combinator is one of ' ' (descendant), '>' (child), '+' (adjacent sibling)
+	// and '~' (general sibling), or 0 if Second is nil
+	Combinator byte
+	Second     Sel
+
+	Pos           Span // position of the whole selector
+	CombinatorPos Span // position of the combinator, including whitespaces
+
+	source string // see SourceOf
 }
 
-func (t combinedSelector) Match(n *html.Node) bool {
-	if t.first == nil {
+func (t CombinedSelector) Match(n *html.Node) bool { return t.MatchNode(htmlNode{n}) }
+
+func (t CombinedSelector) MatchNode(n Node) bool {
+	if t.First == nil {
 		return false // maybe we should panic
 	}
-	switch t.combinator {
+	if typ, ok := nodePseudoElement(t.PseudoElement()); ok {
+		return matchNodePseudo(t, typ, n)
+	}
+	switch t.Combinator {
 	case 0:
-		return t.first.Match(n)
+		return MatchNode(t.First, n)
 	case ' ':
-		return descendantMatch(t.first, t.second, n)
+		return descendantMatch(t.First, t.Second, n)
 	case '>':
-		return childMatch(t.first, t.second, n)
+		return childMatch(t.First, t.Second, n)
 	case '+':
-		return siblingMatch(t.first, t.second, true, n)
+		return siblingMatch(t.First, t.Second, true, n)
 	case '~':
-		return siblingMatch(t.first, t.second, false, n)
+		return siblingMatch(t.First, t.Second, false, n)
 	default:
 		panic("unknown combinator")
 	}
 }
 
 // matches an element if it matches d and has an ancestor that matches a.
-func descendantMatch(a, d Matcher, n *html.Node) bool {
-	if !d.Match(n) {
+func descendantMatch(a, d Matcher, n Node) bool {
+	if !MatchNode(d, n) {
 		return false
 	}
 
-	for p := n.Parent; p != nil; p = p.Parent {
-		if a.Match(p) {
+	for p := n.Parent(); p != nil; p = p.Parent() {
+		if MatchNode(a, p) {
 			return true
 		}
 	}
@@ -548,30 +743,29 @@ func descendantMatch(a, d Matcher, n *html.Node) bool {
 }
 
 // matches an element if it matches d and its parent matches a.
-func childMatch(a, d Matcher, n *html.Node) bool {
-	return d.Match(n) && n.Parent != nil && a.Match(n.Parent)
+func childMatch(a, d Matcher, n Node) bool {
+	if !MatchNode(d, n) {
+		return false
+	}
+	p := n.Parent()
+	return p != nil && MatchNode(a, p)
 }
 
 // matches an element if it matches s2 and is preceded by an element that matches s1.
 // If adjacent is true, the sibling must be immediately before the element.
-func siblingMatch(s1, s2 Matcher, adjacent bool, n *html.Node) bool {
-	if !s2.Match(n) {
+func siblingMatch(s1, s2 Matcher, adjacent bool, n Node) bool {
+	if !MatchNode(s2, n) {
 		return false
 	}
 
 	if adjacent {
-		for n = n.PrevSibling; n != nil; n = n.PrevSibling {
-			if n.Type == html.TextNode || n.Type == html.CommentNode {
-				continue
-			}
-			return s1.Match(n)
-		}
-		return false
+		c := prevElementSibling(n)
+		return c != nil && MatchNode(s1, c)
 	}
 
 	// Walk backwards looking for element that matches s1
-	for c := n.PrevSibling; c != nil; c = c.PrevSibling {
-		if s1.Match(c) {
+	for c := prevElementSibling(n); c != nil; c = prevElementSibling(c) {
+		if MatchNode(s1, c) {
 			return true
 		}
 	}
@@ -579,21 +773,21 @@ func siblingMatch(s1, s2 Matcher, adjacent bool, n *html.Node) bool {
 	return false
 }
 
-func (s combinedSelector) Specificity() Specificity {
-	spec := s.first.Specificity()
-	if s.second != nil {
-		spec = spec.Add(s.second.Specificity())
+func (s CombinedSelector) Specificity() Specificity {
+	spec := s.First.Specificity()
+	if s.Second != nil {
+		spec = spec.Add(s.Second.Specificity())
 	}
 	return spec
 }
 
-// on combinedSelector, a pseudo-element only makes sens on the last
+// on CombinedSelector, a pseudo-element only makes sens on the last
 // selector, although others increase specificity.
-func (c combinedSelector) PseudoElement() string {
-	if c.second == nil {
+func (c CombinedSelector) PseudoElement() string {
+	if c.Second == nil {
 		return ""
 	}
-	return c.second.PseudoElement()
+	return c.Second.PseudoElement()
 }
 
 // A SelectorGroup is a list of selectors, which matches if any of the
@@ -609,3 +803,12 @@ func (s SelectorGroup) Match(n *html.Node) bool {
 	}
 	return false
 }
+
+func (s SelectorGroup) MatchNode(n Node) bool {
+	for _, sel := range s {
+		if MatchNode(sel, n) {
+			return true
+		}
+	}
+	return false
+}
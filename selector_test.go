@@ -484,6 +484,21 @@ var selectorTests = []selectorTest{
 			`<p id="p2">contents <em>2</em></p>`,
 		},
 	},
+	{
+		`<body><p id="p1" class="a"></p><p id="p2"></p><span class="a"></span></body>`,
+		`:is(p, span).a`,
+		[]string{
+			`<p id="p1" class="a"></p>`,
+			`<span class="a"></span>`,
+		},
+	},
+	{
+		`<body><p id="p1" class="a"></p><p id="p2"></p><span class="a"></span></body>`,
+		`p:where(#p2, span)`,
+		[]string{
+			`<p id="p2"></p>`,
+		},
+	},
 	{
 		`<p id="p1">0123456789</p><p id="p2">abcdef</p><p id="p3">0123ABCD</p>`,
 		`p:matches([\d])`,
@@ -605,6 +620,13 @@ var selectorTests = []selectorTest{
 			"<html><head></head><body></body></html>",
 		},
 	},
+	{
+		`<html><head></head><body></body></html>`,
+		":scope",
+		[]string{
+			"<html><head></head><body></body></html>",
+		},
+	},
 	{
 		`<html><head></head><body></body></html>`,
 		"*:root",
@@ -902,3 +924,111 @@ func TestShakespeare(t *testing.T) {
 	assertCount("div[class|=dialog]", 50)
 	assertCount("div[class~=dialog]", 51)
 }
+
+func TestOfTypeNamespace(t *testing.T) {
+	// an HTML <a> and an SVG <a>, siblings in a constructed tree
+	parent := &html.Node{Type: html.ElementNode, Data: "div"}
+	doc := &html.Node{Type: html.DocumentNode}
+	doc.AppendChild(parent)
+	htmlA := &html.Node{Type: html.ElementNode, Data: "a"}
+	svgA := &html.Node{Type: html.ElementNode, Data: "a", Namespace: "svg"}
+	parent.AppendChild(htmlA)
+	parent.AppendChild(svgA)
+
+	for _, test := range []struct {
+		sel       string
+		html, svg bool
+	}{
+		{"a:first-of-type", true, true},
+		{"a:last-of-type", true, true},
+		{"a:only-of-type", true, true},
+		{"a:nth-of-type(1)", true, true},
+		{"a:nth-last-of-type(1)", true, true},
+		{"a:nth-of-type(2n+1)", true, true},
+		{"a:nth-of-type(2)", false, false},
+		{"a:first-child", true, false},
+		{"a:only-child", false, false},
+	} {
+		m := MustCompile(test.sel)
+		if got := m.Match(htmlA); got != test.html {
+			t.Errorf("%s on the HTML element: expected %v, got %v", test.sel, test.html, got)
+		}
+		if got := m.Match(svgA); got != test.svg {
+			t.Errorf("%s on the SVG element: expected %v, got %v", test.sel, test.svg, got)
+		}
+	}
+}
+
+var (
+	packageSel   = MustParse("div > p.a")
+	packageGroup = MustParseGroup("h1, h2")
+)
+
+func TestMustParse(t *testing.T) {
+	if packageSel.String() != "div > p.a" || packageSel.Specificity() != (Specificity{0, 1, 2}) {
+		t.Errorf("unexpected selector %s", packageSel)
+	}
+	if len(packageGroup) != 2 || packageGroup.String() != "h1, h2" {
+		t.Errorf("unexpected group %s", packageGroup)
+	}
+
+	for _, f := range []func(){
+		func() { MustParse("div >") },
+		func() { MustParse("a, b") },
+		func() { MustParseGroup("a,, b") },
+	} {
+		func() {
+			defer func() {
+				if _, ok := recover().(*ParseError); !ok {
+					t.Error("expected a panic with a *ParseError")
+				}
+			}()
+			f()
+		}()
+	}
+}
+
+func TestMatcherFunc(t *testing.T) {
+	doc := MustParseHTML(`<p>a</p><p title="x">b</p><div title="y">c</div>`)
+	titled := MatcherFunc(func(n *html.Node) bool { return n.Type == html.ElementNode && getAttr(n, "title") != "" })
+	if got := QueryAll(doc, titled); len(got) != 2 || got[0].Data != "p" || got[1].Data != "div" {
+		t.Errorf("unexpected matches %v", got)
+	}
+	if got := Query(doc, titled); got == nil || got.Data != "p" {
+		t.Errorf("unexpected match %v", got)
+	}
+	if got := Filter(QueryAll(doc, MustParse("p")), titled); len(got) != 1 {
+		t.Errorf("unexpected filtered nodes %v", got)
+	}
+	if !MatchNode(titled, FromHTML(Query(doc, MustParse("div")))) {
+		t.Error("expected a match with MatchNode")
+	}
+}
+
+func TestContainsToken(t *testing.T) {
+	for _, test := range []struct {
+		list, token string
+		exp, fold   bool
+	}{
+		{"a b c", "b", true, true},
+		{"\ta\n b  ", "a", true, true},
+		{"ab c", "a", false, false},
+		{"Noopener noreferrer", "noopener", false, true},
+		{"a  b", "", false, false},
+		{"a b", "a b", false, false},
+		{"a b", "a", false, false}, // non-ASCII whitespace
+	} {
+		if got := ContainsToken(test.list, test.token); got != test.exp {
+			t.Errorf("ContainsToken(%q, %q): expected %v", test.list, test.token, test.exp)
+		}
+		if got := ContainsTokenFold(test.list, test.token); got != test.fold {
+			t.Errorf("ContainsTokenFold(%q, %q): expected %v", test.list, test.token, test.fold)
+		}
+	}
+	if got := SplitTokens(" a\tb c \n"); !reflect.DeepEqual(got, []string{"a", "b c"}) {
+		t.Errorf("unexpected tokens %q", got)
+	}
+	if got := SplitTokens(" \t"); got != nil {
+		t.Errorf("unexpected tokens %q", got)
+	}
+}
@@ -8,71 +8,128 @@ import (
 
 // implements the reverse operation Sel -> string
 
-var specialCharReplacer *strings.Replacer
-
-func init() {
-	var pairs []string
-	for _, s := range ",!\"#$%&'()*+ -./:;<=>?@[\\]^`{|}~" {
-		pairs = append(pairs, string(s), "\\"+string(s))
+// EscapeIdent returns s serialized as a CSS identifier, escaping
+// the characters which are not allowed, following the CSSOM specification
+// (https://drafts.csswg.org/cssom/#serialize-an-identifier), like
+// the `CSS.escape` Javascript function.
+func EscapeIdent(s string) string { return escapeIdent(s, false) }
+
+// UnescapeIdent is the reverse operation of EscapeIdent: it parses
+// the CSS identifier s, replacing escape sequences by the characters they represent.
+func UnescapeIdent(s string) (string, error) {
+	p := &parser{s: s}
+	out, err := p.parseIdentifier()
+	if err != nil {
+		return "", err
+	}
+	if p.i < len(s) {
+		return "", fmt.Errorf("invalid identifier %q: unexpected %q", s, s[p.i:])
 	}
-	specialCharReplacer = strings.NewReplacer(pairs...)
+	return out, nil
+}
+
+func escapeIdent(s string, upperHex bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == 0:
+			b.WriteString("\uFFFD")
+		case c < 0x20 || c == 0x7f,
+			i == 0 && '0' <= c && c <= '9',
+			i == 1 && '0' <= c && c <= '9' && s[0] == '-':
+			writeHexEscape(&b, c, upperHex)
+		case i == 0 && c == '-' && len(s) == 1:
+			b.WriteString("\\-")
+		case c >= 0x80 || c == '-' || c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z':
+			b.WriteByte(c)
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// quoteString returns s as a CSS string, delimited by quote (' or ")
+func quoteString(s string, quote byte, upperHex bool) string {
+	var b strings.Builder
+	b.WriteByte(quote)
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == 0:
+			b.WriteString("\uFFFD")
+		case c == quote || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c == 0x7f:
+			// control characters must be written as hex escapes
+			writeHexEscape(&b, c, upperHex)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte(quote)
+	return b.String()
 }
 
-// espace special CSS char
-func escape(s string) string { return specialCharReplacer.Replace(s) }
-
-func (c tagSelector) String() string {
-	if c.tag != 0 {
-		return c.tag.String()
+func writeHexEscape(b *strings.Builder, c byte, upperHex bool) {
+	if upperHex {
+		fmt.Fprintf(b, "\\%X ", c)
+	} else {
+		fmt.Fprintf(b, "\\%x ", c)
 	}
-	return c.tagS
 }
 
-func (c idSelector) String() string {
-	return "#" + escape(c.id)
+func (c TagSelector) String() string {
+	return EscapeIdent(c.Tag)
+}
+
+func (c IDSelector) String() string {
+	return "#" + EscapeIdent(c.ID)
 }
 
-func (c classSelector) String() string {
-	return "." + escape(c.class)
+func (c ClassSelector) String() string {
+	return "." + EscapeIdent(c.Class)
 }
 
-func (c attrSelector) String() string {
-	val := c.val
-	if c.operation == "#=" {
-		val = c.regexp.String()
-	} else if c.operation != "" {
-		val = fmt.Sprintf(`"%s"`, val)
+func (c AttrSelector) String() string {
+	val := c.Val
+	if c.Operation == "#=" {
+		val = c.Regexp.String()
+	} else if c.Operation != "" {
+		val = quoteString(val, '"', false)
 	}
-	return fmt.Sprintf(`[%s%s%s]`, c.key, c.operation, val)
+	return fmt.Sprintf(`[%s%s%s]`, EscapeIdent(c.writtenKey()), c.Operation, val)
 }
 
-func (c relativePseudoClassSelector) String() string {
-	return fmt.Sprintf(":%s(%s)", c.name, c.match.String())
+func (c RelativePseudoClassSelector) String() string {
+	return fmt.Sprintf(":%s(%s)", c.Name, c.Args.String())
 }
 
-func (c containsPseudoClassSelector) String() string {
+func (c ContainsPseudoClassSelector) String() string {
 	s := "contains"
-	if c.own {
+	if c.Own {
 		s += "Own"
 	}
-	return fmt.Sprintf(`:%s("%s")`, s, c.value)
+	return fmt.Sprintf(`:%s(%s)`, s, quoteString(c.Value, '"', false))
 }
 
-func (c regexpPseudoClassSelector) String() string {
+func (c RegexpPseudoClassSelector) String() string {
 	s := "matches"
-	if c.own {
+	if c.Own {
 		s += "Own"
 	}
-	return fmt.Sprintf(":%s(%s)", s, c.regexp.String())
+	return fmt.Sprintf(":%s(%s)", s, c.Regexp.String())
 }
 
-func (c nthPseudoClassSelector) String() string {
-	if c.a == 0 && c.b == 1 { // special cases
+func (c NthPseudoClassSelector) String() string {
+	if c.A == 0 && c.B == 1 { // special cases
 		s := ":first-"
-		if c.last {
+		if c.Last {
 			s = ":last-"
 		}
-		if c.ofType {
+		if c.OfType {
 			s += "of-type"
 		} else {
 			s += "child"
@@ -80,7 +137,7 @@ func (c nthPseudoClassSelector) String() string {
 		return s
 	}
 	var name string
-	switch [2]bool{c.last, c.ofType} {
+	switch [2]bool{c.Last, c.OfType} {
 	case [2]bool{true, true}:
 		name = "nth-last-of-type"
 	case [2]bool{true, false}:
@@ -90,75 +147,94 @@ func (c nthPseudoClassSelector) String() string {
 	case [2]bool{false, false}:
 		name = "nth-child"
 	}
-	s := fmt.Sprintf("+%d", c.b)
-	if c.b < 0 { // avoid +-8 invalid syntax
-		s = strconv.Itoa(c.b)
+	s := fmt.Sprintf("+%d", c.B)
+	if c.B < 0 { // avoid +-8 invalid syntax
+		s = strconv.Itoa(c.B)
 	}
-	return fmt.Sprintf(":%s(%dn%s)", name, c.a, s)
+	return fmt.Sprintf(":%s(%dn%s)", name, c.A, s)
 }
 
-func (c onlyChildPseudoClassSelector) String() string {
-	if c.ofType {
+func (c OnlyChildPseudoClassSelector) String() string {
+	if c.OfType {
 		return ":only-of-type"
 	}
 	return ":only-child"
 }
 
-func (c inputPseudoClassSelector) String() string {
+func (c InputPseudoClassSelector) String() string {
 	return ":input"
 }
 
-func (c emptyElementPseudoClassSelector) String() string {
+func (c EmptyElementPseudoClassSelector) String() string {
 	return ":empty"
 }
 
-func (c rootPseudoClassSelector) String() string {
+func (c RootPseudoClassSelector) String() string {
 	return ":root"
 }
 
-func (c linkPseudoClassSelector) String() string {
+func (c ScopePseudoClassSelector) String() string {
+	return ":scope"
+}
+
+func (c NestingSelector) String() string {
+	return "&"
+}
+
+func (c CustomStatePseudoClassSelector) String() string {
+	return fmt.Sprintf(":state(%s)", EscapeIdent(c.State))
+}
+
+func (c TimePseudoClassSelector) String() string {
+	if c.Args != nil {
+		return fmt.Sprintf(":%s(%s)", c.Name, c.Args)
+	}
+	return ":" + c.Name
+}
+
+func (c LinkPseudoClassSelector) String() string {
 	return ":link"
 }
 
-func (c langPseudoClassSelector) String() string {
-	return fmt.Sprintf(":lang(%s)", c.lang)
+func (c LangPseudoClassSelector) String() string {
+	return fmt.Sprintf(":lang(%s)", EscapeIdent(c.Lang))
 }
 
-func (c neverMatchSelector) String() string {
-	return c.value
+func (c NeverMatchSelector) String() string {
+	return c.Value
 }
 
-func (c enabledPseudoClassSelector) String() string {
+func (c EnabledPseudoClassSelector) String() string {
 	return ":enabled"
 }
 
-func (c disabledPseudoClassSelector) String() string {
+func (c DisabledPseudoClassSelector) String() string {
 	return ":disabled"
 }
 
-func (c checkedPseudoClassSelector) String() string {
+func (c CheckedPseudoClassSelector) String() string {
 	return ":checked"
 }
 
-func (c compoundSelector) String() string {
-	if len(c.selectors) == 0 && c.pseudoElement == "" {
+func (c CompoundSelector) String() string {
+	if len(c.Selectors) == 0 && c.Pseudo == "" {
 		return "*"
 	}
-	chunks := make([]string, len(c.selectors))
-	for i, sel := range c.selectors {
+	chunks := make([]string, len(c.Selectors))
+	for i, sel := range c.Selectors {
 		chunks[i] = sel.String()
 	}
 	s := strings.Join(chunks, "")
-	if c.pseudoElement != "" {
-		s += "::" + c.pseudoElement
+	if c.Pseudo != "" {
+		s += "::" + c.Pseudo
 	}
 	return s
 }
 
-func (c combinedSelector) String() string {
-	start := c.first.String()
-	if c.second != nil {
-		start += fmt.Sprintf(" %s %s", string(c.combinator), c.second.String())
+func (c CombinedSelector) String() string {
+	start := c.First.String()
+	if c.Second != nil {
+		start += fmt.Sprintf(" %s %s", string(c.Combinator), c.Second.String())
 	}
 	return start
 }
@@ -36,3 +36,68 @@ func TestSerialize(t *testing.T) {
 		}
 	}
 }
+
+func TestSerializeEscapes(t *testing.T) {
+	for _, test := range []struct {
+		input, expected string
+	}{
+		{`#\31 23`, `#\31 23`},
+		{`#a\.b`, `#a\.b`},
+		{`.a\:b\ c`, `.a\:b\ c`},
+		{`.\-`, `.\-`},
+		{`.-\31 x`, `.-\31 x`},
+		{`.r\e9 sumé`, `.résumé`},
+		{`[data-\:x="a\"b\\c"]`, `[data-\:x="a\"b\\c"]`},
+		{`[a="x\9 y"]`, `[a="x\9 y"]`},
+		{`:contains("a\"b")`, `:contains("a\"b")`},
+		{`d\69 v`, `div`},
+	} {
+		sel, err := Parse(test.input)
+		if err != nil {
+			t.Fatalf("%s: %s", test.input, err)
+		}
+		got := sel.String()
+		if got != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.input, test.expected, got)
+		}
+		back, err := Parse(got)
+		if err != nil {
+			t.Fatalf("%s: invalid serialization: %s", got, err)
+		}
+		if !reflect.DeepEqual(sel, back) {
+			t.Errorf("%s: round trip failed", test.input)
+		}
+	}
+}
+
+func TestEscapeIdent(t *testing.T) {
+	for input, expected := range map[string]string{
+		"abc":   "abc",
+		"a b":   `a\ b`,
+		"1a":    `\31 a`,
+		"-1a":   `-\31 a`,
+		"-":     `\-`,
+		"--x":   "--x",
+		"a\x00": "a�",
+		"é#":    `é\#`,
+		"a\nb":  `a\a b`,
+	} {
+		got := EscapeIdent(input)
+		if got != expected {
+			t.Errorf("EscapeIdent(%q): expected %q, got %q", input, expected, got)
+		}
+		if input == "a\x00" {
+			continue
+		}
+		back, err := UnescapeIdent(got)
+		if err != nil {
+			t.Errorf("UnescapeIdent(%q): %s", got, err)
+		}
+		if back != input {
+			t.Errorf("UnescapeIdent(%q): expected %q, got %q", got, input, back)
+		}
+	}
+	if _, err := UnescapeIdent("a b"); err == nil {
+		t.Error("expected error for invalid identifier")
+	}
+}
@@ -0,0 +1,88 @@
+package cascadia
+
+import (
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// SnapshotOptions configures SnapshotWithOptions.
+// The zero value gives the behavior of Snapshot.
+type SnapshotOptions struct {
+	// CollapseWhitespace replaces the runs of whitespace of the text
+	// by a single space, and removes the text nodes made only of whitespace,
+	// except in the <pre> and <textarea> elements.
+	CollapseWhitespace bool
+	// StripComments removes the comment nodes.
+	StripComments bool
+	// IgnoreAttrs are the attributes removed from the elements, like
+	// generated ids.
+	IgnoreAttrs []string
+}
+
+// Snapshot renders the elements matched by m in n as HTML, one per line,
+// in tree order, for golden-file tests of HTML generators. The rendering
+// is stable: the attributes of each element are sorted by name.
+func Snapshot(n *html.Node, m Matcher) string {
+	return SnapshotWithOptions(n, m, SnapshotOptions{})
+}
+
+// SnapshotWithOptions is like Snapshot, with additional normalizations.
+func SnapshotWithOptions(n *html.Node, m Matcher, opts SnapshotOptions) string {
+	var b strings.Builder
+	for _, match := range QueryAll(n, m) {
+		c := cloneHTML(match)
+		opts.normalize(c, false)
+		html.Render(&b, c) // writing to a strings.Builder never fails
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// normalize modifies the copy n in place
+func (opts SnapshotOptions) normalize(n *html.Node, preformatted bool) {
+	if n.Type == html.ElementNode {
+		attrs := n.Attr[:0]
+		for _, a := range n.Attr {
+			if !containsString(opts.IgnoreAttrs, a.Key) {
+				attrs = append(attrs, a)
+			}
+		}
+		sort.SliceStable(attrs, func(i, j int) bool {
+			if attrs[i].Namespace != attrs[j].Namespace {
+				return attrs[i].Namespace < attrs[j].Namespace
+			}
+			return attrs[i].Key < attrs[j].Key
+		})
+		n.Attr = attrs
+		preformatted = preformatted || n.Data == "pre" || n.Data == "textarea"
+	}
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		switch c.Type {
+		case html.CommentNode:
+			if opts.StripComments {
+				n.RemoveChild(c)
+			}
+		case html.TextNode:
+			if opts.CollapseWhitespace && !preformatted {
+				if text := strings.Join(strings.Fields(c.Data), " "); text == "" {
+					n.RemoveChild(c)
+				} else {
+					// keep the leading and trailing separators, which are significant between inline elements
+					if strings.TrimLeft(c.Data, " \t\r\n\f") != c.Data {
+						text = " " + text
+					}
+					if strings.TrimRight(c.Data, " \t\r\n\f") != c.Data {
+						text += " "
+					}
+					c.Data = text
+				}
+			}
+		default:
+			opts.normalize(c, preformatted)
+		}
+		c = next
+	}
+}
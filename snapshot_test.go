@@ -0,0 +1,35 @@
+package cascadia
+
+import "testing"
+
+func TestSnapshot(t *testing.T) {
+	doc := MustParseHTML(`<ul class="list" id="l"><li data-id="2" class="a">one</li><li   class="b"  data-id="3">two</li></ul>`)
+	expected := `<li class="a" data-id="2">one</li>
+<li class="b" data-id="3">two</li>
+`
+	if got := Snapshot(doc, MustCompile("li")); got != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, got)
+	}
+	if got := Snapshot(doc, MustCompile("table")); got != "" {
+		t.Errorf("expected an empty snapshot, got %q", got)
+	}
+}
+
+func TestSnapshotWithOptions(t *testing.T) {
+	doc := MustParseHTML(`<div id="gen-42" title="t">
+		<!-- comment -->
+		<p>some
+		 <b>bold</b>   text</p>
+		<pre>  keep
+  this</pre>
+	</div>`)
+	opts := SnapshotOptions{CollapseWhitespace: true, StripComments: true, IgnoreAttrs: []string{"id"}}
+	expected := "<div title=\"t\"><p>some <b>bold</b> text</p><pre>  keep\n  this</pre></div>\n"
+	if got := SnapshotWithOptions(doc, MustCompile("div"), opts); got != expected {
+		t.Errorf("expected\n%q\ngot\n%q", expected, got)
+	}
+	// the document is not modified
+	if Query(doc, MustCompile("#gen-42")) == nil {
+		t.Error("the document was modified")
+	}
+}
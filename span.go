@@ -0,0 +1,296 @@
+package cascadia
+
+// Span is a range of bytes [Start, End) in the source text of a selector.
+//
+// Spans are only recorded by ParseWithSpans and ParseGroupWithSpans
+// (the other parsing functions leave them to zero, so that
+// selectors parsed from different inputs stay comparable).
+type Span struct {
+	Start, End int
+}
+
+// ParseWithSpans is like ParseWithPseudoElement, and also records the position
+// of each component of the selector in the `Pos` field of the AST types
+// (see also SpanOf).
+func ParseWithSpans(sel string) (Sel, error) {
+	return ParseWithOptions(sel, Options{PseudoElements: true, Spans: true})
+}
+
+// ParseGroupWithSpans is like ParseGroupWithPseudoElements, and also records the position
+// of each component of the selectors (see ParseWithSpans).
+func ParseGroupWithSpans(sel string) (SelectorGroup, error) {
+	return ParseGroupWithOptions(sel, Options{PseudoElements: true, Spans: true})
+}
+
+// SpanOf returns the position of sel in its source, or an empty span
+// if it was not recorded (or if sel is not one of the types of this package).
+func SpanOf(sel Sel) Span {
+	switch s := sel.(type) {
+	case TagSelector:
+		return s.Pos
+	case ClassSelector:
+		return s.Pos
+	case IDSelector:
+		return s.Pos
+	case AttrSelector:
+		return s.Pos
+	case NeverMatchSelector:
+		return s.Pos
+	case CompoundSelector:
+		return s.Pos
+	case CombinedSelector:
+		return s.Pos
+	case RelativePseudoClassSelector:
+		return s.Pos
+	case ContainsPseudoClassSelector:
+		return s.Pos
+	case RegexpPseudoClassSelector:
+		return s.Pos
+	case NthPseudoClassSelector:
+		return s.Pos
+	case OnlyChildPseudoClassSelector:
+		return s.Pos
+	case InputPseudoClassSelector:
+		return s.Pos
+	case EmptyElementPseudoClassSelector:
+		return s.Pos
+	case RootPseudoClassSelector:
+		return s.Pos
+	case ScopePseudoClassSelector:
+		return s.Pos
+	case NestingSelector:
+		return s.Pos
+	case TimePseudoClassSelector:
+		return s.Pos
+	case CustomStatePseudoClassSelector:
+		return s.Pos
+	case LinkPseudoClassSelector:
+		return s.Pos
+	case LangPseudoClassSelector:
+		return s.Pos
+	case EnabledPseudoClassSelector:
+		return s.Pos
+	case DisabledPseudoClassSelector:
+		return s.Pos
+	case CheckedPseudoClassSelector:
+		return s.Pos
+	}
+	return Span{}
+}
+
+// SourceOf returns the text sel was parsed from, as written in the input,
+// for the members of a group and the arguments of the pseudo-classes
+// like :not(). It is only recorded by the span-recording parsing functions
+// (see ParseWithSpans), and is empty otherwise (or if sel is not one of
+// the types of this package).
+func SourceOf(sel Sel) string {
+	switch s := sel.(type) {
+	case TagSelector:
+		return s.source
+	case ClassSelector:
+		return s.source
+	case IDSelector:
+		return s.source
+	case AttrSelector:
+		return s.source
+	case NeverMatchSelector:
+		return s.source
+	case CompoundSelector:
+		return s.source
+	case CombinedSelector:
+		return s.source
+	case RelativePseudoClassSelector:
+		return s.source
+	case ContainsPseudoClassSelector:
+		return s.source
+	case RegexpPseudoClassSelector:
+		return s.source
+	case NthPseudoClassSelector:
+		return s.source
+	case OnlyChildPseudoClassSelector:
+		return s.source
+	case InputPseudoClassSelector:
+		return s.source
+	case EmptyElementPseudoClassSelector:
+		return s.source
+	case RootPseudoClassSelector:
+		return s.source
+	case ScopePseudoClassSelector:
+		return s.source
+	case NestingSelector:
+		return s.source
+	case TimePseudoClassSelector:
+		return s.source
+	case CustomStatePseudoClassSelector:
+		return s.source
+	case LinkPseudoClassSelector:
+		return s.source
+	case LangPseudoClassSelector:
+		return s.source
+	case EnabledPseudoClassSelector:
+		return s.source
+	case DisabledPseudoClassSelector:
+		return s.source
+	case CheckedPseudoClassSelector:
+		return s.source
+	}
+	return ""
+}
+
+// withSpan returns a copy of sel with the given position
+func withSpan(sel Sel, span Span) Sel {
+	switch s := sel.(type) {
+	case TagSelector:
+		s.Pos = span
+		return s
+	case ClassSelector:
+		s.Pos = span
+		return s
+	case IDSelector:
+		s.Pos = span
+		return s
+	case AttrSelector:
+		s.Pos = span
+		return s
+	case NeverMatchSelector:
+		s.Pos = span
+		return s
+	case CompoundSelector:
+		s.Pos = span
+		return s
+	case CombinedSelector:
+		s.Pos = span
+		return s
+	case RelativePseudoClassSelector:
+		s.Pos = span
+		return s
+	case ContainsPseudoClassSelector:
+		s.Pos = span
+		return s
+	case RegexpPseudoClassSelector:
+		s.Pos = span
+		return s
+	case NthPseudoClassSelector:
+		s.Pos = span
+		return s
+	case OnlyChildPseudoClassSelector:
+		s.Pos = span
+		return s
+	case InputPseudoClassSelector:
+		s.Pos = span
+		return s
+	case EmptyElementPseudoClassSelector:
+		s.Pos = span
+		return s
+	case RootPseudoClassSelector:
+		s.Pos = span
+		return s
+	case ScopePseudoClassSelector:
+		s.Pos = span
+		return s
+	case NestingSelector:
+		s.Pos = span
+		return s
+	case TimePseudoClassSelector:
+		s.Pos = span
+		return s
+	case CustomStatePseudoClassSelector:
+		s.Pos = span
+		return s
+	case LinkPseudoClassSelector:
+		s.Pos = span
+		return s
+	case LangPseudoClassSelector:
+		s.Pos = span
+		return s
+	case EnabledPseudoClassSelector:
+		s.Pos = span
+		return s
+	case DisabledPseudoClassSelector:
+		s.Pos = span
+		return s
+	case CheckedPseudoClassSelector:
+		s.Pos = span
+		return s
+	}
+	return sel
+}
+
+// withSource returns a copy of sel with the given source text (see SourceOf)
+func withSource(sel Sel, source string) Sel {
+	switch s := sel.(type) {
+	case TagSelector:
+		s.source = source
+		return s
+	case ClassSelector:
+		s.source = source
+		return s
+	case IDSelector:
+		s.source = source
+		return s
+	case AttrSelector:
+		s.source = source
+		return s
+	case NeverMatchSelector:
+		s.source = source
+		return s
+	case CompoundSelector:
+		s.source = source
+		return s
+	case CombinedSelector:
+		s.source = source
+		return s
+	case RelativePseudoClassSelector:
+		s.source = source
+		return s
+	case ContainsPseudoClassSelector:
+		s.source = source
+		return s
+	case RegexpPseudoClassSelector:
+		s.source = source
+		return s
+	case NthPseudoClassSelector:
+		s.source = source
+		return s
+	case OnlyChildPseudoClassSelector:
+		s.source = source
+		return s
+	case InputPseudoClassSelector:
+		s.source = source
+		return s
+	case EmptyElementPseudoClassSelector:
+		s.source = source
+		return s
+	case RootPseudoClassSelector:
+		s.source = source
+		return s
+	case ScopePseudoClassSelector:
+		s.source = source
+		return s
+	case NestingSelector:
+		s.source = source
+		return s
+	case TimePseudoClassSelector:
+		s.source = source
+		return s
+	case CustomStatePseudoClassSelector:
+		s.source = source
+		return s
+	case LinkPseudoClassSelector:
+		s.source = source
+		return s
+	case LangPseudoClassSelector:
+		s.source = source
+		return s
+	case EnabledPseudoClassSelector:
+		s.source = source
+		return s
+	case DisabledPseudoClassSelector:
+		s.source = source
+		return s
+	case CheckedPseudoClassSelector:
+		s.source = source
+		return s
+	}
+	return sel
+}
@@ -0,0 +1,87 @@
+package cascadia
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSpans(t *testing.T) {
+	input := ` div.a > p:not(#b, [c])::before,  span`
+	group, err := ParseGroupWithSpans(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	WalkGroup(group, func(s Sel) bool {
+		span := SpanOf(s)
+		got = append(got, input[span.Start:span.End])
+		return true
+	})
+	exp := []string{
+		"div.a > p:not(#b, [c])::before", "div.a", "div", ".a", "p:not(#b, [c])::before", "p", ":not(#b, [c])", "#b", "[c]",
+		"span",
+	}
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected %q, got %q", exp, got)
+	}
+	combined := group[0].(CombinedSelector)
+	if s := input[combined.CombinatorPos.Start:combined.CombinatorPos.End]; s != " > " {
+		t.Errorf("unexpected combinator span %q", s)
+	}
+
+	// spans are ignored by Equal, and not recorded by default
+	plain, err := ParseGroupWithPseudoElements(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !EqualGroup(group, plain) {
+		t.Error("spans should be ignored by Equal")
+	}
+	WalkGroup(plain, func(s Sel) bool {
+		if SpanOf(s) != (Span{}) {
+			t.Errorf("unexpected span for %s", s)
+		}
+		return true
+	})
+
+	if _, err := ParseWithSpans("a ,"); err == nil {
+		t.Error("expected error on left over")
+	}
+}
+
+func TestSource(t *testing.T) {
+	input := "DIV>P.a ,  /* comment */ :NOT( a.b  , #x) ,li:nth-child( 2N+1 )::before"
+	group, err := ParseGroupWithSpans(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []string{"DIV>P.a", ":NOT( a.b  , #x)", "li:nth-child( 2N+1 )::before"}
+	if len(group) != len(exp) {
+		t.Fatalf("unexpected group %s", group)
+	}
+	for i, sel := range group {
+		if got := SourceOf(sel); got != exp[i] {
+			t.Errorf("expected %q, got %q", exp[i], got)
+		}
+	}
+	args := group[1].(RelativePseudoClassSelector).Args
+	if SourceOf(args[0]) != "a.b" || SourceOf(args[1]) != "#x" {
+		t.Errorf("unexpected sources %q and %q", SourceOf(args[0]), SourceOf(args[1]))
+	}
+
+	// the sources are only recorded with the spans
+	plain := MustParseGroup(input[:7])
+	if SourceOf(plain[0]) != "" {
+		t.Errorf("unexpected source %q", SourceOf(plain[0]))
+	}
+	if !Equal(plain[0], group[0]) {
+		t.Error("the sources must be ignored by Equal")
+	}
+	sel, err := ParseWithSpans("  a  b ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if SourceOf(sel) != "a  b" {
+		t.Errorf("unexpected source %q", SourceOf(sel))
+	}
+}
@@ -1,5 +1,11 @@
 package cascadia
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 // Specificity is the CSS specificity as defined in
 // https://www.w3.org/TR/selectors/#specificity-rules
 // with the convention Specificity = [A,B,C].
@@ -24,3 +30,86 @@ func (s Specificity) Add(other Specificity) Specificity {
 	}
 	return s
 }
+
+// String returns the conventional form (a,b,c) of the specificity.
+func (s Specificity) String() string {
+	return fmt.Sprintf("(%d,%d,%d)", s[0], s[1], s[2])
+}
+
+// ParseSpecificity parses the form (a,b,c) returned by Specificity.String.
+// Whitespace is allowed around the components.
+func ParseSpecificity(s string) (Specificity, error) {
+	var out Specificity
+	inner := strings.TrimSpace(s)
+	if !strings.HasPrefix(inner, "(") || !strings.HasSuffix(inner, ")") {
+		return out, fmt.Errorf("invalid specificity %q: expected (a,b,c)", s)
+	}
+	parts := strings.Split(inner[1:len(inner)-1], ",")
+	if len(parts) != 3 {
+		return out, fmt.Errorf("invalid specificity %q: expected 3 components, got %d", s, len(parts))
+	}
+	for i, part := range parts {
+		v, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || v < 0 {
+			return out, fmt.Errorf("invalid specificity %q: invalid component %q", s, part)
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+// SpecificityTerm is the contribution of one component of a selector
+// to its specificity, as returned by SpecificityTerms.
+type SpecificityTerm struct {
+	// Component is the CSS text of the component, like "#main",
+	// ":is(a, .b)" or "::before".
+	Component   string
+	Specificity Specificity
+	// PseudoElement is true if the component is the pseudo-element
+	// of a compound selector, which counts as a type selector.
+	PseudoElement bool
+}
+
+func (t SpecificityTerm) String() string {
+	return t.Component + " " + t.Specificity.String()
+}
+
+// SpecificityTerms returns the specificity of sel broken down by
+// component, in source order, so that cascade debuggers can show where
+// the weight of a rule comes from. The components which don't contribute,
+// like '*' or :where(), are included, with a zero specificity.
+// The functional pseudo-classes, like :is() or :not(), are one component,
+// whose specificity is the one of their most specific argument.
+//
+// The sum of the terms is sel.Specificity().
+func SpecificityTerms(sel Sel) []SpecificityTerm {
+	return appendSpecificityTerms(nil, sel)
+}
+
+func appendSpecificityTerms(terms []SpecificityTerm, sel Sel) []SpecificityTerm {
+	switch sel := sel.(type) {
+	case CompoundSelector:
+		if len(sel.Selectors) == 0 && sel.Pseudo == "" {
+			return append(terms, SpecificityTerm{Component: "*"}) // the universal selector
+		}
+		for _, c := range sel.Selectors {
+			terms = appendSpecificityTerms(terms, c)
+		}
+		if sel.Pseudo != "" {
+			terms = append(terms, SpecificityTerm{
+				Component:     "::" + sel.Pseudo,
+				Specificity:   Specificity{0, 0, 1},
+				PseudoElement: true,
+			})
+		}
+		return terms
+	case CombinedSelector:
+		terms = appendSpecificityTerms(terms, sel.First)
+		if sel.Second != nil {
+			terms = appendSpecificityTerms(terms, sel.Second)
+		}
+		return terms
+	default:
+		return append(terms, SpecificityTerm{Component: sel.String(), Specificity: sel.Specificity()})
+	}
+}
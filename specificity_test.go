@@ -1,6 +1,7 @@
 package cascadia
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 	"testing"
@@ -81,6 +82,21 @@ var testsSpecificity = []testSpec{
 		selector: "#s12:only-child",
 		spec:     Specificity{1, 1, 0},
 	},
+	{
+		HTML:     `<html><body><ul><ol><li id="s12" class="a"></li></ol></ul></body></html>`,
+		selector: "li:is(.a, #s12)",
+		spec:     Specificity{1, 0, 1},
+	},
+	{
+		HTML:     `<html><body><ul><ol><li id="s12" class="a"></li></ol></ul></body></html>`,
+		selector: "li:where(.a, #s12)",
+		spec:     Specificity{0, 0, 1},
+	},
+	{
+		HTML:     `<html><body><ul><ol><li id="s12"><a class="b"></a></li></ol></ul></body></html>`,
+		selector: "li:has(a.b, a)",
+		spec:     Specificity{0, 1, 2},
+	},
 }
 
 func setupSel(selector, HTML string) (Sel, *html.Node, error) {
@@ -125,3 +141,85 @@ func TestCompareSpecificity(t *testing.T) {
 		t.Fatal()
 	}
 }
+
+func TestSpecificityString(t *testing.T) {
+	for _, spec := range []Specificity{{0, 0, 0}, {1, 0, 1}, {12, 3, 45}} {
+		parsed, err := ParseSpecificity(spec.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed != spec {
+			t.Errorf("expected %v, got %v", spec, parsed)
+		}
+	}
+	if s := (Specificity{1, 2, 3}).String(); s != "(1,2,3)" {
+		t.Errorf("unexpected string %s", s)
+	}
+	if spec, err := ParseSpecificity(" ( 1, 2 ,3 ) "); err != nil || spec != (Specificity{1, 2, 3}) {
+		t.Errorf("unexpected %v %v", spec, err)
+	}
+	for _, invalid := range []string{"", "1,2,3", "(1,2)", "(1,2,3,4)", "(a,b,c)", "(-1,0,0)"} {
+		if _, err := ParseSpecificity(invalid); err == nil {
+			t.Errorf("expected error for %q", invalid)
+		}
+	}
+}
+
+func TestSpecificityTerms(t *testing.T) {
+	for _, test := range []struct {
+		sel   string
+		terms string
+	}{
+		{"div", "div (0,0,1)"},
+		{"*", "* (0,0,0)"},
+		{"div#main > p.a:where(.b)", "div (0,0,1), #main (1,0,0), p (0,0,1), .a (0,1,0), :where(.b) (0,0,0)"},
+		{"a:not(em, #x)::before", "a (0,0,1), :not(em, #x) (1,0,0), ::before (0,0,1)"},
+		{"::after", "::after (0,0,1)"},
+		{"ul li ~ li::marker", "ul (0,0,1), li (0,0,1), li (0,0,1), ::marker (0,0,1)"},
+	} {
+		group, err := ParseGroupWithPseudoElements(test.sel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the terms are kept by the serializations of the group
+		encoded, err := json.Marshal(group)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded SelectorGroup
+		if err := json.Unmarshal(encoded, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		reparsed, err := ParseGroupWithPseudoElements(group.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, sel := range []Sel{group[0], decoded[0], reparsed[0]} {
+			terms := SpecificityTerms(sel)
+			var (
+				chunks []string
+				sum    Specificity
+			)
+			for _, term := range terms {
+				chunks = append(chunks, term.String())
+				sum = sum.Add(term.Specificity)
+			}
+			if got := strings.Join(chunks, ", "); got != test.terms {
+				t.Errorf("%s: expected %s, got %s", test.sel, test.terms, got)
+			}
+			if sum != sel.Specificity() {
+				t.Errorf("%s: the terms sum to %s instead of %s", test.sel, sum, sel.Specificity())
+			}
+		}
+	}
+
+	sel, err := ParseWithPseudoElement("p::first-line")
+	if err != nil {
+		t.Fatal(err)
+	}
+	terms := SpecificityTerms(sel)
+	if len(terms) != 2 || terms[0].PseudoElement || !terms[1].PseudoElement {
+		t.Errorf("unexpected terms %v", terms)
+	}
+}
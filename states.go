@@ -0,0 +1,126 @@
+package cascadia
+
+import "golang.org/x/net/html"
+
+// StateProvider reports the dynamic states of the elements, which are
+// not determined by the document, to evaluate the pseudo-classes depending
+// on them, like :past or :future (see WithStates).
+type StateProvider interface {
+	// HasState returns true if n is in the state of the pseudo-class
+	// named state (without colon), like "current" for :current.
+	HasState(n *html.Node, state string) bool
+}
+
+// CustomStateProvider is implemented by the StateProviders reporting
+// the custom states of the elements, matched by :state(), like the ones
+// exposed by the ElementInternals of the custom elements.
+type CustomStateProvider interface {
+	// HasCustomState returns true if n has the custom state, whose
+	// name is case-sensitive, as in :state(checked).
+	HasCustomState(n *html.Node, state string) bool
+}
+
+// WithStates returns m, where the pseudo-classes depending on the dynamic
+// states of the elements, like :current, are evaluated by states
+// (and :state(), if states is a CustomStateProvider).
+// Without provider, they never match, so that the stylesheets using them
+// may be parsed and matched in a static context.
+//
+// m must be a Sel or a SelectorGroup, as returned by Parse or ParseGroup;
+// other matchers are returned unchanged.
+func WithStates(m Matcher, states StateProvider) Matcher {
+	bind := func(s Sel) Sel {
+		switch t := s.(type) {
+		case TimePseudoClassSelector:
+			t.states = states
+			return t
+		case CustomStatePseudoClassSelector:
+			t.states, _ = states.(CustomStateProvider)
+			return t
+		}
+		return s
+	}
+	switch m := m.(type) {
+	case Sel:
+		return Transform(m, bind)
+	case SelectorGroup:
+		return TransformGroup(m, bind)
+	}
+	return m
+}
+
+// TimePseudoClassSelector implements the time-dimensional pseudo-classes
+// :current, :past and :future, used to style the cues of WebVTT subtitles
+// and the elements read by a speech synthesizer, which are evaluated
+// by the provider given to WithStates.
+//
+// :current(sel) matches the current element, if it is matched by sel,
+// or else its innermost ancestor matched by sel.
+type TimePseudoClassSelector struct {
+	abstractPseudoClass
+
+	Name string        // "current", "past" or "future"
+	Args SelectorGroup // for :current(sel), nil otherwise
+
+	states StateProvider
+}
+
+func (s TimePseudoClassSelector) Match(n *html.Node) bool {
+	if n.Type != html.ElementNode || s.states == nil {
+		return false
+	}
+	if s.Args == nil {
+		return s.states.HasState(n, s.Name)
+	}
+	if !s.Args.Match(n) {
+		return false
+	}
+	// search a current descendant, without
+	// a closer ancestor matched by the arguments
+	var hasCurrent func(c *html.Node) bool
+	hasCurrent = func(c *html.Node) bool {
+		if s.states.HasState(c, s.Name) {
+			return true
+		}
+		for child := c.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type == html.ElementNode && !s.Args.Match(child) && hasCurrent(child) {
+				return true
+			}
+		}
+		return false
+	}
+	return hasCurrent(n)
+}
+
+// MatchNode only matches the nodes returned by FromHTML,
+// since the states are provided for *html.Node.
+func (s TimePseudoClassSelector) MatchNode(n Node) bool {
+	if h := ToHTML(n); h != nil {
+		return s.Match(h)
+	}
+	return false
+}
+
+// CustomStatePseudoClassSelector implements :state(), which matches the
+// elements having a custom state, as reported by the CustomStateProvider
+// given to WithStates.
+type CustomStatePseudoClassSelector struct {
+	abstractPseudoClass
+
+	State string
+
+	states CustomStateProvider
+}
+
+func (s CustomStatePseudoClassSelector) Match(n *html.Node) bool {
+	return n.Type == html.ElementNode && s.states != nil && s.states.HasCustomState(n, s.State)
+}
+
+// MatchNode only matches the nodes returned by FromHTML,
+// since the states are provided for *html.Node.
+func (s CustomStatePseudoClassSelector) MatchNode(n Node) bool {
+	if h := ToHTML(n); h != nil {
+		return s.Match(h)
+	}
+	return false
+}
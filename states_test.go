@@ -0,0 +1,130 @@
+package cascadia
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+// cueStates is a StateProvider for the timeline of WebVTT cues,
+// each element being identified by its id
+type cueStates map[string]string
+
+func (c cueStates) HasState(n *html.Node, state string) bool { return c[nodeID(n)] == state }
+
+func TestTimePseudoClasses(t *testing.T) {
+	for _, input := range []string{":current", ":past", ":future", ":current(p, li)", "p:not(:past)"} {
+		sel, err := ParseWithOptions(input, Options{Strict: true})
+		if err != nil {
+			t.Fatalf("%s: %s", input, err)
+		}
+		if sel.String() != input {
+			t.Errorf("expected %s, got %s", input, sel)
+		}
+		b, err := MarshalSel(sel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		back, err := UnmarshalSel(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !Equal(back, sel) {
+			t.Errorf("unexpected JSON round trip %s", back)
+		}
+	}
+	group, err := ParseGroup(":past, :future, p")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc := MustParseHTML(`<div id="d"><p id="1"><span id="1a">a</span><span id="1b">b</span></p><p id="2"><span id="2a">c</span></p></div>`)
+	ids := func(nodes []*html.Node) string {
+		var out []string
+		for _, n := range nodes {
+			out = append(out, nodeID(n))
+		}
+		return strings.Join(out, " ")
+	}
+	// without provider, the time pseudo-classes never match
+	if got := ids(QueryAll(doc, group)); got != "1 2" {
+		t.Errorf("unexpected matches %s", got)
+	}
+
+	states := cueStates{"1": "past", "1a": "past", "1b": "current", "2": "future", "2a": "future"}
+	for _, test := range []struct{ sel, exp string }{
+		{":past", "1 1a"},
+		{":current", "1b"},
+		{"p:future", "2"},
+		{":current(p)", "1"},
+		{":current(p, span)", "1b"},
+		{":current(div)", "d"},
+		{"span:not(:past)", "1b 2a"},
+	} {
+		m := WithStates(MustParse(test.sel), states)
+		if got := ids(QueryAll(doc, m)); got != test.exp {
+			t.Errorf("%s: expected %s, got %s", test.sel, test.exp, got)
+		}
+		if got := QueryAllNodes(FromHTML(doc), m.(NodeMatcher)); len(got) != len(strings.Fields(test.exp)) {
+			t.Errorf("%s: unexpected matches %v", test.sel, got)
+		}
+	}
+	if got := ids(QueryAll(doc, WithStates(group, states))); got != "1 1a 2 2a" {
+		t.Errorf("unexpected matches %s", got)
+	}
+}
+
+// elementStates is a CustomStateProvider, mapping the ids of
+// the elements to their custom states
+type elementStates map[string][]string
+
+func (e elementStates) HasState(n *html.Node, state string) bool { return false }
+
+func (e elementStates) HasCustomState(n *html.Node, state string) bool {
+	for _, s := range e[nodeID(n)] {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCustomStatePseudoClass(t *testing.T) {
+	for _, input := range []string{":state(checked)", "x-toggle:state(--On)", `:not(:state(a\.b))`} {
+		sel, err := ParseWithOptions(input, Options{Strict: true})
+		if err != nil {
+			t.Fatalf("%s: %s", input, err)
+		}
+		if sel.String() != input {
+			t.Errorf("expected %s, got %s", input, sel)
+		}
+		b, err := MarshalSel(sel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if back, err := UnmarshalSel(b); err != nil || !Equal(back, sel) {
+			t.Errorf("unexpected JSON round trip %s (%v)", back, err)
+		}
+	}
+	for _, input := range []string{":state", ":state()", ":state(a b)", ":state(1)"} {
+		if _, err := Parse(input); err == nil {
+			t.Errorf("%s: expected error", input)
+		}
+	}
+
+	doc := MustParseHTML(`<x-toggle id="a"></x-toggle><x-toggle id="b"></x-toggle><x-toggle id="c"></x-toggle>`)
+	states := elementStates{"a": {"checked"}, "b": {"checked", "disabled"}, "c": {"Checked"}}
+	sel := MustParse("x-toggle:state(checked):not(:state(disabled))")
+	if got := QueryAll(doc, sel); len(got) != 0 {
+		t.Errorf("unexpected matches without provider %v", got)
+	}
+	got := QueryAll(doc, WithStates(sel, states))
+	if len(got) != 1 || nodeID(got[0]) != "a" {
+		t.Errorf("unexpected matches %v", got)
+	}
+	// a provider without custom states
+	if got := QueryAll(doc, WithStates(sel, cueStates{})); len(got) != 0 {
+		t.Errorf("unexpected matches %v", got)
+	}
+}
@@ -0,0 +1,357 @@
+package cascadia
+
+import (
+	"fmt"
+	"io"
+
+	"golang.org/x/net/html"
+)
+
+// This file implements the matching of selectors directly on the
+// tokens of an html.Tokenizer, without building the DOM.
+//
+// Only the selectors which may be decided when the start tag is read are
+// supported: they may only depend on the element, its ancestors and its
+// position among its previous siblings. In particular, the sibling
+// combinators, :has(), :contains(), :empty, :only-child and the
+// :nth-last-*() pseudo-classes are not supported.
+
+// StreamMatcher matches a group of selectors against
+// the start tags of a stream of HTML tokens.
+type StreamMatcher struct {
+	group SelectorGroup
+}
+
+// NewStreamMatcher returns a StreamMatcher for group, or an error
+// if one of its selectors is not supported in streaming mode.
+func NewStreamMatcher(group SelectorGroup) (*StreamMatcher, error) {
+	for _, sel := range group {
+		if err := CheckStreamable(sel); err != nil {
+			return nil, err
+		}
+	}
+	return &StreamMatcher{group: group}, nil
+}
+
+// CheckStreamable returns an error if sel can't be
+// evaluated by a StreamMatcher.
+func CheckStreamable(sel Sel) error {
+	switch s := sel.(type) {
+	case TagSelector, ClassSelector, IDSelector, AttrSelector, NeverMatchSelector,
+		RootPseudoClassSelector, LinkPseudoClassSelector, InputPseudoClassSelector,
+		CheckedPseudoClassSelector, LangPseudoClassSelector:
+		return nil
+	case NthPseudoClassSelector:
+		if s.Last {
+			return fmt.Errorf("%s is not supported in streaming mode", s)
+		}
+		return nil
+	case CompoundSelector:
+		for _, c := range s.Selectors {
+			if err := CheckStreamable(c); err != nil {
+				return err
+			}
+		}
+		return nil
+	case CombinedSelector:
+		switch s.Combinator {
+		case 0, ' ', '>':
+		default:
+			return fmt.Errorf("combinator %q is not supported in streaming mode", s.Combinator)
+		}
+		if err := CheckStreamable(s.First); err != nil {
+			return err
+		}
+		if s.Second != nil {
+			return CheckStreamable(s.Second)
+		}
+		return nil
+	case RelativePseudoClassSelector:
+		switch s.Name {
+		case "not", "is", "where":
+			for _, arg := range s.Args {
+				if err := CheckStreamable(arg); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("%s is not supported in streaming mode", sel)
+}
+
+// streamElement is an open element in the stack of a stream.
+// It implements Node, but only the Parent navigation method is
+// meaningful, which is enough for the simple selectors.
+type streamElement struct {
+	typ    html.NodeType
+	tag    string
+	attrs  []html.Attribute
+	parent *streamElement
+
+	index     int // position among the element siblings, starting at 1
+	typeIndex int // position among the siblings with the same tag
+
+	children     int            // number of element children seen so far
+	typeChildren map[string]int // same, by tag
+}
+
+func (e *streamElement) Type() html.NodeType          { return e.typ }
+func (e *streamElement) Data() string                 { return e.tag }
+func (e *streamElement) Namespace() string            { return "" }
+func (e *streamElement) Attributes() []html.Attribute { return e.attrs }
+func (e *streamElement) FirstChild() Node             { return nil }
+func (e *streamElement) LastChild() Node              { return nil }
+func (e *streamElement) PrevSibling() Node            { return nil }
+func (e *streamElement) NextSibling() Node            { return nil }
+
+func (e *streamElement) Parent() Node {
+	if e.parent == nil {
+		return nil
+	}
+	return e.parent
+}
+
+// addChild registers a new child element of e
+func (e *streamElement) addChild(tag string, attrs []html.Attribute) *streamElement {
+	e.children++
+	if e.typeChildren == nil {
+		e.typeChildren = make(map[string]int)
+	}
+	e.typeChildren[tag]++
+	return &streamElement{
+		typ: html.ElementNode, tag: tag, attrs: attrs, parent: e,
+		index: e.children, typeIndex: e.typeChildren[tag],
+	}
+}
+
+// streamMatch evaluates sel, which has been validated by CheckStreamable
+func streamMatch(sel Sel, e *streamElement) bool {
+	switch s := sel.(type) {
+	case CompoundSelector:
+		if len(s.Selectors) == 0 {
+			return e.typ == html.ElementNode
+		}
+		for _, c := range s.Selectors {
+			if !streamMatch(c, e) {
+				return false
+			}
+		}
+		return true
+	case CombinedSelector:
+		switch s.Combinator {
+		case 0:
+			return streamMatch(s.First, e)
+		case '>':
+			return streamMatch(s.Second, e) && e.parent != nil && streamMatch(s.First, e.parent)
+		default: // ' '
+			if !streamMatch(s.Second, e) {
+				return false
+			}
+			for p := e.parent; p != nil; p = p.parent {
+				if streamMatch(s.First, p) {
+					return true
+				}
+			}
+			return false
+		}
+	case RelativePseudoClassSelector:
+		if e.typ != html.ElementNode {
+			return false
+		}
+		matched := false
+		for _, arg := range s.Args {
+			if streamMatch(arg, e) {
+				matched = true
+				break
+			}
+		}
+		if s.Name == "not" {
+			return !matched
+		}
+		return matched
+	case NthPseudoClassSelector:
+		// as for the DOM, the children of the document are not matched
+		if e.typ != html.ElementNode || e.parent == nil || e.parent.typ == html.DocumentNode {
+			return false
+		}
+		i := e.index
+		if s.OfType {
+			i = e.typeIndex
+		}
+		i -= s.B
+		if s.A == 0 {
+			return i == 0
+		}
+		return i%s.A == 0 && i/s.A >= 0
+	default:
+		return MatchNode(sel, e)
+	}
+}
+
+// voidElements have no end tag
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+// closesParagraph are the start tags implicitly closing an open <p>
+var closesParagraph = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "details": true, "div": true,
+	"dl": true, "fieldset": true, "figcaption": true, "figure": true, "footer": true, "form": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "header": true, "hr": true,
+	"main": true, "nav": true, "ol": true, "p": true, "pre": true, "section": true, "table": true, "ul": true,
+}
+
+// implicitlyClosed returns the tags of the open elements
+// closed by a start tag, before a boundary tag.
+func implicitlyClosed(tag string) (closed []string, boundaries []string) {
+	switch tag {
+	case "li":
+		return []string{"li"}, []string{"ul", "ol"}
+	case "dt", "dd":
+		return []string{"dt", "dd"}, []string{"dl"}
+	case "option":
+		return []string{"option"}, []string{"select", "datalist"}
+	case "tr":
+		return []string{"tr", "td", "th"}, []string{"table", "thead", "tbody", "tfoot"}
+	case "td", "th":
+		return []string{"td", "th"}, []string{"tr", "table"}
+	}
+	if closesParagraph[tag] {
+		return []string{"p"}, []string{"button"}
+	}
+	return nil, nil
+}
+
+// Run reads the HTML document from r and calls fn for each start tag
+// matching at least one selector of the group, with the indices of the
+// matching selectors. Iteration stops at the first error returned by fn,
+// which is then returned.
+//
+// The ancestors of an element are deduced from the source, with a simplified
+// handling of the implied end tags (like the ones of <li> and <p>); the
+// elements the HTML parser would insert (like <tbody>) are not created.
+func (m *StreamMatcher) Run(r io.Reader, fn func(tok html.Token, matched []int) error) error {
+	z := html.NewTokenizer(r)
+	current := &streamElement{typ: html.DocumentNode}
+	var matched []int
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if z.Err() == io.EOF {
+				return nil
+			}
+			return z.Err()
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			closed, boundaries := implicitlyClosed(tok.Data)
+			current = closeImplied(current, closed, boundaries)
+
+			e := current.addChild(tok.Data, tok.Attr)
+			matched = matched[:0]
+			for i, sel := range m.group {
+				if streamMatch(sel, e) {
+					matched = append(matched, i)
+				}
+			}
+			if len(matched) != 0 {
+				if err := fn(tok, matched); err != nil {
+					return err
+				}
+			}
+			if tok.Type == html.StartTagToken && !voidElements[tok.Data] {
+				current = e
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			for e := current; e.parent != nil; e = e.parent {
+				if e.tag == string(name) {
+					current = e.parent
+					break
+				}
+			}
+		}
+	}
+}
+
+// closeImplied pops the elements closed by the start of a new element
+func closeImplied(current *streamElement, closed, boundaries []string) *streamElement {
+	for e := current; e.parent != nil; e = e.parent {
+		if containsString(boundaries, e.tag) {
+			break
+		}
+		if containsString(closed, e.tag) {
+			return e.parent
+		}
+	}
+	return current
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// StreamHandlers dispatches the start tags of an HTML stream to
+// handlers registered for selectors, processing the document
+// in one pass. The zero value is ready to use.
+type StreamHandlers struct {
+	group    SelectorGroup
+	owners   []int // index of the handler of each selector of group
+	handlers []func(start html.Token, attrs []html.Attribute)
+}
+
+// OnMatch parses selector as a group, and registers handler to be
+// called with each start tag matching it. An error is returned if the
+// selector is invalid or not supported in streaming mode.
+//
+// When a start tag matches several registered selectors, the handlers
+// are called in the order of their registration.
+func (h *StreamHandlers) OnMatch(selector string, handler func(start html.Token, attrs []html.Attribute)) error {
+	group, err := parseStreamable(selector)
+	if err != nil {
+		return err
+	}
+	for range group {
+		h.owners = append(h.owners, len(h.handlers))
+	}
+	h.group = append(h.group, group...)
+	h.handlers = append(h.handlers, handler)
+	return nil
+}
+
+// parseStreamable parses selector as a group, supported in streaming mode
+func parseStreamable(selector string) (SelectorGroup, error) {
+	group, err := ParseGroup(selector)
+	if err != nil {
+		return nil, err
+	}
+	for _, sel := range group {
+		if err := CheckStreamable(sel); err != nil {
+			return nil, err
+		}
+	}
+	return group, nil
+}
+
+// Run reads the HTML document from r, calling the registered handlers.
+// See StreamMatcher.Run for the limitations of the streaming mode.
+func (h *StreamHandlers) Run(r io.Reader) error {
+	m := StreamMatcher{group: h.group}
+	return m.Run(r, func(tok html.Token, matched []int) error {
+		last := -1
+		for _, i := range matched {
+			// a handler is called once, even if several of its selectors match
+			if owner := h.owners[i]; owner != last {
+				h.handlers[owner](tok, tok.Attr)
+				last = owner
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,292 @@
+package cascadia
+
+import (
+	"io"
+
+	"golang.org/x/net/html"
+)
+
+// StreamRewriter rewrites an HTML document while it streams through,
+// in the spirit of the HTMLRewriter of the edge workers: handlers
+// registered for selectors may modify the attributes of the elements
+// matched, insert content around or inside them, or remove them.
+//
+// Only the stack of the open elements is kept in memory, and the tokens
+// are copied unchanged to the output, unless a handler modifies them.
+// The selectors are restricted as for StreamMatcher, and the ancestors
+// of an element are deduced as in StreamMatcher.Run.
+// The zero value has no handler, and is ready to use.
+type StreamRewriter struct {
+	group    SelectorGroup
+	owners   []int // index of the handler of each selector of group
+	handlers []func(e *RewriteElement) error
+}
+
+// OnElement parses selector as a group, and registers handler to be
+// called with each element matching it, before its start tag is written.
+// An error is returned if the selector is invalid or not supported
+// in streaming mode.
+//
+// When an element matches several registered selectors, the handlers
+// are called in the order of their registration. The elements removed
+// with their content are not given to the handlers.
+func (rw *StreamRewriter) OnElement(selector string, handler func(e *RewriteElement) error) error {
+	group, err := parseStreamable(selector)
+	if err != nil {
+		return err
+	}
+	for range group {
+		rw.owners = append(rw.owners, len(rw.handlers))
+	}
+	rw.group = append(rw.group, group...)
+	rw.handlers = append(rw.handlers, handler)
+	return nil
+}
+
+// RewriteElement is an element matched by a StreamRewriter,
+// given to its handlers, which may modify it.
+//
+// The content inserted is written as is, and must be valid HTML:
+// use html.EscapeString to insert text.
+// The void elements, like <img>, have no content: Prepend, Append and
+// SetInnerContent are ignored for them.
+type RewriteElement struct {
+	tok  html.Token
+	node *streamElement
+	void bool
+
+	changed bool // the start tag must be serialized again
+
+	before, after       []string
+	prepended, appended []string
+	inner               *string // set by SetInnerContent
+
+	removed, unwrapped bool
+}
+
+// Tag returns the lower-cased tag name of the element.
+func (e *RewriteElement) Tag() string { return e.tok.Data }
+
+// Attrs returns the attributes of the element, which must not be modified
+// in place: use SetAttr and RemoveAttr instead.
+func (e *RewriteElement) Attrs() []html.Attribute { return e.tok.Attr }
+
+// Attr returns the value of the attribute key, and whether it is present.
+func (e *RewriteElement) Attr(key string) (string, bool) {
+	key = toLowerASCII(key)
+	for _, a := range e.tok.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+// copyAttrs ensures the attributes are not shared with the stack of
+// the open elements, so that the descendants are matched against the
+// original attributes
+func (e *RewriteElement) copyAttrs() {
+	if !e.changed {
+		e.tok.Attr = append([]html.Attribute(nil), e.tok.Attr...)
+		e.changed = true
+	}
+}
+
+// SetAttr sets the value of the attribute key, adding it if needed.
+func (e *RewriteElement) SetAttr(key, val string) {
+	e.copyAttrs()
+	key = toLowerASCII(key)
+	for i, a := range e.tok.Attr {
+		if a.Key == key {
+			e.tok.Attr[i].Val = val
+			return
+		}
+	}
+	e.tok.Attr = append(e.tok.Attr, html.Attribute{Key: key, Val: val})
+}
+
+// RemoveAttr removes the attribute key, if present.
+func (e *RewriteElement) RemoveAttr(key string) {
+	e.copyAttrs()
+	key = toLowerASCII(key)
+	attrs := e.tok.Attr[:0]
+	for _, a := range e.tok.Attr {
+		if a.Key != key {
+			attrs = append(attrs, a)
+		}
+	}
+	e.tok.Attr = attrs
+}
+
+// Before inserts content before the start tag of the element.
+func (e *RewriteElement) Before(content string) { e.before = append(e.before, content) }
+
+// After inserts content after the end of the element.
+func (e *RewriteElement) After(content string) { e.after = append(e.after, content) }
+
+// Prepend inserts content right after the start tag of the element.
+func (e *RewriteElement) Prepend(content string) { e.prepended = append(e.prepended, content) }
+
+// Append inserts content right before the end of the element.
+func (e *RewriteElement) Append(content string) { e.appended = append(e.appended, content) }
+
+// SetInnerContent replaces the content of the element, which
+// is skipped, including the elements matched in it.
+func (e *RewriteElement) SetInnerContent(content string) { e.inner = &content }
+
+// Remove removes the element, with its content.
+// The content inserted with Before and After is kept.
+func (e *RewriteElement) Remove() { e.removed = true }
+
+// RemoveAndKeepContent removes the start and end tags
+// of the element, but not its content.
+func (e *RewriteElement) RemoveAndKeepContent() { e.unwrapped = true }
+
+// dropsContent returns true if the content of e is not written
+func (e *RewriteElement) dropsContent() bool { return e.removed || e.inner != nil }
+
+// rewriteOutput writes to w, keeping the first error
+type rewriteOutput struct {
+	w   io.Writer
+	err error
+}
+
+func (out *rewriteOutput) write(b []byte) {
+	if out.err == nil {
+		_, out.err = out.w.Write(b)
+	}
+}
+
+func (out *rewriteOutput) writeStrings(chunks []string) {
+	for _, s := range chunks {
+		out.write([]byte(s))
+	}
+}
+
+// start writes the start tag of e, whose source is raw
+func (out *rewriteOutput) start(e *RewriteElement, raw []byte) {
+	out.writeStrings(e.before)
+	if !e.removed && !e.unwrapped {
+		if e.changed {
+			out.write([]byte(e.tok.String()))
+		} else {
+			out.write(raw)
+		}
+	}
+	if !e.removed && !e.void {
+		out.writeStrings(e.prepended)
+		if e.inner != nil {
+			out.write([]byte(*e.inner))
+		}
+	}
+}
+
+// end writes the end of e, whose end tag is raw (nil if implied)
+func (out *rewriteOutput) end(e *RewriteElement, raw []byte) {
+	if !e.removed && !e.void {
+		out.writeStrings(e.appended)
+	}
+	if !e.removed && !e.unwrapped {
+		out.write(raw)
+	}
+	out.writeStrings(e.after)
+}
+
+// Rewrite reads the HTML document from r, and writes it to w, rewritten by
+// the registered handlers. It stops at the first error returned by
+// a handler or w, which is then returned.
+func (rw *StreamRewriter) Rewrite(w io.Writer, r io.Reader) error {
+	z := html.NewTokenizer(r)
+	out := rewriteOutput{w: w}
+	stack := []*RewriteElement{{node: &streamElement{typ: html.DocumentNode}}}
+	skipped := 0 // number of open elements whose content is dropped
+	var raw []byte
+
+	// pop closes the last open element
+	pop := func(endTag []byte) {
+		e := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if e.dropsContent() {
+			skipped--
+		}
+		if skipped == 0 {
+			out.end(e, endTag)
+		}
+	}
+
+	for out.err == nil {
+		tt := z.Next()
+		// the tokenizer lower-cases the names in place
+		raw = append(raw[:0], z.Raw()...)
+		switch tt {
+		case html.ErrorToken:
+			for len(stack) > 1 {
+				pop(nil)
+			}
+			if z.Err() != io.EOF {
+				return z.Err()
+			}
+			return out.err
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			closed, boundaries := implicitlyClosed(tok.Data)
+			parent := closeImplied(stack[len(stack)-1].node, closed, boundaries)
+			for stack[len(stack)-1].node != parent {
+				pop(nil)
+			}
+
+			e := &RewriteElement{
+				tok:  tok,
+				node: parent.addChild(tok.Data, tok.Attr),
+				void: tok.Type == html.SelfClosingTagToken || voidElements[tok.Data],
+			}
+			if skipped == 0 {
+				last := -1
+				for i, sel := range rw.group {
+					// a handler is called once, even if several of its selectors match
+					if owner := rw.owners[i]; owner != last && streamMatch(sel, e.node) {
+						if err := rw.handlers[owner](e); err != nil {
+							return err
+						}
+						last = owner
+					}
+				}
+				out.start(e, raw)
+			}
+			if e.void {
+				if skipped == 0 {
+					out.end(e, nil)
+				}
+			} else {
+				stack = append(stack, e)
+				if e.dropsContent() {
+					skipped++
+				}
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			open := -1
+			for i := len(stack) - 1; i > 0; i-- {
+				if stack[i].node.tag == string(name) {
+					open = i
+					break
+				}
+			}
+			if open == -1 { // stray end tag
+				if skipped == 0 {
+					out.write(raw)
+				}
+				continue
+			}
+			for len(stack)-1 > open {
+				pop(nil)
+			}
+			pop(raw)
+		default:
+			if skipped == 0 {
+				out.write(raw)
+			}
+		}
+	}
+	return out.err
+}
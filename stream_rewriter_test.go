@@ -0,0 +1,98 @@
+package cascadia
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func rewriteString(t *testing.T, rw *StreamRewriter, input string) string {
+	t.Helper()
+	var out strings.Builder
+	if err := rw.Rewrite(&out, strings.NewReader(input)); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+func TestStreamRewriter(t *testing.T) {
+	for _, test := range []struct {
+		sel     string
+		handler func(e *RewriteElement)
+		input   string
+		exp     string
+	}{
+		{"a", func(e *RewriteElement) {}, `<!DOCTYPE html><A HREF="/x">L</A><!-- c -->`, `<!DOCTYPE html><A HREF="/x">L</A><!-- c -->`},
+		{"a[href^='/']", func(e *RewriteElement) {
+			href, _ := e.Attr("href")
+			e.SetAttr("href", "https://example.com"+href)
+			e.SetAttr("rel", "nofollow")
+		}, `<a href="/x">1</a><a href="#">2</a>`, `<a href="https://example.com/x" rel="nofollow">1</a><a href="#">2</a>`},
+		{"img", func(e *RewriteElement) { e.RemoveAttr("SRC"); e.Append("ignored") }, `<img src="a.png" alt="a"><p>`, `<img alt="a"><p>`},
+		{".ad", func(e *RewriteElement) { e.Remove() }, `<div>a<div class="ad"><p class="ad">b</p></div>c</div>`, `<div>ac</div>`},
+		{"font", func(e *RewriteElement) { e.RemoveAndKeepContent() }, `<p><font color="red">a<b>b</b></font></p>`, `<p>a<b>b</b></p>`},
+		{"p", func(e *RewriteElement) {
+			e.Before("<hr>")
+			e.Prepend("[")
+			e.Append("]")
+			e.After("<br>")
+		}, `<p>a</p><p>b`, `<hr><p>[a]</p><br><hr><p>[b]<br>`},
+		{"li", func(e *RewriteElement) { e.Append("!") }, `<ul><li>a<li>b</ul>`, `<ul><li>a!<li>b!</ul>`},
+		{"#app", func(e *RewriteElement) { e.SetInnerContent("<main>ssr</main>") }, `<div id="app"><p>loading</p></div><p>x</p>`, `<div id="app"><main>ssr</main></div><p>x</p>`},
+		{"script", func(e *RewriteElement) { e.Remove(); e.After("<!-- removed -->") }, `<script>if (a < b) {}</script>`, `<!-- removed -->`},
+		{"div > span", func(e *RewriteElement) { e.SetAttr("class", "x") }, `<div><span>a</span><p><span>b</span></p></div>`, `<div><span class="x">a</span><p><span>b</span></p></div>`},
+	} {
+		var rw StreamRewriter
+		if err := rw.OnElement(test.sel, func(e *RewriteElement) error {
+			test.handler(e)
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if got := rewriteString(t, &rw, test.input); got != test.exp {
+			t.Errorf("%s: expected\n%s\ngot\n%s", test.sel, test.exp, got)
+		}
+	}
+}
+
+func TestStreamRewriterHandlers(t *testing.T) {
+	var rw StreamRewriter
+	var calls []string
+	if err := rw.OnElement("p, .a", func(e *RewriteElement) error {
+		calls = append(calls, "first "+e.Tag())
+		e.SetAttr("class", "b")
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := rw.OnElement(".a, .b", func(e *RewriteElement) error {
+		calls = append(calls, "second "+e.Tag())
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := rw.OnElement("li + li", nil); err == nil {
+		t.Error("expected an error for a sibling combinator")
+	}
+	// the selectors are matched against the original attributes
+	got := rewriteString(t, &rw, `<p class="a"><span class="a"></span></p>`)
+	if exp := `<p class="b"><span class="b"></span></p>`; got != exp {
+		t.Errorf("expected %s, got %s", exp, got)
+	}
+	if exp := "first p,second p,first span,second span"; strings.Join(calls, ",") != exp {
+		t.Errorf("unexpected calls %v", calls)
+	}
+
+	stop := errors.New("stop")
+	var failing StreamRewriter
+	if err := failing.OnElement("p", func(*RewriteElement) error { return stop }); err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	if err := failing.Rewrite(&out, strings.NewReader(`a<p>b</p>`)); err != stop {
+		t.Errorf("expected the handler error, got %v", err)
+	}
+	if out.String() != "a" {
+		t.Errorf("unexpected partial output %s", out.String())
+	}
+}
@@ -0,0 +1,149 @@
+package cascadia
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+const streamTestHTML = `<!DOCTYPE html>
+<html lang="en"><head><title>T</title></head>
+<body>
+	<div id="main" class="content">
+		<p class="a">one<br>two</p>
+		<p lang="fr">deux <a href="/x">lien</a></p>
+		<ul><li>1<li class="x">2<li>3</ul>
+		<input type="checkbox" checked/>
+	</div>
+	<section><p>three</p></section>
+</body></html>`
+
+func TestStreamMatcher(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(streamTestHTML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sel := range []string{
+		"p",
+		"div p",
+		"div > p",
+		"body > section > p",
+		"#main .a",
+		"li:nth-child(2)",
+		"li:nth-child(odd)",
+		"p:nth-of-type(2)",
+		"li:first-child, li:nth-of-type(3)",
+		"p:not(.a)",
+		"div :is(a, br)",
+		":root",
+		"p:lang(fr)",
+		"a:link",
+		"[checked]:checked",
+		":input",
+		"ul > li.x",
+		"* > br",
+	} {
+		group := mustParseGroup(t, sel)
+		m, err := NewStreamMatcher(group)
+		if err != nil {
+			t.Errorf("%s: %s", sel, err)
+			continue
+		}
+		var got []string
+		err = m.Run(strings.NewReader(streamTestHTML), func(tok html.Token, _ []int) error {
+			tok.Type = html.StartTagToken
+			got = append(got, tok.String())
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var exp []string
+		for _, n := range QueryAll(doc, group) {
+			exp = append(exp, html.Token{Type: html.StartTagToken, Data: n.Data, Attr: n.Attr}.String())
+		}
+		if strings.Join(got, "") != strings.Join(exp, "") {
+			t.Errorf("%s: expected %v, got %v", sel, exp, got)
+		}
+	}
+}
+
+func TestStreamMatcherIndices(t *testing.T) {
+	m, err := NewStreamMatcher(mustParseGroup(t, "p, .a, li"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got [][]int
+	err = m.Run(strings.NewReader(`<p class="a"></p><span class="a"></span>`), func(_ html.Token, matched []int) error {
+		got = append(got, append([]int(nil), matched...))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || len(got[0]) != 2 || got[0][1] != 1 || len(got[1]) != 1 || got[1][0] != 1 {
+		t.Errorf("unexpected indices %v", got)
+	}
+
+	stop := errors.New("stop")
+	count := 0
+	err = m.Run(strings.NewReader(`<p></p><p></p>`), func(html.Token, []int) error {
+		count++
+		return stop
+	})
+	if err != stop || count != 1 {
+		t.Errorf("expected the iteration to stop, got %v after %d calls", err, count)
+	}
+}
+
+func TestCheckStreamable(t *testing.T) {
+	for _, sel := range []string{
+		"a + b",
+		"a ~ b",
+		"div:has(p)",
+		"li:last-child",
+		"p:nth-last-of-type(2)",
+		"p:empty",
+		"p:only-child",
+		"p:contains(a)",
+		":not(a + b)",
+		"a > b:disabled",
+	} {
+		if _, err := NewStreamMatcher(mustParseGroup(t, sel)); err == nil {
+			t.Errorf("%s: expected an error", sel)
+		}
+	}
+}
+
+func TestStreamHandlers(t *testing.T) {
+	var h StreamHandlers
+	var links, items []string
+	if err := h.OnMatch("a[href], link", func(start html.Token, attrs []html.Attribute) {
+		links = append(links, attrs[0].Val)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.OnMatch("ul > li, li.x", func(start html.Token, attrs []html.Attribute) {
+		items = append(items, start.Data)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.OnMatch("li + li", nil); err == nil {
+		t.Error("expected an error for a sibling combinator")
+	}
+	if err := h.OnMatch("li[", nil); err == nil {
+		t.Error("expected an error for an invalid selector")
+	}
+
+	if err := h.Run(strings.NewReader(streamTestHTML)); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(links, ",") != "/x" {
+		t.Errorf("unexpected links %v", links)
+	}
+	if len(items) != 3 {
+		t.Errorf("expected one call for each item, got %v", items)
+	}
+}
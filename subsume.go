@@ -0,0 +1,316 @@
+package cascadia
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// This file implements a static analysis of the set of elements
+// matched by selectors. It only handles a decidable fragment
+// (type, id, class and attribute selectors, compared through
+// the same combinators), and reports Unknown otherwise.
+
+// Answer is the result of an analysis which may be undecidable.
+type Answer uint8
+
+const (
+	Unknown Answer = iota // the analysis can't decide
+	Yes
+	No
+)
+
+func (a Answer) String() string {
+	switch a {
+	case Unknown:
+		return "unknown"
+	case Yes:
+		return "yes"
+	case No:
+		return "no"
+	default:
+		return fmt.Sprintf("Answer(%d)", a)
+	}
+}
+
+// Subsumes returns true if every element matched by b is proven
+// to be matched by a, such as in Subsumes(".a", "div.a.b").
+// It returns false when it is not the case, or when the analysis can't decide
+// (see CheckSubsumption to distinguish these cases).
+func Subsumes(a, b Sel) bool { return CheckSubsumption(a, b) == Yes }
+
+// CheckSubsumption decides whether every element matched by b
+// is matched by a. It returns Unknown for selectors outside
+// of the supported fragment, like :not() or regular expressions.
+func CheckSubsumption(a, b Sel) Answer {
+	chainA, chainB := flattenChain(a), flattenChain(b)
+	if chainA == nil || chainB == nil {
+		return Unknown
+	}
+	keyA, keyB := chainA[len(chainA)-1], chainB[len(chainB)-1]
+	key := subsumesCompound(keyA.compound, keyB.compound)
+	if key == No {
+		// the other compounds only constrain the context of the element,
+		// not the element itself
+		return No
+	}
+	if len(chainA) == 1 {
+		return key
+	}
+	if len(chainA) != len(chainB) {
+		return Unknown
+	}
+	for i := len(chainA) - 2; i >= 0; i-- {
+		// the combinator is stored on the right operand
+		if !combinatorSubsumes(chainA[i+1].combinator, chainB[i+1].combinator) {
+			return Unknown
+		}
+		if subsumesCompound(chainA[i].compound, chainB[i].compound) != Yes {
+			return Unknown
+		}
+	}
+	return key
+}
+
+// chainLink is one compound selector of a chain, with the
+// combinator linking it to the previous one (0 for the first one)
+type chainLink struct {
+	compound   CompoundSelector
+	combinator byte
+}
+
+// flattenChain returns the compounds of sel, from left to right,
+// or nil if sel is not a chain of compound selectors.
+func flattenChain(sel Sel) []chainLink {
+	switch s := sel.(type) {
+	case CombinedSelector:
+		left := flattenChain(s.First)
+		if left == nil {
+			return nil
+		}
+		if s.Second == nil {
+			return left
+		}
+		right := asCompound(s.Second)
+		if right == nil {
+			return nil
+		}
+		return append(left, chainLink{compound: *right, combinator: s.Combinator})
+	default:
+		c := asCompound(sel)
+		if c == nil {
+			return nil
+		}
+		return []chainLink{{compound: *c}}
+	}
+}
+
+// asCompound returns sel as a compound selector, or nil
+// if sel is a combined selector or an unknown type.
+func asCompound(sel Sel) *CompoundSelector {
+	switch s := sel.(type) {
+	case CompoundSelector:
+		return &s
+	case CombinedSelector, nil:
+		return nil
+	default:
+		return &CompoundSelector{Selectors: []Sel{sel}}
+	}
+}
+
+// combinatorSubsumes returns true if every pair of elements
+// related by b is related by a.
+func combinatorSubsumes(a, b byte) bool {
+	return a == b || (a == ' ' && b == '>') || (a == '~' && b == '+')
+}
+
+// subsumesCompound decides whether every element matched by b is matched by a
+func subsumesCompound(a, b CompoundSelector) Answer {
+	if a.Pseudo != b.Pseudo {
+		return No
+	}
+	if unsatisfiable(b.Selectors) {
+		return Yes
+	}
+	out := Yes
+	for _, s := range a.Selectors {
+		if impliedBy(s, b.Selectors) {
+			continue
+		}
+		if refutable(s, b.Selectors) {
+			return No
+		}
+		out = Unknown
+	}
+	return out
+}
+
+// impliedBy returns true if one of the selectors in list implies s
+func impliedBy(s Sel, list []Sel) bool {
+	for _, other := range list {
+		if implies(other, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// implies returns true if every element matched by the simple selector b
+// is matched by the simple selector a
+func implies(b, a Sel) bool {
+	if tagA, ok := a.(TagSelector); ok {
+		tagB, ok := b.(TagSelector)
+		return ok && tagA.Tag == tagB.Tag
+	}
+	attrA, okA := asAttr(a)
+	attrB, okB := asAttr(b)
+	if !okA || !okB {
+		return Equal(a, b)
+	}
+	return attrImplies(attrB, attrA)
+}
+
+// asAttr returns the attribute selector equivalent to
+// an id, class or attribute selector.
+func asAttr(sel Sel) (AttrSelector, bool) {
+	switch s := sel.(type) {
+	case IDSelector:
+		return AttrSelector{Key: "id", Operation: "=", Val: s.ID}, true
+	case ClassSelector:
+		return AttrSelector{Key: "class", Operation: "~=", Val: s.Class}, true
+	case AttrSelector:
+		return s, true
+	}
+	return AttrSelector{}, false
+}
+
+// attrImplies returns true if every element matched by b is matched by a
+func attrImplies(b, a AttrSelector) bool {
+	if a.Key != b.Key {
+		return false
+	}
+	// values (of b) which can't be blank, as required by ^=, $= and *=
+	nonBlank := strings.TrimSpace(b.Val) != ""
+	switch a.Operation {
+	case "":
+		return b.Operation != "!="
+	case "=":
+		return b.Operation == "=" && b.Val == a.Val
+	case "!=":
+		return (b.Operation == "!=" && b.Val == a.Val) || (b.Operation == "=" && b.Val != a.Val)
+	case "~=":
+		return (b.Operation == "~=" && b.Val == a.Val) || (b.Operation == "=" && matchInclude(a.Val, b.Val))
+	case "|=":
+		return (b.Operation == "|=" || b.Operation == "=") && b.Val == a.Val ||
+			b.Operation == "=" && strings.HasPrefix(b.Val, a.Val+"-")
+	case "^=":
+		return (b.Operation == "=" || b.Operation == "^=" || b.Operation == "|=") && nonBlank && strings.HasPrefix(b.Val, a.Val)
+	case "$=":
+		return (b.Operation == "=" || b.Operation == "$=") && nonBlank && strings.HasSuffix(b.Val, a.Val)
+	case "*=":
+		switch b.Operation {
+		case "=", "^=", "$=", "*=", "|=":
+			return nonBlank && strings.Contains(b.Val, a.Val)
+		case "~=":
+			return b.Val != "" && strings.Contains(b.Val, a.Val)
+		}
+	case "#=":
+		return b.Operation == "#=" && regexpString(a.Regexp) == regexpString(b.Regexp)
+	}
+	return false
+}
+
+// refutable returns true if we can prove that some element matched
+// by the compound list is not matched by the simple selector s,
+// assuming s is not implied by any selector in list.
+func refutable(s Sel, list []Sel) bool {
+	for _, other := range list {
+		switch other.(type) {
+		case TagSelector, IDSelector, ClassSelector, AttrSelector:
+		default:
+			return false
+		}
+	}
+	if _, ok := s.(TagSelector); ok {
+		// an element with the tag of list (if any) matches list but not s
+		return true
+	}
+	attr, ok := asAttr(s)
+	if !ok || attr.Operation == "#=" {
+		return false
+	}
+	// a witness may be built if list only constrains attr.Key
+	// with exact values: use one of them or, without constraints,
+	// omit the attribute (or set it to the value excluded by s for "!=")
+	for _, other := range list {
+		o, ok := asAttr(other)
+		if ok && o.Key == attr.Key && o.Operation != "=" {
+			return false
+		}
+	}
+	return true
+}
+
+// unsatisfiable returns true if the compound list can't match any element,
+// because its type selectors or exact attribute values are contradictory
+func unsatisfiable(list []Sel) bool {
+	for i, s := range list {
+		if _, ok := s.(NeverMatchSelector); ok {
+			return true
+		}
+		for _, other := range list[:i] {
+			if contradicts(s, other) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// contradicts returns true if no element is matched by both the
+// simple selectors a and b
+func contradicts(a, b Sel) bool {
+	if excludes(a, b) || excludes(b, a) {
+		return true
+	}
+	if tagA, ok := a.(TagSelector); ok {
+		tagB, ok := b.(TagSelector)
+		return ok && tagA.Tag != tagB.Tag
+	}
+	attrA, okA := asAttr(a)
+	attrB, okB := asAttr(b)
+	if !okA || !okB || attrA.Key != attrB.Key {
+		return false
+	}
+	switch {
+	case attrA.Operation == "=":
+		return !attrB.Match(elementWithAttr(attrA.Key, attrA.Val))
+	case attrB.Operation == "=":
+		return !attrA.Match(elementWithAttr(attrB.Key, attrB.Val))
+	case attrA.Operation == "^=" && attrB.Operation == "^=":
+		return !strings.HasPrefix(attrA.Val, attrB.Val) && !strings.HasPrefix(attrB.Val, attrA.Val)
+	case attrA.Operation == "$=" && attrB.Operation == "$=":
+		return !strings.HasSuffix(attrA.Val, attrB.Val) && !strings.HasSuffix(attrB.Val, attrA.Val)
+	}
+	return false
+}
+
+// excludes returns true if a is :not(x) and b implies x
+func excludes(a, b Sel) bool {
+	not, ok := a.(RelativePseudoClassSelector)
+	if !ok || not.Name != "not" {
+		return false
+	}
+	for _, arg := range not.Args {
+		if implies(b, arg) {
+			return true
+		}
+	}
+	return false
+}
+
+// elementWithAttr returns an element with only one attribute
+func elementWithAttr(key, val string) *html.Node {
+	return &html.Node{Type: html.ElementNode, Attr: []html.Attribute{{Key: key, Val: val}}}
+}
@@ -0,0 +1,55 @@
+package cascadia
+
+import "testing"
+
+func TestSubsumes(t *testing.T) {
+	for _, test := range []struct {
+		a, b string
+		want Answer
+	}{
+		{".a", "div.a.b", Yes},
+		{"div.a.b", ".a", No},
+		{"*", "div", Yes},
+		{"div", "span", No},
+		{"div", "div", Yes},
+		{"#x", `[id="x"]`, Yes},
+		{`[id="x"]`, "#x", Yes},
+		{".a", `[class~="a"]`, Yes},
+		{"[href]", "[href^=http]", Yes},
+		{"[href^=http]", "[href^=https]", Yes},
+		{"[href^=https]", "[href^=http]", Unknown},
+		{"[href^=http]", `[href="https://x"]`, Yes},
+		{"[lang|=en]", "[lang=en-US]", Yes},
+		{"[a*=b]", "[a$=abc]", Yes},
+		{"[a!=x]", "[a=y]", Yes},
+		{"[a!=x]", "p", No},
+		{"[a]", "p", No},
+		{"p", "#x#y", Yes}, // b is empty
+		{"div p", "div > p.a", Yes},
+		{"div > p", "div p", Unknown},
+		{"div p", "p", Unknown},
+		{"p", "div > p.a", Yes},
+		{"span", "div > p.a", No},
+		{"section div p", "section div > p", Yes},
+		{"a ~ b", "a + b", Yes},
+		{":not(a)", ":not(a)", Yes},
+		{":not(a)", "b", Unknown},
+		{"a::before", "a", No},
+		{"a:first-child", "a:nth-child(1)", Yes},
+	} {
+		a, err := ParseWithPseudoElement(test.a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ParseWithPseudoElement(test.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := CheckSubsumption(a, b); got != test.want {
+			t.Errorf("%s subsumes %s: expected %s, got %s", test.a, test.b, test.want, got)
+		}
+		if Subsumes(a, b) != (test.want == Yes) {
+			t.Errorf("%s subsumes %s: inconsistent Subsumes", test.a, test.b)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package cascadia
+
+import (
+	"sort"
+	"strings"
+)
+
+// This file implements helpers for editors and language servers,
+// built on the tables used by the parser.
+
+// PseudoClasses returns the sorted names of the supported pseudo-classes,
+// without the leading ':'.
+func PseudoClasses() []string { return sortedKeys(pseudoClasses) }
+
+// PseudoElements returns the sorted names of the supported pseudo-elements,
+// without the leading '::'.
+func PseudoElements() []string { return sortedKeys(pseudoElements) }
+
+// AttributeOperators returns the supported attribute operators, like "^=".
+func AttributeOperators() []string {
+	return append([]string(nil), attributeOperators...)
+}
+
+// IsFunctionalPseudoClass returns true if the pseudo-class name
+// is supported and expects arguments, like "nth-child".
+func IsFunctionalPseudoClass(name string) bool {
+	return pseudoClasses[toLowerASCII(name)]
+}
+
+func sortedKeys(m map[string]bool) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// SuggestionKind is the category of a Suggestion.
+type SuggestionKind uint8
+
+const (
+	SuggestPseudoClass SuggestionKind = iota
+	SuggestPseudoElement
+	SuggestAttributeOperator
+)
+
+func (k SuggestionKind) String() string {
+	switch k {
+	case SuggestPseudoClass:
+		return "pseudo-class"
+	case SuggestPseudoElement:
+		return "pseudo-element"
+	case SuggestAttributeOperator:
+		return "attribute operator"
+	default:
+		return "unknown"
+	}
+}
+
+// Suggestion is a possible completion returned by SuggestAt.
+type Suggestion struct {
+	Kind SuggestionKind
+	// Text is the completed name or operator, like "nth-child" or "^=".
+	Text string
+	// Span is the range of the input replaced by Text;
+	// it is empty when the completion is inserted at the cursor.
+	Span Span
+	// Functional is true for pseudo-classes expecting arguments.
+	Functional bool
+}
+
+// SuggestAt returns the plausible completions for the selector sel
+// when the cursor is located at the byte offset. Only the text before
+// the cursor is considered. It returns nil if no completion applies.
+func SuggestAt(sel string, offset int) []Suggestion {
+	if offset < 0 || offset > len(sel) {
+		return nil
+	}
+	tokens := significantTokens(sel[:offset])
+	n := len(tokens)
+	var last Token
+	if n > 0 {
+		last = tokens[n-1]
+	}
+
+	var out []Suggestion
+	empty := Span{offset, offset}
+	switch {
+	case last.Kind == TokenIdent && endsWithColons(tokens[:n-1], 2):
+		out = suggestNames(out, SuggestPseudoElement, PseudoElements(), last.Value, last.Span)
+	case last.Kind == TokenIdent && endsWithColons(tokens[:n-1], 1):
+		out = suggestNames(out, SuggestPseudoClass, PseudoClasses(), last.Value, last.Span)
+		out = suggestNames(out, SuggestPseudoElement, PseudoElements(), last.Value, last.Span)
+	case endsWithColons(tokens, 2):
+		out = suggestNames(out, SuggestPseudoElement, PseudoElements(), "", empty)
+	case endsWithColons(tokens, 1):
+		out = suggestNames(out, SuggestPseudoClass, PseudoClasses(), "", empty)
+		out = suggestNames(out, SuggestPseudoElement, PseudoElements(), "", empty)
+	case last.Kind == TokenDelim && afterAttributeKey(tokens[:n-1]):
+		out = suggestOperators(out, last.Value, last.Span)
+	case afterAttributeKey(tokens):
+		out = suggestOperators(out, "", empty)
+	}
+	return out
+}
+
+func suggestOperators(out []Suggestion, prefix string, span Span) []Suggestion {
+	for _, op := range attributeOperators {
+		if strings.HasPrefix(op, prefix) {
+			out = append(out, Suggestion{Kind: SuggestAttributeOperator, Text: op, Span: span})
+		}
+	}
+	return out
+}
+
+func suggestNames(out []Suggestion, kind SuggestionKind, names []string, prefix string, span Span) []Suggestion {
+	prefix = toLowerASCII(prefix)
+	for _, name := range names {
+		if strings.HasPrefix(name, prefix) {
+			out = append(out, Suggestion{
+				Kind:       kind,
+				Text:       name,
+				Span:       span,
+				Functional: kind == SuggestPseudoClass && pseudoClasses[name],
+			})
+		}
+	}
+	return out
+}
+
+// significantTokens returns all the tokens of s, without comments.
+func significantTokens(s string) []Token {
+	var out []Token
+	t := NewTokenizer(s)
+	for tok := t.NextToken(); tok.Kind != TokenEOF; tok = t.NextToken() {
+		if tok.Kind != TokenComment {
+			out = append(out, tok)
+		}
+	}
+	return out
+}
+
+// endsWithColons returns true if tokens end with exactly n adjacent colons.
+func endsWithColons(tokens []Token, n int) bool {
+	if len(tokens) < n {
+		return false
+	}
+	for _, tok := range tokens[len(tokens)-n:] {
+		if tok.Kind != TokenColon {
+			return false
+		}
+	}
+	return len(tokens) == n || tokens[len(tokens)-n-1].Kind != TokenColon
+}
+
+// afterAttributeKey returns true if tokens end with '[' key,
+// possibly followed by whitespace.
+func afterAttributeKey(tokens []Token) bool {
+	i := len(tokens) - 1
+	if i >= 0 && tokens[i].Kind == TokenWhitespace {
+		i--
+	}
+	if i < 0 || tokens[i].Kind != TokenIdent {
+		return false
+	}
+	i--
+	if i >= 0 && tokens[i].Kind == TokenWhitespace {
+		i--
+	}
+	return i >= 0 && tokens[i].Kind == TokenLeftBracket
+}
@@ -0,0 +1,68 @@
+package cascadia
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPseudoClassesInSync(t *testing.T) {
+	for _, name := range PseudoClasses() {
+		_, err := Parse(":" + name)
+		if IsFunctionalPseudoClass(name) {
+			if !errors.Is(err, ErrUnexpectedToken) {
+				t.Errorf("%s: expected a missing '(' error, got %v", name, err)
+			}
+		} else if err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+	}
+	for _, name := range PseudoElements() {
+		if _, err := ParseWithPseudoElement("::" + name); err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+	}
+	for _, op := range AttributeOperators() {
+		if _, err := Parse("[a" + op + "b]"); err != nil {
+			t.Errorf("%s: %s", op, err)
+		}
+	}
+}
+
+func suggestionTexts(list []Suggestion) []string {
+	var out []string
+	for _, s := range list {
+		out = append(out, s.Text)
+	}
+	return out
+}
+
+func TestSuggestAt(t *testing.T) {
+	for _, test := range []struct {
+		sel    string
+		offset int
+		want   []string
+	}{
+		{"a:nth-l", 7, []string{"nth-last-child", "nth-last-of-type"}},
+		{"a::be", 5, []string{"before"}},
+		{"a:fir", 5, []string{"first-child", "first-of-type", "first-letter", "first-line"}},
+		{"a:fir", 3, []string{"first-child", "first-of-type", "focus", "future", "first-letter", "first-line"}},
+		{"[href ", 6, AttributeOperators()},
+		{"[href^", 6, []string{"^="}},
+		{"[href", 5, AttributeOperators()},
+		{"[", 1, nil},
+		{"div.cl", 6, nil},
+		{"a", 5, nil},
+	} {
+		got := suggestionTexts(SuggestAt(test.sel, test.offset))
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s@%d: expected %v, got %v", test.sel, test.offset, test.want, got)
+		}
+	}
+
+	got := SuggestAt("div:nth-ch", 10)
+	exp := []Suggestion{{Kind: SuggestPseudoClass, Text: "nth-child", Span: Span{4, 10}, Functional: true}}
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected %v, got %v", exp, got)
+	}
+}
@@ -0,0 +1,224 @@
+package cascadia
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TokenKind is the type of a token returned by a Tokenizer.
+type TokenKind uint8
+
+const (
+	TokenEOF          TokenKind = iota // end of input
+	TokenBad                           // invalid input; Value is the error message
+	TokenWhitespace                    // whitespace characters
+	TokenComment                       // comment /* ... */; Value is its content
+	TokenIdent                         // identifier; Value is unescaped
+	TokenFunction                      // identifier followed by '(' ; Value is the unescaped name
+	TokenHash                          // #name; Value is the unescaped name, without '#'
+	TokenString                        // quoted string; Value is unquoted and unescaped
+	TokenNumber                        // integer, with an optional sign
+	TokenDimension                     // integer followed by an identifier, like 2n
+	TokenComma                         // ,
+	TokenColon                         // :
+	TokenLeftBracket                   // [
+	TokenRightBracket                  // ]
+	TokenLeftParen                     // (
+	TokenRightParen                    // )
+	TokenDelim                         // any other character, like '.', '>' or '='
+)
+
+var tokenKindNames = [...]string{
+	TokenEOF:          "EOF",
+	TokenBad:          "bad",
+	TokenWhitespace:   "whitespace",
+	TokenComment:      "comment",
+	TokenIdent:        "ident",
+	TokenFunction:     "function",
+	TokenHash:         "hash",
+	TokenString:       "string",
+	TokenNumber:       "number",
+	TokenDimension:    "dimension",
+	TokenComma:        "comma",
+	TokenColon:        "colon",
+	TokenLeftBracket:  "left-bracket",
+	TokenRightBracket: "right-bracket",
+	TokenLeftParen:    "left-paren",
+	TokenRightParen:   "right-paren",
+	TokenDelim:        "delim",
+}
+
+func (k TokenKind) String() string {
+	if int(k) < len(tokenKindNames) {
+		return tokenKindNames[k]
+	}
+	return fmt.Sprintf("TokenKind(%d)", k)
+}
+
+// Token is a lexical unit of a selector.
+type Token struct {
+	Kind TokenKind
+	// Value is the interpreted content of the token (see TokenKind)
+	Value string
+	// Span is the position of the token in the input
+	Span Span
+}
+
+// Tokenizer splits a selector into tokens, using
+// the same lexical rules as the parser. It is intended for
+// syntax highlighters and editing tools, and accepts any input:
+// invalid characters are reported as TokenBad, and tokenizing
+// then resumes at the next character.
+type Tokenizer struct {
+	p     parser
+	start int // start of the current token
+}
+
+// NewTokenizer returns a tokenizer reading the selector s.
+func NewTokenizer(s string) *Tokenizer {
+	return &Tokenizer{p: parser{s: s, tokenizing: true}}
+}
+
+// NextToken returns the next token, or a token of kind TokenEOF
+// if the input is fully consumed.
+func (t *Tokenizer) NextToken() Token {
+	p := &t.p
+	t.start = p.i
+	if p.i >= len(p.s) {
+		return Token{Kind: TokenEOF, Span: Span{t.start, t.start}}
+	}
+	kind, value := t.next()
+	return Token{Kind: kind, Value: value, Span: Span{t.start, p.i}}
+}
+
+// next consumes a token
+func (t *Tokenizer) next() (TokenKind, string) {
+	p := &t.p
+	c := p.s[p.i]
+	switch c {
+	case ' ', '\t', '\r', '\n', '\f':
+		for p.i < len(p.s) && strings.IndexByte(" \t\r\n\f", p.s[p.i]) != -1 {
+			p.i++
+		}
+		return TokenWhitespace, ""
+	case '"', '\'':
+		val, err := p.parseString()
+		if err != nil {
+			return t.bad(err)
+		}
+		return TokenString, val
+	case '#':
+		if p.i+1 < len(p.s) && (nameChar(p.s[p.i+1]) || p.s[p.i+1] == '\\') {
+			p.i++
+			val, err := p.parseName()
+			if err != nil {
+				return t.bad(err)
+			}
+			return TokenHash, val
+		}
+	case ',':
+		p.i++
+		return TokenComma, ","
+	case ':':
+		p.i++
+		return TokenColon, ":"
+	case '[':
+		p.i++
+		return TokenLeftBracket, "["
+	case ']':
+		p.i++
+		return TokenRightBracket, "]"
+	case '(':
+		p.i++
+		return TokenLeftParen, "("
+	case ')':
+		p.i++
+		return TokenRightParen, ")"
+	case '/':
+		if strings.HasPrefix(p.s[p.i:], "/*") {
+			end := strings.Index(p.s[p.i+2:], "*/")
+			if end == -1 {
+				p.i = len(p.s)
+				return TokenBad, "unterminated comment"
+			}
+			content := p.s[p.i+2 : p.i+2+end]
+			p.i += end + len("/**/")
+			return TokenComment, content
+		}
+	}
+
+	if t.startsNumber() {
+		start := p.i
+		if p.s[p.i] == '+' || p.s[p.i] == '-' {
+			p.i++
+		}
+		for p.i < len(p.s) && '0' <= p.s[p.i] && p.s[p.i] <= '9' {
+			p.i++
+		}
+		if t.startsIdentifier() {
+			if _, err := p.parseIdentifier(); err != nil {
+				return t.bad(err)
+			}
+			return TokenDimension, p.s[start:p.i]
+		}
+		return TokenNumber, p.s[start:p.i]
+	}
+
+	if t.startsIdentifier() {
+		name, err := p.parseIdentifier()
+		if err != nil {
+			return t.bad(err)
+		}
+		if p.i < len(p.s) && p.s[p.i] == '(' {
+			p.i++
+			return TokenFunction, name
+		}
+		return TokenIdent, name
+	}
+
+	if c == '\\' { // invalid escape
+		p.i++
+		return TokenBad, "invalid escape sequence"
+	}
+	p.i++
+	return TokenDelim, string(c)
+}
+
+// bad skips the first character of the current token,
+// after a failed attempt to read it
+func (t *Tokenizer) bad(err error) (TokenKind, string) {
+	t.p.i = t.start + 1
+	if perr, ok := err.(*ParseError); ok {
+		return TokenBad, perr.Message
+	}
+	return TokenBad, err.Error()
+}
+
+// startsNumber returns true if the input at the current position
+// is a digit, possibly preceded by a sign
+func (t *Tokenizer) startsNumber() bool {
+	s, i := t.p.s, t.p.i
+	if s[i] == '+' || s[i] == '-' {
+		i++
+	}
+	return i < len(s) && '0' <= s[i] && s[i] <= '9'
+}
+
+// startsIdentifier returns true if the input at the current position
+// starts an identifier
+func (t *Tokenizer) startsIdentifier() bool {
+	s, i := t.p.s, t.p.i
+	if i < len(s) && s[i] == '-' {
+		i++
+		if i < len(s) && s[i] == '-' {
+			return true
+		}
+	}
+	if i >= len(s) {
+		return false
+	}
+	if s[i] == '\\' {
+		return i+1 < len(s) && s[i+1] != '\n' && s[i+1] != '\r' && s[i+1] != '\f'
+	}
+	return nameStart(s[i])
+}
@@ -0,0 +1,68 @@
+package cascadia
+
+import (
+	"reflect"
+	"testing"
+)
+
+func tokenize(s string) (out []Token) {
+	tk := NewTokenizer(s)
+	for {
+		tok := tk.NextToken()
+		if tok.Kind == TokenEOF {
+			return out
+		}
+		out = append(out, tok)
+	}
+}
+
+func TestTokenizer(t *testing.T) {
+	input := `div#a\.b > .c[href^='x'], p:nth-child(2n+1) /* c */:not(*)`
+	var kinds []TokenKind
+	var values []string
+	end := 0
+	for _, tok := range tokenize(input) {
+		if tok.Span.Start != end {
+			t.Fatalf("non contiguous token %v", tok)
+		}
+		end = tok.Span.End
+		kinds = append(kinds, tok.Kind)
+		values = append(values, tok.Value)
+	}
+	if end != len(input) {
+		t.Fatalf("input not fully consumed")
+	}
+	expKinds := []TokenKind{
+		TokenIdent, TokenHash, TokenWhitespace, TokenDelim, TokenWhitespace, TokenDelim, TokenIdent,
+		TokenLeftBracket, TokenIdent, TokenDelim, TokenDelim, TokenString, TokenRightBracket, TokenComma, TokenWhitespace,
+		TokenIdent, TokenColon, TokenFunction, TokenDimension, TokenNumber, TokenRightParen, TokenWhitespace, TokenComment,
+		TokenColon, TokenFunction, TokenDelim, TokenRightParen,
+	}
+	if !reflect.DeepEqual(kinds, expKinds) {
+		t.Fatalf("expected\n%v\ngot\n%v", expKinds, kinds)
+	}
+	expValues := []string{
+		"div", "a.b", "", ">", "", ".", "c",
+		"[", "href", "^", "=", "x", "]", ",", "",
+		"p", ":", "nth-child", "2n", "+1", ")", "", " c ",
+		":", "not", "*", ")",
+	}
+	if !reflect.DeepEqual(values, expValues) {
+		t.Fatalf("expected\n%q\ngot\n%q", expValues, values)
+	}
+}
+
+func TestTokenizerInvalid(t *testing.T) {
+	toks := tokenize(`a "unterminated`)
+	if toks[len(toks)-1].Kind != TokenIdent || toks[2].Kind != TokenBad {
+		t.Errorf("unexpected tokens %v", toks)
+	}
+	toks = tokenize(`/* x`)
+	if len(toks) != 1 || toks[0].Kind != TokenBad {
+		t.Errorf("unexpected tokens %v", toks)
+	}
+	toks = tokenize("--x -y -3")
+	if toks[0].Kind != TokenIdent || toks[2].Kind != TokenIdent || toks[4].Kind != TokenNumber {
+		t.Errorf("unexpected tokens %v", toks)
+	}
+}
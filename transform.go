@@ -0,0 +1,180 @@
+package cascadia
+
+// Transform returns a new selector, built by applying fn to every component
+// of sel, in depth-first (post) order: when fn is called on a selector,
+// its components have already been transformed.
+//
+// If fn returns nil, the component is removed from its parent :
+// a CompoundSelector or a relative pseudo-class simply drops it.
+// A CombinedSelector whose first operand is removed is replaced by the
+// second one, which selects the same subject, whereas a CombinedSelector
+// whose second operand is removed is removed too, since its subject is gone.
+// A relative pseudo-class whose arguments are all removed is removed too:
+// for :not(), it is dropped from its compound selector, whereas for :is(),
+// :where(), :has() and :current(), which then match nothing, the whole
+// compound selector is removed.
+// Transform returns nil if the root selector itself is removed.
+//
+// sel is never modified.
+func Transform(sel Sel, fn func(Sel) Sel) Sel {
+	out, _ := transform(sel, fn)
+	return out
+}
+
+// transform implements Transform, and also returns true if
+// the compound selector containing sel must be removed
+func transform(sel Sel, fn func(Sel) Sel) (Sel, bool) {
+	switch s := sel.(type) {
+	case CompoundSelector:
+		inner := make([]Sel, 0, len(s.Selectors))
+		for _, c := range s.Selectors {
+			c, dropParent := transform(c, fn)
+			if dropParent {
+				return nil, false
+			}
+			if c != nil {
+				inner = append(inner, c)
+			}
+		}
+		s.Selectors = inner
+		sel = s
+	case CombinedSelector:
+		first := Transform(s.First, fn)
+		var second Sel
+		if s.Second != nil {
+			second = Transform(s.Second, fn)
+		}
+		switch {
+		case s.Second != nil && second == nil:
+			// as for an empty :is(), the selector can't match anymore
+			return nil, true
+		case first == nil:
+			if second == nil {
+				return nil, false
+			}
+			sel = second
+		case second == nil:
+			sel = first
+		default:
+			s.First, s.Second = first, second
+			sel = s
+		}
+	case RelativePseudoClassSelector:
+		args := TransformGroup(s.Args, fn)
+		if len(args) == 0 && len(s.Args) != 0 {
+			return nil, s.Name != "not"
+		}
+		s.Args = args
+		sel = s
+	case TimePseudoClassSelector:
+		if s.Args != nil {
+			args := TransformGroup(s.Args, fn)
+			if len(args) == 0 && len(s.Args) != 0 {
+				// as for :is(), :current() can't match anymore
+				return nil, true
+			}
+			s.Args = args
+			sel = s
+		}
+	}
+	return fn(sel), false
+}
+
+// TransformGroup applies Transform to every selector of group,
+// returning a new group (without the removed selectors).
+func TransformGroup(group SelectorGroup, fn func(Sel) Sel) SelectorGroup {
+	out := make(SelectorGroup, 0, len(group))
+	for _, s := range group {
+		if s = Transform(s, fn); s != nil {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// Clone returns a deep copy of sel, which shares no slice with sel, so that
+// the copy may be modified in place while sel is used, for instance by another
+// goroutine. The values which are not modified by this package, like the
+// compiled regular expressions, are shared; the selectors of other packages
+// are returned unchanged.
+func Clone(sel Sel) Sel {
+	switch s := sel.(type) {
+	case CompoundSelector:
+		s.Selectors = SelectorGroup(s.Selectors).Clone()
+		return s
+	case CombinedSelector:
+		s.First = Clone(s.First)
+		if s.Second != nil {
+			s.Second = Clone(s.Second)
+		}
+		return s
+	case RelativePseudoClassSelector:
+		s.Args = s.Args.Clone()
+		return s
+	case TimePseudoClassSelector:
+		s.Args = s.Args.Clone()
+		return s
+	}
+	return sel
+}
+
+// Clone returns a deep copy of s, made of the Clone of its selectors.
+func (s SelectorGroup) Clone() SelectorGroup {
+	if s == nil {
+		return nil
+	}
+	out := make(SelectorGroup, len(s))
+	for i, sel := range s {
+		out[i] = Clone(sel)
+	}
+	return out
+}
+
+// RenameClass returns a copy of sel where every class selector
+// `.from` is replaced by `.to`.
+func RenameClass(sel Sel, from, to string) Sel {
+	return Transform(sel, func(s Sel) Sel {
+		if c, ok := s.(ClassSelector); ok && c.Class == from {
+			return ClassSelector{Class: to}
+		}
+		return s
+	})
+}
+
+// StripPseudoElements returns a copy of sel without pseudo-elements.
+func StripPseudoElements(sel Sel) Sel {
+	return Transform(sel, func(s Sel) Sel {
+		if c, ok := s.(CompoundSelector); ok && c.Pseudo != "" {
+			c.Pseudo = ""
+			if len(c.Selectors) == 1 {
+				return c.Selectors[0]
+			}
+			return c
+		}
+		return s
+	})
+}
+
+// AddClass returns a copy of sel where the class selector `.class` is
+// added to every compound selector along the combinators chain
+// (but not inside pseudo-classes arguments), the way CSS modules
+// scope a stylesheet.
+// For instance, AddClass("div > p", "scope") is "div.scope > p.scope".
+func AddClass(sel Sel, class string) Sel {
+	scope := ClassSelector{Class: class}
+	switch s := sel.(type) {
+	case CombinedSelector:
+		s.First = AddClass(s.First, class)
+		if s.Second != nil {
+			s.Second = AddClass(s.Second, class)
+		}
+		return s
+	case CompoundSelector:
+		inner := make([]Sel, len(s.Selectors), len(s.Selectors)+1)
+		copy(inner, s.Selectors)
+		s.Selectors = append(inner, scope)
+		return s
+	default: // a single simple selector
+		return CompoundSelector{Selectors: []Sel{sel, scope}}
+	}
+}
@@ -0,0 +1,110 @@
+package cascadia
+
+import "testing"
+
+func removeClassA(s Sel) Sel {
+	return Transform(s, func(c Sel) Sel {
+		if c.String() == ".a" {
+			return nil
+		}
+		return c
+	})
+}
+
+func TestTransform(t *testing.T) {
+	for _, test := range []struct {
+		input, expected string
+		transform       func(Sel) Sel
+	}{
+		{"div.a > .b:not(.a)", "div.z > .b:not(.z)", func(s Sel) Sel { return RenameClass(s, "a", "z") }},
+		{"div.a::before", "div.a", StripPseudoElements},
+		{"div::before", "div", StripPseudoElements},
+		{"p ~ a::after", "p ~ a", StripPseudoElements},
+		{"div > p.b:not(.c) a", "div.s > p.b:not(.c).s a.s", func(s Sel) Sel { return AddClass(s, "s") }},
+		{"*", ".s", func(s Sel) Sel { return AddClass(s, "s") }},
+		{"div.a > p", "p", func(s Sel) Sel {
+			return Transform(s, func(c Sel) Sel {
+				if c.String() == "div.a" {
+					return nil
+				}
+				return c
+			})
+		}},
+		{"div.a.b:not(.a, .c)", "div.b:not(.c)", func(s Sel) Sel {
+			return Transform(s, func(c Sel) Sel {
+				if c.String() == ".a" {
+					return nil
+				}
+				return c
+			})
+		}},
+		{"div:not(.a)", "div", removeClassA},
+		{"p:not(.a, .a)", "p", removeClassA},
+		{"p:where(.a) > div", "div", removeClassA},
+		{"div:has(.a) p", "p", removeClassA},
+		{"div:not(:is(.a)) span", "div span", removeClassA},
+		{":current(.a, p)", ":current(p)", removeClassA},
+		{"li:current(.a)", "li:current(.b)", func(s Sel) Sel { return RenameClass(s, "a", "b") }},
+	} {
+		sel, err := ParseWithPseudoElement(test.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		exp, err := Parse(test.expected)
+		if err != nil {
+			t.Fatal(err)
+		}
+		original := sel.String()
+		if got := test.transform(sel).String(); got != exp.String() {
+			t.Errorf("%s: expected %s, got %s", test.input, exp, got)
+		}
+		if sel.String() != original {
+			t.Errorf("%s: input selector modified to %s", test.input, sel)
+		}
+	}
+
+	// the subject of a combined selector can't be removed
+	for _, input := range []string{"p div:is(.a)", "div > p:is(.a)", "div .a", "div > span + .a", "li:is(div .a)", "li:current(.a)"} {
+		if got := removeClassA(MustParse(input)); got != nil {
+			t.Errorf("%s: expected a removed selector, got %s", input, got)
+		}
+	}
+	if got := removeClassA(MustParse("div .a > p")).String(); got != "p" {
+		t.Errorf("unexpected %s", got)
+	}
+}
+
+func TestClone(t *testing.T) {
+	group, err := ParseGroupWithPseudoElements("div > p.a:not(.b, [c]), :current(li), a:is(.x)::before, *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clone := group.Clone()
+	if !EqualGroup(group, clone) || clone.String() != group.String() {
+		t.Fatalf("unexpected clone %s", clone)
+	}
+
+	// modify the clone in place
+	combined := clone[0].(CombinedSelector)
+	compound := combined.Second.(CompoundSelector)
+	compound.Selectors[0] = TagSelector{Tag: "span"}
+	not := compound.Selectors[2].(RelativePseudoClassSelector)
+	not.Args[0] = ClassSelector{Class: "z"}
+	clone[1].(TimePseudoClassSelector).Args[0] = TagSelector{Tag: "ol"}
+	clone[2].(CompoundSelector).Selectors[1].(RelativePseudoClassSelector).Args[0] = IDSelector{ID: "y"}
+	clone[3] = nil
+
+	if exp := "div > p.a:not(.b, [c]), :current(li), a:is(.x)::before, *"; group.String() != exp {
+		t.Errorf("the original was modified: %s", group)
+	}
+	if got := clone[:3].String(); got != "div > span.a:not(.z, [c]), :current(ol), a:is(#y)::before" {
+		t.Errorf("unexpected modified clone %s", got)
+	}
+
+	if SelectorGroup(nil).Clone() != nil {
+		t.Error("expected a nil clone")
+	}
+	if sel := MustParse(":nth-child(2)"); Clone(sel) != sel {
+		t.Error("expected an identical simple selector")
+	}
+}
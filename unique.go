@@ -0,0 +1,184 @@
+package cascadia
+
+import (
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// SelectorFor returns a short selector matching only n in its document,
+// like the "Copy selector" feature of browser developer tools.
+// Unique ids are preferred, then paths of child combinators using
+// :nth-child() where the tag is not enough to distinguish siblings,
+// such as "#main > ul > li:nth-child(2)".
+// It returns an empty string if n is not an element.
+func SelectorFor(n *html.Node) string {
+	if n == nil || n.Type != html.ElementNode {
+		return ""
+	}
+	root := n
+	for root.Parent != nil {
+		root = root.Parent
+	}
+
+	// segments, from n to its ancestors
+	var segments []string
+	for e := n; e != nil && e.Type == html.ElementNode; e = e.Parent {
+		if id := nodeID(e); id != "" && isUnique(root, IDSelector{ID: id}) {
+			segments = append(segments, "#"+EscapeIdent(id))
+			break
+		}
+		segments = append(segments, pathSegment(e))
+	}
+
+	// the shortest suffix of the path which is unique
+	for k := 1; k <= len(segments); k++ {
+		candidate := strings.Join(reverse(segments[:k]), " > ")
+		if sel, err := Parse(candidate); err == nil && isUnique(root, sel) {
+			return candidate
+		}
+	}
+	// not reached for a valid tree, since the full path is unique
+	return strings.Join(reverse(segments), " > ")
+}
+
+// pathSegment returns the tag of e, with its position
+// if a sibling has the same tag. As in typeSegment, the tags not
+// matched by a type selector (like a foreignObject) are replaced by *.
+func pathSegment(e *html.Node) string {
+	tag := EscapeIdent(e.Data)
+	if sel, err := Parse(tag); err != nil || !sel.Match(e) {
+		return "*:nth-child(" + strconv.Itoa(childIndex(e)) + ")"
+	}
+	index, ambiguous := 0, false
+	for c := e; c != nil; c = c.PrevSibling {
+		if c.Type == html.ElementNode {
+			index++
+			if c != e && c.Data == e.Data {
+				ambiguous = true
+			}
+		}
+	}
+	for c := e.NextSibling; c != nil && !ambiguous; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == e.Data {
+			ambiguous = true
+		}
+	}
+	if !ambiguous {
+		return tag
+	}
+	return tag + ":nth-child(" + strconv.Itoa(index) + ")"
+}
+
+// Path returns the full structural path of n, from the top of its tree,
+// such as "html > body > div:nth-of-type(2) > p", for log messages and
+// developer tools. Contrary to SelectorFor, ids and classes are ignored,
+// and :nth-of-type() is used where the tag is not enough to distinguish
+// siblings, so that the path of an element is not changed by the
+// insertion of siblings of another type.
+//
+// The path is parsed and checked to select only n in its tree; when needed,
+// the first segment is anchored with :root or :not(* > *), and the tags
+// not written as type selectors (like a foreignObject) are replaced by
+// *:nth-child(). It returns an empty string if n is not an element.
+func Path(n *html.Node) string {
+	if n == nil || n.Type != html.ElementNode {
+		return ""
+	}
+	var elements []*html.Node // from n to the top element
+	for e := n; e != nil && e.Type == html.ElementNode; e = e.Parent {
+		elements = append(elements, e)
+	}
+	top := elements[len(elements)-1]
+	root := top
+	for root.Parent != nil {
+		root = root.Parent
+	}
+
+	for _, strict := range []bool{false, true} {
+		segments := make([]string, len(elements))
+		for i, e := range elements {
+			segments[len(elements)-1-i] = typeSegment(e, strict && e != top)
+		}
+		if strict {
+			if (RootPseudoClassSelector{}).Match(top) {
+				segments[0] += ":root"
+			} else {
+				segments[0] += ":not(* > *)"
+			}
+		}
+		path := strings.Join(segments, " > ")
+		if sel, err := Parse(path); err == nil && sel.Match(n) && isUnique(root, sel) {
+			return path
+		}
+	}
+	// not reached for a valid tree, since the strict path is unique
+	return ""
+}
+
+// typeSegment returns the tag of e, with its position among the siblings
+// of the same type if needed, or always its position among the
+// sibling elements, if strict.
+func typeSegment(e *html.Node, strict bool) string {
+	tag := EscapeIdent(e.Data)
+	if sel, err := Parse(tag); err != nil || !sel.Match(e) {
+		return "*:nth-child(" + strconv.Itoa(childIndex(e)) + ")"
+	}
+	if strict {
+		return tag + ":nth-child(" + strconv.Itoa(childIndex(e)) + ")"
+	}
+	index, ambiguous := 0, false
+	for c := e; c != nil; c = c.PrevSibling {
+		if c.Type == html.ElementNode && c.Data == e.Data && c.Namespace == e.Namespace {
+			index++
+			ambiguous = ambiguous || c != e
+		}
+	}
+	for c := e.NextSibling; c != nil && !ambiguous; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == e.Data && c.Namespace == e.Namespace {
+			ambiguous = true
+		}
+	}
+	if !ambiguous {
+		return tag
+	}
+	return tag + ":nth-of-type(" + strconv.Itoa(index) + ")"
+}
+
+// childIndex returns the 1-based position of e among its sibling elements
+func childIndex(e *html.Node) int {
+	index := 0
+	for c := e; c != nil; c = c.PrevSibling {
+		if c.Type == html.ElementNode {
+			index++
+		}
+	}
+	return index
+}
+
+func nodeID(n *html.Node) string {
+	for _, a := range n.Attr {
+		if a.Key == "id" {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// isUnique returns true if m matches exactly one node in the tree root
+func isUnique(root *html.Node, m Matcher) bool {
+	count := len(QueryAll(root, m))
+	if m.Match(root) {
+		count++
+	}
+	return count == 1
+}
+
+func reverse(list []string) []string {
+	out := make([]string, len(list))
+	for i, s := range list {
+		out[len(list)-1-i] = s
+	}
+	return out
+}
@@ -0,0 +1,109 @@
+package cascadia
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestSelectorFor(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(`<html><body>
+		<div id="main"><ul><li>a</li><li class="x">b</li></ul><p>c</p></div>
+		<div id="main2"><p id="p"></p><p></p><span></span></div>
+		<section><p></p></section>
+		<div id="a.b"><em></em></div>
+	</body></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct{ sel, exp string }{
+		{"#main li.x", "li:nth-child(2)"},
+		{"#main > p", "#main > p"},
+		{"#p", "#p"},
+		{"#main2 > p:nth-child(2)", "#main2 > p:nth-child(2)"},
+		{"span", "span"},
+		{"section > p", "section > p"},
+		{"body", "body"},
+		{"em", "em"},
+		{"#main ul", "ul"},
+	} {
+		n := Query(doc, MustCompile(test.sel))
+		if n == nil {
+			t.Fatalf("%s not found", test.sel)
+		}
+		got := SelectorFor(n)
+		if got != test.exp {
+			t.Errorf("%s: expected %q, got %q", test.sel, test.exp, got)
+		}
+		if matches := QueryAll(doc, MustCompile(got)); len(matches) != 1 || matches[0] != n {
+			t.Errorf("%s: %s is not unique", test.sel, got)
+		}
+	}
+	if SelectorFor(doc) != "" {
+		t.Error("expected empty selector for the document node")
+	}
+
+	n := Query(doc, MustCompile(`[id="a.b"]`))
+	if got := SelectorFor(n); got != `#a\.b` {
+		t.Errorf("unexpected %s", got)
+	}
+
+	// the mixed-case foreign tags are not matched by type selectors
+	svg, err := html.Parse(strings.NewReader(`<svg><foreignObject></foreignObject><foreignObject><p></p></foreignObject></svg>
+		<svg><linearGradient></linearGradient></svg>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range QueryAll(svg, MustCompile("*")) {
+		got := SelectorFor(n)
+		if matches := QueryAll(svg, MustCompile(got)); len(matches) != 1 || matches[0] != n {
+			t.Errorf("<%s>: %s is not unique", n.Data, got)
+		}
+	}
+}
+
+func TestPath(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(`<html><body>
+		<div id="main"><ul><li>a</li><li class="x">b</li></ul><p>c</p></div>
+		<div><p></p><span></span><p id="p"></p></div>
+		<svg><foreignObject><p>f</p></foreignObject></svg>
+	</body></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct{ sel, exp string }{
+		{"html", "html"},
+		{"body", "html > body"},
+		{"li.x", "html > body > div:nth-of-type(1) > ul > li:nth-of-type(2)"},
+		{"#main > p", "html > body > div:nth-of-type(1) > p"},
+		{"#p", "html > body > div:nth-of-type(2) > p:nth-of-type(2)"},
+		{"span", "html > body > div:nth-of-type(2) > span"},
+		{"svg p", "html > body > svg > *:nth-child(1) > p"},
+	} {
+		n := Query(doc, MustCompile(test.sel))
+		if n == nil {
+			t.Fatalf("%s not found", test.sel)
+		}
+		got := Path(n)
+		if got != test.exp {
+			t.Errorf("%s: expected %q, got %q", test.sel, test.exp, got)
+		}
+		if matches := QueryAll(doc, MustCompile(got)); len(matches) != 1 || matches[0] != n {
+			t.Errorf("%s: %s does not select the node", test.sel, got)
+		}
+	}
+	if Path(doc) != "" || Path(nil) != "" {
+		t.Error("expected empty path for the document node")
+	}
+
+	// in a detached tree, the path is anchored at its top
+	div := MustParseHTML(`<div><div><div></div></div></div>`).FirstChild.LastChild.FirstChild
+	div.Parent.RemoveChild(div)
+	if got := Path(div.FirstChild); got != "div:not(* > *) > div:nth-child(1)" {
+		t.Errorf("unexpected path %q", got)
+	}
+	if got := Path(div); got != "div:not(* > *)" {
+		t.Errorf("unexpected path %q", got)
+	}
+}
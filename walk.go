@@ -0,0 +1,42 @@
+package cascadia
+
+// Walk traverses sel in depth-first order: it calls fn(sel), and,
+// if fn returns true, walks each of the components of sel.
+//
+// The components of a CompoundSelector are its simple selectors,
+// the ones of a CombinedSelector are its two operands,
+// and the ones of a relative pseudo-class (like :not()) or of :current()
+// are its arguments.
+func Walk(sel Sel, fn func(Sel) bool) {
+	if sel == nil || !fn(sel) {
+		return
+	}
+	for _, c := range children(sel) {
+		Walk(c, fn)
+	}
+}
+
+// WalkGroup calls Walk on each selector of group.
+func WalkGroup(group SelectorGroup, fn func(Sel) bool) {
+	for _, sel := range group {
+		Walk(sel, fn)
+	}
+}
+
+// children returns the direct components of sel
+func children(sel Sel) []Sel {
+	switch s := sel.(type) {
+	case CompoundSelector:
+		return s.Selectors
+	case CombinedSelector:
+		if s.Second == nil {
+			return []Sel{s.First}
+		}
+		return []Sel{s.First, s.Second}
+	case RelativePseudoClassSelector:
+		return s.Args
+	case TimePseudoClassSelector:
+		return s.Args
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package cascadia
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	sel, err := Parse("div.a > p.b:not(.c, span.d) + .a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var classes []string
+	Walk(sel, func(s Sel) bool {
+		if c, ok := s.(ClassSelector); ok {
+			classes = append(classes, c.Class)
+		}
+		return true
+	})
+	if exp := []string{"a", "b", "c", "d", "a"}; !reflect.DeepEqual(classes, exp) {
+		t.Errorf("expected %v, got %v", exp, classes)
+	}
+
+	// do not enter :not()
+	classes = classes[:0]
+	Walk(sel, func(s Sel) bool {
+		if c, ok := s.(ClassSelector); ok {
+			classes = append(classes, c.Class)
+		}
+		_, isRelative := s.(RelativePseudoClassSelector)
+		return !isRelative
+	})
+	if exp := []string{"a", "b", "a"}; !reflect.DeepEqual(classes, exp) {
+		t.Errorf("expected %v, got %v", exp, classes)
+	}
+
+	group, err := ParseGroup("a, b, c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	WalkGroup(group, func(Sel) bool { count++; return true })
+	if count != 3 {
+		t.Errorf("expected 3 selectors, got %d", count)
+	}
+}
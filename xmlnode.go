@@ -0,0 +1,119 @@
+package cascadia
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+
+	"golang.org/x/net/html"
+)
+
+// XMLNode is a node of a tree built from an XML document
+// by ParseXML, which implements Node.
+//
+// Since the parser lower-cases the tag and attribute names of the
+// selectors, Data and Attributes return lower-cased local names:
+// the original names are available in Name and Attr.
+type XMLNode struct {
+	Kind html.NodeType // one of DocumentNode, ElementNode, TextNode, CommentNode
+	Name xml.Name      // for elements
+	Attr []xml.Attr    // for elements
+	Text string        // for text and comment nodes
+
+	parent, firstChild, lastChild, prevSibling, nextSibling *XMLNode
+
+	data  string           // lower-cased local name, or Text
+	attrs []html.Attribute // lower-cased attributes
+}
+
+// ParseXML reads the XML document from r and returns its root,
+// which is a DocumentNode.
+func ParseXML(r io.Reader) (*XMLNode, error) {
+	return ParseXMLDecoder(xml.NewDecoder(r))
+}
+
+// ParseXMLDecoder is like ParseXML, but reads the tokens from d, which
+// may be configured to accept non-strict XML (see xml.Decoder.Strict).
+// Processing instructions and directives are ignored.
+func ParseXMLDecoder(d *xml.Decoder) (*XMLNode, error) {
+	root := &XMLNode{Kind: html.DocumentNode}
+	current := root
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			n := &XMLNode{Kind: html.ElementNode, Name: tok.Name, Attr: tok.Copy().Attr, data: toLowerASCII(tok.Name.Local)}
+			n.attrs = make([]html.Attribute, len(n.Attr))
+			for i, a := range n.Attr {
+				n.attrs[i] = html.Attribute{Namespace: a.Name.Space, Key: toLowerASCII(a.Name.Local), Val: a.Value}
+			}
+			current.appendChild(n)
+			current = n
+		case xml.EndElement:
+			if current == root {
+				return nil, errors.New("unexpected end element " + tok.Name.Local)
+			}
+			current = current.parent
+		case xml.CharData:
+			current.appendChild(&XMLNode{Kind: html.TextNode, Text: string(tok), data: string(tok)})
+		case xml.Comment:
+			current.appendChild(&XMLNode{Kind: html.CommentNode, Text: string(tok), data: string(tok)})
+		}
+	}
+	if current != root {
+		return nil, errors.New("unexpected EOF: element " + current.Name.Local + " is not closed")
+	}
+	return root, nil
+}
+
+func (n *XMLNode) appendChild(c *XMLNode) {
+	c.parent = n
+	if n.lastChild == nil {
+		n.firstChild = c
+	} else {
+		n.lastChild.nextSibling, c.prevSibling = c, n.lastChild
+	}
+	n.lastChild = c
+}
+
+// xmlNode avoids typed nil interfaces
+func xmlNode(n *XMLNode) Node {
+	if n == nil {
+		return nil
+	}
+	return n
+}
+
+func (n *XMLNode) Type() html.NodeType          { return n.Kind }
+func (n *XMLNode) Data() string                 { return n.data }
+func (n *XMLNode) Namespace() string            { return n.Name.Space }
+func (n *XMLNode) Attributes() []html.Attribute { return n.attrs }
+func (n *XMLNode) Parent() Node                 { return xmlNode(n.parent) }
+func (n *XMLNode) FirstChild() Node             { return xmlNode(n.firstChild) }
+func (n *XMLNode) LastChild() Node              { return xmlNode(n.lastChild) }
+func (n *XMLNode) PrevSibling() Node            { return xmlNode(n.prevSibling) }
+func (n *XMLNode) NextSibling() Node            { return xmlNode(n.nextSibling) }
+
+// QueryAll returns the elements matching m, from the descendants of n.
+func (n *XMLNode) QueryAll(m NodeMatcher) []*XMLNode {
+	var out []*XMLNode
+	for _, c := range QueryAllNodes(n, m) {
+		out = append(out, c.(*XMLNode))
+	}
+	return out
+}
+
+// Query returns the first element matching m, from the descendants of n,
+// or nil.
+func (n *XMLNode) Query(m NodeMatcher) *XMLNode {
+	if c := QueryNode(n, m); c != nil {
+		return c.(*XMLNode)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package cascadia
+
+import (
+	"strings"
+	"testing"
+)
+
+const testFeed = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom" xmlns:media="http://search.yahoo.com/mrss/">
+	<title>Example</title>
+	<!-- entries -->
+	<entry id="a"><title>First</title><link rel="alternate" href="/1"/></entry>
+	<entry id="b" class="hot"><title>Second</title><media:thumbnail url="t.png"/></entry>
+	<svg viewBox="0 0 10 10"><linearGradient id="g"/></svg>
+</feed>`
+
+func TestXMLNode(t *testing.T) {
+	root, err := ParseXML(strings.NewReader(testFeed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		sel string
+		exp []string // local names or ids
+	}{
+		{"entry", []string{"a", "b"}},
+		{"entry.hot > title", []string{"Second"}},
+		{"feed > title", []string{"Example"}},
+		{"entry:nth-child(3)", []string{"b"}},
+		{"link[rel=alternate]", []string{"link"}},
+		{"thumbnail[url$=png]", []string{"thumbnail"}},
+		{"entry + entry", []string{"b"}},
+		{"title:contains(sec)", []string{"Second"}},
+		{"entry:has(link)", []string{"a"}},
+		{"svg[viewBox] linearGradient", []string{"linearGradient"}},
+		{":root", []string{"feed"}},
+		{"title:empty", nil},
+	} {
+		group, err := ParseGroup(test.sel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, n := range root.QueryAll(group) {
+			switch {
+			case n.Name.Local == "entry":
+				got = append(got, n.Attr[0].Value)
+			case n.Name.Local == "title":
+				got = append(got, n.firstChild.Text)
+			default:
+				got = append(got, n.Name.Local)
+			}
+		}
+		if strings.Join(got, ",") != strings.Join(test.exp, ",") {
+			t.Errorf("%s: expected %v, got %v", test.sel, test.exp, got)
+		}
+	}
+
+	thumb := root.Query(mustParseGroup(t, "thumbnail"))
+	if thumb == nil || thumb.Namespace() != "http://search.yahoo.com/mrss/" {
+		t.Errorf("unexpected thumbnail %v", thumb)
+	}
+	if root.Query(mustParseGroup(t, "nope")) != nil {
+		t.Error("expected no match")
+	}
+}
+
+// mustParseGroup parses sel as a group, failing the test on error
+func mustParseGroup(t *testing.T, sel string) SelectorGroup {
+	group, err := ParseGroup(sel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return group
+}
+
+func TestParseXMLErrors(t *testing.T) {
+	for _, input := range []string{
+		"<a><b></a>",
+		"<a>",
+		"<a></a></b>",
+	} {
+		if _, err := ParseXML(strings.NewReader(input)); err == nil {
+			t.Errorf("%s: expected an error", input)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package cascadia
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// This file implements the translation of selectors to XPath 1.0
+// expressions, following the conventions of the Python cssselect package.
+
+// ToXPath translates sel into an XPath 1.0 expression selecting the same
+// elements, relative to the context node (and including it).
+// For instance, div > p.a is translated to
+//
+//	descendant-or-self::div/p[contains(concat(' ', normalize-space(@class), ' '), ' a ')]
+//
+// An error is returned for the constructs without an XPath equivalent,
+// like regular expressions, pseudo-elements or :enabled.
+func ToXPath(sel Sel) (string, error) {
+	var x xpathWriter
+	if err := x.writeChain(sel, "descendant-or-self::"); err != nil {
+		return "", err
+	}
+	return x.String(), nil
+}
+
+// ToXPathGroup is like ToXPath, for the union of the selectors of group.
+func ToXPathGroup(group SelectorGroup) (string, error) {
+	parts := make([]string, len(group))
+	for i, sel := range group {
+		var err error
+		parts[i], err = ToXPath(sel)
+		if err != nil {
+			return "", err
+		}
+	}
+	return strings.Join(parts, " | "), nil
+}
+
+type xpathWriter struct {
+	strings.Builder
+}
+
+// writeChain writes the path steps for sel, the first one
+// using the given axis
+func (x *xpathWriter) writeChain(sel Sel, axis string) error {
+	if comb, ok := sel.(CombinedSelector); ok {
+		if comb.Second == nil {
+			return x.writeChain(comb.First, axis)
+		}
+		if err := x.writeChain(comb.First, axis); err != nil {
+			return err
+		}
+		switch comb.Combinator {
+		case ' ':
+			return x.writeStep(comb.Second, "/descendant-or-self::*/")
+		case '>':
+			return x.writeStep(comb.Second, "/")
+		case '+':
+			return x.writeStep(comb.Second, "/following-sibling::*[1]/self::")
+		case '~':
+			return x.writeStep(comb.Second, "/following-sibling::")
+		default:
+			return fmt.Errorf("unsupported combinator %q in XPath", comb.Combinator)
+		}
+	}
+	return x.writeStep(sel, axis)
+}
+
+// writeStep writes axis, followed by the element test and the predicates of sel,
+// which must be a compound (or simple) selector
+func (x *xpathWriter) writeStep(sel Sel, axis string) error {
+	var simples []Sel
+	switch s := sel.(type) {
+	case CompoundSelector:
+		if s.Pseudo != "" {
+			return fmt.Errorf("pseudo-element ::%s can't be translated to XPath", s.Pseudo)
+		}
+		simples = s.Selectors
+	case CombinedSelector:
+		return fmt.Errorf("unexpected combined selector %s in XPath step", s)
+	default:
+		simples = []Sel{sel}
+	}
+
+	tag := "*"
+	var predicates []Sel
+	for _, s := range simples {
+		if t, ok := s.(TagSelector); ok && tag == "*" {
+			tag = t.Tag
+		} else {
+			predicates = append(predicates, s)
+		}
+	}
+	x.WriteString(axis)
+	x.WriteString(tag)
+	for _, s := range predicates {
+		cond, err := xpathCondition(s, tag)
+		if err != nil {
+			return err
+		}
+		x.WriteByte('[')
+		x.WriteString(cond)
+		x.WriteByte(']')
+	}
+	return nil
+}
+
+// xpathCondition returns the XPath boolean expression for the
+// simple selector s, evaluated on an element (whose tag is given, or "*")
+func xpathCondition(s Sel, tag string) (string, error) {
+	switch s := s.(type) {
+	case TagSelector:
+		return "name() = " + xpathLiteral(s.Tag), nil
+	case IDSelector:
+		return "@id = " + xpathLiteral(s.ID), nil
+	case ClassSelector:
+		return xpathIncludes("class", s.Class), nil
+	case AttrSelector:
+		return xpathAttribute(s)
+	case NeverMatchSelector:
+		return "false()", nil
+	case NthPseudoClassSelector:
+		return xpathNth(s, tag)
+	case OnlyChildPseudoClassSelector:
+		test := "*"
+		if s.OfType {
+			if tag == "*" {
+				return "", fmt.Errorf("%s requires a type selector in XPath", s)
+			}
+			test = tag
+		}
+		return fmt.Sprintf("count(preceding-sibling::%s) = 0 and count(following-sibling::%s) = 0", test, test), nil
+	case EmptyElementPseudoClassSelector:
+		return "not(*) and not(text()[normalize-space()])", nil
+	case RootPseudoClassSelector:
+		return "not(parent::*)", nil
+	case ScopePseudoClassSelector:
+		if s.scope != nil {
+			return "", fmt.Errorf("%s bound to an element is not supported in XPath", s)
+		}
+		return "not(parent::*)", nil
+	case NestingSelector:
+		if s.scope != nil {
+			return "", fmt.Errorf("%s bound to an element is not supported in XPath", s)
+		}
+		return "not(parent::*)", nil
+	case LinkPseudoClassSelector:
+		return "@href and (name() = 'a' or name() = 'area' or name() = 'link')", nil
+	case InputPseudoClassSelector:
+		return "name() = 'input' or name() = 'select' or name() = 'textarea' or name() = 'button'", nil
+	case LangPseudoClassSelector:
+		// only the nearest lang attribute applies, and is case-insensitive
+		lang, lower := toLowerASCII(s.Lang), xpathLower("@lang")
+		return fmt.Sprintf("ancestor-or-self::*[@lang][1][%s = %s or starts-with(%s, %s)]",
+			lower, xpathLiteral(lang), lower, xpathLiteral(lang+"-")), nil
+	case ContainsPseudoClassSelector:
+		if s.Own {
+			// approximation: the value is searched in each text node,
+			// instead of their concatenation
+			return fmt.Sprintf("text()[contains(%s, %s)]", xpathLower("."), xpathLiteral(s.Value)), nil
+		}
+		return fmt.Sprintf("contains(%s, %s)", xpathLower("string(.)"), xpathLiteral(s.Value)), nil
+	case RelativePseudoClassSelector:
+		return xpathRelative(s)
+	}
+	return "", fmt.Errorf("%s can't be translated to XPath", s)
+}
+
+func xpathAttribute(s AttrSelector) (string, error) {
+	attr, val := "@"+s.writtenKey(), xpathLiteral(s.Val)
+	switch s.Operation {
+	case "":
+		return attr, nil
+	case "=":
+		return attr + " = " + val, nil
+	case "!=":
+		return fmt.Sprintf("not(%s = %s)", attr, val), nil
+	case "~=":
+		if (s.Val == "" || containsWhitespace(s.Val)) && !s.legacyInclude {
+			return "false()", nil
+		}
+		return xpathIncludes(s.writtenKey(), s.Val), nil
+	case "|=":
+		return fmt.Sprintf("%s = %s or starts-with(%s, %s)", attr, val, attr, xpathLiteral(s.Val+"-")), nil
+	case "^=":
+		return fmt.Sprintf("normalize-space(%s) and starts-with(%s, %s)", attr, attr, val), nil
+	case "$=":
+		// string-length counts the characters, not the bytes
+		return fmt.Sprintf("normalize-space(%s) and substring(%s, string-length(%s) - %d) = %s",
+			attr, attr, attr, utf8.RuneCountInString(s.Val)-1, val), nil
+	case "*=":
+		return fmt.Sprintf("normalize-space(%s) and contains(%s, %s)", attr, attr, val), nil
+	}
+	return "", fmt.Errorf("attribute operator %s can't be translated to XPath", s.Operation)
+}
+
+// xpathIncludes tests if the whitespace-separated list key contains val
+func xpathIncludes(key, val string) string {
+	return fmt.Sprintf("contains(concat(' ', normalize-space(@%s), ' '), %s)", key, xpathLiteral(" "+val+" "))
+}
+
+func xpathNth(s NthPseudoClassSelector, tag string) (string, error) {
+	test := "*"
+	if s.OfType {
+		if tag == "*" {
+			return "", fmt.Errorf("%s requires a type selector in XPath", s)
+		}
+		test = tag
+	}
+	axis := "preceding-sibling"
+	if s.Last {
+		axis = "following-sibling"
+	}
+	// position of the element, starting at 1
+	pos := fmt.Sprintf("count(%s::%s) + 1", axis, test)
+	if s.A == 0 {
+		return fmt.Sprintf("%s = %d", pos, s.B), nil
+	}
+	// pos = a*n + b for some n >= 0
+	diff := fmt.Sprintf("(%s - %d)", pos, s.B)
+	return fmt.Sprintf("%s mod %d = 0 and %s * %d >= 0", diff, s.A, diff, s.A), nil
+}
+
+func xpathRelative(s RelativePseudoClassSelector) (string, error) {
+	var axis string
+	switch s.Name {
+	case "not", "is", "where":
+		axis = "self::"
+	case "has":
+		axis = "descendant::"
+	case "haschild":
+		axis = "child::"
+	}
+	parts := make([]string, len(s.Args))
+	for i, arg := range s.Args {
+		var x xpathWriter
+		if axis == "self::" && HasCombinators(arg) {
+			return "", fmt.Errorf("complex selector %s in :%s() can't be translated to XPath", arg, s.Name)
+		}
+		if err := x.writeChain(arg, axis); err != nil {
+			return "", err
+		}
+		parts[i] = x.String()
+	}
+	cond := strings.Join(parts, " or ")
+	if s.Name == "not" {
+		return "not(" + cond + ")", nil
+	}
+	return cond, nil
+}
+
+// xpathLower returns an expression lower-casing the ASCII letters of expr
+func xpathLower(expr string) string {
+	return fmt.Sprintf("translate(%s, 'ABCDEFGHIJKLMNOPQRSTUVWXYZ', 'abcdefghijklmnopqrstuvwxyz')", expr)
+}
+
+// xpathLiteral returns an XPath string literal for s,
+// which has no escape mechanism
+func xpathLiteral(s string) string {
+	if !strings.ContainsRune(s, '\'') {
+		return "'" + s + "'"
+	}
+	if !strings.ContainsRune(s, '"') {
+		return `"` + s + `"`
+	}
+	parts := strings.Split(s, "'")
+	for i, p := range parts {
+		parts[i] = "'" + p + "'"
+	}
+	return "concat(" + strings.Join(parts, `, "'", `) + ")"
+}
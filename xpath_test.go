@@ -0,0 +1,66 @@
+package cascadia
+
+import "testing"
+
+func TestToXPath(t *testing.T) {
+	for _, test := range []struct{ sel, exp string }{
+		{"div", "descendant-or-self::div"},
+		{"*", "descendant-or-self::*"},
+		{"#a", "descendant-or-self::*[@id = 'a']"},
+		{"p.a", "descendant-or-self::p[contains(concat(' ', normalize-space(@class), ' '), ' a ')]"},
+		{"div > p", "descendant-or-self::div/p"},
+		{"div p", "descendant-or-self::div/descendant-or-self::*/p"},
+		{"h1 + p", "descendant-or-self::h1/following-sibling::*[1]/self::p"},
+		{"h1 ~ p", "descendant-or-self::h1/following-sibling::p"},
+		{"[href]", "descendant-or-self::*[@href]"},
+		{"svg[viewBox]", "descendant-or-self::svg[@viewBox]"},
+		{`[title="it's"]`, `descendant-or-self::*[@title = "it's"]`},
+		{`[title="say \"it's\""]`, `descendant-or-self::*[@title = concat('say "it', "'", 's"')]`},
+		{"[lang|=en]", "descendant-or-self::*[@lang = 'en' or starts-with(@lang, 'en-')]"},
+		{"[a^=x]", "descendant-or-self::*[normalize-space(@a) and starts-with(@a, 'x')]"},
+		{"[a$=xyz]", "descendant-or-self::*[normalize-space(@a) and substring(@a, string-length(@a) - 2) = 'xyz']"},
+		{"[a$=été]", "descendant-or-self::*[normalize-space(@a) and substring(@a, string-length(@a) - 2) = 'été']"},
+		{"[a!=x]", "descendant-or-self::*[not(@a = 'x')]"},
+		{"li:first-child", "descendant-or-self::li[count(preceding-sibling::*) + 1 = 1]"},
+		{"li:nth-of-type(2n+1)", "descendant-or-self::li[(count(preceding-sibling::li) + 1 - 1) mod 2 = 0 and (count(preceding-sibling::li) + 1 - 1) * 2 >= 0]"},
+		{"li:nth-last-child(-n+3)", "descendant-or-self::li[(count(following-sibling::*) + 1 - 3) mod -1 = 0 and (count(following-sibling::*) + 1 - 3) * -1 >= 0]"},
+		{"p:not(.a, span)", "descendant-or-self::p[not(self::*[contains(concat(' ', normalize-space(@class), ' '), ' a ')] or self::span)]"},
+		{"div:has(> p)", ""},
+		{"div:has(p a)", "descendant-or-self::div[descendant::p/descendant-or-self::*/a]"},
+		{"p:empty", "descendant-or-self::p[not(*) and not(text()[normalize-space()])]"},
+		{"p:lang(en)", "descendant-or-self::p[ancestor-or-self::*[@lang][1][translate(@lang, 'ABCDEFGHIJKLMNOPQRSTUVWXYZ', 'abcdefghijklmnopqrstuvwxyz') = 'en' or " +
+			"starts-with(translate(@lang, 'ABCDEFGHIJKLMNOPQRSTUVWXYZ', 'abcdefghijklmnopqrstuvwxyz'), 'en-')]]"},
+	} {
+		sel, err := Parse(test.sel)
+		if err != nil {
+			if test.exp == "" {
+				continue
+			}
+			t.Fatal(err)
+		}
+		got, err := ToXPath(sel)
+		if err != nil {
+			t.Fatalf("%s: %s", test.sel, err)
+		}
+		if got != test.exp {
+			t.Errorf("%s: expected\n%s\ngot\n%s", test.sel, test.exp, got)
+		}
+	}
+}
+
+func TestToXPathErrors(t *testing.T) {
+	for _, input := range []string{"p:matches(x)", "[a#=(x)]", "p::before", ":nth-of-type(2)", ":enabled", ":not(a b)"} {
+		sel, err := ParseWithPseudoElement(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ToXPath(sel); err == nil {
+			t.Errorf("%s: expected an error", input)
+		}
+	}
+
+	group, _ := ParseGroup("a, b")
+	if x, err := ToXPathGroup(group); err != nil || x != "descendant-or-self::a | descendant-or-self::b" {
+		t.Errorf("unexpected %s %v", x, err)
+	}
+}